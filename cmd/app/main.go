@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -31,22 +30,33 @@ func main() {
 		}
 	}()
 
-	db, err := psql.Connect(fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.Host, cfg.DBPort, cfg.User, cfg.Password, cfg.DBName))
+	db, err := psql.Connect(cfg.PostgresConfig.ConnString())
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 
-	application := app.New(logger, cfg.GRPCPort, db)
+	application := app.New(logger, cfg, db)
 	go application.GRPCServer.Run()
+	go application.Purger.Run()
+	go application.Presence.Run()
+	go application.Relay.Run()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
 	<-stop
 
+	//Слушатель изменений закрывается первым: потоки WatchUsers завершаются и не задерживают остановку grpc сервера
+	application.Changes.Close()
+	//Карта присутствия закрывает каналы подписчиков: потоки WatchPresence тоже завершаются до остановки grpc сервера
+	application.Tracker.Close()
 	application.GRPCServer.Stop()
+	application.Purger.Stop()
+	application.Presence.Stop()
+	application.Relay.Stop()
+	//Соединение с брокером закрывается после остановки публикации событий
+	application.Events.Close()
 
 	logger.Info("app stopped")
 }
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"net/url"
+	"strings"
 
+	"github.com/al3ksus/messengerusers/internal/lib/username"
+	"github.com/al3ksus/messengerusers/internal/repositories/psql"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+
+	_ "github.com/lib/pq"
 )
 
 func main() {
@@ -41,13 +48,57 @@ func main() {
 	}
 
 	if err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			fmt.Println("no migrations to apply")
-			return
+		if !errors.Is(err, migrate.ErrNoChange) {
+			panic(err)
 		}
 
+		fmt.Println("no migrations to apply")
+	} else {
+		fmt.Println("migration successfull")
+	}
+
+	//Пересчет выполняется и без новых миграций, чтобы подхватить изменения политики username
+	if upOrDown == "up" {
+		canonicalizeUsernames(database)
+	}
+}
+
+// canonicalizeUsernames пересчитывает канонические формы username политикой приложения.
+// SQL миграция заполняет только предварительную форму, так как NFKC и замена похожих символов реализованы в Go.
+// Вызывает панику в случае ошибки.
+func canonicalizeUsernames(database string) {
+	db, err := psql.Connect(connString(database))
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	policy := username.NewPolicy(0, 0, nil)
+	updated, conflicts, err := psql.New(db).CanonicalizeUsernames(context.Background(), policy.Canonical)
+	if err != nil {
 		panic(err)
 	}
 
-	fmt.Println("migration successfull")
+	fmt.Printf("canonical usernames updated: %d\n", updated)
+	if len(conflicts) > 0 {
+		fmt.Printf("username conflicts need manual resolution (see table username_conflicts), user ids: %v\n", conflicts)
+	}
+}
+
+// connString возвращает адрес базы данных без параметров migrate (x-*), которые не поддерживает драйвер lib/pq.
+func connString(database string) string {
+	u, err := url.Parse(database)
+	if err != nil {
+		return database
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "x-") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
 }
@@ -2,29 +2,215 @@ package app
 
 import (
 	"database/sql"
+	"io"
 
 	"github.com/al3ksus/messengerusers/internal/app/grpcapp"
+	"github.com/al3ksus/messengerusers/internal/app/presenceapp"
+	"github.com/al3ksus/messengerusers/internal/app/purgerapp"
+	"github.com/al3ksus/messengerusers/internal/app/relayapp"
+	"github.com/al3ksus/messengerusers/internal/config"
+	usersgrpc "github.com/al3ksus/messengerusers/internal/grpc/users"
+	"github.com/al3ksus/messengerusers/internal/lib/aead"
 	"github.com/al3ksus/messengerusers/internal/lib/crypt"
+	"github.com/al3ksus/messengerusers/internal/lib/events"
+	"github.com/al3ksus/messengerusers/internal/lib/jwt"
+	"github.com/al3ksus/messengerusers/internal/lib/notifier"
+	"github.com/al3ksus/messengerusers/internal/lib/password"
+	"github.com/al3ksus/messengerusers/internal/lib/totp"
+	"github.com/al3ksus/messengerusers/internal/lib/username"
 	"github.com/al3ksus/messengerusers/internal/logger"
+	"github.com/al3ksus/messengerusers/internal/repositories/memory"
 	"github.com/al3ksus/messengerusers/internal/repositories/psql"
 	"github.com/al3ksus/messengerusers/internal/services/users"
 )
 
 type App struct {
 	GRPCServer *grpcapp.GRPCServer
+	Purger     *purgerapp.PurgerApp
+	Presence   *presenceapp.PresenceApp
+	Relay      *relayapp.RelayApp
+	Changes    *psql.ChangeListener
+	Tracker    io.Closer
+	Events     io.Closer
 }
 
-func New(log logger.Logger, gRPCPort int, db *sql.DB) *App {
+// eventPublisher - публикатор событий, соединение которого закрывается при остановке приложения.
+type eventPublisher interface {
+	users.EventPublisher
+	io.Closer
+}
+
+func New(log logger.Logger, cfg *config.Config, db *sql.DB) *App {
 	//Репозиторий (DAO)
 	rep := psql.New(db)
-	crypter := &crypt.Crypter{}
+	crypter, err := crypt.New(crypt.Params{
+		Algorithm:     cfg.HashConfig.Algorithm,
+		BcryptCost:    cfg.HashConfig.BcryptCost,
+		Argon2Time:    cfg.HashConfig.Argon2Time,
+		Argon2Memory:  cfg.HashConfig.Argon2Memory,
+		Argon2Threads: cfg.HashConfig.Argon2Threads,
+		ScryptLogN:    cfg.HashConfig.ScryptLogN,
+		ScryptR:       cfg.HashConfig.ScryptR,
+		ScryptP:       cfg.HashConfig.ScryptP,
+	})
+	if err != nil {
+		panic("error creating crypter. " + err.Error())
+	}
+	tokenManager := jwt.New(cfg.TokenConfig.Secret, cfg.TokenConfig.AccessTTL, cfg.TokenConfig.RefreshTTL)
+	//Канал доставки кодов сброса пароля
+	var resetNotifier users.Notifier
+	switch cfg.NotifierConfig.Kind {
+	case "webhook":
+		resetNotifier, err = notifier.NewWebhookNotifier(cfg.NotifierConfig.WebhookURL, cfg.NotifierConfig.WebhookToken, cfg.NotifierConfig.Timeout)
+		if err != nil {
+			panic("error creating notifier. " + err.Error())
+		}
+	case "log", "":
+		//Лог раскрывает коды сброса в открытом виде, поэтому допустим только при локальном запуске
+		if cfg.Env != config.EnvDev {
+			panic("notifier is not configured. log notifier is allowed only in " + config.EnvDev + " env")
+		}
+		resetNotifier = notifier.NewLogNotifier(log)
+	default:
+		panic("unknown notifier: " + cfg.NotifierConfig.Kind)
+	}
+	passwordPolicy := password.NewPolicy(
+		cfg.PasswordPolicyConfig.MinLength,
+		cfg.PasswordPolicyConfig.MaxLength,
+		cfg.PasswordPolicyConfig.MinClasses,
+		crypt.MaxPasswordBytes(cfg.HashConfig.Algorithm),
+		password.MustLoadDenyList(cfg.PasswordPolicyConfig.DenyListPath),
+	)
+	secretCipher, err := aead.New(cfg.MFAConfig.EncryptionKey)
+	if err != nil {
+		panic("error creating secret cipher. " + err.Error())
+	}
+	totpManager := totp.New(cfg.MFAConfig.Issuer, cfg.MFAConfig.Skew)
+	usernamePolicy := username.NewPolicy(
+		cfg.UsernamePolicyConfig.MinLength,
+		cfg.UsernamePolicyConfig.MaxLength,
+		cfg.UsernamePolicyConfig.Reserved,
+	)
+	//Присутствие пользователей в сети хранится в памяти процесса
+	presenceTracker := memory.NewPresenceTracker(cfg.PresenceConfig.Shards)
+	//Брокер, через который события жизненного цикла пользователей доходят до других сервисов
+	var publisher eventPublisher
+	switch cfg.EventsConfig.Broker {
+	case "nats":
+		publisher, err = events.NewNATSPublisher(events.NATSParams{
+			URL:           cfg.EventsConfig.NATSURL,
+			SubjectPrefix: cfg.EventsConfig.SubjectPrefix,
+			Stream:        cfg.EventsConfig.Stream,
+			Timeout:       cfg.EventsConfig.PublishTimeout,
+			Token:         cfg.EventsConfig.NATSToken,
+			CredsFile:     cfg.EventsConfig.NATSCredsFile,
+			TLSCAFile:     cfg.EventsConfig.NATSTLSCAFile,
+			TLSCertFile:   cfg.EventsConfig.NATSTLSCertFile,
+			TLSKeyFile:    cfg.EventsConfig.NATSTLSKeyFile,
+		})
+		if err != nil {
+			panic("error creating event publisher. " + err.Error())
+		}
+	case "memory":
+		publisher = events.NewMemoryPublisher()
+	default:
+		panic("unknown events broker: " + cfg.EventsConfig.Broker)
+	}
+
+	//Уведомления о новых изменениях пользователей для потоков WatchUsers
+	changeListener, err := psql.NewChangeListener(
+		cfg.PostgresConfig.ConnString(),
+		cfg.ChangesConfig.ListenerMinReconnect,
+		cfg.ChangesConfig.ListenerMaxReconnect,
+	)
+	if err != nil {
+		panic("error creating change listener. " + err.Error())
+	}
 
 	//Сервис
-	users := users.New(log, rep, rep, crypter)
+	users := users.New(log, users.Deps{
+		UserSaver:            rep,
+		UserProvider:         rep,
+		Crypter:              crypter,
+		TokenManager:         tokenManager,
+		SessionSaver:         rep,
+		SessionProvider:      rep,
+		ResetCodeSaver:       rep,
+		ResetCodeProvider:    rep,
+		Notifier:             resetNotifier,
+		PasswordPolicy:       passwordPolicy,
+		UsernamePolicy:       usernamePolicy,
+		AttemptTracker:       rep,
+		SecondFactorSaver:    rep,
+		SecondFactorProvider: rep,
+		TOTPManager:          totpManager,
+		SecretEncrypter:      secretCipher,
+		ContactSaver:         rep,
+		ContactProvider:      rep,
+		BlockSaver:           rep,
+		BlockProvider:        rep,
+		PresenceTracker:      presenceTracker,
+		PresenceSaver:        rep,
+		PresenceProvider:     rep,
+		PrivacySaver:         rep,
+		PrivacyProvider:      rep,
+		DeviceSaver:          rep,
+		DeviceProvider:       rep,
+		EventOutbox:          rep,
+		EventPublisher:       publisher,
+		ChangeProvider:       rep,
+		ChangeListener:       changeListener,
+	}, users.Params{
+		ResetCodeTTL:  cfg.PasswordResetConfig.CodeTTL,
+		MaxResetCodes: cfg.PasswordResetConfig.MaxActiveCodes,
+		ResetThrottle: users.ThrottleParams{
+			Window:           cfg.PasswordResetConfig.Window,
+			UserFreeAttempts: cfg.PasswordResetConfig.UserFreeRequests,
+			IPFreeAttempts:   cfg.PasswordResetConfig.IPFreeRequests,
+			BaseLockout:      cfg.PasswordResetConfig.BaseLockout,
+			MaxLockout:       cfg.PasswordResetConfig.MaxLockout,
+		},
+		Throttle: users.ThrottleParams{
+			Window:           cfg.LoginThrottleConfig.Window,
+			UserFreeAttempts: cfg.LoginThrottleConfig.UserFreeAttempts,
+			IPFreeAttempts:   cfg.LoginThrottleConfig.IPFreeAttempts,
+			BaseLockout:      cfg.LoginThrottleConfig.BaseLockout,
+			MaxLockout:       cfg.LoginThrottleConfig.MaxLockout,
+		},
+		ChallengeTTL: cfg.MFAConfig.ChallengeTTL,
+		MaxBatchSize: cfg.LookupConfig.MaxBatchSize,
+		PageSize: users.PageSizeParams{
+			Default: cfg.LookupConfig.DefaultPageSize,
+			Max:     cfg.LookupConfig.MaxPageSize,
+		},
+		UsernameReservation:  cfg.UsernamePolicyConfig.ReservationPeriod,
+		DeletionGracePeriod:  cfg.DeletionConfig.GracePeriod,
+		PresenceTTL:          cfg.PresenceConfig.OnlineTTL,
+		SessionTouchInterval: cfg.TokenConfig.SessionTouchInterval,
+		ChangePollInterval:   cfg.ChangesConfig.PollInterval,
+		ChangeRetention:      cfg.ChangesConfig.Retention,
+	})
+	//Шлюзы, от которых принимается IP клиента из x-forwarded-for
+	trustedProxies, err := usersgrpc.ParseTrustedProxies(cfg.GRPCConfig.TrustedProxies)
+	if err != nil {
+		panic("error parsing trusted proxies. " + err.Error())
+	}
 	//обертка grpc сервера
-	grpcApp := grpcapp.New(log, gRPCPort, users)
+	grpcApp := grpcapp.New(log, cfg.GRPCPort, users, trustedProxies)
+	//Фоновая очистка удаленных пользователей
+	purgerApp := purgerapp.New(log, users, cfg.DeletionConfig.PurgeInterval, cfg.DeletionConfig.PurgeBatch)
+	//Фоновое сохранение времени последней активности
+	presenceApp := presenceapp.New(log, users, cfg.PresenceConfig.FlushInterval)
+	//Фоновая публикация событий из outbox
+	relayApp := relayapp.New(log, users, cfg.EventsConfig.RelayInterval, cfg.EventsConfig.RelayBatch)
 
 	return &App{
 		GRPCServer: grpcApp,
+		Purger:     purgerApp,
+		Presence:   presenceApp,
+		Relay:      relayApp,
+		Changes:    changeListener,
+		Tracker:    presenceTracker,
+		Events:     publisher,
 	}
 }
@@ -3,6 +3,7 @@ package grpcapp
 import (
 	"fmt"
 	"net"
+	"net/netip"
 
 	usersgrpc "github.com/al3ksus/messengerusers/internal/grpc/users"
 	"github.com/al3ksus/messengerusers/internal/logger"
@@ -18,9 +19,10 @@ type GRPCServer struct {
 }
 
 // New - контсруктор для типа *GRPCServer.
-func New(log logger.Logger, port int, users usersgrpc.Users) *GRPCServer {
+// trustedProxies - адреса шлюзов, которым разрешено передавать IP клиента в заголовке x-forwarded-for.
+func New(log logger.Logger, port int, users usersgrpc.Users, trustedProxies []netip.Prefix) *GRPCServer {
 	grpcServer := grpc.NewServer()
-	usersgrpc.Register(grpcServer, users)
+	usersgrpc.Register(grpcServer, users, trustedProxies)
 	return &GRPCServer{
 		log:        log,
 		grpcServer: grpcServer,
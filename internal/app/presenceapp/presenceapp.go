@@ -0,0 +1,81 @@
+package presenceapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/logger"
+)
+
+// flushTimeout - время на сохранение активности при остановке.
+const flushTimeout = 5 * time.Second
+
+// Flusher предоставляет метод сохранения активности пользователей из карты присутствия.
+type Flusher interface {
+	// FlushPresence сохраняет несохраненную активность пользователей, возвращает число сохраненных пользователей.
+	FlushPresence(ctx context.Context) (int, error)
+}
+
+// PresenceApp представляет собой фоновый процесс сохранения времени последней активности пользователей.
+type PresenceApp struct {
+	log      logger.Logger
+	flusher  Flusher
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New - конструктор для типа *PresenceApp.
+func New(log logger.Logger, flusher Flusher, interval time.Duration) *PresenceApp {
+	return &PresenceApp{
+		log:      log,
+		flusher:  flusher,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run запускает сохранение активности с заданным интервалом, пока не будет вызван Stop.
+// При остановке активность сохраняется последний раз, чтобы она не потерялась при перезапуске.
+func (a *PresenceApp) Run() {
+	defer close(a.done)
+
+	a.log.Infof("presence flusher is running. interval=%s", a.interval)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			a.flush(ctx)
+			cancel()
+			return
+		case <-ticker.C:
+			a.flush(context.Background())
+		}
+	}
+}
+
+// Stop останавливает сохранение активности и дожидается завершения Run.
+func (a *PresenceApp) Stop() {
+	a.log.Infof("stopping presence flusher")
+
+	close(a.stop)
+	<-a.done
+}
+
+// flush выполняет одно сохранение. Ошибки только логируются, сохранение повторится на следующем тике.
+func (a *PresenceApp) flush(ctx context.Context) {
+	flushed, err := a.flusher.FlushPresence(ctx)
+	if err != nil {
+		a.log.Errorf("error flushing presence. %w", err)
+		return
+	}
+
+	if flushed > 0 {
+		a.log.Debugf("presence flushed. count=%d", flushed)
+	}
+}
@@ -0,0 +1,128 @@
+package purgerapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/logger"
+)
+
+// Purger предоставляет методы окончательного удаления пользователей, льготный период которых истек,
+// изменений пользователей, срок хранения которых истек, сессий с истекшими refresh токенами
+// и ключей учета попыток входа с истекшими окном и блокировкой.
+type Purger interface {
+	// PurgeDeletedUsers удаляет пользователей пачками по batchSize, возвращает число удаленных пользователей.
+	PurgeDeletedUsers(ctx context.Context, batchSize int) (int, error)
+
+	// PurgeUserChanges удаляет изменения пользователей пачками по batchSize, возвращает число удаленных изменений.
+	PurgeUserChanges(ctx context.Context, batchSize int) (int, error)
+
+	// PurgeExpiredSessions удаляет сессии с истекшими refresh токенами пачками по batchSize, возвращает число удаленных сессий.
+	PurgeExpiredSessions(ctx context.Context, batchSize int) (int, error)
+
+	// PurgeExpiredAttempts удаляет истекшие ключи учета попыток входа пачками по batchSize, возвращает число удаленных ключей.
+	PurgeExpiredAttempts(ctx context.Context, batchSize int) (int, error)
+}
+
+// PurgerApp представляет собой фоновый процесс очистки удаленных пользователей, устаревших изменений пользователей,
+// истекших сессий и попыток входа.
+type PurgerApp struct {
+	log       logger.Logger
+	purger    Purger
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New - конструктор для типа *PurgerApp.
+func New(log logger.Logger, purger Purger, interval time.Duration, batchSize int) *PurgerApp {
+	return &PurgerApp{
+		log:       log,
+		purger:    purger,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run запускает очистку сразу и затем с заданным интервалом, пока не будет вызван Stop.
+func (a *PurgerApp) Run() {
+	defer close(a.done)
+
+	a.log.Infof("purger is running. interval=%s", a.interval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//Остановка прерывает очистку, выполняемую в данный момент
+	go func() {
+		select {
+		case <-a.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop останавливает очистку и дожидается завершения Run.
+func (a *PurgerApp) Stop() {
+	a.log.Infof("stopping purger")
+
+	close(a.stop)
+	<-a.done
+}
+
+// purge выполняет один проход очистки. Ошибки только логируются, проход повторится на следующем тике.
+func (a *PurgerApp) purge(ctx context.Context) {
+	purged, err := a.purger.PurgeDeletedUsers(ctx, a.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.log.Errorf("error purging deleted users. %w", err)
+		}
+	} else if purged > 0 {
+		a.log.Infof("deleted users purged. count=%d", purged)
+	}
+
+	//Ошибка очистки пользователей не задерживает очистку журнала изменений
+	purged, err = a.purger.PurgeUserChanges(ctx, a.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.log.Errorf("error purging user changes. %w", err)
+		}
+	} else if purged > 0 {
+		a.log.Infof("user changes purged. count=%d", purged)
+	}
+
+	purged, err = a.purger.PurgeExpiredSessions(ctx, a.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.log.Errorf("error purging expired sessions. %w", err)
+		}
+	} else if purged > 0 {
+		a.log.Infof("expired sessions purged. count=%d", purged)
+	}
+
+	purged, err = a.purger.PurgeExpiredAttempts(ctx, a.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.log.Errorf("error purging expired login attempts. %w", err)
+		}
+	} else if purged > 0 {
+		a.log.Infof("expired login attempts purged. count=%d", purged)
+	}
+}
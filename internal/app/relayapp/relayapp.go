@@ -0,0 +1,98 @@
+package relayapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/logger"
+)
+
+// Relay предоставляет метод публикации событий из outbox.
+type Relay interface {
+	// RelayEvents публикует не более batchSize событий, возвращает число опубликованных событий.
+	RelayEvents(ctx context.Context, batchSize int) (int, error)
+}
+
+// RelayApp представляет собой фоновый процесс публикации событий жизненного цикла пользователей из outbox.
+type RelayApp struct {
+	log       logger.Logger
+	relay     Relay
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New - конструктор для типа *RelayApp.
+func New(log logger.Logger, relay Relay, interval time.Duration, batchSize int) *RelayApp {
+	return &RelayApp{
+		log:       log,
+		relay:     relay,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run запускает публикацию событий с заданным интервалом, пока не будет вызван Stop.
+// Если пачка опубликована полностью, следующая публикуется сразу, не дожидаясь интервала.
+func (a *RelayApp) Run() {
+	defer close(a.done)
+
+	a.log.Infof("event relay is running. interval=%s", a.interval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//Остановка прерывает публикацию, выполняемую в данный момент.
+	//Прерванные события остаются в outbox и будут опубликованы после перезапуска
+	go func() {
+		select {
+		case <-a.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if a.relayBatch(ctx) == a.batchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop останавливает публикацию событий и дожидается завершения Run.
+func (a *RelayApp) Stop() {
+	a.log.Infof("stopping event relay")
+
+	close(a.stop)
+	<-a.done
+}
+
+// relayBatch выполняет одну публикацию, возвращает число опубликованных событий.
+// Ошибки только логируются, неопубликованные события повторятся на следующем тике.
+func (a *RelayApp) relayBatch(ctx context.Context) int {
+	relayed, err := a.relay.RelayEvents(ctx, a.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.log.Errorf("error relaying events. %w", err)
+		}
+		return relayed
+	}
+
+	if relayed > 0 {
+		a.log.Debugf("events relayed. count=%d", relayed)
+	}
+
+	return relayed
+}
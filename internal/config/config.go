@@ -2,20 +2,41 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"time"
 
+	"github.com/al3ksus/messengerusers/internal/lib/aead"
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 )
 
+// EnvDev - окружение локального запуска.
+const EnvDev = "dev"
+
 type Config struct {
-	GRPCConfig     `yaml:"grpc" env-required:"true"`
-	PostgresConfig `yaml:"postgres" env-required:"true"`
+	Env                  string `yaml:"env" env-default:"prod"`
+	GRPCConfig           `yaml:"grpc" env-required:"true"`
+	PostgresConfig       `yaml:"postgres" env-required:"true"`
+	TokenConfig          `yaml:"token" env-required:"true"`
+	PasswordResetConfig  `yaml:"password_reset"`
+	NotifierConfig       `yaml:"notifier"`
+	PasswordPolicyConfig `yaml:"password_policy"`
+	UsernamePolicyConfig `yaml:"username_policy"`
+	HashConfig           `yaml:"hash"`
+	LoginThrottleConfig  `yaml:"login_throttle"`
+	MFAConfig            `yaml:"mfa"`
+	LookupConfig         `yaml:"lookup"`
+	DeletionConfig       `yaml:"deletion"`
+	PresenceConfig       `yaml:"presence"`
+	EventsConfig         `yaml:"events"`
+	ChangesConfig        `yaml:"changes"`
 }
 
 type GRPCConfig struct {
-	GRPCPort int `yaml:"port" env-required:"true"`
+	GRPCPort       int      `yaml:"port" env-required:"true"`
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type PostgresConfig struct {
@@ -26,6 +47,116 @@ type PostgresConfig struct {
 	DBName   string `yaml:"dbname" env-required:"true"`
 }
 
+// ConnString возвращает строку подключения к базе данных PostgreSQL.
+func (c PostgresConfig) ConnString() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		c.Host, c.DBPort, c.User, c.Password, c.DBName)
+}
+
+type TokenConfig struct {
+	Secret               string        `yaml:"secret" env-required:"true"`
+	AccessTTL            time.Duration `yaml:"access_ttl" env-default:"15m"`
+	RefreshTTL           time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	SessionTouchInterval time.Duration `yaml:"session_touch_interval" env-default:"5m"`
+}
+
+type PasswordResetConfig struct {
+	CodeTTL          time.Duration `yaml:"code_ttl" env-default:"15m"`
+	MaxActiveCodes   int           `yaml:"max_active_codes" env-default:"3"`
+	Window           time.Duration `yaml:"window" env-default:"1h"`
+	UserFreeRequests int           `yaml:"user_free_requests" env-default:"3"`
+	IPFreeRequests   int           `yaml:"ip_free_requests" env-default:"10"`
+	BaseLockout      time.Duration `yaml:"base_lockout" env-default:"1m"`
+	MaxLockout       time.Duration `yaml:"max_lockout" env-default:"1h"`
+}
+
+type NotifierConfig struct {
+	Kind         string        `yaml:"kind"`
+	WebhookURL   string        `yaml:"webhook_url"`
+	WebhookToken string        `yaml:"webhook_token"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength    int    `yaml:"min_length" env-default:"8"`
+	MaxLength    int    `yaml:"max_length" env-default:"64"`
+	MinClasses   int    `yaml:"min_classes" env-default:"2"`
+	DenyListPath string `yaml:"deny_list_path"`
+}
+
+type HashConfig struct {
+	Algorithm     string `yaml:"algorithm" env-default:"argon2id"`
+	BcryptCost    int    `yaml:"bcrypt_cost" env-default:"10"`
+	Argon2Time    uint32 `yaml:"argon2_time" env-default:"3"`
+	Argon2Memory  uint32 `yaml:"argon2_memory" env-default:"65536"`
+	Argon2Threads uint8  `yaml:"argon2_threads" env-default:"2"`
+	ScryptLogN    int    `yaml:"scrypt_log_n" env-default:"15"`
+	ScryptR       int    `yaml:"scrypt_r" env-default:"8"`
+	ScryptP       int    `yaml:"scrypt_p" env-default:"1"`
+}
+
+type LoginThrottleConfig struct {
+	Window           time.Duration `yaml:"window" env-default:"15m"`
+	UserFreeAttempts int           `yaml:"user_free_attempts" env-default:"5"`
+	IPFreeAttempts   int           `yaml:"ip_free_attempts" env-default:"20"`
+	BaseLockout      time.Duration `yaml:"base_lockout" env-default:"30s"`
+	MaxLockout       time.Duration `yaml:"max_lockout" env-default:"1h"`
+}
+
+type MFAConfig struct {
+	Issuer        string        `yaml:"issuer" env-default:"Messenger"`
+	Skew          int           `yaml:"skew" env-default:"1"`
+	EncryptionKey string        `yaml:"encryption_key" env:"MFA_ENCRYPTION_KEY" env-required:"true"`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+}
+
+type LookupConfig struct {
+	MaxBatchSize    int `yaml:"max_batch_size" env-default:"100"`
+	DefaultPageSize int `yaml:"default_page_size" env-default:"20"`
+	MaxPageSize     int `yaml:"max_page_size" env-default:"100"`
+}
+
+type DeletionConfig struct {
+	GracePeriod   time.Duration `yaml:"grace_period" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+	PurgeBatch    int           `yaml:"purge_batch" env-default:"100"`
+}
+
+type PresenceConfig struct {
+	OnlineTTL     time.Duration `yaml:"online_ttl" env-default:"1m"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"30s"`
+	Shards        int           `yaml:"shards" env-default:"64"`
+}
+
+type EventsConfig struct {
+	Broker          string        `yaml:"broker" env-default:"nats"`
+	NATSURL         string        `yaml:"nats_url" env-default:"nats://localhost:4222"`
+	NATSToken       string        `yaml:"nats_token" env:"NATS_TOKEN"`
+	NATSCredsFile   string        `yaml:"nats_creds_file"`
+	NATSTLSCAFile   string        `yaml:"nats_tls_ca_file"`
+	NATSTLSCertFile string        `yaml:"nats_tls_cert_file"`
+	NATSTLSKeyFile  string        `yaml:"nats_tls_key_file"`
+	Stream          string        `yaml:"stream"`
+	SubjectPrefix   string        `yaml:"subject_prefix" env-default:"users"`
+	PublishTimeout  time.Duration `yaml:"publish_timeout" env-default:"5s"`
+	RelayInterval   time.Duration `yaml:"relay_interval" env-default:"1s"`
+	RelayBatch      int           `yaml:"relay_batch" env-default:"100"`
+}
+
+type ChangesConfig struct {
+	ListenerMinReconnect time.Duration `yaml:"listener_min_reconnect" env-default:"1s"`
+	ListenerMaxReconnect time.Duration `yaml:"listener_max_reconnect" env-default:"1m"`
+	PollInterval         time.Duration `yaml:"poll_interval" env-default:"1s"`
+	Retention            time.Duration `yaml:"retention" env-default:"168h"`
+}
+
+type UsernamePolicyConfig struct {
+	MinLength         int           `yaml:"min_length" env-default:"3"`
+	MaxLength         int           `yaml:"max_length" env-default:"32"`
+	Reserved          []string      `yaml:"reserved" env-default:"admin,administrator,root,support,system,moderator,messenger"`
+	ReservationPeriod time.Duration `yaml:"reservation_period" env-default:"720h"`
+}
+
 // MustLoad возвращает объект конфига, получая данные из файла конфигурации.
 // Вызывает панику в случае ошибки.
 func MustLoad() *Config {
@@ -43,9 +174,22 @@ func MustLoad() *Config {
 		panic("error while loading config: " + err.Error())
 	}
 
+	if err := cfg.validate(); err != nil {
+		panic("invalid config: " + err.Error())
+	}
+
 	return &cfg
 }
 
+// validate проверяет значения конфига, которые нельзя проверить тегами cleanenv.
+func (c *Config) validate() error {
+	if _, err := aead.ParseKey(c.MFAConfig.EncryptionKey); err != nil {
+		return fmt.Errorf("mfa.encryption_key: %w", err)
+	}
+
+	return nil
+}
+
 // fetchConfigPath возвращает путь к файлу конфигурации, полученный из флагов или переменной среды окружения.
 func fetchConfigPath() string {
 	var res string
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/al3ksus/messengerusers/internal/lib/aead"
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+var (
+	TestEncryptionKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	TestConfigYAML    = `
+grpc:
+  port: 44044
+postgres:
+  host: localhost
+  port: 5432
+  user: users
+  password: users
+  dbname: users
+token:
+  secret: secret
+`
+)
+
+// readTestConfig читает конфиг из временного файла с содержимым TestConfigYAML и дополнительными строками extra.
+func readTestConfig(t *testing.T, extra string) (Config, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(TestConfigYAML+extra), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg Config
+	err := cleanenv.ReadConfig(path, &cfg)
+
+	return cfg, err
+}
+
+func TestConfig_EncryptionKeyRequired(t *testing.T) {
+	//t.Setenv восстанавливает переменную окружения после теста
+	t.Setenv("MFA_ENCRYPTION_KEY", "")
+	os.Unsetenv("MFA_ENCRYPTION_KEY")
+
+	if _, err := readTestConfig(t, ""); err == nil {
+		t.Errorf("cleanenv.ReadConfig() error = nil, want error for missing mfa.encryption_key")
+	}
+}
+
+func TestConfig_EncryptionKeyFromEnv(t *testing.T) {
+	t.Setenv("MFA_ENCRYPTION_KEY", TestEncryptionKey)
+
+	cfg, err := readTestConfig(t, "")
+	if err != nil {
+		t.Fatalf("cleanenv.ReadConfig() error = %v", err)
+	}
+
+	if cfg.MFAConfig.EncryptionKey != TestEncryptionKey {
+		t.Errorf("EncryptionKey = %q, want %q", cfg.MFAConfig.EncryptionKey, TestEncryptionKey)
+	}
+
+	if err = cfg.validate(); err != nil {
+		t.Errorf("Config.validate() error = %v", err)
+	}
+}
+
+func TestConfig_validate(t *testing.T) {
+	t.Setenv("MFA_ENCRYPTION_KEY", "")
+	os.Unsetenv("MFA_ENCRYPTION_KEY")
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{
+			name: "OK",
+			key:  TestEncryptionKey,
+		},
+		{
+			name:    "TooShort",
+			key:     base64.StdEncoding.EncodeToString([]byte("short")),
+			wantErr: aead.ErrInvalidKey,
+		},
+		{
+			name:    "NotBase64",
+			key:     "not base64!",
+			wantErr: aead.ErrInvalidKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := readTestConfig(t, "mfa:\n  encryption_key: \""+tt.key+"\"\n")
+			if err != nil {
+				t.Fatalf("cleanenv.ReadConfig() error = %v", err)
+			}
+
+			if err = cfg.validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Config.validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// BlockedUser - пользователь, заблокированный другим пользователем.
+type BlockedUser struct {
+	User User
+	// BlockedAt - время блокировки.
+	BlockedAt time.Time
+}
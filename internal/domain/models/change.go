@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserChange - запись журнала изменений пользователей, передаваемая в потоке WatchUsers.
+type UserChange struct {
+	// Id - номер изменения в журнале.
+	Id int64
+	// TxId - транзакция, добавившая изменение. Изменения упорядочены по возрастанию пары (TxId, Id).
+	TxId   int64
+	Type   string
+	UserId int64
+	// Payload - данные изменения в формате JSON.
+	Payload   []byte
+	CreatedAt time.Time
+}
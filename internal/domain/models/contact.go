@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Contact - контакт пользователя.
+type Contact struct {
+	User User
+	// Since - время, с которого пользователи состоят в контактах.
+	Since time.Time
+}
+
+// ContactRequest - заявка на добавление в контакты, ожидающая ответа получателя.
+type ContactRequest struct {
+	FromUserId int64
+	ToUserId   int64
+	CreatedAt  time.Time
+}
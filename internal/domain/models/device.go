@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Платформы устройств, на которые доставляются push уведомления.
+const (
+	DevicePlatformIOS     = "ios"
+	DevicePlatformAndroid = "android"
+	DevicePlatformWeb     = "web"
+)
+
+// Device - устройство пользователя, на которое доставляются push уведомления.
+type Device struct {
+	Id         int64
+	UserId     int64
+	Platform   string
+	PushToken  string
+	AppVersion string
+	CreatedAt  time.Time
+	// LastActiveAt - время последней регистрации устройства.
+	LastActiveAt time.Time
+}
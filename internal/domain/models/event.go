@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Типы событий жизненного цикла пользователя.
+const (
+	EventUserRegistered     = "user.registered"
+	EventUserProfileUpdated = "user.profile_updated"
+	EventUserDeactivated    = "user.deactivated"
+	EventUserActivated      = "user.activated"
+	EventUserDeleted        = "user.deleted"
+)
+
+// Event - событие жизненного цикла пользователя, ожидающее публикации.
+type Event struct {
+	Id int64
+	// IdempotencyKey - уникальный ключ события, по которому потребители отбрасывают повторную доставку.
+	IdempotencyKey string
+	Type           string
+	UserId         int64
+	// Payload - данные события в формате JSON.
+	Payload   []byte
+	CreatedAt time.Time
+}
@@ -0,0 +1,10 @@
+package models
+
+// TOTP модель данных второго фактора пользователя.
+// Secret хранится в зашифрованном виде, LastUsedStep - последний принятый шаг, коды которого повторно не принимаются.
+type TOTP struct {
+	UserId       int64
+	Secret       []byte
+	Confirmed    bool
+	LastUsedStep int64
+}
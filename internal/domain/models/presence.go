@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Presence - состояние присутствия пользователя в сети.
+type Presence struct {
+	UserId int64
+	Online bool
+	// LastSeenAt - время последней активности пользователя, нулевое, если пользователь еще не был в сети.
+	LastSeenAt time.Time
+}
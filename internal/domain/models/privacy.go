@@ -0,0 +1,36 @@
+package models
+
+// Visibility - круг пользователей, которым доступны данные пользователя.
+type Visibility string
+
+const (
+	VisibilityEveryone Visibility = "everyone"
+	VisibilityContacts Visibility = "contacts"
+	VisibilityNobody   Visibility = "nobody"
+)
+
+// Поля настроек приватности, которые пользователь может изменить.
+const (
+	PrivacyFieldLastSeen = "last_seen"
+	PrivacyFieldAvatar   = "avatar"
+	PrivacyFieldSearch   = "search"
+)
+
+// PrivacySettings - настройки приватности пользователя. Настройки не действуют на самого пользователя.
+type PrivacySettings struct {
+	// LastSeen - кому видно присутствие пользователя в сети и время его последней активности.
+	LastSeen Visibility
+	// Avatar - кому видна ссылка на аватар пользователя.
+	Avatar Visibility
+	// Search - кто может найти пользователя поиском по username.
+	Search Visibility
+}
+
+// DefaultPrivacySettings возвращает настройки приватности пользователя, который их не изменял.
+func DefaultPrivacySettings() PrivacySettings {
+	return PrivacySettings{
+		LastSeen: VisibilityEveryone,
+		Avatar:   VisibilityEveryone,
+		Search:   VisibilityEveryone,
+	}
+}
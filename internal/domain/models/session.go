@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ClientInfo - данные клиента, с которого выполнен вход.
+type ClientInfo struct {
+	DeviceName string
+	IP         string
+	UserAgent  string
+}
+
+// Session модель данных сессии пользователя.
+type Session struct {
+	Id     int64
+	UserId int64
+	ClientInfo
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
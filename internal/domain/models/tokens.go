@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Tokens - пара токенов, выдаваемая пользователю при авторизации.
+type Tokens struct {
+	UserId           int64
+	SessionId        int64
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// TokenInfo - данные проверенного access токена.
+type TokenInfo struct {
+	UserId    int64
+	SessionId int64
+	ExpiresAt time.Time
+}
@@ -1,9 +1,81 @@
 package models
 
+import "time"
+
+// Поля профиля, которые пользователь может изменить.
+const (
+	ProfileFieldDisplayName = "display_name"
+	ProfileFieldBio         = "bio"
+	ProfileFieldAvatarURL   = "avatar_url"
+	ProfileFieldLocale      = "locale"
+	ProfileFieldTimeZone    = "time_zone"
+)
+
 // Users модель данных пользователя.
 type User struct {
 	Id           int64
 	Username     string
 	PasswordHash []byte
 	IsActive     bool
+	Profile
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Profile - публичные данные профиля пользователя.
+type Profile struct {
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+	Locale      string
+	TimeZone    string
+}
+
+// UserSearchCursor - позиция пользователя в результатах поиска.
+// Результаты упорядочены по совпадению префикса, затем по убыванию схожести, затем по id.
+type UserSearchCursor struct {
+	PrefixMatch bool
+	Score       float32
+	UserId      int64
+}
+
+// UserSearchHit - пользователь, найденный поиском, и его позиция в результатах.
+type UserSearchHit struct {
+	User   User
+	Cursor UserSearchCursor
+}
+
+// UserFilter - условия отбора пользователей. Нулевые значения полей не ограничивают выборку.
+type UserFilter struct {
+	// IsActive - статус активности пользователя, nil - любой статус.
+	IsActive *bool
+	// CreatedAfter - начало периода создания пользователя включительно.
+	CreatedAfter time.Time
+	// CreatedBefore - конец периода создания пользователя, не включая его.
+	CreatedBefore time.Time
+	// UsernamePrefix - начало канонической формы username.
+	UsernamePrefix string
+}
+
+// ErasedUser - пользователь, окончательно удаленный после запроса на удаление.
+type ErasedUser struct {
+	UserId int64
+	// Canonical - каноническая форма username удаленного пользователя.
+	Canonical string
+}
+
+// UsernameChange - запись истории смены username пользователя.
+type UsernameChange struct {
+	// Username - прежний username пользователя.
+	Username      string
+	ChangedAt     time.Time
+	ReservedUntil time.Time
+}
+
+// ErasureEvent - запись журнала удаления пользователя.
+type ErasureEvent struct {
+	Event string
+	// DeleteAfter - назначенное время удаления, нулевое, если событие его не содержит.
+	DeleteAfter time.Time
+	CreatedAt   time.Time
 }
@@ -4,7 +4,10 @@ package mocks
 
 import (
 	context "context"
+	io "io"
+	time "time"
 
+	models "github.com/al3ksus/messengerusers/internal/domain/models"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,32 +16,787 @@ type Users struct {
 	mock.Mock
 }
 
-// Login provides a mock function with given fields: ctx, username, password
-func (_m *Users) Login(ctx context.Context, username string, password string) (int64, error) {
-	ret := _m.Called(ctx, username, password)
+// AcceptContactRequest provides a mock function with given fields: ctx, userId, fromUserId
+func (_m *Users) AcceptContactRequest(ctx context.Context, userId int64, fromUserId int64) error {
+	ret := _m.Called(ctx, userId, fromUserId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcceptContactRequest")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, fromUserId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AreContacts provides a mock function with given fields: ctx, userId, contactId
+func (_m *Users) AreContacts(ctx context.Context, userId int64, contactId int64) (bool, error) {
+	ret := _m.Called(ctx, userId, contactId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AreContacts")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (bool, error)); ok {
+		return rf(ctx, userId, contactId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) bool); ok {
+		r0 = rf(ctx, userId, contactId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, userId, contactId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BlockUser provides a mock function with given fields: ctx, userId, blockedId
+func (_m *Users) BlockUser(ctx context.Context, userId int64, blockedId int64) error {
+	ret := _m.Called(ctx, userId, blockedId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BlockUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, blockedId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CancelContactRequest provides a mock function with given fields: ctx, userId, toUserId
+func (_m *Users) CancelContactRequest(ctx context.Context, userId int64, toUserId int64) error {
+	ret := _m.Called(ctx, userId, toUserId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelContactRequest")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, toUserId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CancelUserDeletion provides a mock function with given fields: ctx, userId
+func (_m *Users) CancelUserDeletion(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelUserDeletion")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ChangePassword provides a mock function with given fields: ctx, userId, oldPassword, newPassword
+func (_m *Users) ChangePassword(ctx context.Context, userId int64, oldPassword string, newPassword string) error {
+	ret := _m.Called(ctx, userId, oldPassword, newPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangePassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) error); ok {
+		r0 = rf(ctx, userId, oldPassword, newPassword)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ChangeUsername provides a mock function with given fields: ctx, userId, username
+func (_m *Users) ChangeUsername(ctx context.Context, userId int64, username string) error {
+	ret := _m.Called(ctx, userId, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangeUsername")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userId, username)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ClearLockout provides a mock function with given fields: ctx, username, ip
+func (_m *Users) ClearLockout(ctx context.Context, username string, ip string) error {
+	ret := _m.Called(ctx, username, ip)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClearLockout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, username, ip)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmTOTP provides a mock function with given fields: ctx, userId, code
+func (_m *Users) ConfirmTOTP(ctx context.Context, userId int64, code string) ([]string, error) {
+	ret := _m.Called(ctx, userId, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) ([]string, error)); ok {
+		return rf(ctx, userId, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) []string); ok {
+		r0 = rf(ctx, userId, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, userId, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeclineContactRequest provides a mock function with given fields: ctx, userId, fromUserId
+func (_m *Users) DeclineContactRequest(ctx context.Context, userId int64, fromUserId int64) error {
+	ret := _m.Called(ctx, userId, fromUserId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeclineContactRequest")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, fromUserId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: ctx, userId
+func (_m *Users) DeleteUser(ctx context.Context, userId int64) (time.Time, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUser")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (time.Time, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) time.Time); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DisableTOTP provides a mock function with given fields: ctx, userId, password
+func (_m *Users) DisableTOTP(ctx context.Context, userId int64, password string) error {
+	ret := _m.Called(ctx, userId, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userId, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableTOTP provides a mock function with given fields: ctx, userId
+func (_m *Users) EnableTOTP(ctx context.Context, userId int64) (string, string, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableTOTP")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (string, string, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) string); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) string); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64) error); ok {
+		r2 = rf(ctx, userId)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ExportUserData provides a mock function with given fields: ctx, userId, w
+func (_m *Users) ExportUserData(ctx context.Context, userId int64, w io.Writer) error {
+	ret := _m.Called(ctx, userId, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportUserData")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, io.Writer) error); ok {
+		r0 = rf(ctx, userId, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetPresence provides a mock function with given fields: ctx, viewerId, userIds
+func (_m *Users) GetPresence(ctx context.Context, viewerId int64, userIds []int64) ([]models.Presence, error) {
+	ret := _m.Called(ctx, viewerId, userIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPresence")
+	}
+
+	var r0 []models.Presence
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) ([]models.Presence, error)); ok {
+		return rf(ctx, viewerId, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) []models.Presence); ok {
+		r0 = rf(ctx, viewerId, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Presence)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []int64) error); ok {
+		r1 = rf(ctx, viewerId, userIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPrivacySettings provides a mock function with given fields: ctx, userId
+func (_m *Users) GetPrivacySettings(ctx context.Context, userId int64) (models.PrivacySettings, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPrivacySettings")
+	}
+
+	var r0 models.PrivacySettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (models.PrivacySettings, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) models.PrivacySettings); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(models.PrivacySettings)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserById provides a mock function with given fields: ctx, viewerId, userId
+func (_m *Users) GetUserById(ctx context.Context, viewerId int64, userId int64) (models.User, error) {
+	ret := _m.Called(ctx, viewerId, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserById")
+	}
+
+	var r0 models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (models.User, error)); ok {
+		return rf(ctx, viewerId, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) models.User); ok {
+		r0 = rf(ctx, viewerId, userId)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, viewerId, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByUsername provides a mock function with given fields: ctx, viewerId, username
+func (_m *Users) GetUserByUsername(ctx context.Context, viewerId int64, username string) (models.User, error) {
+	ret := _m.Called(ctx, viewerId, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserByUsername")
+	}
+
+	var r0 models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) (models.User, error)); ok {
+		return rf(ctx, viewerId, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) models.User); ok {
+		r0 = rf(ctx, viewerId, username)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, viewerId, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsers provides a mock function with given fields: ctx, viewerId, userIds
+func (_m *Users) GetUsers(ctx context.Context, viewerId int64, userIds []int64) (map[int64]models.User, []int64, error) {
+	ret := _m.Called(ctx, viewerId, userIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsers")
+	}
+
+	var r0 map[int64]models.User
+	var r1 []int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) (map[int64]models.User, []int64, error)); ok {
+		return rf(ctx, viewerId, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64) map[int64]models.User); ok {
+		r0 = rf(ctx, viewerId, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int64]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []int64) []int64); ok {
+		r1 = rf(ctx, viewerId, userIds)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, []int64) error); ok {
+		r2 = rf(ctx, viewerId, userIds)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Heartbeat provides a mock function with given fields: ctx, userId
+func (_m *Users) Heartbeat(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Heartbeat")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IsBlocked provides a mock function with given fields: ctx, userId, targetId
+func (_m *Users) IsBlocked(ctx context.Context, userId int64, targetId int64) (bool, error) {
+	ret := _m.Called(ctx, userId, targetId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsBlocked")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (bool, error)); ok {
+		return rf(ctx, userId, targetId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) bool); ok {
+		r0 = rf(ctx, userId, targetId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, userId, targetId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListBlocked provides a mock function with given fields: ctx, userId, pageToken, limit
+func (_m *Users) ListBlocked(ctx context.Context, userId int64, pageToken string, limit int) ([]models.BlockedUser, string, error) {
+	ret := _m.Called(ctx, userId, pageToken, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBlocked")
+	}
+
+	var r0 []models.BlockedUser
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int) ([]models.BlockedUser, string, error)); ok {
+		return rf(ctx, userId, pageToken, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int) []models.BlockedUser); ok {
+		r0 = rf(ctx, userId, pageToken, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.BlockedUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int) string); ok {
+		r1 = rf(ctx, userId, pageToken, limit)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, string, int) error); ok {
+		r2 = rf(ctx, userId, pageToken, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListContactRequests provides a mock function with given fields: ctx, userId, incoming
+func (_m *Users) ListContactRequests(ctx context.Context, userId int64, incoming bool) ([]models.ContactRequest, error) {
+	ret := _m.Called(ctx, userId, incoming)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListContactRequests")
+	}
+
+	var r0 []models.ContactRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) ([]models.ContactRequest, error)); ok {
+		return rf(ctx, userId, incoming)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) []models.ContactRequest); ok {
+		r0 = rf(ctx, userId, incoming)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ContactRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, bool) error); ok {
+		r1 = rf(ctx, userId, incoming)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListContacts provides a mock function with given fields: ctx, userId, pageToken, limit
+func (_m *Users) ListContacts(ctx context.Context, userId int64, pageToken string, limit int) ([]models.Contact, string, error) {
+	ret := _m.Called(ctx, userId, pageToken, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListContacts")
+	}
+
+	var r0 []models.Contact
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int) ([]models.Contact, string, error)); ok {
+		return rf(ctx, userId, pageToken, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int) []models.Contact); ok {
+		r0 = rf(ctx, userId, pageToken, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Contact)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int) string); ok {
+		r1 = rf(ctx, userId, pageToken, limit)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, string, int) error); ok {
+		r2 = rf(ctx, userId, pageToken, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListDevices provides a mock function with given fields: ctx, userId
+func (_m *Users) ListDevices(ctx context.Context, userId int64) ([]models.Device, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDevices")
+	}
+
+	var r0 []models.Device
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.Device, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.Device); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Device)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSessions provides a mock function with given fields: ctx, userId
+func (_m *Users) ListSessions(ctx context.Context, userId int64) ([]models.Session, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSessions")
+	}
+
+	var r0 []models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.Session, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.Session); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsers provides a mock function with given fields: ctx, filter, desc, pageToken, limit
+func (_m *Users) ListUsers(ctx context.Context, filter models.UserFilter, desc bool, pageToken string, limit int) ([]models.User, string, error) {
+	ret := _m.Called(ctx, filter, desc, pageToken, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []models.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserFilter, bool, string, int) ([]models.User, string, error)); ok {
+		return rf(ctx, filter, desc, pageToken, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserFilter, bool, string, int) []models.User); ok {
+		r0 = rf(ctx, filter, desc, pageToken, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.UserFilter, bool, string, int) string); ok {
+		r1 = rf(ctx, filter, desc, pageToken, limit)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, models.UserFilter, bool, string, int) error); ok {
+		r2 = rf(ctx, filter, desc, pageToken, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Login provides a mock function with given fields: ctx, username, password, client
+func (_m *Users) Login(ctx context.Context, username string, password string, client models.ClientInfo) (models.Tokens, error) {
+	ret := _m.Called(ctx, username, password, client)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Login")
+	}
+
+	var r0 models.Tokens
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.ClientInfo) (models.Tokens, error)); ok {
+		return rf(ctx, username, password, client)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.ClientInfo) models.Tokens); ok {
+		r0 = rf(ctx, username, password, client)
+	} else {
+		r0 = ret.Get(0).(models.Tokens)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, models.ClientInfo) error); ok {
+		r1 = rf(ctx, username, password, client)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Logout provides a mock function with given fields: ctx, userId, sessionId
+func (_m *Users) Logout(ctx context.Context, userId int64, sessionId int64) error {
+	ret := _m.Called(ctx, userId, sessionId)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Login")
+		panic("no return value specified for Logout")
 	}
 
-	var r0 int64
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (int64, error)); ok {
-		return rf(ctx, username, password)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, sessionId)
+	} else {
+		r0 = ret.Error(0)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) int64); ok {
-		r0 = rf(ctx, username, password)
+
+	return r0
+}
+
+// LogoutAll provides a mock function with given fields: ctx, userId
+func (_m *Users) LogoutAll(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogoutAll")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
 	} else {
-		r0 = ret.Get(0).(int64)
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, username, password)
+	return r0
+}
+
+// MakeUserActive provides a mock function with given fields: ctx, userId
+func (_m *Users) MakeUserActive(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MakeUserActive")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
 // MakeUserInactive provides a mock function with given fields: ctx, userId
@@ -59,6 +817,62 @@ func (_m *Users) MakeUserInactive(ctx context.Context, userId int64) error {
 	return r0
 }
 
+// RefreshTokens provides a mock function with given fields: ctx, refreshToken
+func (_m *Users) RefreshTokens(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	ret := _m.Called(ctx, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshTokens")
+	}
+
+	var r0 models.Tokens
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Tokens, error)); ok {
+		return rf(ctx, refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Tokens); ok {
+		r0 = rf(ctx, refreshToken)
+	} else {
+		r0 = ret.Get(0).(models.Tokens)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, refreshToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RegisterDevice provides a mock function with given fields: ctx, userId, platform, pushToken, appVersion
+func (_m *Users) RegisterDevice(ctx context.Context, userId int64, platform string, pushToken string, appVersion string) (models.Device, error) {
+	ret := _m.Called(ctx, userId, platform, pushToken, appVersion)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterDevice")
+	}
+
+	var r0 models.Device
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, string) (models.Device, error)); ok {
+		return rf(ctx, userId, platform, pushToken, appVersion)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, string) models.Device); ok {
+		r0 = rf(ctx, userId, platform, pushToken, appVersion)
+	} else {
+		r0 = ret.Get(0).(models.Device)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, string, string) error); ok {
+		r1 = rf(ctx, userId, platform, pushToken, appVersion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RegisterNewUser provides a mock function with given fields: ctx, username, password
 func (_m *Users) RegisterNewUser(ctx context.Context, username string, password string) (int64, error) {
 	ret := _m.Called(ctx, username, password)
@@ -87,6 +901,309 @@ func (_m *Users) RegisterNewUser(ctx context.Context, username string, password
 	return r0, r1
 }
 
+// RemoveContact provides a mock function with given fields: ctx, userId, contactId
+func (_m *Users) RemoveContact(ctx context.Context, userId int64, contactId int64) error {
+	ret := _m.Called(ctx, userId, contactId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveContact")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, contactId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RequestPasswordReset provides a mock function with given fields: ctx, username, ip
+func (_m *Users) RequestPasswordReset(ctx context.Context, username string, ip string) error {
+	ret := _m.Called(ctx, username, ip)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestPasswordReset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, username, ip)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, code, newPassword
+func (_m *Users) ResetPassword(ctx context.Context, code string, newPassword string) error {
+	ret := _m.Called(ctx, code, newPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, code, newPassword)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SearchUsers provides a mock function with given fields: ctx, viewerId, query, pageToken, limit
+func (_m *Users) SearchUsers(ctx context.Context, viewerId int64, query string, pageToken string, limit int) ([]models.User, string, error) {
+	ret := _m.Called(ctx, viewerId, query, pageToken, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchUsers")
+	}
+
+	var r0 []models.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, int) ([]models.User, string, error)); ok {
+		return rf(ctx, viewerId, query, pageToken, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, int) []models.User); ok {
+		r0 = rf(ctx, viewerId, query, pageToken, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, string, int) string); ok {
+		r1 = rf(ctx, viewerId, query, pageToken, limit)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, string, string, int) error); ok {
+		r2 = rf(ctx, viewerId, query, pageToken, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SendContactRequest provides a mock function with given fields: ctx, userId, contactId
+func (_m *Users) SendContactRequest(ctx context.Context, userId int64, contactId int64) (bool, error) {
+	ret := _m.Called(ctx, userId, contactId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendContactRequest")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (bool, error)); ok {
+		return rf(ctx, userId, contactId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) bool); ok {
+		r0 = rf(ctx, userId, contactId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, userId, contactId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UnblockUser provides a mock function with given fields: ctx, userId, blockedId
+func (_m *Users) UnblockUser(ctx context.Context, userId int64, blockedId int64) error {
+	ret := _m.Called(ctx, userId, blockedId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnblockUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, blockedId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnregisterDevice provides a mock function with given fields: ctx, userId, deviceId
+func (_m *Users) UnregisterDevice(ctx context.Context, userId int64, deviceId int64) error {
+	ret := _m.Called(ctx, userId, deviceId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnregisterDevice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userId, deviceId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePrivacySettings provides a mock function with given fields: ctx, userId, settings, fields
+func (_m *Users) UpdatePrivacySettings(ctx context.Context, userId int64, settings models.PrivacySettings, fields []string) (models.PrivacySettings, error) {
+	ret := _m.Called(ctx, userId, settings, fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePrivacySettings")
+	}
+
+	var r0 models.PrivacySettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, models.PrivacySettings, []string) (models.PrivacySettings, error)); ok {
+		return rf(ctx, userId, settings, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, models.PrivacySettings, []string) models.PrivacySettings); ok {
+		r0 = rf(ctx, userId, settings, fields)
+	} else {
+		r0 = ret.Get(0).(models.PrivacySettings)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, models.PrivacySettings, []string) error); ok {
+		r1 = rf(ctx, userId, settings, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, userId, profile, fields
+func (_m *Users) UpdateProfile(ctx context.Context, userId int64, profile models.Profile, fields []string) (models.User, error) {
+	ret := _m.Called(ctx, userId, profile, fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, models.Profile, []string) (models.User, error)); ok {
+		return rf(ctx, userId, profile, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, models.Profile, []string) models.User); ok {
+		r0 = rf(ctx, userId, profile, fields)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, models.Profile, []string) error); ok {
+		r1 = rf(ctx, userId, profile, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ValidateToken provides a mock function with given fields: ctx, accessToken
+func (_m *Users) ValidateToken(ctx context.Context, accessToken string) (models.TokenInfo, error) {
+	ret := _m.Called(ctx, accessToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateToken")
+	}
+
+	var r0 models.TokenInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.TokenInfo, error)); ok {
+		return rf(ctx, accessToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.TokenInfo); ok {
+		r0 = rf(ctx, accessToken)
+	} else {
+		r0 = ret.Get(0).(models.TokenInfo)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifySecondFactor provides a mock function with given fields: ctx, challenge, code, client
+func (_m *Users) VerifySecondFactor(ctx context.Context, challenge string, code string, client models.ClientInfo) (models.Tokens, error) {
+	ret := _m.Called(ctx, challenge, code, client)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifySecondFactor")
+	}
+
+	var r0 models.Tokens
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.ClientInfo) (models.Tokens, error)); ok {
+		return rf(ctx, challenge, code, client)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.ClientInfo) models.Tokens); ok {
+		r0 = rf(ctx, challenge, code, client)
+	} else {
+		r0 = ret.Get(0).(models.Tokens)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, models.ClientInfo) error); ok {
+		r1 = rf(ctx, challenge, code, client)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WatchPresence provides a mock function with given fields: ctx, viewerId, userIds, send
+func (_m *Users) WatchPresence(ctx context.Context, viewerId int64, userIds []int64, send func(models.Presence) error) error {
+	ret := _m.Called(ctx, viewerId, userIds, send)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WatchPresence")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []int64, func(models.Presence) error) error); ok {
+		r0 = rf(ctx, viewerId, userIds, send)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WatchUsers provides a mock function with given fields: ctx, sinceCursor, send
+func (_m *Users) WatchUsers(ctx context.Context, sinceCursor string, send func(change models.UserChange, cursor string) error) error {
+	ret := _m.Called(ctx, sinceCursor, send)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WatchUsers")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(change models.UserChange, cursor string) error) error); ok {
+		r0 = rf(ctx, sinceCursor, send)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewUsers creates a new instance of Users. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUsers(t interface {
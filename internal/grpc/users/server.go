@@ -3,72 +3,619 @@ package usersgrpc
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
 
 	messengerv1 "github.com/al3ksus/messengerprotos/gen/go"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
 	"github.com/al3ksus/messengerusers/internal/services/users"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // serverAPI реализует хэндлеры
 type serverAPI struct {
 	messengerv1.UnimplementedUsersServer
-	users Users
+	users          Users
+	trustedProxies []netip.Prefix
 }
 
 var (
-	EmptyPassword       = ""
-	EmptyUsername       = ""
-	EmptyUserId   int64 = 0
+	EmptyPassword        = ""
+	EmptyUsername        = ""
+	EmptyToken           = ""
+	EmptyUserId    int64 = 0
+	EmptySessionId int64 = 0
 )
 
 // Users предоставляет методы для работы с сервисным слоем приложения.
 //
 //go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=Users
 type Users interface {
-	// Login - авторизация пользователя по логину и паролю.
+	// Login - авторизация пользователя по логину и паролю, создает сессию и возвращает ее пару токенов.
 	// Если логин или пароль неверные, возвращает users.ErrInvalidCredentials.
-	Login(ctx context.Context, username string, password string) (id int64, err error)
+	// Если у пользователя подключен TOTP, возвращает *users.SecondFactorRequiredError с токеном незавершенного входа.
+	// Если вход временно заблокирован, возвращает *users.LockoutError.
+	// Если состояние блокировок получить не удалось, возвращает users.ErrLoginUnavailable.
+	Login(ctx context.Context, username string, password string, client models.ClientInfo) (tokens models.Tokens, err error)
+
+	// VerifySecondFactor - завершение входа кодом TOTP или кодом восстановления, создает сессию и возвращает ее пару токенов.
+	// Если токен незавершенного входа недействителен, возвращает users.ErrInvalidChallenge.
+	// Если код неверный, возвращает users.ErrInvalidSecondFactor.
+	// Если вход временно заблокирован, возвращает *users.LockoutError.
+	VerifySecondFactor(ctx context.Context, challenge string, code string, client models.ClientInfo) (tokens models.Tokens, err error)
+
+	// EnableTOTP - начало подключения TOTP, возвращает секрет и otpauth:// URI.
+	// Если пользователь не найден, возвращает users.ErrInvalidCredentials.
+	// Если TOTP уже подключен, возвращает users.ErrTOTPAlreadyEnabled.
+	EnableTOTP(ctx context.Context, userId int64) (secret string, uri string, err error)
+
+	// ConfirmTOTP - подтверждение подключения TOTP первым кодом, возвращает коды восстановления.
+	// Если подключение не начато, возвращает users.ErrTOTPNotEnabled.
+	// Если TOTP уже подключен, возвращает users.ErrTOTPAlreadyEnabled.
+	// Если код неверный, возвращает users.ErrInvalidSecondFactor.
+	ConfirmTOTP(ctx context.Context, userId int64, code string) (recoveryCodes []string, err error)
+
+	// DisableTOTP - отключение TOTP с проверкой пароля.
+	// Если пароль неверный, возвращает users.ErrInvalidCredentials.
+	// Если TOTP не подключен, возвращает users.ErrTOTPNotEnabled.
+	DisableTOTP(ctx context.Context, userId int64, password string) error
+
+	// ClearLockout - снятие блокировки входа по username и (или) IP адресу.
+	ClearLockout(ctx context.Context, username string, ip string) error
+
+	// RefreshTokens - обмен refresh токена на новую пару токенов.
+	// Если refresh токен не найден или истек, возвращает users.ErrInvalidToken.
+	RefreshTokens(ctx context.Context, refreshToken string) (tokens models.Tokens, err error)
+
+	// ValidateToken - проверка access токена.
+	// Если токен недействителен или принадлежит неактивному пользователю, возвращает users.ErrInvalidToken.
+	ValidateToken(ctx context.Context, accessToken string) (info models.TokenInfo, err error)
+
+	// Logout - завершение сессии пользователя.
+	// Если сессия не найдена, возвращает users.ErrSessionNotFound.
+	Logout(ctx context.Context, userId int64, sessionId int64) error
+
+	// LogoutAll - завершение всех сессий пользователя.
+	LogoutAll(ctx context.Context, userId int64) error
+
+	// ListSessions - получение активных сессий пользователя.
+	ListSessions(ctx context.Context, userId int64) ([]models.Session, error)
 
 	// RegisterNewUser - регистрация нового пользователя.
 	// Если заданный username уже занят, возвращает users.ErrUserAlreadyExists.
+	// Если пароль не соответствует политике паролей, возвращает *users.ValidationError.
 	RegisterNewUser(ctx context.Context, username string, password string) (id int64, err error)
 
+	// ChangePassword - смена пароля пользователя с проверкой старого пароля.
+	// Если старый пароль неверный, возвращает users.ErrInvalidCredentials.
+	// Если новый пароль совпадает с текущим, возвращает users.ErrSamePassword.
+	// Если новый пароль не соответствует политике паролей, возвращает *users.ValidationError.
+	ChangePassword(ctx context.Context, userId int64, oldPassword string, newPassword string) error
+
+	// ChangeUsername - смена username пользователя, прежний username резервируется за пользователем.
+	// Если новый username занят или зарезервирован за другим пользователем, возвращает users.ErrUserAlreadyExists.
+	// Если новый username не соответствует правилам, возвращает *users.ValidationError.
+	// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+	ChangeUsername(ctx context.Context, userId int64, username string) error
+
+	// RequestPasswordReset - выдача одноразового кода сброса пароля.
+	// Для неизвестного username ошибка не возвращается.
+	// Запросы учитываются по username и IP клиента, при превышении лимита возвращает *users.LockoutError.
+	RequestPasswordReset(ctx context.Context, username, ip string) error
+
+	// ResetPassword - установка нового пароля по коду сброса.
+	// Если код недействителен, возвращает users.ErrInvalidResetCode.
+	// Если новый пароль не соответствует политике паролей, возвращает *users.ValidationError.
+	ResetPassword(ctx context.Context, code string, newPassword string) error
+
 	// MakeUserInactive переводит пользователя в статус 'неактивен'.
 	// Если пользователь с заданным userId не найден, возвращает users.ErrInvalidCredentials.
 	// Если найденный пользователь уже имеет статус 'неактивен', возвращает ошибку repository.ErrUserAlreadyInactive.
 	MakeUserInactive(ctx context.Context, userId int64) error
+
+	// MakeUserActive возвращает неактивного пользователя в статус 'активен'.
+	// Если пользователь с заданным userId не найден, возвращает users.ErrInvalidCredentials.
+	// Если найденный пользователь уже активен, возвращает ошибку users.ErrUserAlreadyActive.
+	MakeUserActive(ctx context.Context, userId int64) error
+
+	// DeleteUser - запрос на удаление пользователя: пользователь становится неактивным,
+	// а окончательное удаление назначается по истечении льготного периода. Возвращает время удаления.
+	// Если пользователь не найден, возвращает users.ErrUserNotFound.
+	// Если удаление уже назначено, возвращает users.ErrDeletionScheduled.
+	DeleteUser(ctx context.Context, userId int64) (deleteAfter time.Time, err error)
+
+	// CancelUserDeletion - отмена удаления пользователя в течение льготного периода.
+	// Если пользователь не найден или его удаление не назначено, возвращает users.ErrDeletionNotScheduled.
+	CancelUserDeletion(ctx context.Context, userId int64) error
+
+	// ExportUserData - выгрузка всех данных пользователя в виде документа JSON, записываемого в w.
+	// Если пользователь не найден, возвращает users.ErrUserNotFound.
+	ExportUserData(ctx context.Context, userId int64, w io.Writer) error
+
+	// SendContactRequest - отправка заявки в контакты. Если встречная заявка уже есть, пользователи сразу
+	// становятся контактами и возвращается accepted = true.
+	// Если userId и contactId совпадают, возвращает users.ErrSelfContact.
+	// Если получатель заблокировал отправителя, возвращает users.ErrContactRequestBlocked.
+	// Если получатель не найден или неактивен, возвращает users.ErrUserNotFound.
+	// Если пользователи уже состоят в контактах, возвращает users.ErrAlreadyContacts.
+	// Если заявка уже отправлена, возвращает users.ErrContactRequestExists.
+	SendContactRequest(ctx context.Context, userId int64, contactId int64) (accepted bool, err error)
+
+	// AcceptContactRequest - принятие заявки в контакты от пользователя fromUserId.
+	// Если заявка не найдена, возвращает users.ErrContactRequestNotFound.
+	AcceptContactRequest(ctx context.Context, userId int64, fromUserId int64) error
+
+	// DeclineContactRequest - отклонение заявки в контакты от пользователя fromUserId.
+	// Если заявка не найдена, возвращает users.ErrContactRequestNotFound.
+	DeclineContactRequest(ctx context.Context, userId int64, fromUserId int64) error
+
+	// CancelContactRequest - отзыв своей заявки в контакты пользователю toUserId.
+	// Если заявка не найдена, возвращает users.ErrContactRequestNotFound.
+	CancelContactRequest(ctx context.Context, userId int64, toUserId int64) error
+
+	// RemoveContact - удаление пользователей из контактов друг друга.
+	// Если пользователи не состоят в контактах, возвращает users.ErrContactNotFound.
+	RemoveContact(ctx context.Context, userId int64, contactId int64) error
+
+	// ListContacts - постраничное получение активных контактов пользователя.
+	// Возвращает контакты и токен следующей страницы, пустой, если контактов больше нет.
+	// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+	ListContacts(ctx context.Context, userId int64, pageToken string, limit int) (contacts []models.Contact, nextPageToken string, err error)
+
+	// ListContactRequests - получение входящих, если incoming = true, или исходящих заявок пользователя в контакты.
+	ListContactRequests(ctx context.Context, userId int64, incoming bool) (requests []models.ContactRequest, err error)
+
+	// AreContacts - проверка, состоят ли пользователи в контактах друг друга.
+	AreContacts(ctx context.Context, userId int64, contactId int64) (bool, error)
+
+	// BlockUser - блокировка пользователя blockedId. Пользователи удаляются из контактов друг друга.
+	// Если userId и blockedId совпадают, возвращает users.ErrSelfBlock.
+	// Если блокируемый пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+	// Если пользователь уже заблокирован, возвращает users.ErrAlreadyBlocked.
+	BlockUser(ctx context.Context, userId int64, blockedId int64) error
+
+	// UnblockUser - снятие блокировки с пользователя blockedId.
+	// Если пользователь не заблокирован, возвращает users.ErrBlockNotFound.
+	UnblockUser(ctx context.Context, userId int64, blockedId int64) error
+
+	// ListBlocked - постраничное получение пользователей, заблокированных пользователем.
+	// Возвращает пользователей и токен следующей страницы, пустой, если пользователей больше нет.
+	// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+	ListBlocked(ctx context.Context, userId int64, pageToken string, limit int) (blocked []models.BlockedUser, nextPageToken string, err error)
+
+	// IsBlocked - проверка, заблокировал ли пользователь userId пользователя targetId.
+	IsBlocked(ctx context.Context, userId int64, targetId int64) (bool, error)
+
+	// Heartbeat - отметка активности пользователя.
+	Heartbeat(ctx context.Context, userId int64) error
+
+	// GetPresence - получение присутствия пользователей в сети по списку id пользователем viewerId.
+	// Если id больше, чем допускает конфигурация, возвращает users.ErrBatchTooLarge.
+	GetPresence(ctx context.Context, viewerId int64, userIds []int64) (presences []models.Presence, err error)
+
+	// WatchPresence - наблюдение за присутствием пользователей в сети пользователем viewerId.
+	// Текущее состояние и изменения передаются в send, пока не будет отменен ctx.
+	// Если id больше, чем допускает конфигурация, возвращает users.ErrBatchTooLarge.
+	WatchPresence(ctx context.Context, viewerId int64, userIds []int64, send func(models.Presence) error) error
+
+	// GetPrivacySettings - получение настроек приватности пользователя.
+	GetPrivacySettings(ctx context.Context, userId int64) (settings models.PrivacySettings, err error)
+
+	// UpdatePrivacySettings - изменение настроек приватности пользователя, перечисленных в fields.
+	// Если поле неизвестно или значение недопустимо, возвращает *users.ValidationError.
+	// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+	UpdatePrivacySettings(ctx context.Context, userId int64, settings models.PrivacySettings, fields []string) (updated models.PrivacySettings, err error)
+
+	// RegisterDevice - регистрация устройства пользователя для push уведомлений.
+	// Если push токен уже зарегистрирован, устройство переходит к пользователю и обновляется.
+	// Если платформа неизвестна или данные устройства недопустимы, возвращает *users.ValidationError.
+	// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+	RegisterDevice(ctx context.Context, userId int64, platform, pushToken, appVersion string) (device models.Device, err error)
+
+	// UnregisterDevice - удаление устройства пользователя.
+	// Если у пользователя нет такого устройства, возвращает users.ErrDeviceNotFound.
+	UnregisterDevice(ctx context.Context, userId int64, deviceId int64) error
+
+	// ListDevices - получение устройств пользователя, начиная с последнего активного.
+	ListDevices(ctx context.Context, userId int64) (devices []models.Device, err error)
+
+	// WatchUsers - поток изменений пользователей, следующих после sinceCursor, и новых изменений.
+	// Изменения и их курсоры передаются в send, пока не будет отменен ctx.
+	// Если sinceCursor пустой, передаются только изменения, зафиксированные после начала потока.
+	// Если курсор недействителен, возвращает users.ErrInvalidCursor.
+	// Если изменения после курсора могли быть удалены из журнала изменений, возвращает users.ErrCursorExpired.
+	WatchUsers(ctx context.Context, sinceCursor string, send func(change models.UserChange, cursor string) error) error
+
+	// GetUserById - получение активного пользователя по id пользователем viewerId.
+	// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+	GetUserById(ctx context.Context, viewerId int64, userId int64) (user models.User, err error)
+
+	// GetUserByUsername - получение активного пользователя по username пользователем viewerId.
+	// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+	GetUserByUsername(ctx context.Context, viewerId int64, username string) (user models.User, err error)
+
+	// GetUsers - получение активных пользователей по списку id одним запросом пользователем viewerId.
+	// Возвращает найденных пользователей по их id и id, для которых пользователь не найден.
+	// Если id больше, чем допускает конфигурация, возвращает users.ErrBatchTooLarge.
+	GetUsers(ctx context.Context, viewerId int64, userIds []int64) (users map[int64]models.User, missingIds []int64, err error)
+
+	// SearchUsers - поиск активных пользователей по username с постраничной выдачей пользователем viewerId.
+	// Возвращает найденных пользователей и токен следующей страницы, пустой, если результатов больше нет.
+	// Если запрос недопустим, возвращает *users.ValidationError.
+	// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+	SearchUsers(ctx context.Context, viewerId int64, query string, pageToken string, limit int) (users []models.User, nextPageToken string, err error)
+
+	// ListUsers - постраничное получение пользователей, подходящих под условия filter, упорядоченных по id.
+	// Возвращает пользователей и токен следующей страницы, пустой, если пользователей больше нет.
+	// Если условия отбора недопустимы, возвращает *users.ValidationError.
+	// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+	ListUsers(ctx context.Context, filter models.UserFilter, desc bool, pageToken string, limit int) (users []models.User, nextPageToken string, err error)
+
+	// UpdateProfile - изменение полей профиля пользователя, перечисленных в fields.
+	// Если поле неизвестно или значение не соответствует правилам, возвращает *users.ValidationError.
+	// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+	UpdateProfile(ctx context.Context, userId int64, profile models.Profile, fields []string) (user models.User, err error)
+}
+
+// Register регистрирует grpc сервер.
+// trustedProxies - адреса шлюзов, которым разрешено передавать IP клиента в заголовке x-forwarded-for.
+func Register(gRPCServer *grpc.Server, users Users, trustedProxies []netip.Prefix) {
+	messengerv1.RegisterUsersServer(gRPCServer, &serverAPI{users: users, trustedProxies: trustedProxies})
 }
 
-// Register регистрирует grpc сервер
-func Register(gRPCServer *grpc.Server, users Users) {
-	messengerv1.RegisterUsersServer(gRPCServer, &serverAPI{users: users})
+// ParseTrustedProxies разбирает список адресов доверенных шлюзов. Элемент списка - IP адрес или подсеть в нотации CIDR.
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, err
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
 }
 
 // Хэндлер Login отвечает за авторизацию пользователей по логину и паролю.
 // Если логин или пароль неверные, возвращает ошибку InvalidArguments.
+// Если у пользователя подключен TOTP, вместо токенов возвращает токен незавершенного входа для VerifySecondFactor.
+// Если вход временно заблокирован, возвращает ошибку ResourceExhausted с деталями RetryInfo.
+// Если состояние блокировок получить не удалось, возвращает ошибку Unavailable.
 func (s *serverAPI) Login(ctx context.Context, in *messengerv1.LoginRequest) (*messengerv1.LoginResponse, error) {
 	if err := validate(in.Password, in.Username); err != nil {
 		return nil, err
 	}
 
-	id, err := s.users.Login(ctx, in.GetUsername(), in.GetPassword())
+	tokens, err := s.users.Login(ctx, in.GetUsername(), in.GetPassword(), s.clientInfo(ctx, in.GetDeviceName()))
 	if err != nil {
 		if errors.Is(err, users.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
 		}
+		var challengeErr *users.SecondFactorRequiredError
+		if errors.As(err, &challengeErr) {
+			return &messengerv1.LoginResponse{
+				SecondFactorChallenge: &messengerv1.SecondFactorChallenge{
+					Challenge: challengeErr.Challenge,
+					ExpiresAt: timestamppb.New(challengeErr.ExpiresAt),
+				},
+			}, nil
+		}
+		var lockoutErr *users.LockoutError
+		if errors.As(err, &lockoutErr) {
+			return nil, lockoutStatus("too many login attempts", lockoutErr)
+		}
+		if errors.Is(err, users.ErrLoginUnavailable) {
+			return nil, status.Error(codes.Unavailable, "login temporarily unavailable")
+		}
 
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
 	return &messengerv1.LoginResponse{
-		UserId: id,
+		UserId: tokens.UserId,
+		Tokens: toTokenPair(tokens),
+	}, nil
+}
+
+// Хэндлер VerifySecondFactor отвечает за завершение входа кодом TOTP или кодом восстановления.
+// Если токен незавершенного входа недействителен, возвращает ошибку Unauthenticated.
+// Если код неверный, возвращает ошибку InvalidArgument.
+// Если вход временно заблокирован, возвращает ошибку ResourceExhausted с деталями RetryInfo.
+func (s *serverAPI) VerifySecondFactor(ctx context.Context, in *messengerv1.VerifySecondFactorRequest) (*messengerv1.LoginResponse, error) {
+	if in.GetChallenge() == EmptyToken {
+		return nil, status.Error(codes.InvalidArgument, "challenge is required")
+	}
+
+	if in.GetCode() == EmptyToken {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	tokens, err := s.users.VerifySecondFactor(ctx, in.GetChallenge(), in.GetCode(), s.clientInfo(ctx, in.GetDeviceName()))
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidChallenge) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired challenge")
+		}
+		if errors.Is(err, users.ErrInvalidSecondFactor) {
+			return nil, status.Error(codes.InvalidArgument, "invalid code")
+		}
+		var lockoutErr *users.LockoutError
+		if errors.As(err, &lockoutErr) {
+			return nil, lockoutStatus("too many login attempts", lockoutErr)
+		}
+		if errors.Is(err, users.ErrLoginUnavailable) {
+			return nil, status.Error(codes.Unavailable, "login temporarily unavailable")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.LoginResponse{
+		UserId: tokens.UserId,
+		Tokens: toTokenPair(tokens),
+	}, nil
+}
+
+// Хэндлер EnableTOTP отвечает за начало подключения TOTP.
+// Если пользователь не найден, возвращает ошибку InvalidArgument.
+// Если TOTP уже подключен, возвращает ошибку AlreadyExists.
+func (s *serverAPI) EnableTOTP(ctx context.Context, in *messengerv1.EnableTOTPRequest) (*messengerv1.EnableTOTPResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	secret, uri, err := s.users.EnableTOTP(ctx, in.GetUserId())
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "user not found")
+		}
+		if errors.Is(err, users.ErrTOTPAlreadyEnabled) {
+			return nil, status.Error(codes.AlreadyExists, "totp already enabled")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.EnableTOTPResponse{
+		Secret: secret,
+		Uri:    uri,
+	}, nil
+}
+
+// Хэндлер ConfirmTOTP отвечает за подтверждение подключения TOTP и выдачу кодов восстановления.
+// Если подключение не начато, возвращает ошибку FailedPrecondition.
+// Если TOTP уже подключен, возвращает ошибку AlreadyExists.
+// Если код неверный, возвращает ошибку InvalidArgument.
+func (s *serverAPI) ConfirmTOTP(ctx context.Context, in *messengerv1.ConfirmTOTPRequest) (*messengerv1.ConfirmTOTPResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetCode() == EmptyToken {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	recoveryCodes, err := s.users.ConfirmTOTP(ctx, in.GetUserId(), in.GetCode())
+	if err != nil {
+		if errors.Is(err, users.ErrTOTPNotEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "totp enrollment not started")
+		}
+		if errors.Is(err, users.ErrTOTPAlreadyEnabled) {
+			return nil, status.Error(codes.AlreadyExists, "totp already enabled")
+		}
+		if errors.Is(err, users.ErrInvalidSecondFactor) {
+			return nil, status.Error(codes.InvalidArgument, "invalid code")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.ConfirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// Хэндлер DisableTOTP отвечает за отключение TOTP с проверкой пароля.
+// Если пароль неверный, возвращает ошибку InvalidArgument.
+// Если TOTP не подключен, возвращает ошибку FailedPrecondition.
+func (s *serverAPI) DisableTOTP(ctx context.Context, in *messengerv1.DisableTOTPRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetPassword() == EmptyPassword {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	if err := s.users.DisableTOTP(ctx, in.GetUserId(), in.GetPassword()); err != nil {
+		if errors.Is(err, users.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
+		}
+		if errors.Is(err, users.ErrTOTPNotEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "totp not enabled")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ClearLockout отвечает за снятие блокировки входа по username и (или) IP адресу.
+func (s *serverAPI) ClearLockout(ctx context.Context, in *messengerv1.ClearLockoutRequest) (*messengerv1.Empty, error) {
+	if in.GetUsername() == EmptyUsername && in.GetIp() == "" {
+		return nil, status.Error(codes.InvalidArgument, "username or ip is required")
+	}
+
+	if err := s.users.ClearLockout(ctx, in.GetUsername(), in.GetIp()); err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер RefreshToken отвечает за ротацию токенов.
+// Если refresh токен недействителен, возвращает ошибку Unauthenticated.
+func (s *serverAPI) RefreshToken(ctx context.Context, in *messengerv1.RefreshTokenRequest) (*messengerv1.RefreshTokenResponse, error) {
+	if in.GetRefreshToken() == EmptyToken {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	tokens, err := s.users.RefreshTokens(ctx, in.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.RefreshTokenResponse{
+		UserId: tokens.UserId,
+		Tokens: toTokenPair(tokens),
+	}, nil
+}
+
+// Хэндлер ValidateToken отвечает за проверку access токенов другими сервисами.
+// Для недействительного токена возвращает ответ с active = false.
+func (s *serverAPI) ValidateToken(ctx context.Context, in *messengerv1.ValidateTokenRequest) (*messengerv1.ValidateTokenResponse, error) {
+	if in.GetAccessToken() == EmptyToken {
+		return nil, status.Error(codes.InvalidArgument, "access_token is required")
+	}
+
+	info, err := s.users.ValidateToken(ctx, in.GetAccessToken())
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidToken) {
+			return &messengerv1.ValidateTokenResponse{
+				Active: false,
+			}, nil
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.ValidateTokenResponse{
+		UserId:    info.UserId,
+		SessionId: info.SessionId,
+		Active:    true,
+		ExpiresAt: timestamppb.New(info.ExpiresAt),
 	}, nil
 }
 
+// Хэндлер Logout отвечает за завершение сессии пользователя.
+// Если сессия не найдена, возвращает ошибку NotFound.
+func (s *serverAPI) Logout(ctx context.Context, in *messengerv1.LogoutRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetSessionId() == EmptySessionId {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+
+	if err := s.users.Logout(ctx, in.GetUserId(), in.GetSessionId()); err != nil {
+		if errors.Is(err, users.ErrSessionNotFound) {
+			return nil, status.Error(codes.NotFound, "session not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер LogoutAll отвечает за завершение всех сессий пользователя.
+func (s *serverAPI) LogoutAll(ctx context.Context, in *messengerv1.LogoutAllRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.LogoutAll(ctx, in.GetUserId()); err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ListSessions отвечает за получение списка активных сессий (устройств) пользователя.
+func (s *serverAPI) ListSessions(ctx context.Context, in *messengerv1.ListSessionsRequest) (*messengerv1.ListSessionsResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.users.ListSessions(ctx, in.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.ListSessionsResponse{
+		Sessions: make([]*messengerv1.Session, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &messengerv1.Session{
+			Id:         session.Id,
+			DeviceName: session.DeviceName,
+			Ip:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  timestamppb.New(session.CreatedAt),
+			LastSeenAt: timestamppb.New(session.LastSeenAt),
+		})
+	}
+
+	return resp, nil
+}
+
 // Хэндлер Register отвечает за регистрацию новых пользователей.
 // Если логин уже занят, возвращает ошибку AlreadyExists.
+// Если пароль не соответствует политике паролей, возвращает ошибку InvalidArgument с перечнем нарушений.
 func (s *serverAPI) Register(ctx context.Context, in *messengerv1.RegisterRequest) (*messengerv1.RegisterResponse, error) {
 	if err := validate(in.Password, in.Username); err != nil {
 		return nil, err
@@ -80,6 +627,10 @@ func (s *serverAPI) Register(ctx context.Context, in *messengerv1.RegisterReques
 			return nil, status.Error(codes.AlreadyExists, "username already taken")
 
 		}
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
 
 		return nil, status.Error(codes.Internal, "internal error")
 	}
@@ -89,21 +640,36 @@ func (s *serverAPI) Register(ctx context.Context, in *messengerv1.RegisterReques
 	}, nil
 }
 
-// Хэндлер ToInactive отвечает за перевод пользователей в состояние 'неаткивен'.
-// Если пользователь не найден, возвращает ошибку InvalidArgument.
-// Если пользователь уже неактивен, возвращает ошибку AlreadyExists.
-func (s *serverAPI) ToInactive(ctx context.Context, in *messengerv1.ToInactiveRequest) (*messengerv1.Empty, error) {
-	if err := validateId(in.UserId); err != nil {
+// Хэндлер ChangePassword отвечает за смену пароля пользователя.
+// Если старый пароль неверный, новый совпадает с текущим или не соответствует политике паролей,
+// возвращает ошибку InvalidArgument.
+func (s *serverAPI) ChangePassword(ctx context.Context, in *messengerv1.ChangePasswordRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
 		return nil, err
 	}
 
-	if err := s.users.MakeUserInactive(ctx, in.GetUserId()); err != nil {
-		//Пользователь с таким id не существует или неактивен
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetOldPassword() == EmptyPassword {
+		return nil, status.Error(codes.InvalidArgument, "old_password is required")
+	}
+
+	if in.GetNewPassword() == EmptyPassword {
+		return nil, status.Error(codes.InvalidArgument, "new_password is required")
+	}
+
+	if err := s.users.ChangePassword(ctx, in.GetUserId(), in.GetOldPassword(), in.GetNewPassword()); err != nil {
 		if errors.Is(err, users.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "user not found")
+			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
 		}
-		if errors.Is(err, users.ErrUserAlreadyInactive) {
-			return nil, status.Error(codes.AlreadyExists, "user already inactive")
+		if errors.Is(err, users.ErrSamePassword) {
+			return nil, status.Error(codes.InvalidArgument, "new password must differ from the current one")
+		}
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
 		}
 
 		return nil, status.Error(codes.Internal, "internal error")
@@ -112,18 +678,1281 @@ func (s *serverAPI) ToInactive(ctx context.Context, in *messengerv1.ToInactiveRe
 	return &messengerv1.Empty{}, nil
 }
 
-// validate валидирует пароль и логин.
-// Проверка на пустоту.
-func validate(password, username string) error {
-	if username == EmptyUsername {
-		return status.Error(codes.InvalidArgument, "username is required")
+// Хэндлер ChangeUsername отвечает за смену username пользователя.
+// Если username занят или недавно принадлежал другому пользователю, возвращает ошибку AlreadyExists.
+// Если username не соответствует правилам, возвращает ошибку InvalidArgument с перечнем нарушений.
+// Если пользователь не найден или неактивен, возвращает ошибку NotFound.
+func (s *serverAPI) ChangeUsername(ctx context.Context, in *messengerv1.ChangeUsernameRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
 	}
 
-	if password == EmptyPassword {
-		return status.Error(codes.InvalidArgument, "password is required")
+	if in.GetUsername() == EmptyUsername {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
 	}
 
-	return nil
+	if err := s.users.ChangeUsername(ctx, in.GetUserId(), in.GetUsername()); err != nil {
+		if errors.Is(err, users.ErrUserAlreadyExists) {
+			return nil, status.Error(codes.AlreadyExists, "username already taken")
+		}
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер RequestPasswordReset отвечает за отправку кода сброса пароля.
+// Ответ не зависит от того, существует ли пользователь.
+// Если запросов с username или IP клиента слишком много, возвращает ошибку ResourceExhausted с RetryInfo.
+func (s *serverAPI) RequestPasswordReset(ctx context.Context, in *messengerv1.RequestPasswordResetRequest) (*messengerv1.Empty, error) {
+	if in.GetUsername() == EmptyUsername {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
+	}
+
+	if err := s.users.RequestPasswordReset(ctx, in.GetUsername(), s.clientInfo(ctx, "").IP); err != nil {
+		var lockoutErr *users.LockoutError
+		if errors.As(err, &lockoutErr) {
+			return nil, lockoutStatus("too many password reset requests", lockoutErr)
+		}
+		if errors.Is(err, users.ErrLoginUnavailable) {
+			return nil, status.Error(codes.Unavailable, "password reset temporarily unavailable")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ResetPassword отвечает за установку нового пароля по коду сброса.
+// Если код недействителен или новый пароль не соответствует политике паролей, возвращает ошибку InvalidArgument.
+func (s *serverAPI) ResetPassword(ctx context.Context, in *messengerv1.ResetPasswordRequest) (*messengerv1.Empty, error) {
+	if in.GetCode() == EmptyToken {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	if in.GetNewPassword() == EmptyPassword {
+		return nil, status.Error(codes.InvalidArgument, "new_password is required")
+	}
+
+	if err := s.users.ResetPassword(ctx, in.GetCode(), in.GetNewPassword()); err != nil {
+		if errors.Is(err, users.ErrInvalidResetCode) {
+			return nil, status.Error(codes.InvalidArgument, "invalid or expired reset code")
+		}
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ToInactive отвечает за перевод пользователей в состояние 'неаткивен'.
+// Если пользователь не найден, возвращает ошибку InvalidArgument.
+// Если пользователь уже неактивен, возвращает ошибку AlreadyExists.
+func (s *serverAPI) ToInactive(ctx context.Context, in *messengerv1.ToInactiveRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.UserId); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.MakeUserInactive(ctx, in.GetUserId()); err != nil {
+		//Пользователь с таким id не существует или неактивен
+		if errors.Is(err, users.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "user not found")
+		}
+		if errors.Is(err, users.ErrUserAlreadyInactive) {
+			return nil, status.Error(codes.AlreadyExists, "user already inactive")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер Reactivate отвечает за возвращение пользователей из состояния 'неактивен'.
+// Если пользователь не найден, возвращает ошибку InvalidArgument.
+// Если пользователь уже активен, возвращает ошибку AlreadyExists.
+func (s *serverAPI) Reactivate(ctx context.Context, in *messengerv1.ReactivateRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.MakeUserActive(ctx, in.GetUserId()); err != nil {
+		if errors.Is(err, users.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "user not found")
+		}
+		if errors.Is(err, users.ErrUserAlreadyActive) {
+			return nil, status.Error(codes.AlreadyExists, "user already active")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер DeleteUser отвечает за запрос на удаление пользователя.
+// Пользователь сразу становится неактивным, окончательное удаление выполняется после времени delete_after.
+// Если пользователь не найден, возвращает ошибку NotFound.
+// Если удаление уже назначено, возвращает ошибку FailedPrecondition.
+func (s *serverAPI) DeleteUser(ctx context.Context, in *messengerv1.DeleteUserRequest) (*messengerv1.DeleteUserResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	deleteAfter, err := s.users.DeleteUser(ctx, in.GetUserId())
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		if errors.Is(err, users.ErrDeletionScheduled) {
+			return nil, status.Error(codes.FailedPrecondition, "deletion already scheduled")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.DeleteUserResponse{
+		DeleteAfter: timestamppb.New(deleteAfter),
+	}, nil
+}
+
+// Хэндлер CancelUserDeletion отвечает за отмену удаления пользователя до времени delete_after.
+// Если удаление пользователя не назначено, возвращает ошибку FailedPrecondition.
+func (s *serverAPI) CancelUserDeletion(ctx context.Context, in *messengerv1.CancelUserDeletionRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.CancelUserDeletion(ctx, in.GetUserId()); err != nil {
+		if errors.Is(err, users.ErrDeletionNotScheduled) {
+			return nil, status.Error(codes.FailedPrecondition, "deletion not scheduled")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ExportUserData отвечает за выгрузку всех данных пользователя.
+// Документ JSON передается в потоке частями не больше exportChunkSize.
+// Если пользователь не найден, возвращает ошибку NotFound.
+func (s *serverAPI) ExportUserData(in *messengerv1.ExportUserDataRequest, stream messengerv1.Users_ExportUserDataServer) error {
+	if err := validateId(in.GetUserId()); err != nil {
+		return err
+	}
+
+	if err := authorize(stream.Context(), in.GetUserId()); err != nil {
+		return err
+	}
+
+	if err := s.users.ExportUserData(stream.Context(), in.GetUserId(), chunkWriter{stream: stream}); err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return status.Error(codes.NotFound, "user not found")
+		}
+
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return nil
+}
+
+// Хэндлер SendContactRequest отвечает за отправку заявки в контакты.
+// Если получатель уже отправил встречную заявку, пользователи сразу становятся контактами и возвращается accepted = true.
+// Если заявка отправлена самому себе, возвращает ошибку InvalidArgument.
+// Если получатель заблокировал отправителя, возвращает ошибку PermissionDenied.
+// Если получатель не найден или неактивен, возвращает ошибку NotFound.
+// Если пользователи уже состоят в контактах или заявка уже отправлена, возвращает ошибку AlreadyExists.
+func (s *serverAPI) SendContactRequest(ctx context.Context, in *messengerv1.SendContactRequestRequest) (*messengerv1.SendContactRequestResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetContactId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "contact_id is required")
+	}
+
+	accepted, err := s.users.SendContactRequest(ctx, in.GetUserId(), in.GetContactId())
+	if err != nil {
+		if errors.Is(err, users.ErrSelfContact) {
+			return nil, status.Error(codes.InvalidArgument, "cannot add yourself to contacts")
+		}
+		if errors.Is(err, users.ErrContactRequestBlocked) {
+			return nil, status.Error(codes.PermissionDenied, "contact request not allowed")
+		}
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		if errors.Is(err, users.ErrAlreadyContacts) {
+			return nil, status.Error(codes.AlreadyExists, "already contacts")
+		}
+		if errors.Is(err, users.ErrContactRequestExists) {
+			return nil, status.Error(codes.AlreadyExists, "contact request already exists")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.SendContactRequestResponse{
+		Accepted: accepted,
+	}, nil
+}
+
+// Хэндлер AcceptContactRequest отвечает за принятие заявки в контакты.
+// Если заявка не найдена, возвращает ошибку NotFound.
+func (s *serverAPI) AcceptContactRequest(ctx context.Context, in *messengerv1.AcceptContactRequestRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetFromUserId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "from_user_id is required")
+	}
+
+	if err := s.users.AcceptContactRequest(ctx, in.GetUserId(), in.GetFromUserId()); err != nil {
+		return nil, contactRequestStatus(err)
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер DeclineContactRequest отвечает за отклонение заявки в контакты.
+// Если заявка не найдена, возвращает ошибку NotFound.
+func (s *serverAPI) DeclineContactRequest(ctx context.Context, in *messengerv1.DeclineContactRequestRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetFromUserId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "from_user_id is required")
+	}
+
+	if err := s.users.DeclineContactRequest(ctx, in.GetUserId(), in.GetFromUserId()); err != nil {
+		return nil, contactRequestStatus(err)
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер CancelContactRequest отвечает за отзыв отправленной заявки в контакты.
+// Если заявка не найдена, возвращает ошибку NotFound.
+func (s *serverAPI) CancelContactRequest(ctx context.Context, in *messengerv1.CancelContactRequestRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetToUserId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "to_user_id is required")
+	}
+
+	if err := s.users.CancelContactRequest(ctx, in.GetUserId(), in.GetToUserId()); err != nil {
+		return nil, contactRequestStatus(err)
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер RemoveContact отвечает за удаление пользователей из контактов друг друга.
+// Если пользователи не состоят в контактах, возвращает ошибку NotFound.
+func (s *serverAPI) RemoveContact(ctx context.Context, in *messengerv1.RemoveContactRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetContactId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "contact_id is required")
+	}
+
+	if err := s.users.RemoveContact(ctx, in.GetUserId(), in.GetContactId()); err != nil {
+		if errors.Is(err, users.ErrContactNotFound) {
+			return nil, status.Error(codes.NotFound, "contact not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ListContacts отвечает за постраничное получение контактов пользователя, упорядоченных по id.
+// Если токен страницы недействителен, возвращает ошибку InvalidArgument.
+func (s *serverAPI) ListContacts(ctx context.Context, in *messengerv1.ListContactsRequest) (*messengerv1.ListContactsResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	contacts, nextPageToken, err := s.users.ListContacts(ctx, in.GetUserId(), in.GetPageToken(), int(in.GetLimit()))
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.ListContactsResponse{
+		Contacts:      make([]*messengerv1.Contact, 0, len(contacts)),
+		NextPageToken: nextPageToken,
+	}
+	for _, contact := range contacts {
+		resp.Contacts = append(resp.Contacts, &messengerv1.Contact{
+			User:  toUser(contact.User),
+			Since: timestamppb.New(contact.Since),
+		})
+	}
+
+	return resp, nil
+}
+
+// Хэндлер ListContactRequests отвечает за получение заявок пользователя в контакты.
+// Если направление не указано, возвращает входящие заявки.
+func (s *serverAPI) ListContactRequests(ctx context.Context, in *messengerv1.ListContactRequestsRequest) (*messengerv1.ListContactRequestsResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	var incoming bool
+	switch in.GetDirection() {
+	case messengerv1.ContactRequestDirection_CONTACT_REQUEST_DIRECTION_UNSPECIFIED,
+		messengerv1.ContactRequestDirection_CONTACT_REQUEST_DIRECTION_INCOMING:
+		incoming = true
+	case messengerv1.ContactRequestDirection_CONTACT_REQUEST_DIRECTION_OUTGOING:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid direction")
+	}
+
+	requests, err := s.users.ListContactRequests(ctx, in.GetUserId(), incoming)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.ListContactRequestsResponse{
+		Requests: make([]*messengerv1.ContactRequest, 0, len(requests)),
+	}
+	for _, request := range requests {
+		resp.Requests = append(resp.Requests, &messengerv1.ContactRequest{
+			FromUserId: request.FromUserId,
+			ToUserId:   request.ToUserId,
+			CreatedAt:  timestamppb.New(request.CreatedAt),
+		})
+	}
+
+	return resp, nil
+}
+
+// Хэндлер CheckContact отвечает за проверку, состоят ли пользователи в контактах друг друга.
+func (s *serverAPI) CheckContact(ctx context.Context, in *messengerv1.CheckContactRequest) (*messengerv1.CheckContactResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetContactId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "contact_id is required")
+	}
+
+	isContact, err := s.users.AreContacts(ctx, in.GetUserId(), in.GetContactId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.CheckContactResponse{
+		IsContact: isContact,
+	}, nil
+}
+
+// Хэндлер BlockUser отвечает за блокировку пользователя.
+// Если пользователь блокирует самого себя, возвращает ошибку InvalidArgument.
+// Если блокируемый пользователь не найден или неактивен, возвращает ошибку NotFound.
+// Если пользователь уже заблокирован, возвращает ошибку AlreadyExists.
+func (s *serverAPI) BlockUser(ctx context.Context, in *messengerv1.BlockUserRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetBlockedUserId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "blocked_user_id is required")
+	}
+
+	if err := s.users.BlockUser(ctx, in.GetUserId(), in.GetBlockedUserId()); err != nil {
+		if errors.Is(err, users.ErrSelfBlock) {
+			return nil, status.Error(codes.InvalidArgument, "cannot block yourself")
+		}
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		if errors.Is(err, users.ErrAlreadyBlocked) {
+			return nil, status.Error(codes.AlreadyExists, "user already blocked")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер UnblockUser отвечает за снятие блокировки с пользователя.
+// Если пользователь не заблокирован, возвращает ошибку NotFound.
+func (s *serverAPI) UnblockUser(ctx context.Context, in *messengerv1.UnblockUserRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetBlockedUserId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "blocked_user_id is required")
+	}
+
+	if err := s.users.UnblockUser(ctx, in.GetUserId(), in.GetBlockedUserId()); err != nil {
+		if errors.Is(err, users.ErrBlockNotFound) {
+			return nil, status.Error(codes.NotFound, "user not blocked")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ListBlocked отвечает за постраничное получение заблокированных пользователей, упорядоченных по id.
+// Если токен страницы недействителен, возвращает ошибку InvalidArgument.
+func (s *serverAPI) ListBlocked(ctx context.Context, in *messengerv1.ListBlockedRequest) (*messengerv1.ListBlockedResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	blocked, nextPageToken, err := s.users.ListBlocked(ctx, in.GetUserId(), in.GetPageToken(), int(in.GetLimit()))
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.ListBlockedResponse{
+		Users:         make([]*messengerv1.BlockedUser, 0, len(blocked)),
+		NextPageToken: nextPageToken,
+	}
+	for _, user := range blocked {
+		resp.Users = append(resp.Users, &messengerv1.BlockedUser{
+			User:      toUser(user.User),
+			BlockedAt: timestamppb.New(user.BlockedAt),
+		})
+	}
+
+	return resp, nil
+}
+
+// Хэндлер IsBlocked отвечает за проверку, заблокировал ли пользователь user_id пользователя target_user_id.
+// Вызывается другими сервисами перед доставкой сообщения, поэтому не обращается к таблице пользователей.
+func (s *serverAPI) IsBlocked(ctx context.Context, in *messengerv1.IsBlockedRequest) (*messengerv1.IsBlockedResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetTargetUserId() == EmptyUserId {
+		return nil, status.Error(codes.InvalidArgument, "target_user_id is required")
+	}
+
+	blocked, err := s.users.IsBlocked(ctx, in.GetUserId(), in.GetTargetUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.IsBlockedResponse{
+		Blocked: blocked,
+	}, nil
+}
+
+// Хэндлер Heartbeat отвечает за отметку активности пользователя.
+// Клиент вызывает его периодически, пока пользователь в сети.
+// Если пользователь не найден или неактивен, возвращает ошибку NotFound.
+func (s *serverAPI) Heartbeat(ctx context.Context, in *messengerv1.HeartbeatRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := authorize(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.Heartbeat(ctx, in.GetUserId()); err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер GetPresence отвечает за получение присутствия нескольких пользователей в сети одним запросом.
+// Если список id пустой или длиннее допустимого, возвращает ошибку InvalidArgument.
+func (s *serverAPI) GetPresence(ctx context.Context, in *messengerv1.GetPresenceRequest) (*messengerv1.GetPresenceResponse, error) {
+	if len(in.GetUserIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_ids is required")
+	}
+
+	viewerId, err := callerId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	presences, err := s.users.GetPresence(ctx, viewerId, in.GetUserIds())
+	if err != nil {
+		if errors.Is(err, users.ErrBatchTooLarge) {
+			return nil, status.Error(codes.InvalidArgument, "too many user_ids")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.GetPresenceResponse{
+		Presences: make([]*messengerv1.Presence, 0, len(presences)),
+	}
+	for _, p := range presences {
+		resp.Presences = append(resp.Presences, toPresence(p))
+	}
+
+	return resp, nil
+}
+
+// Хэндлер WatchPresence отвечает за передачу клиенту присутствия пользователей в сети и его изменений.
+// Сначала передается текущее состояние всех пользователей, затем изменения, пока клиент не закроет поток.
+// Если список id пустой или длиннее допустимого, возвращает ошибку InvalidArgument.
+func (s *serverAPI) WatchPresence(in *messengerv1.WatchPresenceRequest, stream messengerv1.Users_WatchPresenceServer) error {
+	if len(in.GetUserIds()) == 0 {
+		return status.Error(codes.InvalidArgument, "user_ids is required")
+	}
+
+	viewerId, err := callerId(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	err = s.users.WatchPresence(stream.Context(), viewerId, in.GetUserIds(), func(p models.Presence) error {
+		return stream.Send(toPresence(p))
+	})
+	if err != nil {
+		if errors.Is(err, users.ErrBatchTooLarge) {
+			return status.Error(codes.InvalidArgument, "too many user_ids")
+		}
+
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return nil
+}
+
+// Хэндлер GetPrivacySettings отвечает за получение настроек приватности пользователя.
+func (s *serverAPI) GetPrivacySettings(ctx context.Context, in *messengerv1.GetPrivacySettingsRequest) (*messengerv1.PrivacySettings, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.users.GetPrivacySettings(ctx, in.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return toPrivacySettings(settings), nil
+}
+
+// Хэндлер UpdatePrivacySettings отвечает за изменение настроек приватности пользователя.
+// Изменяются только настройки из update_mask.
+// Если маска пустая, содержит неизвестные поля или значения не указаны, возвращает ошибку InvalidArgument.
+// Если пользователь не найден или неактивен, возвращает ошибку NotFound.
+func (s *serverAPI) UpdatePrivacySettings(ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest) (*messengerv1.PrivacySettings, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	fields := in.GetUpdateMask().GetPaths()
+	if len(fields) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "update_mask is required")
+	}
+
+	settings := models.PrivacySettings{
+		LastSeen: fromVisibility(in.GetSettings().GetLastSeen()),
+		Avatar:   fromVisibility(in.GetSettings().GetAvatar()),
+		Search:   fromVisibility(in.GetSettings().GetSearch()),
+	}
+
+	updated, err := s.users.UpdatePrivacySettings(ctx, in.GetUserId(), settings, fields)
+	if err != nil {
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return toPrivacySettings(updated), nil
+}
+
+// Хэндлер RegisterDevice отвечает за регистрацию устройства пользователя для push уведомлений.
+// Если push токен уже зарегистрирован другим пользователем, устройство переходит к user_id.
+// Если платформа не указана или данные устройства недопустимы, возвращает ошибку InvalidArgument.
+// Если пользователь не найден или неактивен, возвращает ошибку NotFound.
+func (s *serverAPI) RegisterDevice(ctx context.Context, in *messengerv1.RegisterDeviceRequest) (*messengerv1.Device, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetPushToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "push_token is required")
+	}
+
+	device, err := s.users.RegisterDevice(ctx, in.GetUserId(), fromDevicePlatform(in.GetPlatform()), in.GetPushToken(), in.GetAppVersion())
+	if err != nil {
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return toDevice(device), nil
+}
+
+// Хэндлер UnregisterDevice отвечает за удаление устройства пользователя.
+// Если у пользователя нет такого устройства, возвращает ошибку NotFound.
+func (s *serverAPI) UnregisterDevice(ctx context.Context, in *messengerv1.UnregisterDeviceRequest) (*messengerv1.Empty, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if in.GetDeviceId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "device_id is required")
+	}
+
+	if err := s.users.UnregisterDevice(ctx, in.GetUserId(), in.GetDeviceId()); err != nil {
+		if errors.Is(err, users.ErrDeviceNotFound) {
+			return nil, status.Error(codes.NotFound, "device not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &messengerv1.Empty{}, nil
+}
+
+// Хэндлер ListDevices отвечает за получение устройств пользователя, начиная с последнего активного.
+func (s *serverAPI) ListDevices(ctx context.Context, in *messengerv1.ListDevicesRequest) (*messengerv1.ListDevicesResponse, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	devices, err := s.users.ListDevices(ctx, in.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.ListDevicesResponse{
+		Devices: make([]*messengerv1.Device, 0, len(devices)),
+	}
+	for _, device := range devices {
+		resp.Devices = append(resp.Devices, toDevice(device))
+	}
+
+	return resp, nil
+}
+
+// Хэндлер WatchUsers отвечает за передачу клиенту потока изменений пользователей.
+// Сначала передаются изменения после since_cursor, затем новые изменения, пока клиент не закроет поток.
+// Курсор каждого изменения позволяет продолжить поток после разрыва без пропусков.
+// Если since_cursor пустой, передаются только изменения, зафиксированные после начала потока.
+// Если курсор недействителен, возвращает ошибку InvalidArgument.
+// Если курсор старше срока хранения журнала изменений, возвращает ошибку OutOfRange.
+func (s *serverAPI) WatchUsers(in *messengerv1.WatchUsersRequest, stream messengerv1.Users_WatchUsersServer) error {
+	err := s.users.WatchUsers(stream.Context(), in.GetSinceCursor(), func(change models.UserChange, cursor string) error {
+		return stream.Send(toUserChange(change, cursor))
+	})
+	if err != nil {
+		if errors.Is(err, users.ErrInvalidCursor) {
+			return status.Error(codes.InvalidArgument, "invalid since_cursor")
+		}
+		if errors.Is(err, users.ErrCursorExpired) {
+			return status.Error(codes.OutOfRange, "since_cursor expired")
+		}
+
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return nil
+}
+
+// Хэндлер GetUserById отвечает за получение профиля пользователя по id.
+// Если пользователь не найден или неактивен, возвращает ошибку NotFound.
+func (s *serverAPI) GetUserById(ctx context.Context, in *messengerv1.GetUserByIdRequest) (*messengerv1.User, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	viewerId, err := callerId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetUserById(ctx, viewerId, in.GetUserId())
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return toUser(user), nil
+}
+
+// Хэндлер GetUserByUsername отвечает за получение профиля пользователя по username.
+// Если пользователь не найден или неактивен, возвращает ошибку NotFound.
+func (s *serverAPI) GetUserByUsername(ctx context.Context, in *messengerv1.GetUserByUsernameRequest) (*messengerv1.User, error) {
+	if in.GetUsername() == EmptyUsername {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
+	}
+
+	viewerId, err := callerId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetUserByUsername(ctx, viewerId, in.GetUsername())
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return toUser(user), nil
+}
+
+// Хэндлер GetUsers отвечает за получение профилей нескольких пользователей одним запросом.
+// Ненайденные и неактивные пользователи возвращаются в списке missing_ids.
+// Если список id пустой или длиннее допустимого, возвращает ошибку InvalidArgument.
+func (s *serverAPI) GetUsers(ctx context.Context, in *messengerv1.GetUsersRequest) (*messengerv1.GetUsersResponse, error) {
+	if len(in.GetUserIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_ids is required")
+	}
+
+	viewerId, err := callerId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found, missingIds, err := s.users.GetUsers(ctx, viewerId, in.GetUserIds())
+	if err != nil {
+		if errors.Is(err, users.ErrBatchTooLarge) {
+			return nil, status.Error(codes.InvalidArgument, "too many user_ids")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.GetUsersResponse{
+		Users:      make(map[int64]*messengerv1.User, len(found)),
+		MissingIds: missingIds,
+	}
+	for id, user := range found {
+		resp.Users[id] = toUser(user)
+	}
+
+	return resp, nil
+}
+
+// Хэндлер SearchUsers отвечает за поиск пользователей по username.
+// Совпадения по началу username идут первыми, затем похожие username.
+// Если запрос пустой или недопустимый, лимит отрицательный или токен страницы недействителен,
+// возвращает ошибку InvalidArgument.
+func (s *serverAPI) SearchUsers(ctx context.Context, in *messengerv1.SearchUsersRequest) (*messengerv1.SearchUsersResponse, error) {
+	if strings.TrimSpace(in.GetQuery()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	if in.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	viewerId, err := callerId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found, nextPageToken, err := s.users.SearchUsers(ctx, viewerId, in.GetQuery(), in.GetPageToken(), int(in.GetLimit()))
+	if err != nil {
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
+		if errors.Is(err, users.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.SearchUsersResponse{
+		Users:         make([]*messengerv1.User, 0, len(found)),
+		NextPageToken: nextPageToken,
+	}
+	for _, user := range found {
+		resp.Users = append(resp.Users, toUser(user))
+	}
+
+	return resp, nil
+}
+
+// Хэндлер ListUsers отвечает за постраничное получение списка пользователей для администрирования.
+// Пользователи отбираются по статусу, периоду создания и началу username и упорядочены по id.
+// Если условия отбора недопустимы, лимит отрицательный или токен страницы недействителен,
+// возвращает ошибку InvalidArgument.
+func (s *serverAPI) ListUsers(ctx context.Context, in *messengerv1.ListUsersRequest) (*messengerv1.ListUsersResponse, error) {
+	if in.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	filter := models.UserFilter{UsernamePrefix: in.GetUsernamePrefix()}
+	switch in.GetStatus() {
+	case messengerv1.UserStatusFilter_USER_STATUS_FILTER_UNSPECIFIED:
+	case messengerv1.UserStatusFilter_USER_STATUS_FILTER_ACTIVE:
+		active := true
+		filter.IsActive = &active
+	case messengerv1.UserStatusFilter_USER_STATUS_FILTER_INACTIVE:
+		active := false
+		filter.IsActive = &active
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid status")
+	}
+
+	if in.GetCreatedAfter() != nil {
+		filter.CreatedAfter = in.GetCreatedAfter().AsTime()
+	}
+	if in.GetCreatedBefore() != nil {
+		filter.CreatedBefore = in.GetCreatedBefore().AsTime()
+	}
+
+	var desc bool
+	switch in.GetOrder() {
+	case messengerv1.SortOrder_SORT_ORDER_UNSPECIFIED, messengerv1.SortOrder_SORT_ORDER_ASC:
+	case messengerv1.SortOrder_SORT_ORDER_DESC:
+		desc = true
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid order")
+	}
+
+	found, nextPageToken, err := s.users.ListUsers(ctx, filter, desc, in.GetPageToken(), int(in.GetLimit()))
+	if err != nil {
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
+		if errors.Is(err, users.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &messengerv1.ListUsersResponse{
+		Users:         make([]*messengerv1.User, 0, len(found)),
+		NextPageToken: nextPageToken,
+	}
+	for _, user := range found {
+		resp.Users = append(resp.Users, toUser(user))
+	}
+
+	return resp, nil
+}
+
+// Хэндлер UpdateProfile отвечает за изменение профиля пользователя.
+// Изменяются только поля из update_mask, пустое значение поля из маски очищает его.
+// Если маска пустая, содержит неизвестные поля или значения не соответствуют правилам,
+// возвращает ошибку InvalidArgument.
+// Если пользователь не найден или неактивен, возвращает ошибку NotFound.
+func (s *serverAPI) UpdateProfile(ctx context.Context, in *messengerv1.UpdateProfileRequest) (*messengerv1.User, error) {
+	if err := validateId(in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	fields := in.GetUpdateMask().GetPaths()
+	if len(fields) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "update_mask is required")
+	}
+
+	profile := models.Profile{
+		DisplayName: in.GetProfile().GetDisplayName(),
+		Bio:         in.GetProfile().GetBio(),
+		AvatarURL:   in.GetProfile().GetAvatarUrl(),
+		Locale:      in.GetProfile().GetLocale(),
+		TimeZone:    in.GetProfile().GetTimeZone(),
+	}
+
+	user, err := s.users.UpdateProfile(ctx, in.GetUserId(), profile, fields)
+	if err != nil {
+		var validationErr *users.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationStatus(validationErr)
+		}
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return toUser(user), nil
+}
+
+// clientInfo собирает данные клиента из контекста запроса.
+// IP берется из адреса соединения. Если соединение пришло от доверенного шлюза, IP берется из заголовка
+// x-forwarded-for: цепочка адресов просматривается справа налево до первого адреса, не принадлежащего доверенным шлюзам.
+func (s *serverAPI) clientInfo(ctx context.Context, deviceName string) models.ClientInfo {
+	client := models.ClientInfo{
+		DeviceName: deviceName,
+	}
+
+	var forwarded []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			client.UserAgent = ua[0]
+		}
+		for _, xff := range md.Get("x-forwarded-for") {
+			forwarded = append(forwarded, strings.Split(xff, ",")...)
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return client
+	}
+
+	client.IP = p.Addr.String()
+	if host, _, err := net.SplitHostPort(client.IP); err == nil {
+		client.IP = host
+	}
+
+	addr, err := netip.ParseAddr(client.IP)
+	if err != nil {
+		return client
+	}
+
+	//Каждый доверенный шлюз дописывает адрес, от которого получил запрос, в конец цепочки
+	for i := len(forwarded) - 1; i >= 0 && s.trustedProxy(addr); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+
+		addr = hop
+	}
+	client.IP = addr.Unmap().String()
+
+	return client
+}
+
+// trustedProxy сообщает, что адрес принадлежит доверенному шлюзу.
+func (s *serverAPI) trustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// callerIdHeader - заголовок метаданных запроса с id пользователя, от имени которого выполняется запрос.
+// Заголовок заполняет шлюз после проверки access токена.
+const callerIdHeader = "x-user-id"
+
+// callerId возвращает id пользователя, от имени которого выполняется запрос, из метаданных запроса.
+// Если заголовка нет, возвращает 0 - запрос считается анонимным, и ему доступны только данные, открытые всем.
+// Если значение заголовка не является id пользователя, возвращает ошибку InvalidArgument.
+func callerId(ctx context.Context) (int64, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	values := md.Get(callerIdHeader)
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(values[0]), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "invalid "+callerIdHeader)
+	}
+
+	return id, nil
+}
+
+// authorize проверяет, что запрос выполняется от имени пользователя userId.
+// Если id вызывающего пользователя не передан, возвращает ошибку Unauthenticated.
+// Если запрос выполняется от имени другого пользователя, возвращает ошибку PermissionDenied.
+func authorize(ctx context.Context, userId int64) error {
+	id, err := callerId(ctx)
+	if err != nil {
+		return err
+	}
+
+	if id == EmptyUserId {
+		return status.Error(codes.Unauthenticated, callerIdHeader+" is required")
+	}
+
+	if id != userId {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	return nil
+}
+
+// toTokenPair преобразует пару токенов в grpc сообщение.
+func toTokenPair(tokens models.Tokens) *messengerv1.TokenPair {
+	return &messengerv1.TokenPair{
+		AccessToken:      tokens.AccessToken,
+		AccessExpiresAt:  timestamppb.New(tokens.AccessExpiresAt),
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: timestamppb.New(tokens.RefreshExpiresAt),
+	}
+}
+
+// exportChunkSize - максимальный размер части документа в одном сообщении ExportUserData.
+const exportChunkSize = 64 << 10
+
+// chunkWriter отправляет записанные данные в поток ExportUserData сообщениями не больше exportChunkSize.
+type chunkWriter struct {
+	stream messengerv1.Users_ExportUserDataServer
+}
+
+// Write реализует io.Writer.
+func (w chunkWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n := min(len(p), exportChunkSize)
+		if err := w.stream.Send(&messengerv1.ExportUserDataChunk{Data: p[:n]}); err != nil {
+			return written, err
+		}
+
+		written += n
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+// contactRequestStatus преобразует ошибку обработки заявки в контакты в grpc статус.
+func contactRequestStatus(err error) error {
+	if errors.Is(err, users.ErrContactRequestNotFound) {
+		return status.Error(codes.NotFound, "contact request not found")
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// toPresence преобразует присутствие пользователя в сообщение grpc.
+// Если пользователь еще не был в сети, время последней активности не заполняется.
+func toPresence(p models.Presence) *messengerv1.Presence {
+	presence := &messengerv1.Presence{
+		UserId: p.UserId,
+		Online: p.Online,
+	}
+	if !p.LastSeenAt.IsZero() {
+		presence.LastSeenAt = timestamppb.New(p.LastSeenAt)
+	}
+
+	return presence
+}
+
+// toPrivacySettings преобразует настройки приватности в сообщение grpc.
+func toPrivacySettings(settings models.PrivacySettings) *messengerv1.PrivacySettings {
+	return &messengerv1.PrivacySettings{
+		LastSeen: toVisibility(settings.LastSeen),
+		Avatar:   toVisibility(settings.Avatar),
+		Search:   toVisibility(settings.Search),
+	}
+}
+
+// toVisibility преобразует видимость данных в значение перечисления grpc.
+func toVisibility(visibility models.Visibility) messengerv1.Visibility {
+	switch visibility {
+	case models.VisibilityEveryone:
+		return messengerv1.Visibility_VISIBILITY_EVERYONE
+	case models.VisibilityContacts:
+		return messengerv1.Visibility_VISIBILITY_CONTACTS
+	case models.VisibilityNobody:
+		return messengerv1.Visibility_VISIBILITY_NOBODY
+	default:
+		return messengerv1.Visibility_VISIBILITY_UNSPECIFIED
+	}
+}
+
+// fromVisibility преобразует значение перечисления grpc в видимость данных.
+// Для неуказанного или неизвестного значения возвращает пустую видимость, которую отклонит проверка сервиса.
+func fromVisibility(visibility messengerv1.Visibility) models.Visibility {
+	switch visibility {
+	case messengerv1.Visibility_VISIBILITY_EVERYONE:
+		return models.VisibilityEveryone
+	case messengerv1.Visibility_VISIBILITY_CONTACTS:
+		return models.VisibilityContacts
+	case messengerv1.Visibility_VISIBILITY_NOBODY:
+		return models.VisibilityNobody
+	default:
+		return ""
+	}
+}
+
+// toDevice преобразует устройство пользователя в сообщение grpc.
+func toDevice(device models.Device) *messengerv1.Device {
+	return &messengerv1.Device{
+		Id:           device.Id,
+		Platform:     toDevicePlatform(device.Platform),
+		PushToken:    device.PushToken,
+		AppVersion:   device.AppVersion,
+		CreatedAt:    timestamppb.New(device.CreatedAt),
+		LastActiveAt: timestamppb.New(device.LastActiveAt),
+	}
+}
+
+// toDevicePlatform преобразует платформу устройства в значение перечисления grpc.
+func toDevicePlatform(platform string) messengerv1.DevicePlatform {
+	switch platform {
+	case models.DevicePlatformIOS:
+		return messengerv1.DevicePlatform_DEVICE_PLATFORM_IOS
+	case models.DevicePlatformAndroid:
+		return messengerv1.DevicePlatform_DEVICE_PLATFORM_ANDROID
+	case models.DevicePlatformWeb:
+		return messengerv1.DevicePlatform_DEVICE_PLATFORM_WEB
+	default:
+		return messengerv1.DevicePlatform_DEVICE_PLATFORM_UNSPECIFIED
+	}
+}
+
+// fromDevicePlatform преобразует значение перечисления grpc в платформу устройства.
+// Для неуказанного или неизвестного значения возвращает пустую платформу, которую отклонит проверка сервиса.
+func fromDevicePlatform(platform messengerv1.DevicePlatform) string {
+	switch platform {
+	case messengerv1.DevicePlatform_DEVICE_PLATFORM_IOS:
+		return models.DevicePlatformIOS
+	case messengerv1.DevicePlatform_DEVICE_PLATFORM_ANDROID:
+		return models.DevicePlatformAndroid
+	case messengerv1.DevicePlatform_DEVICE_PLATFORM_WEB:
+		return models.DevicePlatformWeb
+	default:
+		return ""
+	}
+}
+
+// toUserChange преобразует изменение пользователя и его курсор в сообщение grpc.
+func toUserChange(change models.UserChange, cursor string) *messengerv1.UserChange {
+	return &messengerv1.UserChange{
+		Cursor:     cursor,
+		Type:       change.Type,
+		UserId:     change.UserId,
+		Payload:    change.Payload,
+		OccurredAt: timestamppb.New(change.CreatedAt),
+	}
+}
+
+// toUser преобразует пользователя в grpc сообщение. Хэш пароля не передается.
+func toUser(user models.User) *messengerv1.User {
+	return &messengerv1.User{
+		Id:          user.Id,
+		Username:    user.Username,
+		IsActive:    user.IsActive,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarUrl:   user.AvatarURL,
+		Locale:      user.Locale,
+		TimeZone:    user.TimeZone,
+		CreatedAt:   timestamppb.New(user.CreatedAt),
+		UpdatedAt:   timestamppb.New(user.UpdatedAt),
+	}
+}
+
+// validate валидирует пароль и логин.
+// Проверка на пустоту.
+func validate(password, username string) error {
+	if username == EmptyUsername {
+		return status.Error(codes.InvalidArgument, "username is required")
+	}
+
+	if password == EmptyPassword {
+		return status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	return nil
+}
+
+// validationStatus возвращает ошибку InvalidArgument с деталями BadRequest, содержащими нарушения по полям.
+func validationStatus(validationErr *users.ValidationError) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range validationErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, "validation failed").WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return st.Err()
+}
+
+// lockoutStatus возвращает ошибку ResourceExhausted с сообщением msg и деталями RetryInfo, содержащими время до снятия блокировки.
+func lockoutStatus(msg string, lockoutErr *users.LockoutError) error {
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(time.Until(lockoutErr.Until).Round(time.Second)),
+	})
+	if err != nil {
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return st.Err()
 }
 
 // validateId проверяет id пользователя на пустоту.
@@ -1,17 +1,30 @@
 package usersgrpc
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/netip"
 	"testing"
+	"time"
 
 	messengerv1 "github.com/al3ksus/messengerprotos/gen/go"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
 	"github.com/al3ksus/messengerusers/internal/grpc/users/mocks"
 	usersservice "github.com/al3ksus/messengerusers/internal/services/users"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var (
@@ -19,8 +32,29 @@ var (
 	// EmptyUserId   int64 = 0
 	TestUsername = "user1"
 	// EmptyUsername       = ""
-	TestPassword = "qwerty"
+	TestPassword    = "qwerty"
+	TestNewPassword = "newqwerty"
 	// EmptyPassword       = ""
+	TestRefreshToken         = "refresh"
+	TestAccessToken          = "access"
+	TestSessionId      int64 = 2
+	TestDeviceName           = "phone"
+	TestUserAgent            = "grpc-go"
+	TestResetCode            = "ABCDE23456"
+	TestIP                   = "203.0.113.7"
+	TestTrustedProxies       = []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16"), netip.MustParsePrefix("fd00::/8")}
+)
+
+var (
+	TestTokens = models.Tokens{
+		UserId:           TestUserId,
+		SessionId:        TestSessionId,
+		AccessToken:      TestAccessToken,
+		AccessExpiresAt:  time.Unix(1700000000, 0),
+		RefreshToken:     TestRefreshToken,
+		RefreshExpiresAt: time.Unix(1800000000, 0),
+	}
+	EmptyTokens = models.Tokens{}
 )
 
 var (
@@ -31,9 +65,66 @@ var (
 	TestErrUsernameTaken      = status.Error(codes.AlreadyExists, "username already taken")
 	TestErrInternal           = status.Error(codes.Internal, "internal error")
 	TestErrAlreadyInactive    = status.Error(codes.AlreadyExists, "user already inactive")
+	TestErrAlreadyActive      = status.Error(codes.AlreadyExists, "user already active")
+	TestErrSamePassword       = status.Error(codes.InvalidArgument, "new password must differ from the current one")
+	TestErrEmptyOldPassword   = status.Error(codes.InvalidArgument, "old_password is required")
+	TestErrEmptyNewPassword   = status.Error(codes.InvalidArgument, "new_password is required")
 	TestErrUserNotFound       = status.Error(codes.InvalidArgument, "user not found")
+	TestErrEmptyRefreshToken  = status.Error(codes.InvalidArgument, "refresh_token is required")
+	TestErrInvalidToken       = status.Error(codes.Unauthenticated, "invalid refresh token")
+	TestErrEmptyAccessToken   = status.Error(codes.InvalidArgument, "access_token is required")
+	TestErrEmptySessionId     = status.Error(codes.InvalidArgument, "session_id is required")
+	TestErrSessionNotFound    = status.Error(codes.NotFound, "session not found")
+	TestErrEmptyResetCode     = status.Error(codes.InvalidArgument, "code is required")
+	TestErrInvalidResetCode   = status.Error(codes.InvalidArgument, "invalid or expired reset code")
+	TestErrLoginUnavailable   = status.Error(codes.Unavailable, "login temporarily unavailable")
+	TestErrResetUnavailable   = status.Error(codes.Unavailable, "password reset temporarily unavailable")
+	TestErrEmptyLockoutTarget = status.Error(codes.InvalidArgument, "username or ip is required")
+)
+
+var (
+	TestChallenge          = "challenge"
+	TestTOTPCode           = "123456"
+	TestTOTPSecret         = "JBSWY3DPEHPK3PXP"
+	TestTOTPURI            = "otpauth://totp/Messenger:user1?secret=JBSWY3DPEHPK3PXP"
+	TestRecoveryCodes      = []string{"ABCDE-23456", "FGHJK-78923"}
+	TestChallengeExpiresAt = time.Unix(1700000300, 0)
+)
+
+var (
+	TestErrEmptyChallenge   = status.Error(codes.InvalidArgument, "challenge is required")
+	TestErrEmptyCode        = status.Error(codes.InvalidArgument, "code is required")
+	TestErrInvalidChallenge = status.Error(codes.Unauthenticated, "invalid or expired challenge")
+	TestErrInvalidCode      = status.Error(codes.InvalidArgument, "invalid code")
+	TestErrTOTPEnabled      = status.Error(codes.AlreadyExists, "totp already enabled")
+	TestErrTOTPNotStarted   = status.Error(codes.FailedPrecondition, "totp enrollment not started")
+	TestErrTOTPNotEnabled   = status.Error(codes.FailedPrecondition, "totp not enabled")
+)
+
+var (
+	TestPolicyViolation = "must be at least 8 characters long"
+	TestValidationErr   = &usersservice.ValidationError{
+		Violations: []usersservice.FieldViolation{{Field: "password", Description: TestPolicyViolation}},
+	}
+	TestNewPasswordValidationErr = &usersservice.ValidationError{
+		Violations: []usersservice.FieldViolation{{Field: "new_password", Description: TestPolicyViolation}},
+	}
+	TestErrValidation            = newTestValidationStatus("password", TestPolicyViolation)
+	TestErrNewPasswordValidation = newTestValidationStatus("new_password", TestPolicyViolation)
 )
 
+// newTestValidationStatus возвращает ожидаемую ошибку InvalidArgument с деталями BadRequest для одного поля.
+func newTestValidationStatus(field, description string) error {
+	st, err := status.New(codes.InvalidArgument, "validation failed").WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return st.Err()
+}
+
 func Test_serverAPI_Login(t *testing.T) {
 	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.LoginRequest)
 	type args struct {
@@ -57,7 +148,7 @@ func Test_serverAPI_Login(t *testing.T) {
 				},
 			},
 			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LoginRequest) {
-				users.On("Login", ctx, in.Username, in.Password).Return(EmptyUserId, usersservice.ErrInvalidCredentials)
+				users.On("Login", ctx, in.Username, in.Password, models.ClientInfo{DeviceName: in.DeviceName}).Return(EmptyTokens, usersservice.ErrInvalidCredentials)
 			},
 			wantErr: TestErrInvalidCredentials,
 		},
@@ -71,10 +162,44 @@ func Test_serverAPI_Login(t *testing.T) {
 				},
 			},
 			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LoginRequest) {
-				users.On("Login", ctx, in.Username, in.Password).Return(EmptyUserId, errors.New(""))
+				users.On("Login", ctx, in.Username, in.Password, models.ClientInfo{DeviceName: in.DeviceName}).Return(EmptyTokens, errors.New(""))
 			},
 			wantErr: TestErrInternal,
 		},
+		{
+			name: "SecondFactorRequired",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.LoginRequest{
+					Username: TestUsername,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LoginRequest) {
+				challengeErr := &usersservice.SecondFactorRequiredError{Challenge: TestChallenge, ExpiresAt: TestChallengeExpiresAt}
+				users.On("Login", ctx, in.Username, in.Password, models.ClientInfo{DeviceName: in.DeviceName}).Return(EmptyTokens, fmt.Errorf("users.Login, %w", challengeErr))
+			},
+			want: &messengerv1.LoginResponse{
+				SecondFactorChallenge: &messengerv1.SecondFactorChallenge{
+					Challenge: TestChallenge,
+					ExpiresAt: timestamppb.New(TestChallengeExpiresAt),
+				},
+			},
+		},
+		{
+			name: "LoginUnavailable",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.LoginRequest{
+					Username: TestUsername,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LoginRequest) {
+				users.On("Login", ctx, in.Username, in.Password, models.ClientInfo{DeviceName: in.DeviceName}).Return(EmptyTokens, usersservice.ErrLoginUnavailable)
+			},
+			wantErr: TestErrLoginUnavailable,
+		},
 		{
 			name: "OK",
 			args: args{
@@ -85,10 +210,37 @@ func Test_serverAPI_Login(t *testing.T) {
 				},
 			},
 			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LoginRequest) {
-				users.On("Login", ctx, in.Username, in.Password).Return(TestUserId, nil)
+				users.On("Login", ctx, in.Username, in.Password, models.ClientInfo{DeviceName: in.DeviceName}).Return(TestTokens, nil)
+			},
+			want: &messengerv1.LoginResponse{
+				UserId: TestUserId,
+				Tokens: toTokenPair(TestTokens),
+			},
+		},
+		{
+			name: "ClientInfoFromMetadata",
+			args: args{
+				ctx: peer.NewContext(metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+					"user-agent", TestUserAgent,
+					"x-forwarded-for", "10.0.0.1, 192.168.0.1",
+				)), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.168.0.2"), Port: 50051}}),
+				in: &messengerv1.LoginRequest{
+					Username:   TestUsername,
+					Password:   TestPassword,
+					DeviceName: TestDeviceName,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LoginRequest) {
+				client := models.ClientInfo{
+					DeviceName: TestDeviceName,
+					IP:         "10.0.0.1",
+					UserAgent:  TestUserAgent,
+				}
+				users.On("Login", ctx, in.Username, in.Password, client).Return(TestTokens, nil)
 			},
 			want: &messengerv1.LoginResponse{
 				UserId: TestUserId,
+				Tokens: toTokenPair(TestTokens),
 			},
 		},
 		{
@@ -122,7 +274,8 @@ func Test_serverAPI_Login(t *testing.T) {
 
 			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
 			s := &serverAPI{
-				users: users,
+				users:          users,
+				trustedProxies: TestTrustedProxies,
 			}
 			got, err := s.Login(tt.args.ctx, tt.args.in)
 			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.Login() error = %v, wantErr %v", err, tt.wantErr))
@@ -131,86 +284,193 @@ func Test_serverAPI_Login(t *testing.T) {
 	}
 }
 
-func Test_serverAPI_Register(t *testing.T) {
-	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest)
+func Test_serverAPI_Login_Lockout(t *testing.T) {
+	users := mocks.NewUsers(t)
+	ctx := context.Background()
+	in := &messengerv1.LoginRequest{
+		Username: TestUsername,
+		Password: TestPassword,
+	}
+	lockoutErr := &usersservice.LockoutError{Until: time.Now().Add(time.Minute)}
+	users.On("Login", ctx, in.Username, in.Password, models.ClientInfo{}).Return(EmptyTokens, fmt.Errorf("users.Login, %w", lockoutErr))
+
+	s := &serverAPI{
+		users: users,
+	}
+	got, err := s.Login(ctx, in)
+	assert.Nil(t, got, fmt.Sprintf("serverAPI.Login() = %v, want nil", got))
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code(), fmt.Sprintf("serverAPI.Login() code = %v, want %v", st.Code(), codes.ResourceExhausted))
+	assert.Equal(t, "too many login attempts", st.Message())
+
+	if assert.Len(t, st.Details(), 1) {
+		retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+		if assert.True(t, ok, "serverAPI.Login() details must contain RetryInfo") {
+			delay := retryInfo.GetRetryDelay().AsDuration()
+			assert.True(t, delay > 0 && delay <= time.Minute, fmt.Sprintf("serverAPI.Login() retry delay = %v", delay))
+		}
+	}
+}
+
+func Test_serverAPI_ClearLockout(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ClearLockoutRequest)
 	type args struct {
 		ctx context.Context
-		in  *messengerv1.RegisterRequest
+		in  *messengerv1.ClearLockoutRequest
 	}
 	tests := []struct {
 		name         string
 		args         args
 		mockBehavior mockBehavior
-		want         *messengerv1.RegisterResponse
+		want         *messengerv1.Empty
 		wantErr      error
 	}{
 		{
-			name: "UsernameTaken",
+			name: "OK",
 			args: args{
 				ctx: context.Background(),
-				in: &messengerv1.RegisterRequest{
+				in: &messengerv1.ClearLockoutRequest{
 					Username: TestUsername,
-					Password: TestPassword,
+					Ip:       "10.0.0.1",
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {
-				users.On("RegisterNewUser", ctx, in.Username, in.Password).Return(EmptyUserId, usersservice.ErrUserAlreadyExists)
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ClearLockoutRequest) {
+				users.On("ClearLockout", ctx, in.Username, in.Ip).Return(nil)
 			},
-			wantErr: TestErrUsernameTaken,
+			want: &messengerv1.Empty{},
 		},
 		{
 			name: "InternalError",
 			args: args{
 				ctx: context.Background(),
-				in: &messengerv1.RegisterRequest{
+				in: &messengerv1.ClearLockoutRequest{
 					Username: TestUsername,
-					Password: TestPassword,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {
-				users.On("RegisterNewUser", ctx, in.Username, in.Password).Return(EmptyUserId, errors.New(""))
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ClearLockoutRequest) {
+				users.On("ClearLockout", ctx, in.Username, in.Ip).Return(errors.New(""))
 			},
 			wantErr: TestErrInternal,
 		},
+		{
+			name: "EmptyTarget",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ClearLockoutRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ClearLockoutRequest) {},
+			wantErr:      TestErrEmptyLockoutTarget,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ClearLockout(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ClearLockout() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ClearLockout() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_VerifySecondFactor(t *testing.T) {
+	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.VerifySecondFactorRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.VerifySecondFactorRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.LoginResponse
+		wantErr      error
+	}{
 		{
 			name: "OK",
 			args: args{
 				ctx: context.Background(),
-				in: &messengerv1.RegisterRequest{
-					Username: TestUsername,
-					Password: TestPassword,
+				in: &messengerv1.VerifySecondFactorRequest{
+					Challenge: TestChallenge,
+					Code:      TestTOTPCode,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {
-				users.On("RegisterNewUser", ctx, in.Username, in.Password).Return(TestUserId, nil)
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.VerifySecondFactorRequest) {
+				users.On("VerifySecondFactor", ctx, in.Challenge, in.Code, models.ClientInfo{}).Return(TestTokens, nil)
 			},
-			want: &messengerv1.RegisterResponse{
+			want: &messengerv1.LoginResponse{
 				UserId: TestUserId,
+				Tokens: toTokenPair(TestTokens),
 			},
 		},
 		{
-			name: "EmptyPassword",
+			name: "InvalidChallenge",
 			args: args{
 				ctx: context.Background(),
-				in: &messengerv1.RegisterRequest{
-					Username: TestUsername,
-					Password: EmptyPassword,
+				in: &messengerv1.VerifySecondFactorRequest{
+					Challenge: TestChallenge,
+					Code:      TestTOTPCode,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {},
-			wantErr:      TestErrEmptyPassword,
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.VerifySecondFactorRequest) {
+				users.On("VerifySecondFactor", ctx, in.Challenge, in.Code, models.ClientInfo{}).Return(EmptyTokens, usersservice.ErrInvalidChallenge)
+			},
+			wantErr: TestErrInvalidChallenge,
 		},
 		{
-			name: "EmptyUsername",
+			name: "InvalidCode",
 			args: args{
 				ctx: context.Background(),
-				in: &messengerv1.RegisterRequest{
-					Username: EmptyUsername,
-					Password: TestPassword,
+				in: &messengerv1.VerifySecondFactorRequest{
+					Challenge: TestChallenge,
+					Code:      TestTOTPCode,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {},
-			wantErr:      TestErrEmptyUsername,
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.VerifySecondFactorRequest) {
+				users.On("VerifySecondFactor", ctx, in.Challenge, in.Code, models.ClientInfo{}).Return(EmptyTokens, usersservice.ErrInvalidSecondFactor)
+			},
+			wantErr: TestErrInvalidCode,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.VerifySecondFactorRequest{
+					Challenge: TestChallenge,
+					Code:      TestTOTPCode,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.VerifySecondFactorRequest) {
+				users.On("VerifySecondFactor", ctx, in.Challenge, in.Code, models.ClientInfo{}).Return(EmptyTokens, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyChallenge",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.VerifySecondFactorRequest{
+					Code: TestTOTPCode,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.VerifySecondFactorRequest) {},
+			wantErr:      TestErrEmptyChallenge,
+		},
+		{
+			name: "EmptyCode",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.VerifySecondFactorRequest{
+					Challenge: TestChallenge,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.VerifySecondFactorRequest) {},
+			wantErr:      TestErrEmptyCode,
 		},
 	}
 	for _, tt := range tests {
@@ -221,88 +481,208 @@ func Test_serverAPI_Register(t *testing.T) {
 			s := &serverAPI{
 				users: users,
 			}
-			got, err := s.Register(tt.args.ctx, tt.args.in)
-			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.Register() error = %v, wantErr %v", err, tt.wantErr))
-			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.Register() = %v, want %v", got, tt.want))
+			got, err := s.VerifySecondFactor(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.VerifySecondFactor() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.VerifySecondFactor() = %v, want %v", got, tt.want))
 		})
 	}
 }
 
-func Test_serverAPI_ToInactive(t *testing.T) {
-	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest)
+func Test_serverAPI_EnableTOTP(t *testing.T) {
+	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.EnableTOTPRequest)
 	type args struct {
 		ctx context.Context
-		in  *messengerv1.ToInactiveRequest
+		in  *messengerv1.EnableTOTPRequest
 	}
 	tests := []struct {
 		name         string
 		args         args
 		mockBehavior mockBehavior
-		want         *messengerv1.Empty
+		want         *messengerv1.EnableTOTPResponse
 		wantErr      error
 	}{
 		{
-			name: "AlreadyInactive",
+			name: "OK",
 			args: args{
-				ctx: context.Background(),
-				in: &messengerv1.ToInactiveRequest{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.EnableTOTPRequest{
 					UserId: TestUserId,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
-				users.On("MakeUserInactive", ctx, in.UserId).Return(usersservice.ErrUserAlreadyInactive)
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.EnableTOTPRequest) {
+				users.On("EnableTOTP", ctx, in.UserId).Return(TestTOTPSecret, TestTOTPURI, nil)
+			},
+			want: &messengerv1.EnableTOTPResponse{
+				Secret: TestTOTPSecret,
+				Uri:    TestTOTPURI,
 			},
-			wantErr: TestErrAlreadyInactive,
 		},
 		{
 			name: "WrongId",
 			args: args{
-				ctx: context.Background(),
-				in: &messengerv1.ToInactiveRequest{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.EnableTOTPRequest{
 					UserId: TestUserId,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
-				users.On("MakeUserInactive", ctx, in.UserId).Return(usersservice.ErrInvalidCredentials)
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.EnableTOTPRequest) {
+				users.On("EnableTOTP", ctx, in.UserId).Return("", "", usersservice.ErrInvalidCredentials)
 			},
 			wantErr: TestErrUserNotFound,
 		},
 		{
-			name: "InternalError",
+			name: "AlreadyEnabled",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.EnableTOTPRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.EnableTOTPRequest) {
+				users.On("EnableTOTP", ctx, in.UserId).Return("", "", usersservice.ErrTOTPAlreadyEnabled)
+			},
+			wantErr: TestErrTOTPEnabled,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.EnableTOTPRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.EnableTOTPRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "NoCaller",
 			args: args{
 				ctx: context.Background(),
-				in: &messengerv1.ToInactiveRequest{
+				in: &messengerv1.EnableTOTPRequest{
 					UserId: TestUserId,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
-				users.On("MakeUserInactive", ctx, in.UserId).Return(errors.New(""))
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.EnableTOTPRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.EnableTOTPRequest{
+					UserId: TestUserId,
+				},
 			},
-			wantErr: TestErrInternal,
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.EnableTOTPRequest) {},
+			wantErr:      TestErrForeignCaller,
 		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.EnableTOTP(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.EnableTOTP() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.EnableTOTP() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ConfirmTOTP(t *testing.T) {
+	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.ConfirmTOTPRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ConfirmTOTPRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ConfirmTOTPResponse
+		wantErr      error
+	}{
 		{
 			name: "OK",
 			args: args{
-				ctx: context.Background(),
-				in: &messengerv1.ToInactiveRequest{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ConfirmTOTPRequest{
 					UserId: TestUserId,
+					Code:   TestTOTPCode,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
-				users.On("MakeUserInactive", ctx, in.UserId).Return(nil)
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ConfirmTOTPRequest) {
+				users.On("ConfirmTOTP", ctx, in.UserId, in.Code).Return(TestRecoveryCodes, nil)
+			},
+			want: &messengerv1.ConfirmTOTPResponse{
+				RecoveryCodes: TestRecoveryCodes,
 			},
-			want: &messengerv1.Empty{},
 		},
 		{
-			name: "EmptyId",
+			name: "NotStarted",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ConfirmTOTPRequest{
+					UserId: TestUserId,
+					Code:   TestTOTPCode,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ConfirmTOTPRequest) {
+				users.On("ConfirmTOTP", ctx, in.UserId, in.Code).Return(nil, usersservice.ErrTOTPNotEnabled)
+			},
+			wantErr: TestErrTOTPNotStarted,
+		},
+		{
+			name: "InvalidCode",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ConfirmTOTPRequest{
+					UserId: TestUserId,
+					Code:   TestTOTPCode,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ConfirmTOTPRequest) {
+				users.On("ConfirmTOTP", ctx, in.UserId, in.Code).Return(nil, usersservice.ErrInvalidSecondFactor)
+			},
+			wantErr: TestErrInvalidCode,
+		},
+		{
+			name: "EmptyCode",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ConfirmTOTPRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ConfirmTOTPRequest) {},
+			wantErr:      TestErrEmptyCode,
+		},
+		{
+			name: "NoCaller",
 			args: args{
 				ctx: context.Background(),
-				in: &messengerv1.ToInactiveRequest{
-					UserId: EmptyUserId,
+				in: &messengerv1.ConfirmTOTPRequest{
+					UserId: TestUserId,
+					Code:   TestTOTPCode,
 				},
 			},
-			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {},
-			wantErr:      TestErrEmptyUserId,
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ConfirmTOTPRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.ConfirmTOTPRequest{
+					UserId: TestUserId,
+					Code:   TestTOTPCode,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ConfirmTOTPRequest) {},
+			wantErr:      TestErrForeignCaller,
 		},
 	}
 	for _, tt := range tests {
@@ -313,9 +693,4535 @@ func Test_serverAPI_ToInactive(t *testing.T) {
 			s := &serverAPI{
 				users: users,
 			}
-			got, err := s.ToInactive(tt.args.ctx, tt.args.in)
+			got, err := s.ConfirmTOTP(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ConfirmTOTP() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ConfirmTOTP() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_DisableTOTP(t *testing.T) {
+	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.DisableTOTPRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.DisableTOTPRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DisableTOTPRequest{
+					UserId:   TestUserId,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DisableTOTPRequest) {
+				users.On("DisableTOTP", ctx, in.UserId, in.Password).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "InvalidCredentials",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DisableTOTPRequest{
+					UserId:   TestUserId,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DisableTOTPRequest) {
+				users.On("DisableTOTP", ctx, in.UserId, in.Password).Return(usersservice.ErrInvalidCredentials)
+			},
+			wantErr: TestErrInvalidCredentials,
+		},
+		{
+			name: "NotEnabled",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DisableTOTPRequest{
+					UserId:   TestUserId,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DisableTOTPRequest) {
+				users.On("DisableTOTP", ctx, in.UserId, in.Password).Return(usersservice.ErrTOTPNotEnabled)
+			},
+			wantErr: TestErrTOTPNotEnabled,
+		},
+		{
+			name: "EmptyPassword",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DisableTOTPRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DisableTOTPRequest) {},
+			wantErr:      TestErrEmptyPassword,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.DisableTOTPRequest{
+					UserId:   TestUserId,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DisableTOTPRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.DisableTOTPRequest{
+					UserId:   TestUserId,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DisableTOTPRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.DisableTOTP(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.DisableTOTP() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.DisableTOTP() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_RefreshToken(t *testing.T) {
+	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.RefreshTokenRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.RefreshTokenRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.RefreshTokenResponse
+		wantErr      error
+	}{
+		{
+			name: "InvalidToken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RefreshTokenRequest{
+					RefreshToken: TestRefreshToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RefreshTokenRequest) {
+				users.On("RefreshTokens", ctx, in.RefreshToken).Return(EmptyTokens, usersservice.ErrInvalidToken)
+			},
+			wantErr: TestErrInvalidToken,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RefreshTokenRequest{
+					RefreshToken: TestRefreshToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RefreshTokenRequest) {
+				users.On("RefreshTokens", ctx, in.RefreshToken).Return(EmptyTokens, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RefreshTokenRequest{
+					RefreshToken: TestRefreshToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RefreshTokenRequest) {
+				users.On("RefreshTokens", ctx, in.RefreshToken).Return(TestTokens, nil)
+			},
+			want: &messengerv1.RefreshTokenResponse{
+				UserId: TestUserId,
+				Tokens: toTokenPair(TestTokens),
+			},
+		},
+		{
+			name: "EmptyToken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RefreshTokenRequest{
+					RefreshToken: EmptyToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RefreshTokenRequest) {},
+			wantErr:      TestErrEmptyRefreshToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.RefreshToken(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.RefreshToken() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.RefreshToken() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ValidateToken(t *testing.T) {
+	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.ValidateTokenRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ValidateTokenRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ValidateTokenResponse
+		wantErr      error
+	}{
+		{
+			name: "InvalidToken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ValidateTokenRequest{
+					AccessToken: TestAccessToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ValidateTokenRequest) {
+				users.On("ValidateToken", ctx, in.AccessToken).Return(models.TokenInfo{}, usersservice.ErrInvalidToken)
+			},
+			want: &messengerv1.ValidateTokenResponse{
+				Active: false,
+			},
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ValidateTokenRequest{
+					AccessToken: TestAccessToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ValidateTokenRequest) {
+				users.On("ValidateToken", ctx, in.AccessToken).Return(models.TokenInfo{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ValidateTokenRequest{
+					AccessToken: TestAccessToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ValidateTokenRequest) {
+				users.On("ValidateToken", ctx, in.AccessToken).Return(models.TokenInfo{
+					UserId:    TestUserId,
+					SessionId: TestSessionId,
+					ExpiresAt: TestTokens.AccessExpiresAt,
+				}, nil)
+			},
+			want: &messengerv1.ValidateTokenResponse{
+				UserId:    TestUserId,
+				SessionId: TestSessionId,
+				Active:    true,
+				ExpiresAt: timestamppb.New(TestTokens.AccessExpiresAt),
+			},
+		},
+		{
+			name: "EmptyToken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ValidateTokenRequest{
+					AccessToken: EmptyToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ValidateTokenRequest) {},
+			wantErr:      TestErrEmptyAccessToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ValidateToken(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ValidateToken() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ValidateToken() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_Register(t *testing.T) {
+	type mockBehavior func(s *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.RegisterRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.RegisterResponse
+		wantErr      error
+	}{
+		{
+			name: "UsernameTaken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterRequest{
+					Username: TestUsername,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {
+				users.On("RegisterNewUser", ctx, in.Username, in.Password).Return(EmptyUserId, usersservice.ErrUserAlreadyExists)
+			},
+			wantErr: TestErrUsernameTaken,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterRequest{
+					Username: TestUsername,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {
+				users.On("RegisterNewUser", ctx, in.Username, in.Password).Return(EmptyUserId, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterRequest{
+					Username: TestUsername,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {
+				users.On("RegisterNewUser", ctx, in.Username, in.Password).Return(TestUserId, nil)
+			},
+			want: &messengerv1.RegisterResponse{
+				UserId: TestUserId,
+			},
+		},
+		{
+			name: "EmptyPassword",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterRequest{
+					Username: TestUsername,
+					Password: EmptyPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {},
+			wantErr:      TestErrEmptyPassword,
+		},
+		{
+			name: "EmptyUsername",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterRequest{
+					Username: EmptyUsername,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {},
+			wantErr:      TestErrEmptyUsername,
+		},
+		{
+			name: "PasswordPolicyViolation",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterRequest{
+					Username: TestUsername,
+					Password: TestPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterRequest) {
+				users.On("RegisterNewUser", ctx, in.Username, in.Password).Return(EmptyUserId, fmt.Errorf("users.RegisterNewUser, %w", TestValidationErr))
+			},
+			wantErr: TestErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.Register(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.Register() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.Register() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ChangePassword(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ChangePasswordRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "InvalidCredentials",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {
+				users.On("ChangePassword", ctx, in.UserId, in.OldPassword, in.NewPassword).Return(usersservice.ErrInvalidCredentials)
+			},
+			wantErr: TestErrInvalidCredentials,
+		},
+		{
+			name: "SamePassword",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {
+				users.On("ChangePassword", ctx, in.UserId, in.OldPassword, in.NewPassword).Return(usersservice.ErrSamePassword)
+			},
+			wantErr: TestErrSamePassword,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {
+				users.On("ChangePassword", ctx, in.UserId, in.OldPassword, in.NewPassword).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {
+				users.On("ChangePassword", ctx, in.UserId, in.OldPassword, in.NewPassword).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      EmptyUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "EmptyOldPassword",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: EmptyPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {},
+			wantErr:      TestErrEmptyOldPassword,
+		},
+		{
+			name: "EmptyNewPassword",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: EmptyPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {},
+			wantErr:      TestErrEmptyNewPassword,
+		},
+		{
+			name: "PasswordPolicyViolation",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {
+				users.On("ChangePassword", ctx, in.UserId, in.OldPassword, in.NewPassword).Return(TestNewPasswordValidationErr)
+			},
+			wantErr: TestErrNewPasswordValidation,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.ChangePasswordRequest{
+					UserId:      TestUserId,
+					OldPassword: TestPassword,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangePasswordRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ChangePassword(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ChangePassword() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ChangePassword() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_RequestPasswordReset(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.RequestPasswordResetRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.RequestPasswordResetRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RequestPasswordResetRequest{
+					Username: TestUsername,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RequestPasswordResetRequest) {
+				users.On("RequestPasswordReset", ctx, in.Username, "").Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RequestPasswordResetRequest{
+					Username: TestUsername,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RequestPasswordResetRequest) {
+				users.On("RequestPasswordReset", ctx, in.Username, "").Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "ResetUnavailable",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RequestPasswordResetRequest{
+					Username: TestUsername,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RequestPasswordResetRequest) {
+				users.On("RequestPasswordReset", ctx, in.Username, "").Return(usersservice.ErrLoginUnavailable)
+			},
+			wantErr: TestErrResetUnavailable,
+		},
+		{
+			name: "EmptyUsername",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RequestPasswordResetRequest{
+					Username: EmptyUsername,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RequestPasswordResetRequest) {},
+			wantErr:      TestErrEmptyUsername,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.RequestPasswordReset(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.RequestPasswordReset() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.RequestPasswordReset() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_RequestPasswordReset_Lockout(t *testing.T) {
+	users := mocks.NewUsers(t)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(TestIP), Port: 443}})
+	in := &messengerv1.RequestPasswordResetRequest{
+		Username: TestUsername,
+	}
+	lockoutErr := &usersservice.LockoutError{Until: time.Now().Add(time.Minute)}
+	users.On("RequestPasswordReset", ctx, in.Username, TestIP).Return(fmt.Errorf("users.RequestPasswordReset, %w", lockoutErr))
+
+	s := &serverAPI{
+		users: users,
+	}
+	got, err := s.RequestPasswordReset(ctx, in)
+	assert.Nil(t, got, fmt.Sprintf("serverAPI.RequestPasswordReset() = %v, want nil", got))
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code(), fmt.Sprintf("serverAPI.RequestPasswordReset() code = %v, want %v", st.Code(), codes.ResourceExhausted))
+	assert.Equal(t, "too many password reset requests", st.Message())
+
+	if assert.Len(t, st.Details(), 1) {
+		_, ok := st.Details()[0].(*errdetails.RetryInfo)
+		assert.True(t, ok, "serverAPI.RequestPasswordReset() details must contain RetryInfo")
+	}
+}
+
+func Test_serverAPI_ResetPassword(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ResetPasswordRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ResetPasswordRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ResetPasswordRequest{
+					Code:        TestResetCode,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ResetPasswordRequest) {
+				users.On("ResetPassword", ctx, in.Code, in.NewPassword).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "InvalidResetCode",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ResetPasswordRequest{
+					Code:        TestResetCode,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ResetPasswordRequest) {
+				users.On("ResetPassword", ctx, in.Code, in.NewPassword).Return(usersservice.ErrInvalidResetCode)
+			},
+			wantErr: TestErrInvalidResetCode,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ResetPasswordRequest{
+					Code:        TestResetCode,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ResetPasswordRequest) {
+				users.On("ResetPassword", ctx, in.Code, in.NewPassword).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyCode",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ResetPasswordRequest{
+					Code:        EmptyToken,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ResetPasswordRequest) {},
+			wantErr:      TestErrEmptyResetCode,
+		},
+		{
+			name: "EmptyNewPassword",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ResetPasswordRequest{
+					Code:        TestResetCode,
+					NewPassword: EmptyPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ResetPasswordRequest) {},
+			wantErr:      TestErrEmptyNewPassword,
+		},
+		{
+			name: "PasswordPolicyViolation",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ResetPasswordRequest{
+					Code:        TestResetCode,
+					NewPassword: TestNewPassword,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ResetPasswordRequest) {
+				users.On("ResetPassword", ctx, in.Code, in.NewPassword).Return(TestNewPasswordValidationErr)
+			},
+			wantErr: TestErrNewPasswordValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ResetPassword(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ResetPassword() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ResetPassword() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ToInactive(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ToInactiveRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "AlreadyInactive",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ToInactiveRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
+				users.On("MakeUserInactive", ctx, in.UserId).Return(usersservice.ErrUserAlreadyInactive)
+			},
+			wantErr: TestErrAlreadyInactive,
+		},
+		{
+			name: "WrongId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ToInactiveRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
+				users.On("MakeUserInactive", ctx, in.UserId).Return(usersservice.ErrInvalidCredentials)
+			},
+			wantErr: TestErrUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ToInactiveRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
+				users.On("MakeUserInactive", ctx, in.UserId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ToInactiveRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {
+				users.On("MakeUserInactive", ctx, in.UserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ToInactiveRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ToInactiveRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ToInactive(tt.args.ctx, tt.args.in)
 			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.Register() error = %v, wantErr %v", err, tt.wantErr))
 			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.Register() = %v, want %v", got, tt.want))
 		})
 	}
 }
+
+func Test_serverAPI_Reactivate(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ReactivateRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ReactivateRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "AlreadyActive",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ReactivateRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ReactivateRequest) {
+				users.On("MakeUserActive", ctx, in.UserId).Return(usersservice.ErrUserAlreadyActive)
+			},
+			wantErr: TestErrAlreadyActive,
+		},
+		{
+			name: "WrongId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ReactivateRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ReactivateRequest) {
+				users.On("MakeUserActive", ctx, in.UserId).Return(usersservice.ErrInvalidCredentials)
+			},
+			wantErr: TestErrUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ReactivateRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ReactivateRequest) {
+				users.On("MakeUserActive", ctx, in.UserId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ReactivateRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ReactivateRequest) {
+				users.On("MakeUserActive", ctx, in.UserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ReactivateRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ReactivateRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.Reactivate(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.Reactivate() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.Reactivate() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_Logout(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.LogoutRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "SessionNotFound",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutRequest{
+					UserId:    TestUserId,
+					SessionId: TestSessionId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest) {
+				users.On("Logout", ctx, in.UserId, in.SessionId).Return(usersservice.ErrSessionNotFound)
+			},
+			wantErr: TestErrSessionNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutRequest{
+					UserId:    TestUserId,
+					SessionId: TestSessionId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest) {
+				users.On("Logout", ctx, in.UserId, in.SessionId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutRequest{
+					UserId:    TestUserId,
+					SessionId: TestSessionId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest) {
+				users.On("Logout", ctx, in.UserId, in.SessionId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutRequest{
+					UserId:    EmptyUserId,
+					SessionId: TestSessionId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "EmptySessionId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutRequest{
+					UserId:    TestUserId,
+					SessionId: EmptySessionId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest) {},
+			wantErr:      TestErrEmptySessionId,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.LogoutRequest{
+					UserId:    TestUserId,
+					SessionId: TestSessionId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.LogoutRequest{
+					UserId:    TestUserId,
+					SessionId: TestSessionId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.Logout(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.Logout() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.Logout() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_LogoutAll(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.LogoutAllRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.LogoutAllRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutAllRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutAllRequest) {
+				users.On("LogoutAll", ctx, in.UserId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutAllRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutAllRequest) {
+				users.On("LogoutAll", ctx, in.UserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.LogoutAllRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutAllRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.LogoutAllRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutAllRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.LogoutAllRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.LogoutAllRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.LogoutAll(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.LogoutAll() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.LogoutAll() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ListSessions(t *testing.T) {
+	session := models.Session{
+		Id:     TestSessionId,
+		UserId: TestUserId,
+		ClientInfo: models.ClientInfo{
+			DeviceName: TestDeviceName,
+			IP:         "127.0.0.1",
+			UserAgent:  TestUserAgent,
+		},
+		CreatedAt:  time.Unix(1700000000, 0),
+		LastSeenAt: time.Unix(1700000100, 0),
+	}
+
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ListSessionsRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ListSessionsRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ListSessionsResponse
+		wantErr      error
+	}{
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ListSessionsRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListSessionsRequest) {
+				users.On("ListSessions", ctx, in.UserId).Return(nil, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ListSessionsRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListSessionsRequest) {
+				users.On("ListSessions", ctx, in.UserId).Return([]models.Session{session}, nil)
+			},
+			want: &messengerv1.ListSessionsResponse{
+				Sessions: []*messengerv1.Session{
+					{
+						Id:         TestSessionId,
+						DeviceName: TestDeviceName,
+						Ip:         "127.0.0.1",
+						UserAgent:  TestUserAgent,
+						CreatedAt:  timestamppb.New(session.CreatedAt),
+						LastSeenAt: timestamppb.New(session.LastSeenAt),
+					},
+				},
+			},
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ListSessionsRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListSessionsRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListSessionsRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListSessionsRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.ListSessionsRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListSessionsRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ListSessions(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ListSessions() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ListSessions() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+var (
+	TestProfileUser = models.User{
+		Id:           TestUserId,
+		Username:     TestUsername,
+		PasswordHash: []byte("hash"),
+		IsActive:     true,
+		Profile: models.Profile{
+			DisplayName: "User One",
+			Bio:         "bio",
+			AvatarURL:   "https://example.com/avatar.png",
+			Locale:      "en-US",
+			TimeZone:    "Europe/Moscow",
+		},
+		CreatedAt: time.Unix(1700000000, 0),
+		UpdatedAt: time.Unix(1700000100, 0),
+	}
+	TestProfileValidationErr = &usersservice.ValidationError{
+		Violations: []usersservice.FieldViolation{{Field: "time_zone", Description: "must be a valid IANA time zone"}},
+	}
+)
+
+// newTestUserMessage возвращает ожидаемое grpc сообщение для TestProfileUser.
+// Сообщение создается заново, так как protobuf сохраняет внутреннее состояние при выводе.
+func newTestUserMessage() *messengerv1.User {
+	return &messengerv1.User{
+		Id:          TestUserId,
+		Username:    TestUsername,
+		IsActive:    true,
+		DisplayName: "User One",
+		Bio:         "bio",
+		AvatarUrl:   "https://example.com/avatar.png",
+		Locale:      "en-US",
+		TimeZone:    "Europe/Moscow",
+		CreatedAt:   timestamppb.New(time.Unix(1700000000, 0)),
+		UpdatedAt:   timestamppb.New(time.Unix(1700000100, 0)),
+	}
+}
+
+var (
+	TestErrProfileUserNotFound = status.Error(codes.NotFound, "user not found")
+	TestErrEmptyUpdateMask     = status.Error(codes.InvalidArgument, "update_mask is required")
+	TestErrProfileValidation   = newTestValidationStatus("time_zone", "must be a valid IANA time zone")
+)
+
+func Test_serverAPI_GetUserById(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.GetUserByIdRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.GetUserByIdRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByIdRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByIdRequest) {
+				users.On("GetUserById", ctx, EmptyUserId, in.UserId).Return(TestProfileUser, nil)
+			},
+			want: newTestUserMessage(),
+		},
+		{
+			name: "WithCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in:  &messengerv1.GetUserByIdRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByIdRequest) {
+				users.On("GetUserById", ctx, TestContactId, in.UserId).Return(TestProfileUser, nil)
+			},
+			want: newTestUserMessage(),
+		},
+		{
+			name: "InvalidCaller",
+			args: args{
+				ctx: newTestCallerContext("user"),
+				in:  &messengerv1.GetUserByIdRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByIdRequest) {},
+			wantErr:      TestErrInvalidCaller,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByIdRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByIdRequest) {
+				users.On("GetUserById", ctx, EmptyUserId, in.UserId).
+					Return(models.User{}, fmt.Errorf("users.GetUserById, %w", usersservice.ErrUserNotFound))
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByIdRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByIdRequest) {
+				users.On("GetUserById", ctx, EmptyUserId, in.UserId).Return(models.User{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByIdRequest{UserId: EmptyUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByIdRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.GetUserById(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.GetUserById() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.GetUserById() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_GetUserByUsername(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.GetUserByUsernameRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.GetUserByUsernameRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByUsernameRequest{Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByUsernameRequest) {
+				users.On("GetUserByUsername", ctx, EmptyUserId, in.Username).Return(TestProfileUser, nil)
+			},
+			want: newTestUserMessage(),
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByUsernameRequest{Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByUsernameRequest) {
+				users.On("GetUserByUsername", ctx, EmptyUserId, in.Username).
+					Return(models.User{}, fmt.Errorf("users.GetUserByUsername, %w", usersservice.ErrUserNotFound))
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByUsernameRequest{Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByUsernameRequest) {
+				users.On("GetUserByUsername", ctx, EmptyUserId, in.Username).Return(models.User{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyUsername",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUserByUsernameRequest{Username: EmptyUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUserByUsernameRequest) {},
+			wantErr:      TestErrEmptyUsername,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.GetUserByUsername(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.GetUserByUsername() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.GetUserByUsername() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_UpdateProfile(t *testing.T) {
+	profile := &messengerv1.Profile{
+		DisplayName: "User One",
+		TimeZone:    "Europe/Moscow",
+	}
+	fields := []string{models.ProfileFieldDisplayName, models.ProfileFieldTimeZone}
+
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.UpdateProfileRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdateProfileRequest{
+					UserId:     TestUserId,
+					Profile:    profile,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest) {
+				users.On(
+					"UpdateProfile",
+					ctx,
+					in.UserId,
+					models.Profile{DisplayName: "User One", TimeZone: "Europe/Moscow"},
+					fields,
+				).Return(TestProfileUser, nil)
+			},
+			want: newTestUserMessage(),
+		},
+		{
+			name: "ClearWithoutProfile",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdateProfileRequest{
+					UserId:     TestUserId,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{models.ProfileFieldBio}},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest) {
+				users.On("UpdateProfile", ctx, in.UserId, models.Profile{}, []string{models.ProfileFieldBio}).
+					Return(TestProfileUser, nil)
+			},
+			want: newTestUserMessage(),
+		},
+		{
+			name: "ValidationFailed",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdateProfileRequest{
+					UserId:     TestUserId,
+					Profile:    profile,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest) {
+				users.On(
+					"UpdateProfile",
+					ctx,
+					in.UserId,
+					models.Profile{DisplayName: "User One", TimeZone: "Europe/Moscow"},
+					fields,
+				).Return(models.User{}, fmt.Errorf("users.UpdateProfile, %w", TestProfileValidationErr))
+			},
+			wantErr: TestErrProfileValidation,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdateProfileRequest{
+					UserId:     TestUserId,
+					Profile:    profile,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest) {
+				users.On(
+					"UpdateProfile",
+					ctx,
+					in.UserId,
+					models.Profile{DisplayName: "User One", TimeZone: "Europe/Moscow"},
+					fields,
+				).Return(models.User{}, fmt.Errorf("users.UpdateProfile, %w", usersservice.ErrUserNotFound))
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdateProfileRequest{
+					UserId:     TestUserId,
+					Profile:    profile,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest) {
+				users.On(
+					"UpdateProfile",
+					ctx,
+					in.UserId,
+					models.Profile{DisplayName: "User One", TimeZone: "Europe/Moscow"},
+					fields,
+				).Return(models.User{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyUpdateMask",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdateProfileRequest{
+					UserId:  TestUserId,
+					Profile: profile,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest) {},
+			wantErr:      TestErrEmptyUpdateMask,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdateProfileRequest{
+					UserId:     EmptyUserId,
+					Profile:    profile,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdateProfileRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.UpdateProfile(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.UpdateProfile() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.UpdateProfile() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_GetUsers(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.GetUsersRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.GetUsersRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.GetUsersResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUsersRequest{UserIds: []int64{TestUserId, 2}},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUsersRequest) {
+				users.On("GetUsers", ctx, EmptyUserId, in.UserIds).
+					Return(map[int64]models.User{TestUserId: TestProfileUser}, []int64{2}, nil)
+			},
+			want: &messengerv1.GetUsersResponse{
+				Users:      map[int64]*messengerv1.User{TestUserId: newTestUserMessage()},
+				MissingIds: []int64{2},
+			},
+		},
+		{
+			name: "BatchTooLarge",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUsersRequest{UserIds: []int64{TestUserId, 2}},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUsersRequest) {
+				users.On("GetUsers", ctx, EmptyUserId, in.UserIds).
+					Return(nil, nil, fmt.Errorf("users.GetUsers, %w", usersservice.ErrBatchTooLarge))
+			},
+			wantErr: status.Error(codes.InvalidArgument, "too many user_ids"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUsersRequest{UserIds: []int64{TestUserId}},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUsersRequest) {
+				users.On("GetUsers", ctx, EmptyUserId, in.UserIds).Return(nil, nil, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyIds",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetUsersRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetUsersRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "user_ids is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.GetUsers(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.GetUsers() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.GetUsers() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_SearchUsers(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.SearchUsersRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.SearchUsersRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.SearchUsersResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.SearchUsersRequest{Query: "user", PageToken: "token", Limit: 10},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SearchUsersRequest) {
+				users.On("SearchUsers", ctx, EmptyUserId, in.Query, in.PageToken, 10).
+					Return([]models.User{TestProfileUser}, "next", nil)
+			},
+			want: &messengerv1.SearchUsersResponse{
+				Users:         []*messengerv1.User{newTestUserMessage()},
+				NextPageToken: "next",
+			},
+		},
+		{
+			name: "ValidationFailed",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.SearchUsersRequest{Query: "user"},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SearchUsersRequest) {
+				users.On("SearchUsers", ctx, EmptyUserId, in.Query, in.PageToken, 0).
+					Return(nil, "", fmt.Errorf("users.SearchUsers, %w", &usersservice.ValidationError{
+						Violations: []usersservice.FieldViolation{{Field: "query", Description: "must not be empty"}},
+					}))
+			},
+			wantErr: newTestValidationStatus("query", "must not be empty"),
+		},
+		{
+			name: "InvalidPageToken",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.SearchUsersRequest{Query: "user", PageToken: "token"},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SearchUsersRequest) {
+				users.On("SearchUsers", ctx, EmptyUserId, in.Query, in.PageToken, 0).
+					Return(nil, "", fmt.Errorf("users.SearchUsers, %w", usersservice.ErrInvalidPageToken))
+			},
+			wantErr: status.Error(codes.InvalidArgument, "invalid page_token"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.SearchUsersRequest{Query: "user"},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SearchUsersRequest) {
+				users.On("SearchUsers", ctx, EmptyUserId, in.Query, in.PageToken, 0).Return(nil, "", errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyQuery",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.SearchUsersRequest{Query: "  "},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SearchUsersRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "query is required"),
+		},
+		{
+			name: "NegativeLimit",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.SearchUsersRequest{Query: "user", Limit: -1},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SearchUsersRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "limit must not be negative"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.SearchUsers(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.SearchUsers() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.SearchUsers() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ListUsers(t *testing.T) {
+	active := true
+	inactive := false
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ListUsersRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ListUsersResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListUsersRequest{
+					Status:         messengerv1.UserStatusFilter_USER_STATUS_FILTER_ACTIVE,
+					CreatedAfter:   timestamppb.New(createdAfter),
+					CreatedBefore:  timestamppb.New(createdBefore),
+					UsernamePrefix: "user",
+					Order:          messengerv1.SortOrder_SORT_ORDER_DESC,
+					PageToken:      "token",
+					Limit:          10,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {
+				filter := models.UserFilter{
+					IsActive:       &active,
+					CreatedAfter:   createdAfter,
+					CreatedBefore:  createdBefore,
+					UsernamePrefix: "user",
+				}
+				users.On("ListUsers", ctx, filter, true, in.PageToken, 10).
+					Return([]models.User{TestProfileUser}, "next", nil)
+			},
+			want: &messengerv1.ListUsersResponse{
+				Users:         []*messengerv1.User{newTestUserMessage()},
+				NextPageToken: "next",
+			},
+		},
+		{
+			name: "Inactive",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListUsersRequest{
+					Status: messengerv1.UserStatusFilter_USER_STATUS_FILTER_INACTIVE,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {
+				users.On("ListUsers", ctx, models.UserFilter{IsActive: &inactive}, false, "", 0).
+					Return(nil, "", nil)
+			},
+			want: &messengerv1.ListUsersResponse{Users: []*messengerv1.User{}},
+		},
+		{
+			name: "ValidationFailed",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListUsersRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {
+				users.On("ListUsers", ctx, models.UserFilter{}, false, "", 0).
+					Return(nil, "", fmt.Errorf("users.ListUsers, %w", &usersservice.ValidationError{
+						Violations: []usersservice.FieldViolation{{Field: "created_before", Description: "must be after created_after"}},
+					}))
+			},
+			wantErr: newTestValidationStatus("created_before", "must be after created_after"),
+		},
+		{
+			name: "InvalidPageToken",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListUsersRequest{PageToken: "token"},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {
+				users.On("ListUsers", ctx, models.UserFilter{}, false, in.PageToken, 0).
+					Return(nil, "", fmt.Errorf("users.ListUsers, %w", usersservice.ErrInvalidPageToken))
+			},
+			wantErr: status.Error(codes.InvalidArgument, "invalid page_token"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListUsersRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {
+				users.On("ListUsers", ctx, models.UserFilter{}, false, "", 0).Return(nil, "", errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "InvalidStatus",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListUsersRequest{Status: 10},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "invalid status"),
+		},
+		{
+			name: "InvalidOrder",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListUsersRequest{Order: 10},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "invalid order"),
+		},
+		{
+			name: "NegativeLimit",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListUsersRequest{Limit: -1},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListUsersRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "limit must not be negative"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ListUsers(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ListUsers() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ListUsers() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ChangeUsername(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ChangeUsernameRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ChangeUsernameRequest{UserId: TestUserId, Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest) {
+				users.On("ChangeUsername", ctx, in.UserId, in.Username).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "UsernameTaken",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ChangeUsernameRequest{UserId: TestUserId, Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest) {
+				users.On("ChangeUsername", ctx, in.UserId, in.Username).
+					Return(fmt.Errorf("users.ChangeUsername, %w", usersservice.ErrUserAlreadyExists))
+			},
+			wantErr: TestErrUsernameTaken,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ChangeUsernameRequest{UserId: TestUserId, Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest) {
+				users.On("ChangeUsername", ctx, in.UserId, in.Username).
+					Return(fmt.Errorf("users.ChangeUsername, %w", usersservice.ErrUserNotFound))
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "ValidationFailed",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ChangeUsernameRequest{UserId: TestUserId, Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest) {
+				users.On("ChangeUsername", ctx, in.UserId, in.Username).
+					Return(fmt.Errorf("users.ChangeUsername, %w", &usersservice.ValidationError{
+						Violations: []usersservice.FieldViolation{{Field: "username", Description: "is reserved"}},
+					}))
+			},
+			wantErr: newTestValidationStatus("username", "is reserved"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ChangeUsernameRequest{UserId: TestUserId, Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest) {
+				users.On("ChangeUsername", ctx, in.UserId, in.Username).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyUsername",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ChangeUsernameRequest{UserId: TestUserId, Username: EmptyUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest) {},
+			wantErr:      TestErrEmptyUsername,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ChangeUsernameRequest{UserId: EmptyUserId, Username: TestUsername},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ChangeUsernameRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ChangeUsername(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ChangeUsername() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ChangeUsername() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+var (
+	TestDeleteAfter             = time.Unix(1800000000, 0)
+	TestErrDeletionScheduled    = status.Error(codes.FailedPrecondition, "deletion already scheduled")
+	TestErrDeletionNotScheduled = status.Error(codes.FailedPrecondition, "deletion not scheduled")
+)
+
+func Test_serverAPI_DeleteUser(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.DeleteUserRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.DeleteUserResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DeleteUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest) {
+				users.On("DeleteUser", ctx, in.UserId).Return(TestDeleteAfter, nil)
+			},
+			want: &messengerv1.DeleteUserResponse{
+				DeleteAfter: timestamppb.New(TestDeleteAfter),
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DeleteUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest) {
+				users.On("DeleteUser", ctx, in.UserId).Return(time.Time{}, usersservice.ErrUserNotFound)
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "AlreadyScheduled",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DeleteUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest) {
+				users.On("DeleteUser", ctx, in.UserId).Return(time.Time{}, usersservice.ErrDeletionScheduled)
+			},
+			wantErr: TestErrDeletionScheduled,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DeleteUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest) {
+				users.On("DeleteUser", ctx, in.UserId).Return(time.Time{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.DeleteUserRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.DeleteUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.DeleteUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeleteUserRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.DeleteUser(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.DeleteUser() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.DeleteUser() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_CancelUserDeletion(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.CancelUserDeletionRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.CancelUserDeletionRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.CancelUserDeletionRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelUserDeletionRequest) {
+				users.On("CancelUserDeletion", ctx, in.UserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "NotScheduled",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.CancelUserDeletionRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelUserDeletionRequest) {
+				users.On("CancelUserDeletion", ctx, in.UserId).Return(usersservice.ErrDeletionNotScheduled)
+			},
+			wantErr: TestErrDeletionNotScheduled,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.CancelUserDeletionRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelUserDeletionRequest) {
+				users.On("CancelUserDeletion", ctx, in.UserId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.CancelUserDeletionRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelUserDeletionRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.CancelUserDeletionRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelUserDeletionRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.CancelUserDeletionRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelUserDeletionRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.CancelUserDeletion(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.CancelUserDeletion() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.CancelUserDeletion() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+// exportStream - поток ExportUserData, сохраняющий отправленные части документа.
+type exportStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks [][]byte
+}
+
+func (s *exportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *exportStream) Send(chunk *messengerv1.ExportUserDataChunk) error {
+	s.chunks = append(s.chunks, bytes.Clone(chunk.GetData()))
+	return nil
+}
+
+func Test_serverAPI_ExportUserData(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest)
+
+	// writeExport возвращает действие мока, записывающее data в io.Writer из аргументов вызова.
+	writeExport := func(data []byte) func(args mock.Arguments) {
+		return func(args mock.Arguments) {
+			_, _ = args.Get(2).(io.Writer).Write(data)
+		}
+	}
+	smallDoc := []byte(`{"version":1}`)
+	largeDoc := bytes.Repeat([]byte("a"), 2*exportChunkSize+1)
+
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ExportUserDataRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantChunks   [][]byte
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ExportUserDataRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest) {
+				users.On("ExportUserData", ctx, in.UserId, mock.Anything).Run(writeExport(smallDoc)).Return(nil)
+			},
+			wantChunks: [][]byte{smallDoc},
+		},
+		{
+			name: "SplitIntoChunks",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ExportUserDataRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest) {
+				users.On("ExportUserData", ctx, in.UserId, mock.Anything).Run(writeExport(largeDoc)).Return(nil)
+			},
+			wantChunks: [][]byte{
+				largeDoc[:exportChunkSize],
+				largeDoc[exportChunkSize : 2*exportChunkSize],
+				largeDoc[2*exportChunkSize:],
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ExportUserDataRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest) {
+				users.On("ExportUserData", ctx, in.UserId, mock.Anything).Return(usersservice.ErrUserNotFound)
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ExportUserDataRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest) {
+				users.On("ExportUserData", ctx, in.UserId, mock.Anything).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.ExportUserDataRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ExportUserDataRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.ExportUserDataRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ExportUserDataRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			stream := &exportStream{ctx: tt.args.ctx}
+			err := s.ExportUserData(tt.args.in, stream)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ExportUserData() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, tt.wantChunks, stream.chunks)
+		})
+	}
+}
+
+var (
+	TestContactId                 int64 = 2
+	TestContactSince                    = time.Unix(1700000200, 0)
+	TestErrEmptyContactId               = status.Error(codes.InvalidArgument, "contact_id is required")
+	TestErrContactRequestNotFound       = status.Error(codes.NotFound, "contact request not found")
+)
+
+func Test_serverAPI_SendContactRequest(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.SendContactRequestRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.SendContactRequestResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {
+				users.On("SendContactRequest", ctx, in.UserId, in.ContactId).Return(true, nil)
+			},
+			want: &messengerv1.SendContactRequestResponse{Accepted: true},
+		},
+		{
+			name: "Self",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId:    TestUserId,
+					ContactId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {
+				users.On("SendContactRequest", ctx, in.UserId, in.ContactId).Return(false, usersservice.ErrSelfContact)
+			},
+			wantErr: status.Error(codes.InvalidArgument, "cannot add yourself to contacts"),
+		},
+		{
+			name: "Blocked",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {
+				users.On("SendContactRequest", ctx, in.UserId, in.ContactId).Return(false, usersservice.ErrContactRequestBlocked)
+			},
+			wantErr: status.Error(codes.PermissionDenied, "contact request not allowed"),
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {
+				users.On("SendContactRequest", ctx, in.UserId, in.ContactId).Return(false, usersservice.ErrUserNotFound)
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "AlreadyContacts",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {
+				users.On("SendContactRequest", ctx, in.UserId, in.ContactId).Return(false, usersservice.ErrAlreadyContacts)
+			},
+			wantErr: status.Error(codes.AlreadyExists, "already contacts"),
+		},
+		{
+			name: "RequestExists",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {
+				users.On("SendContactRequest", ctx, in.UserId, in.ContactId).Return(false, usersservice.ErrContactRequestExists)
+			},
+			wantErr: status.Error(codes.AlreadyExists, "contact request already exists"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {
+				users.On("SendContactRequest", ctx, in.UserId, in.ContactId).Return(false, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyContactId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.SendContactRequestRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.SendContactRequestRequest) {},
+			wantErr:      TestErrEmptyContactId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.SendContactRequest(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.SendContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.SendContactRequest() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_AcceptContactRequest(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.AcceptContactRequestRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.AcceptContactRequestRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.AcceptContactRequestRequest{
+					UserId:     TestUserId,
+					FromUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.AcceptContactRequestRequest) {
+				users.On("AcceptContactRequest", ctx, in.UserId, in.FromUserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "RequestNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.AcceptContactRequestRequest{
+					UserId:     TestUserId,
+					FromUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.AcceptContactRequestRequest) {
+				users.On("AcceptContactRequest", ctx, in.UserId, in.FromUserId).Return(usersservice.ErrContactRequestNotFound)
+			},
+			wantErr: TestErrContactRequestNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.AcceptContactRequestRequest{
+					UserId:     TestUserId,
+					FromUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.AcceptContactRequestRequest) {
+				users.On("AcceptContactRequest", ctx, in.UserId, in.FromUserId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyFromUserId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.AcceptContactRequestRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.AcceptContactRequestRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "from_user_id is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.AcceptContactRequest(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.AcceptContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.AcceptContactRequest() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_DeclineContactRequest(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.DeclineContactRequestRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.DeclineContactRequestRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.DeclineContactRequestRequest{
+					UserId:     TestUserId,
+					FromUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeclineContactRequestRequest) {
+				users.On("DeclineContactRequest", ctx, in.UserId, in.FromUserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "RequestNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.DeclineContactRequestRequest{
+					UserId:     TestUserId,
+					FromUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.DeclineContactRequestRequest) {
+				users.On("DeclineContactRequest", ctx, in.UserId, in.FromUserId).Return(usersservice.ErrContactRequestNotFound)
+			},
+			wantErr: TestErrContactRequestNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.DeclineContactRequest(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.DeclineContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.DeclineContactRequest() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_CancelContactRequest(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.CancelContactRequestRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.CancelContactRequestRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.CancelContactRequestRequest{
+					UserId:   TestUserId,
+					ToUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelContactRequestRequest) {
+				users.On("CancelContactRequest", ctx, in.UserId, in.ToUserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "RequestNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.CancelContactRequestRequest{
+					UserId:   TestUserId,
+					ToUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelContactRequestRequest) {
+				users.On("CancelContactRequest", ctx, in.UserId, in.ToUserId).Return(usersservice.ErrContactRequestNotFound)
+			},
+			wantErr: TestErrContactRequestNotFound,
+		},
+		{
+			name: "EmptyToUserId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.CancelContactRequestRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CancelContactRequestRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "to_user_id is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.CancelContactRequest(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.CancelContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.CancelContactRequest() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_RemoveContact(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.RemoveContactRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.RemoveContactRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RemoveContactRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RemoveContactRequest) {
+				users.On("RemoveContact", ctx, in.UserId, in.ContactId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "ContactNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RemoveContactRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RemoveContactRequest) {
+				users.On("RemoveContact", ctx, in.UserId, in.ContactId).Return(usersservice.ErrContactNotFound)
+			},
+			wantErr: status.Error(codes.NotFound, "contact not found"),
+		},
+		{
+			name: "EmptyContactId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RemoveContactRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RemoveContactRequest) {},
+			wantErr:      TestErrEmptyContactId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.RemoveContact(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.RemoveContact() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.RemoveContact() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ListContacts(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ListContactsRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ListContactsRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ListContactsResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListContactsRequest{
+					UserId:    TestContactId,
+					PageToken: "token",
+					Limit:     1,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactsRequest) {
+				contacts := []models.Contact{{User: TestProfileUser, Since: TestContactSince}}
+				users.On("ListContacts", ctx, in.UserId, in.PageToken, 1).Return(contacts, "next", nil)
+			},
+			want: &messengerv1.ListContactsResponse{
+				Contacts: []*messengerv1.Contact{{
+					User:  newTestUserMessage(),
+					Since: timestamppb.New(TestContactSince),
+				}},
+				NextPageToken: "next",
+			},
+		},
+		{
+			name: "InvalidPageToken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListContactsRequest{
+					UserId:    TestUserId,
+					PageToken: "token",
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactsRequest) {
+				users.On("ListContacts", ctx, in.UserId, in.PageToken, 0).Return(nil, "", usersservice.ErrInvalidPageToken)
+			},
+			wantErr: status.Error(codes.InvalidArgument, "invalid page_token"),
+		},
+		{
+			name: "NegativeLimit",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListContactsRequest{
+					UserId: TestUserId,
+					Limit:  -1,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactsRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "limit must not be negative"),
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListContactsRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactsRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ListContacts(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ListContacts() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ListContacts() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ListContactRequests(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ListContactRequestsRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ListContactRequestsRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ListContactRequestsResponse
+		wantErr      error
+	}{
+		{
+			name: "Incoming",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListContactRequestsRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactRequestsRequest) {
+				requests := []models.ContactRequest{{FromUserId: TestContactId, ToUserId: TestUserId, CreatedAt: TestContactSince}}
+				users.On("ListContactRequests", ctx, in.UserId, true).Return(requests, nil)
+			},
+			want: &messengerv1.ListContactRequestsResponse{
+				Requests: []*messengerv1.ContactRequest{{
+					FromUserId: TestContactId,
+					ToUserId:   TestUserId,
+					CreatedAt:  timestamppb.New(TestContactSince),
+				}},
+			},
+		},
+		{
+			name: "Outgoing",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListContactRequestsRequest{
+					UserId:    TestUserId,
+					Direction: messengerv1.ContactRequestDirection_CONTACT_REQUEST_DIRECTION_OUTGOING,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactRequestsRequest) {
+				users.On("ListContactRequests", ctx, in.UserId, false).Return(nil, nil)
+			},
+			want: &messengerv1.ListContactRequestsResponse{
+				Requests: []*messengerv1.ContactRequest{},
+			},
+		},
+		{
+			name: "InvalidDirection",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListContactRequestsRequest{
+					UserId:    TestUserId,
+					Direction: 100,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactRequestsRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "invalid direction"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListContactRequestsRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListContactRequestsRequest) {
+				users.On("ListContactRequests", ctx, in.UserId, true).Return(nil, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ListContactRequests(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ListContactRequests() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ListContactRequests() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_CheckContact(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.CheckContactRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.CheckContactRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.CheckContactResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.CheckContactRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CheckContactRequest) {
+				users.On("AreContacts", ctx, in.UserId, in.ContactId).Return(true, nil)
+			},
+			want: &messengerv1.CheckContactResponse{IsContact: true},
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.CheckContactRequest{
+					UserId:    TestUserId,
+					ContactId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CheckContactRequest) {
+				users.On("AreContacts", ctx, in.UserId, in.ContactId).Return(false, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyContactId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.CheckContactRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.CheckContactRequest) {},
+			wantErr:      TestErrEmptyContactId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.CheckContact(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.CheckContact() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.CheckContact() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+var TestErrEmptyBlockedUserId = status.Error(codes.InvalidArgument, "blocked_user_id is required")
+
+func Test_serverAPI_BlockUser(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.BlockUserRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.BlockUserRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.BlockUserRequest{
+					UserId:        TestUserId,
+					BlockedUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.BlockUserRequest) {
+				users.On("BlockUser", ctx, in.UserId, in.BlockedUserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "Self",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.BlockUserRequest{
+					UserId:        TestUserId,
+					BlockedUserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.BlockUserRequest) {
+				users.On("BlockUser", ctx, in.UserId, in.BlockedUserId).Return(usersservice.ErrSelfBlock)
+			},
+			wantErr: status.Error(codes.InvalidArgument, "cannot block yourself"),
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.BlockUserRequest{
+					UserId:        TestUserId,
+					BlockedUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.BlockUserRequest) {
+				users.On("BlockUser", ctx, in.UserId, in.BlockedUserId).Return(usersservice.ErrUserNotFound)
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "AlreadyBlocked",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.BlockUserRequest{
+					UserId:        TestUserId,
+					BlockedUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.BlockUserRequest) {
+				users.On("BlockUser", ctx, in.UserId, in.BlockedUserId).Return(usersservice.ErrAlreadyBlocked)
+			},
+			wantErr: status.Error(codes.AlreadyExists, "user already blocked"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.BlockUserRequest{
+					UserId:        TestUserId,
+					BlockedUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.BlockUserRequest) {
+				users.On("BlockUser", ctx, in.UserId, in.BlockedUserId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyBlockedUserId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.BlockUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.BlockUserRequest) {},
+			wantErr:      TestErrEmptyBlockedUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.BlockUser(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.BlockUser() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.BlockUser() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_UnblockUser(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.UnblockUserRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.UnblockUserRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UnblockUserRequest{
+					UserId:        TestUserId,
+					BlockedUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnblockUserRequest) {
+				users.On("UnblockUser", ctx, in.UserId, in.BlockedUserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "BlockNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UnblockUserRequest{
+					UserId:        TestUserId,
+					BlockedUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnblockUserRequest) {
+				users.On("UnblockUser", ctx, in.UserId, in.BlockedUserId).Return(usersservice.ErrBlockNotFound)
+			},
+			wantErr: status.Error(codes.NotFound, "user not blocked"),
+		},
+		{
+			name: "EmptyBlockedUserId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UnblockUserRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnblockUserRequest) {},
+			wantErr:      TestErrEmptyBlockedUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.UnblockUser(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.UnblockUser() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.UnblockUser() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ListBlocked(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ListBlockedRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ListBlockedRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ListBlockedResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListBlockedRequest{
+					UserId:    TestContactId,
+					PageToken: "token",
+					Limit:     1,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListBlockedRequest) {
+				blocked := []models.BlockedUser{{User: TestProfileUser, BlockedAt: TestContactSince}}
+				users.On("ListBlocked", ctx, in.UserId, in.PageToken, 1).Return(blocked, "next", nil)
+			},
+			want: &messengerv1.ListBlockedResponse{
+				Users: []*messengerv1.BlockedUser{{
+					User:      newTestUserMessage(),
+					BlockedAt: timestamppb.New(TestContactSince),
+				}},
+				NextPageToken: "next",
+			},
+		},
+		{
+			name: "InvalidPageToken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListBlockedRequest{
+					UserId:    TestUserId,
+					PageToken: "token",
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListBlockedRequest) {
+				users.On("ListBlocked", ctx, in.UserId, in.PageToken, 0).Return(nil, "", usersservice.ErrInvalidPageToken)
+			},
+			wantErr: status.Error(codes.InvalidArgument, "invalid page_token"),
+		},
+		{
+			name: "NegativeLimit",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.ListBlockedRequest{
+					UserId: TestUserId,
+					Limit:  -1,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListBlockedRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "limit must not be negative"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ListBlocked(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ListBlocked() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ListBlocked() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_IsBlocked(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.IsBlockedRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.IsBlockedRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.IsBlockedResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.IsBlockedRequest{
+					UserId:       TestUserId,
+					TargetUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.IsBlockedRequest) {
+				users.On("IsBlocked", ctx, in.UserId, in.TargetUserId).Return(true, nil)
+			},
+			want: &messengerv1.IsBlockedResponse{Blocked: true},
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.IsBlockedRequest{
+					UserId:       TestUserId,
+					TargetUserId: TestContactId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.IsBlockedRequest) {
+				users.On("IsBlocked", ctx, in.UserId, in.TargetUserId).Return(false, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyTargetUserId",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.IsBlockedRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.IsBlockedRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "target_user_id is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.IsBlocked(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.IsBlocked() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.IsBlocked() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+var TestLastSeen = time.Unix(1700000300, 0)
+
+func Test_serverAPI_Heartbeat(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.HeartbeatRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.HeartbeatRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.HeartbeatRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.HeartbeatRequest) {
+				users.On("Heartbeat", ctx, in.UserId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.HeartbeatRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.HeartbeatRequest) {
+				users.On("Heartbeat", ctx, in.UserId).Return(usersservice.ErrUserNotFound)
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.HeartbeatRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.HeartbeatRequest) {
+				users.On("Heartbeat", ctx, in.UserId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: newTestCallerContext("1"),
+				in: &messengerv1.HeartbeatRequest{
+					UserId: EmptyUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.HeartbeatRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+		{
+			name: "NoCaller",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.HeartbeatRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.HeartbeatRequest) {},
+			wantErr:      TestErrNoCaller,
+		},
+		{
+			name: "ForeignCaller",
+			args: args{
+				ctx: newTestCallerContext("2"),
+				in: &messengerv1.HeartbeatRequest{
+					UserId: TestUserId,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.HeartbeatRequest) {},
+			wantErr:      TestErrForeignCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.Heartbeat(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.Heartbeat() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.Heartbeat() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_GetPresence(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.GetPresenceRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.GetPresenceRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.GetPresenceResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.GetPresenceRequest{
+					UserIds: []int64{TestUserId, TestContactId},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetPresenceRequest) {
+				presences := []models.Presence{
+					{UserId: TestUserId, Online: true, LastSeenAt: TestLastSeen},
+					{UserId: TestContactId},
+				}
+				users.On("GetPresence", ctx, EmptyUserId, in.UserIds).Return(presences, nil)
+			},
+			want: &messengerv1.GetPresenceResponse{
+				Presences: []*messengerv1.Presence{
+					{UserId: TestUserId, Online: true, LastSeenAt: timestamppb.New(TestLastSeen)},
+					{UserId: TestContactId},
+				},
+			},
+		},
+		{
+			name: "BatchTooLarge",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.GetPresenceRequest{
+					UserIds: []int64{TestUserId, TestContactId},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetPresenceRequest) {
+				users.On("GetPresence", ctx, EmptyUserId, in.UserIds).Return(nil, usersservice.ErrBatchTooLarge)
+			},
+			wantErr: status.Error(codes.InvalidArgument, "too many user_ids"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.GetPresenceRequest{
+					UserIds: []int64{TestUserId},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetPresenceRequest) {
+				users.On("GetPresence", ctx, EmptyUserId, in.UserIds).Return(nil, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyIds",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetPresenceRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetPresenceRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "user_ids is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.GetPresence(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.GetPresence() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.GetPresence() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+// presenceStream - поток WatchPresence, сохраняющий отправленные сообщения.
+type presenceStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	presences []*messengerv1.Presence
+}
+
+func (s *presenceStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *presenceStream) Send(presence *messengerv1.Presence) error {
+	s.presences = append(s.presences, presence)
+	return nil
+}
+
+func Test_serverAPI_WatchPresence(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.WatchPresenceRequest)
+
+	// sendPresence возвращает действие мока, передающее presences в функцию send из аргументов вызова.
+	sendPresence := func(presences ...models.Presence) func(args mock.Arguments) {
+		return func(args mock.Arguments) {
+			send := args.Get(3).(func(models.Presence) error)
+			for _, p := range presences {
+				_ = send(p)
+			}
+		}
+	}
+
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.WatchPresenceRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []*messengerv1.Presence
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.WatchPresenceRequest{
+					UserIds: []int64{TestUserId},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchPresenceRequest) {
+				users.On("WatchPresence", ctx, EmptyUserId, in.UserIds, mock.Anything).
+					Run(sendPresence(
+						models.Presence{UserId: TestUserId},
+						models.Presence{UserId: TestUserId, Online: true, LastSeenAt: TestLastSeen},
+					)).
+					Return(nil)
+			},
+			want: []*messengerv1.Presence{
+				{UserId: TestUserId},
+				{UserId: TestUserId, Online: true, LastSeenAt: timestamppb.New(TestLastSeen)},
+			},
+		},
+		{
+			name: "BatchTooLarge",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.WatchPresenceRequest{
+					UserIds: []int64{TestUserId},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchPresenceRequest) {
+				users.On("WatchPresence", ctx, EmptyUserId, in.UserIds, mock.Anything).Return(usersservice.ErrBatchTooLarge)
+			},
+			wantErr: status.Error(codes.InvalidArgument, "too many user_ids"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.WatchPresenceRequest{
+					UserIds: []int64{TestUserId},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchPresenceRequest) {
+				users.On("WatchPresence", ctx, EmptyUserId, in.UserIds, mock.Anything).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyIds",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.WatchPresenceRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchPresenceRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "user_ids is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			stream := &presenceStream{ctx: tt.args.ctx}
+			err := s.WatchPresence(tt.args.in, stream)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.WatchPresence() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, tt.want, stream.presences)
+		})
+	}
+}
+
+var (
+	TestPrivacySettings = models.PrivacySettings{
+		LastSeen: models.VisibilityContacts,
+		Avatar:   models.VisibilityEveryone,
+		Search:   models.VisibilityNobody,
+	}
+	TestPrivacySettingsMessage = &messengerv1.PrivacySettings{
+		LastSeen: messengerv1.Visibility_VISIBILITY_CONTACTS,
+		Avatar:   messengerv1.Visibility_VISIBILITY_EVERYONE,
+		Search:   messengerv1.Visibility_VISIBILITY_NOBODY,
+	}
+	TestErrInvalidCaller = status.Error(codes.InvalidArgument, "invalid x-user-id")
+	TestErrNoCaller      = status.Error(codes.Unauthenticated, "x-user-id is required")
+	TestErrForeignCaller = status.Error(codes.PermissionDenied, "permission denied")
+)
+
+// newTestCallerContext возвращает контекст входящего запроса с заголовком id вызывающего пользователя.
+func newTestCallerContext(callerId string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(callerIdHeader, callerId))
+}
+
+func Test_callerId(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    int64
+		wantErr error
+	}{
+		{
+			name: "OK",
+			ctx:  newTestCallerContext(" 42 "),
+			want: 42,
+		},
+		{
+			name: "NoMetadata",
+			ctx:  context.Background(),
+		},
+		{
+			name: "NoHeader",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs("user-agent", "test")),
+		},
+		{
+			name:    "NotNumber",
+			ctx:     newTestCallerContext("user"),
+			wantErr: TestErrInvalidCaller,
+		},
+		{
+			name:    "NotPositive",
+			ctx:     newTestCallerContext("0"),
+			wantErr: TestErrInvalidCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := callerId(tt.ctx)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("callerId() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("callerId() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_authorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{
+			name: "OK",
+			ctx:  newTestCallerContext("1"),
+		},
+		{
+			name:    "NoCaller",
+			ctx:     context.Background(),
+			wantErr: TestErrNoCaller,
+		},
+		{
+			name:    "ForeignCaller",
+			ctx:     newTestCallerContext("2"),
+			wantErr: TestErrForeignCaller,
+		},
+		{
+			name:    "InvalidCaller",
+			ctx:     newTestCallerContext("user"),
+			wantErr: TestErrInvalidCaller,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorize(tt.ctx, TestUserId)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("authorize() error = %v, wantErr %v", err, tt.wantErr))
+		})
+	}
+}
+
+func Test_serverAPI_GetPrivacySettings(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.GetPrivacySettingsRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.GetPrivacySettingsRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.PrivacySettings
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetPrivacySettingsRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetPrivacySettingsRequest) {
+				users.On("GetPrivacySettings", ctx, in.UserId).Return(TestPrivacySettings, nil)
+			},
+			want: TestPrivacySettingsMessage,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetPrivacySettingsRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetPrivacySettingsRequest) {
+				users.On("GetPrivacySettings", ctx, in.UserId).Return(models.PrivacySettings{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.GetPrivacySettingsRequest{UserId: EmptyUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.GetPrivacySettingsRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.GetPrivacySettings(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.GetPrivacySettings() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.GetPrivacySettings() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_UpdatePrivacySettings(t *testing.T) {
+	fields := []string{models.PrivacyFieldLastSeen, models.PrivacyFieldSearch}
+
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.UpdatePrivacySettingsRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.PrivacySettings
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdatePrivacySettingsRequest{
+					UserId:     TestUserId,
+					Settings:   TestPrivacySettingsMessage,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest) {
+				users.On("UpdatePrivacySettings", ctx, in.UserId, TestPrivacySettings, fields).Return(TestPrivacySettings, nil)
+			},
+			want: TestPrivacySettingsMessage,
+		},
+		{
+			name: "ValidationError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdatePrivacySettingsRequest{
+					UserId:     TestUserId,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{models.PrivacyFieldAvatar}},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest) {
+				users.On("UpdatePrivacySettings", ctx, in.UserId, models.PrivacySettings{}, []string{models.PrivacyFieldAvatar}).
+					Return(models.PrivacySettings{}, &usersservice.ValidationError{Violations: []usersservice.FieldViolation{
+						{Field: models.PrivacyFieldAvatar, Description: "must be everyone, contacts or nobody"},
+					}})
+			},
+			wantErr: newTestValidationStatus(models.PrivacyFieldAvatar, "must be everyone, contacts or nobody"),
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdatePrivacySettingsRequest{
+					UserId:     TestUserId,
+					Settings:   TestPrivacySettingsMessage,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest) {
+				users.On("UpdatePrivacySettings", ctx, in.UserId, TestPrivacySettings, fields).
+					Return(models.PrivacySettings{}, fmt.Errorf("users.UpdatePrivacySettings, %w", usersservice.ErrUserNotFound))
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdatePrivacySettingsRequest{
+					UserId:     TestUserId,
+					Settings:   TestPrivacySettingsMessage,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: fields},
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest) {
+				users.On("UpdatePrivacySettings", ctx, in.UserId, TestPrivacySettings, fields).
+					Return(models.PrivacySettings{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyMask",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UpdatePrivacySettingsRequest{
+					UserId:   TestUserId,
+					Settings: TestPrivacySettingsMessage,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "update_mask is required"),
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.UpdatePrivacySettingsRequest{UserId: EmptyUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UpdatePrivacySettingsRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.UpdatePrivacySettings(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.UpdatePrivacySettings() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.UpdatePrivacySettings() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+var TestDevice = models.Device{
+	Id:           3,
+	UserId:       TestUserId,
+	Platform:     models.DevicePlatformIOS,
+	PushToken:    "push-token",
+	AppVersion:   "1.2.0",
+	CreatedAt:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	LastActiveAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+}
+
+// newTestDeviceMessage возвращает ожидаемое grpc сообщение для TestDevice.
+func newTestDeviceMessage() *messengerv1.Device {
+	return &messengerv1.Device{
+		Id:           TestDevice.Id,
+		Platform:     messengerv1.DevicePlatform_DEVICE_PLATFORM_IOS,
+		PushToken:    TestDevice.PushToken,
+		AppVersion:   TestDevice.AppVersion,
+		CreatedAt:    timestamppb.New(TestDevice.CreatedAt),
+		LastActiveAt: timestamppb.New(TestDevice.LastActiveAt),
+	}
+}
+
+func Test_serverAPI_RegisterDevice(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.RegisterDeviceRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.RegisterDeviceRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Device
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterDeviceRequest{
+					UserId:     TestUserId,
+					Platform:   messengerv1.DevicePlatform_DEVICE_PLATFORM_IOS,
+					PushToken:  TestDevice.PushToken,
+					AppVersion: TestDevice.AppVersion,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterDeviceRequest) {
+				users.On("RegisterDevice", ctx, in.UserId, models.DevicePlatformIOS, in.PushToken, in.AppVersion).Return(TestDevice, nil)
+			},
+			want: newTestDeviceMessage(),
+		},
+		{
+			name: "ValidationError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterDeviceRequest{
+					UserId:    TestUserId,
+					PushToken: TestDevice.PushToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterDeviceRequest) {
+				users.On("RegisterDevice", ctx, in.UserId, "", in.PushToken, "").
+					Return(models.Device{}, &usersservice.ValidationError{Violations: []usersservice.FieldViolation{
+						{Field: "platform", Description: "must be ios, android or web"},
+					}})
+			},
+			wantErr: newTestValidationStatus("platform", "must be ios, android or web"),
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterDeviceRequest{
+					UserId:    TestUserId,
+					Platform:  messengerv1.DevicePlatform_DEVICE_PLATFORM_WEB,
+					PushToken: TestDevice.PushToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterDeviceRequest) {
+				users.On("RegisterDevice", ctx, in.UserId, models.DevicePlatformWeb, in.PushToken, "").
+					Return(models.Device{}, fmt.Errorf("users.RegisterDevice, %w", usersservice.ErrUserNotFound))
+			},
+			wantErr: TestErrProfileUserNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterDeviceRequest{
+					UserId:    TestUserId,
+					Platform:  messengerv1.DevicePlatform_DEVICE_PLATFORM_ANDROID,
+					PushToken: TestDevice.PushToken,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterDeviceRequest) {
+				users.On("RegisterDevice", ctx, in.UserId, models.DevicePlatformAndroid, in.PushToken, "").
+					Return(models.Device{}, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyPushToken",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.RegisterDeviceRequest{
+					UserId:   TestUserId,
+					Platform: messengerv1.DevicePlatform_DEVICE_PLATFORM_IOS,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterDeviceRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "push_token is required"),
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.RegisterDeviceRequest{UserId: EmptyUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.RegisterDeviceRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.RegisterDevice(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.RegisterDevice() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.RegisterDevice() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_UnregisterDevice(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.UnregisterDeviceRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.UnregisterDeviceRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.Empty
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UnregisterDeviceRequest{
+					UserId:   TestUserId,
+					DeviceId: TestDevice.Id,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnregisterDeviceRequest) {
+				users.On("UnregisterDevice", ctx, in.UserId, in.DeviceId).Return(nil)
+			},
+			want: &messengerv1.Empty{},
+		},
+		{
+			name: "DeviceNotFound",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UnregisterDeviceRequest{
+					UserId:   TestUserId,
+					DeviceId: TestDevice.Id,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnregisterDeviceRequest) {
+				users.On("UnregisterDevice", ctx, in.UserId, in.DeviceId).
+					Return(fmt.Errorf("users.UnregisterDevice, %w", usersservice.ErrDeviceNotFound))
+			},
+			wantErr: status.Error(codes.NotFound, "device not found"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.UnregisterDeviceRequest{
+					UserId:   TestUserId,
+					DeviceId: TestDevice.Id,
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnregisterDeviceRequest) {
+				users.On("UnregisterDevice", ctx, in.UserId, in.DeviceId).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyDeviceId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.UnregisterDeviceRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnregisterDeviceRequest) {},
+			wantErr:      status.Error(codes.InvalidArgument, "device_id is required"),
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.UnregisterDeviceRequest{UserId: EmptyUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.UnregisterDeviceRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.UnregisterDevice(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.UnregisterDevice() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.UnregisterDevice() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func Test_serverAPI_ListDevices(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.ListDevicesRequest)
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.ListDevicesRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         *messengerv1.ListDevicesResponse
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListDevicesRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListDevicesRequest) {
+				users.On("ListDevices", ctx, in.UserId).Return([]models.Device{TestDevice}, nil)
+			},
+			want: &messengerv1.ListDevicesResponse{Devices: []*messengerv1.Device{newTestDeviceMessage()}},
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListDevicesRequest{UserId: TestUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListDevicesRequest) {
+				users.On("ListDevices", ctx, in.UserId).Return(nil, errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+		{
+			name: "EmptyId",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.ListDevicesRequest{UserId: EmptyUserId},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.ListDevicesRequest) {},
+			wantErr:      TestErrEmptyUserId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			got, err := s.ListDevices(tt.args.ctx, tt.args.in)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.ListDevices() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("serverAPI.ListDevices() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+// userChangeStream - поток WatchUsers, сохраняющий отправленные сообщения.
+type userChangeStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	changes []*messengerv1.UserChange
+}
+
+func (s *userChangeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *userChangeStream) Send(change *messengerv1.UserChange) error {
+	s.changes = append(s.changes, change)
+	return nil
+}
+
+func Test_serverAPI_WatchUsers(t *testing.T) {
+	type mockBehavior func(u *mocks.Users, ctx context.Context, in *messengerv1.WatchUsersRequest)
+
+	change := models.UserChange{
+		Id:        2,
+		Type:      models.EventUserProfileUpdated,
+		UserId:    TestUserId,
+		Payload:   []byte(`{"user_id":1}`),
+		CreatedAt: TestLastSeen,
+	}
+
+	type args struct {
+		ctx context.Context
+		in  *messengerv1.WatchUsersRequest
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []*messengerv1.UserChange
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.WatchUsersRequest{
+					SinceCursor: "cursor-1",
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchUsersRequest) {
+				users.On("WatchUsers", ctx, in.SinceCursor, mock.Anything).
+					Run(func(args mock.Arguments) {
+						send := args.Get(2).(func(models.UserChange, string) error)
+						_ = send(change, "cursor-2")
+					}).
+					Return(nil)
+			},
+			want: []*messengerv1.UserChange{
+				{
+					Cursor:     "cursor-2",
+					Type:       change.Type,
+					UserId:     TestUserId,
+					Payload:    change.Payload,
+					OccurredAt: timestamppb.New(TestLastSeen),
+				},
+			},
+		},
+		{
+			name: "InvalidCursor",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.WatchUsersRequest{
+					SinceCursor: "invalid",
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchUsersRequest) {
+				users.On("WatchUsers", ctx, in.SinceCursor, mock.Anything).Return(usersservice.ErrInvalidCursor)
+			},
+			wantErr: status.Error(codes.InvalidArgument, "invalid since_cursor"),
+		},
+		{
+			name: "ExpiredCursor",
+			args: args{
+				ctx: context.Background(),
+				in: &messengerv1.WatchUsersRequest{
+					SinceCursor: "expired",
+				},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchUsersRequest) {
+				users.On("WatchUsers", ctx, in.SinceCursor, mock.Anything).Return(usersservice.ErrCursorExpired)
+			},
+			wantErr: status.Error(codes.OutOfRange, "since_cursor expired"),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx: context.Background(),
+				in:  &messengerv1.WatchUsersRequest{},
+			},
+			mockBehavior: func(users *mocks.Users, ctx context.Context, in *messengerv1.WatchUsersRequest) {
+				users.On("WatchUsers", ctx, "", mock.Anything).Return(errors.New(""))
+			},
+			wantErr: TestErrInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := mocks.NewUsers(t)
+
+			tt.mockBehavior(users, tt.args.ctx, tt.args.in)
+			s := &serverAPI{
+				users: users,
+			}
+			stream := &userChangeStream{ctx: tt.args.ctx}
+			err := s.WatchUsers(tt.args.in, stream)
+			assert.Equal(t, err, tt.wantErr, fmt.Sprintf("serverAPI.WatchUsers() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, tt.want, stream.changes)
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{
+			name:    "OK",
+			proxies: []string{"10.0.0.0/8", " 192.168.1.1 ", "::ffff:172.16.0.1", "fd00::1/8"},
+			want: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.168.1.1/32"),
+				netip.MustParsePrefix("172.16.0.1/32"),
+				netip.MustParsePrefix("fd00::/8"),
+			},
+		},
+		{
+			name:    "Empty",
+			proxies: nil,
+			want:    []netip.Prefix{},
+		},
+		{
+			name:    "InvalidAddr",
+			proxies: []string{"gateway"},
+			wantErr: true,
+		},
+		{
+			name:    "InvalidPrefix",
+			proxies: []string{"10.0.0.0/33"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTrustedProxies(tt.proxies)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTrustedProxies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, got, fmt.Sprintf("ParseTrustedProxies() = %v, want %v", got, tt.want))
+			}
+		})
+	}
+}
+
+func Test_serverAPI_clientInfo(t *testing.T) {
+	withPeer := func(ctx context.Context, ip string) context.Context {
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50051}})
+	}
+	withXFF := func(xff ...string) context.Context {
+		md := metadata.MD{}
+		for _, v := range xff {
+			md.Append("x-forwarded-for", v)
+		}
+
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		wantIP string
+	}{
+		{
+			name:   "NoPeer",
+			ctx:    withXFF(TestIP),
+			wantIP: "",
+		},
+		{
+			name:   "DirectClient",
+			ctx:    withPeer(context.Background(), TestIP),
+			wantIP: TestIP,
+		},
+		{
+			name:   "UntrustedPeerSpoofedHeader",
+			ctx:    withPeer(withXFF("1.2.3.4"), TestIP),
+			wantIP: TestIP,
+		},
+		{
+			name:   "TrustedPeer",
+			ctx:    withPeer(withXFF(TestIP), "192.168.0.2"),
+			wantIP: TestIP,
+		},
+		{
+			name:   "TrustedChainSpoofedPrefix",
+			ctx:    withPeer(withXFF("1.2.3.4, "+TestIP+", 192.168.0.1"), "192.168.0.2"),
+			wantIP: TestIP,
+		},
+		{
+			name:   "MultipleHeaderValues",
+			ctx:    withPeer(withXFF("1.2.3.4", TestIP), "192.168.0.2"),
+			wantIP: TestIP,
+		},
+		{
+			name:   "AllTrusted",
+			ctx:    withPeer(withXFF("192.168.0.7, 192.168.0.1"), "192.168.0.2"),
+			wantIP: "192.168.0.7",
+		},
+		{
+			name:   "InvalidHop",
+			ctx:    withPeer(withXFF(TestIP+", unknown"), "192.168.0.2"),
+			wantIP: "192.168.0.2",
+		},
+		{
+			name:   "TrustedPeerNoHeader",
+			ctx:    withPeer(context.Background(), "192.168.0.2"),
+			wantIP: "192.168.0.2",
+		},
+		{
+			name:   "IPv6TrustedPeer",
+			ctx:    withPeer(withXFF("2001:db8::1"), "fd00::2"),
+			wantIP: "2001:db8::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &serverAPI{
+				trustedProxies: TestTrustedProxies,
+			}
+			got := s.clientInfo(tt.ctx, TestDeviceName)
+			assert.Equal(t, tt.wantIP, got.IP, fmt.Sprintf("serverAPI.clientInfo() IP = %v, want %v", got.IP, tt.wantIP))
+			assert.Equal(t, TestDeviceName, got.DeviceName)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package aead
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// Размер ключа AES-256 в байтах.
+const keySize = 32
+
+var (
+	ErrInvalidKey        = errors.New("encryption key must be 32 bytes encoded in base64")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Cipher - структура реализует шифрование небольших секретов алгоритмом AES-256-GCM.
+// Случайный nonce записывается перед шифртекстом.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New - конструктор для типа *Cipher, принимает ключ в кодировке base64.
+// Если ключ не декодируется или его длина не 32 байта, возвращает ошибку aead.ErrInvalidKey.
+func New(key string) (*Cipher, error) {
+	rawKey, err := ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{
+		aead: aead,
+	}, nil
+}
+
+// ParseKey декодирует ключ из base64.
+// Если ключ не декодируется или его длина не 32 байта, возвращает ошибку aead.ErrInvalidKey.
+func ParseKey(key string) ([]byte, error) {
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(rawKey) != keySize {
+		return nil, ErrInvalidKey
+	}
+
+	return rawKey, nil
+}
+
+// Encrypt шифрует данные, возвращает nonce вместе с шифртекстом.
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt расшифровывает данные, полученные методом Encrypt.
+// Если данные повреждены или зашифрованы другим ключом, возвращает ошибку aead.ErrInvalidCiphertext.
+func (c *Cipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := c.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,167 @@
+package aead
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+var (
+	TestKey      = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, keySize))
+	TestOtherKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, keySize))
+	TestSecret   = []byte("JBSWY3DPEHPK3PXP")
+)
+
+// newTestCipher возвращает Cipher с ключом key.
+func newTestCipher(t *testing.T, key string) *Cipher {
+	t.Helper()
+
+	c, err := New(key)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{
+			name: "OK",
+			key:  TestKey,
+		},
+		{
+			name:    "Empty",
+			key:     "",
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:    "NotBase64",
+			key:     "not base64!",
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:    "TooShort",
+			key:     base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16)),
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:    "TooLong",
+			key:     base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 33)),
+			wantErr: ErrInvalidKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.key); !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c := newTestCipher(t, TestKey)
+
+	for _, plaintext := range [][]byte{TestSecret, {}, bytes.Repeat([]byte("x"), 4096)} {
+		ciphertext, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Cipher.Encrypt() error = %v", err)
+		}
+
+		if len(plaintext) > 0 && bytes.Contains(ciphertext, plaintext) {
+			t.Errorf("Cipher.Encrypt() ciphertext contains plaintext")
+		}
+
+		got, err := c.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("Cipher.Decrypt() error = %v", err)
+		}
+
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("Cipher.Decrypt() = %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestCipher_Encrypt_RandomNonce(t *testing.T) {
+	c := newTestCipher(t, TestKey)
+
+	first, err := c.Encrypt(TestSecret)
+	if err != nil {
+		t.Fatalf("Cipher.Encrypt() error = %v", err)
+	}
+
+	second, err := c.Encrypt(TestSecret)
+	if err != nil {
+		t.Fatalf("Cipher.Encrypt() error = %v", err)
+	}
+
+	if bytes.Equal(first, second) {
+		t.Errorf("Cipher.Encrypt() returned the same ciphertext twice, nonce is not random")
+	}
+}
+
+func TestCipher_Decrypt_Invalid(t *testing.T) {
+	c := newTestCipher(t, TestKey)
+
+	ciphertext, err := c.Encrypt(TestSecret)
+	if err != nil {
+		t.Fatalf("Cipher.Encrypt() error = %v", err)
+	}
+
+	tamper := func(i int) []byte {
+		b := bytes.Clone(ciphertext)
+		b[i] ^= 0x01
+		return b
+	}
+
+	tests := []struct {
+		name       string
+		cipher     *Cipher
+		ciphertext []byte
+	}{
+		{
+			name:       "TamperedNonce",
+			cipher:     c,
+			ciphertext: tamper(0),
+		},
+		{
+			name:       "TamperedBody",
+			cipher:     c,
+			ciphertext: tamper(c.aead.NonceSize()),
+		},
+		{
+			name:       "TamperedTag",
+			cipher:     c,
+			ciphertext: tamper(len(ciphertext) - 1),
+		},
+		{
+			name:       "Truncated",
+			cipher:     c,
+			ciphertext: ciphertext[:len(ciphertext)-1],
+		},
+		{
+			name:       "ShorterThanNonce",
+			cipher:     c,
+			ciphertext: ciphertext[:c.aead.NonceSize()-1],
+		},
+		{
+			name:       "WrongKey",
+			cipher:     newTestCipher(t, TestOtherKey),
+			ciphertext: ciphertext,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cipher.Decrypt(tt.ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+				t.Errorf("Cipher.Decrypt() error = %v, wantErr %v", err, ErrInvalidCiphertext)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// argon2Hasher - реализация хэширования алгоритмом argon2id.
+// Формат хэша: $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>.
+type argon2Hasher struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// argon2Hash - разобранный хэш argon2id.
+type argon2Hash struct {
+	version int
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func (h *argon2Hasher) hash(password []byte) ([]byte, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(password, salt, h.time, h.memory, h.threads, argon2KeyLength)
+
+	return encodePHC(AlgorithmArgon2id, []string{
+		fmt.Sprintf("v=%d", argon2.Version),
+		fmt.Sprintf("m=%d,t=%d,p=%d", h.memory, h.time, h.threads),
+	}, salt, key), nil
+}
+
+func (h *argon2Hasher) compare(hash, password []byte) error {
+	decoded, err := decodeArgon2(hash)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey(password, decoded.salt, decoded.time, decoded.memory, decoded.threads, uint32(len(decoded.key)))
+	if subtle.ConstantTimeCompare(key, decoded.key) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+func (h *argon2Hasher) needsRehash(hash []byte) bool {
+	decoded, err := decodeArgon2(hash)
+	if err != nil {
+		return true
+	}
+
+	return decoded.version != argon2.Version ||
+		decoded.time != h.time ||
+		decoded.memory != h.memory ||
+		decoded.threads != h.threads ||
+		len(decoded.key) != argon2KeyLength
+}
+
+// decodeArgon2 разбирает хэш argon2id. Если формат хэша неверный, возвращает ошибку ErrInvalidHash.
+func decodeArgon2(hash []byte) (argon2Hash, error) {
+	phc, err := decodePHC(hash, AlgorithmArgon2id, 2)
+	if err != nil {
+		return argon2Hash{}, err
+	}
+
+	decoded := argon2Hash{
+		salt: phc.salt,
+		key:  phc.key,
+	}
+
+	if _, err = fmt.Sscanf(phc.fields[0], "v=%d", &decoded.version); err != nil {
+		return argon2Hash{}, ErrInvalidHash
+	}
+
+	if _, err = fmt.Sscanf(phc.fields[1], "m=%d,t=%d,p=%d", &decoded.memory, &decoded.time, &decoded.threads); err != nil {
+		return argon2Hash{}, ErrInvalidHash
+	}
+
+	if decoded.time == 0 || decoded.threads == 0 {
+		return argon2Hash{}, ErrInvalidHash
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,34 @@
+package crypt
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptHasher - реализация хэширования алгоритмом bcrypt.
+type bcryptHasher struct {
+	cost int
+}
+
+func (h *bcryptHasher) hash(password []byte) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(password, h.cost)
+}
+
+func (h *bcryptHasher) compare(hash, password []byte) error {
+	if err := bcrypt.CompareHashAndPassword(hash, password); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedHashAndPassword
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (h *bcryptHasher) needsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+
+	return err != nil || cost != h.cost
+}
@@ -1,27 +1,133 @@
 package crypt
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
 
-//Crypter - структура реализует методы хэширования пароля и сравнения пароля с хэшем.
-type Crypter struct{}
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+)
 
-// GenerateFromPassword возвращает хэш указанного пароля с заданной стоимостью.
-// Использует пакет bcrypt.
-func (c *Crypter) GenerateFromPassword(pass []byte, cost int) ([]byte, error) {
-	passHash, err := bcrypt.GenerateFromPassword(pass, cost)
-	if err != nil {
-		return nil, err
+// bcryptMaxBytes - максимальная длина пароля в байтах, которую принимает bcrypt.
+const bcryptMaxBytes = 72
+
+var (
+	ErrMismatchedHashAndPassword = errors.New("hash and password mismatch")
+	ErrUnknownAlgorithm          = errors.New("unknown hash algorithm")
+	ErrInvalidHash               = errors.New("invalid hash format")
+)
+
+// Params - параметры хэширования. Algorithm определяет алгоритм, которым хэшируются новые пароли.
+type Params struct {
+	Algorithm string
+
+	BcryptCost int
+
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+
+	ScryptLogN int
+	ScryptR    int
+	ScryptP    int
+}
+
+// hasher - интерфейс реализации отдельного алгоритма хэширования.
+type hasher interface {
+	// hash возвращает закодированный хэш пароля с текущими параметрами.
+	hash(password []byte) ([]byte, error)
+
+	// compare сравнивает закодированный хэш с паролем, используя параметры из хэша.
+	compare(hash, password []byte) error
+
+	// needsRehash сообщает, что хэш получен с параметрами, отличными от текущих.
+	needsRehash(hash []byte) bool
+}
+
+// Crypter - структура реализует методы хэширования пароля и сравнения пароля с хэшем.
+// Хэши bcrypt хранятся в формате MCF ($2a$...), хэши argon2id и scrypt - в формате PHC ($argon2id$..., $scrypt$...).
+type Crypter struct {
+	algorithm string
+	hashers   map[string]hasher
+}
+
+// New возвращает новый объект *Crypter. Если алгоритм из params не поддерживается, возвращает ошибку ErrUnknownAlgorithm.
+func New(params Params) (*Crypter, error) {
+	c := &Crypter{
+		algorithm: params.Algorithm,
+		hashers: map[string]hasher{
+			AlgorithmBcrypt: &bcryptHasher{cost: params.BcryptCost},
+			AlgorithmArgon2id: &argon2Hasher{
+				time:    params.Argon2Time,
+				memory:  params.Argon2Memory,
+				threads: params.Argon2Threads,
+			},
+			AlgorithmScrypt: &scryptHasher{
+				logN: params.ScryptLogN,
+				r:    params.ScryptR,
+				p:    params.ScryptP,
+			},
+		},
+	}
+
+	if _, ok := c.hashers[params.Algorithm]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, params.Algorithm)
 	}
 
-	return passHash, nil
+	return c, nil
+}
+
+// GenerateFromPassword возвращает хэш указанного пароля.
+// Использует алгоритм и параметры, заданные при создании Crypter.
+func (c *Crypter) GenerateFromPassword(pass []byte) ([]byte, error) {
+	return c.hashers[c.algorithm].hash(pass)
 }
 
 // CompareHashAndPassword сравнивает захэшированный пароль с исходным.
-// Использует пакет bcrypt.
+// Алгоритм и параметры определяются по самому хэшу. В случае несовпадения возвращает ошибку ErrMismatchedHashAndPassword.
 func (c *Crypter) CompareHashAndPassword(hashedPassword []byte, password []byte) error {
-	if err := bcrypt.CompareHashAndPassword(hashedPassword, password); err != nil {
-		return err
+	h, ok := c.hashers[identify(hashedPassword)]
+	if !ok {
+		return ErrUnknownAlgorithm
+	}
+
+	return h.compare(hashedPassword, password)
+}
+
+// NeedsRehash сообщает, что хэш получен другим алгоритмом или с параметрами, отличными от текущих.
+func (c *Crypter) NeedsRehash(hashedPassword []byte) bool {
+	algorithm := identify(hashedPassword)
+	if algorithm != c.algorithm {
+		return true
+	}
+
+	return c.hashers[algorithm].needsRehash(hashedPassword)
+}
+
+// MaxPasswordBytes возвращает максимальную длину пароля в байтах, которую допускает алгоритм.
+// 0 означает, что алгоритм не ограничивает длину пароля.
+func MaxPasswordBytes(algorithm string) int {
+	if algorithm == AlgorithmBcrypt {
+		return bcryptMaxBytes
+	}
+
+	return 0
+}
+
+// identify возвращает название алгоритма, которым получен хэш.
+func identify(hash []byte) string {
+	if bytes.HasPrefix(hash, []byte("$2a$")) || bytes.HasPrefix(hash, []byte("$2b$")) || bytes.HasPrefix(hash, []byte("$2y$")) {
+		return AlgorithmBcrypt
+	}
+
+	parts := bytes.SplitN(hash, []byte("$"), 3)
+	if len(parts) < 3 || len(parts[0]) != 0 {
+		return ""
 	}
 
-	return nil
+	return string(parts[1])
 }
@@ -0,0 +1,499 @@
+package crypt
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	TestPassword      = []byte("correct horse battery staple")
+	TestWrongPassword = []byte("correct horse battery stapler")
+	TestParams        = Params{
+		BcryptCost:    4,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+		ScryptLogN:    4,
+		ScryptR:       8,
+		ScryptP:       1,
+	}
+	TestAlgorithms = []string{AlgorithmBcrypt, AlgorithmArgon2id, AlgorithmScrypt}
+)
+
+// newTestCrypter возвращает Crypter с параметрами TestParams и алгоритмом algorithm.
+func newTestCrypter(t *testing.T, algorithm string) *Crypter {
+	t.Helper()
+
+	params := TestParams
+	params.Algorithm = algorithm
+	c, err := New(params)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return c
+}
+
+func TestNew(t *testing.T) {
+	for _, algorithm := range TestAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			params := TestParams
+			params.Algorithm = algorithm
+			if _, err := New(params); err != nil {
+				t.Errorf("New() error = %v", err)
+			}
+		})
+	}
+
+	t.Run("UnknownAlgorithm", func(t *testing.T) {
+		params := TestParams
+		params.Algorithm = "md5"
+		if _, err := New(params); !errors.Is(err, ErrUnknownAlgorithm) {
+			t.Errorf("New() error = %v, wantErr %v", err, ErrUnknownAlgorithm)
+		}
+	})
+}
+
+func TestCrypter_RoundTrip(t *testing.T) {
+	for _, algorithm := range TestAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			c := newTestCrypter(t, algorithm)
+
+			hash, err := c.GenerateFromPassword(TestPassword)
+			if err != nil {
+				t.Fatalf("Crypter.GenerateFromPassword() error = %v", err)
+			}
+
+			if got := identify(hash); got != algorithm {
+				t.Errorf("identify() = %v, want %v", got, algorithm)
+			}
+
+			if err = c.CompareHashAndPassword(hash, TestPassword); err != nil {
+				t.Errorf("Crypter.CompareHashAndPassword() error = %v", err)
+			}
+
+			if err = c.CompareHashAndPassword(hash, TestWrongPassword); !errors.Is(err, ErrMismatchedHashAndPassword) {
+				t.Errorf("Crypter.CompareHashAndPassword() error = %v, wantErr %v", err, ErrMismatchedHashAndPassword)
+			}
+
+			if c.NeedsRehash(hash) {
+				t.Errorf("Crypter.NeedsRehash() = true, want false")
+			}
+
+			other, err := c.GenerateFromPassword(TestPassword)
+			if err != nil {
+				t.Fatalf("Crypter.GenerateFromPassword() error = %v", err)
+			}
+
+			if string(other) == string(hash) {
+				t.Errorf("Crypter.GenerateFromPassword() returned the same hash twice, salt is not random")
+			}
+		})
+	}
+}
+
+func TestCrypter_CompareHashAndPassword_DetectsAlgorithm(t *testing.T) {
+	current := newTestCrypter(t, AlgorithmArgon2id)
+
+	for _, algorithm := range TestAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			hash, err := newTestCrypter(t, algorithm).GenerateFromPassword(TestPassword)
+			if err != nil {
+				t.Fatalf("Crypter.GenerateFromPassword() error = %v", err)
+			}
+
+			if err = current.CompareHashAndPassword(hash, TestPassword); err != nil {
+				t.Errorf("Crypter.CompareHashAndPassword() error = %v", err)
+			}
+
+			if got, want := current.NeedsRehash(hash), algorithm != AlgorithmArgon2id; got != want {
+				t.Errorf("Crypter.NeedsRehash() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCrypter_CompareHashAndPassword_Malformed(t *testing.T) {
+	c := newTestCrypter(t, AlgorithmArgon2id)
+
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr error
+	}{
+		{
+			name:    "Empty",
+			hash:    "",
+			wantErr: ErrUnknownAlgorithm,
+		},
+		{
+			name:    "UnknownAlgorithm",
+			hash:    "$md5$salt$key",
+			wantErr: ErrUnknownAlgorithm,
+		},
+		{
+			name:    "Argon2idMissingParts",
+			hash:    "$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "ScryptBadParams",
+			hash:    "$scrypt$ln=x,r=8,p=1$c2FsdA$a2V5",
+			wantErr: ErrInvalidHash,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.CompareHashAndPassword([]byte(tt.hash), TestPassword); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Crypter.CompareHashAndPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCrypter_NeedsRehash(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		change    func(p *Params)
+	}{
+		{
+			name:      "BcryptCost",
+			algorithm: AlgorithmBcrypt,
+			change:    func(p *Params) { p.BcryptCost = 5 },
+		},
+		{
+			name:      "Argon2Time",
+			algorithm: AlgorithmArgon2id,
+			change:    func(p *Params) { p.Argon2Time = 2 },
+		},
+		{
+			name:      "Argon2Memory",
+			algorithm: AlgorithmArgon2id,
+			change:    func(p *Params) { p.Argon2Memory = 128 },
+		},
+		{
+			name:      "Argon2Threads",
+			algorithm: AlgorithmArgon2id,
+			change:    func(p *Params) { p.Argon2Threads = 2 },
+		},
+		{
+			name:      "ScryptLogN",
+			algorithm: AlgorithmScrypt,
+			change:    func(p *Params) { p.ScryptLogN = 5 },
+		},
+		{
+			name:      "ScryptR",
+			algorithm: AlgorithmScrypt,
+			change:    func(p *Params) { p.ScryptR = 4 },
+		},
+		{
+			name:      "ScryptP",
+			algorithm: AlgorithmScrypt,
+			change:    func(p *Params) { p.ScryptP = 2 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := newTestCrypter(t, tt.algorithm).GenerateFromPassword(TestPassword)
+			if err != nil {
+				t.Fatalf("Crypter.GenerateFromPassword() error = %v", err)
+			}
+
+			params := TestParams
+			params.Algorithm = tt.algorithm
+			tt.change(&params)
+			c, err := New(params)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if !c.NeedsRehash(hash) {
+				t.Errorf("Crypter.NeedsRehash() = false, want true")
+			}
+		})
+	}
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, algorithm := range TestAlgorithms {
+			c := newTestCrypter(t, algorithm)
+			malformed := "$" + algorithm + "$broken"
+			if algorithm == AlgorithmBcrypt {
+				malformed = "$2a$broken"
+			}
+
+			if !c.NeedsRehash([]byte(malformed)) {
+				t.Errorf("Crypter.NeedsRehash(%q) = false, want true", malformed)
+			}
+		}
+	})
+}
+
+func TestCrypter_GenerateFromPassword_BcryptTooLong(t *testing.T) {
+	c := newTestCrypter(t, AlgorithmBcrypt)
+	if _, err := c.GenerateFromPassword([]byte(strings.Repeat("a", bcryptMaxBytes+1))); err == nil {
+		t.Errorf("Crypter.GenerateFromPassword() error = nil, want error for password longer than %d bytes", bcryptMaxBytes)
+	}
+
+	c = newTestCrypter(t, AlgorithmArgon2id)
+	if _, err := c.GenerateFromPassword([]byte(strings.Repeat("a", bcryptMaxBytes+1))); err != nil {
+		t.Errorf("Crypter.GenerateFromPassword() error = %v", err)
+	}
+}
+
+func TestMaxPasswordBytes(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      int
+	}{
+		{algorithm: AlgorithmBcrypt, want: bcryptMaxBytes},
+		{algorithm: AlgorithmArgon2id, want: 0},
+		{algorithm: AlgorithmScrypt, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			if got := MaxPasswordBytes(tt.algorithm); got != tt.want {
+				t.Errorf("MaxPasswordBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_identify(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+		want string
+	}{
+		{
+			name: "Bcrypt2a",
+			hash: "$2a$10$abcdefghijklmnopqrstuu",
+			want: AlgorithmBcrypt,
+		},
+		{
+			name: "Bcrypt2b",
+			hash: "$2b$10$abcdefghijklmnopqrstuu",
+			want: AlgorithmBcrypt,
+		},
+		{
+			name: "Bcrypt2y",
+			hash: "$2y$10$abcdefghijklmnopqrstuu",
+			want: AlgorithmBcrypt,
+		},
+		{
+			name: "Argon2id",
+			hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5",
+			want: AlgorithmArgon2id,
+		},
+		{
+			name: "Scrypt",
+			hash: "$scrypt$ln=4,r=8,p=1$c2FsdA$a2V5",
+			want: AlgorithmScrypt,
+		},
+		{
+			name: "NoLeadingDollar",
+			hash: "argon2id$v=19$m=64,t=1,p=1",
+			want: "",
+		},
+		{
+			name: "Empty",
+			hash: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := identify([]byte(tt.hash)); got != tt.want {
+				t.Errorf("identify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decodePHC(t *testing.T) {
+	salt, key := []byte("salt"), []byte("key")
+	hash := encodePHC(AlgorithmScrypt, []string{"ln=4,r=8,p=1"}, salt, key)
+	if string(hash) != "$scrypt$ln=4,r=8,p=1$c2FsdA$a2V5" {
+		t.Fatalf("encodePHC() = %s", hash)
+	}
+
+	want := phcHash{
+		fields: []string{"ln=4,r=8,p=1"},
+		salt:   salt,
+		key:    key,
+	}
+	got, err := decodePHC(hash, AlgorithmScrypt, 1)
+	if err != nil {
+		t.Fatalf("decodePHC() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodePHC() = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name        string
+		hash        string
+		id          string
+		fieldsCount int
+	}{
+		{
+			name:        "OtherAlgorithm",
+			hash:        "$scrypt$ln=4,r=8,p=1$c2FsdA$a2V5",
+			id:          AlgorithmArgon2id,
+			fieldsCount: 1,
+		},
+		{
+			name:        "TooFewParts",
+			hash:        "$scrypt$ln=4,r=8,p=1$c2FsdA",
+			id:          AlgorithmScrypt,
+			fieldsCount: 1,
+		},
+		{
+			name:        "TooManyParts",
+			hash:        "$scrypt$v=1$ln=4,r=8,p=1$c2FsdA$a2V5",
+			id:          AlgorithmScrypt,
+			fieldsCount: 1,
+		},
+		{
+			name:        "NoLeadingDollar",
+			hash:        "x$scrypt$ln=4,r=8,p=1$c2FsdA$a2V5",
+			id:          AlgorithmScrypt,
+			fieldsCount: 1,
+		},
+		{
+			name:        "BadSalt",
+			hash:        "$scrypt$ln=4,r=8,p=1$c2F*dA$a2V5",
+			id:          AlgorithmScrypt,
+			fieldsCount: 1,
+		},
+		{
+			name:        "BadKey",
+			hash:        "$scrypt$ln=4,r=8,p=1$c2FsdA$a2V5==",
+			id:          AlgorithmScrypt,
+			fieldsCount: 1,
+		},
+		{
+			name:        "EmptyKey",
+			hash:        "$scrypt$ln=4,r=8,p=1$c2FsdA$",
+			id:          AlgorithmScrypt,
+			fieldsCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodePHC([]byte(tt.hash), tt.id, tt.fieldsCount); !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("decodePHC() error = %v, wantErr %v", err, ErrInvalidHash)
+			}
+		})
+	}
+}
+
+func Test_decodeArgon2(t *testing.T) {
+	want := argon2Hash{
+		version: 19,
+		memory:  64,
+		time:    1,
+		threads: 2,
+		salt:    []byte("salt"),
+		key:     []byte("key"),
+	}
+	got, err := decodeArgon2([]byte("$argon2id$v=19$m=64,t=1,p=2$c2FsdA$a2V5"))
+	if err != nil {
+		t.Fatalf("decodeArgon2() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeArgon2() = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{
+			name: "Argon2i",
+			hash: "$argon2i$v=19$m=64,t=1,p=2$c2FsdA$a2V5",
+		},
+		{
+			name: "NoVersion",
+			hash: "$argon2id$m=64,t=1,p=2$c2FsdA$a2V5",
+		},
+		{
+			name: "BadVersion",
+			hash: "$argon2id$v=x$m=64,t=1,p=2$c2FsdA$a2V5",
+		},
+		{
+			name: "BadParams",
+			hash: "$argon2id$v=19$m=64,p=2$c2FsdA$a2V5",
+		},
+		{
+			name: "ZeroTime",
+			hash: "$argon2id$v=19$m=64,t=0,p=2$c2FsdA$a2V5",
+		},
+		{
+			name: "ZeroThreads",
+			hash: "$argon2id$v=19$m=64,t=1,p=0$c2FsdA$a2V5",
+		},
+		{
+			name: "ThreadsOverflow",
+			hash: "$argon2id$v=19$m=64,t=1,p=300$c2FsdA$a2V5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeArgon2([]byte(tt.hash)); !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("decodeArgon2() error = %v, wantErr %v", err, ErrInvalidHash)
+			}
+		})
+	}
+}
+
+func Test_decodeScrypt(t *testing.T) {
+	want := scryptHash{
+		logN: 4,
+		r:    8,
+		p:    1,
+		salt: []byte("salt"),
+		key:  []byte("key"),
+	}
+	got, err := decodeScrypt([]byte("$scrypt$ln=4,r=8,p=1$c2FsdA$a2V5"))
+	if err != nil {
+		t.Fatalf("decodeScrypt() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeScrypt() = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{
+			name: "BadParams",
+			hash: "$scrypt$ln=4,p=1$c2FsdA$a2V5",
+		},
+		{
+			name: "ZeroLogN",
+			hash: "$scrypt$ln=0,r=8,p=1$c2FsdA$a2V5",
+		},
+		{
+			name: "LogNTooLarge",
+			hash: "$scrypt$ln=32,r=8,p=1$c2FsdA$a2V5",
+		},
+		{
+			name: "ExtraField",
+			hash: "$scrypt$v=1$ln=4,r=8,p=1$c2FsdA$a2V5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeScrypt([]byte(tt.hash)); !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("decodeScrypt() error = %v, wantErr %v", err, ErrInvalidHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package crypt
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// phcHash - разобранный хэш в формате PHC: $<id>$<поле>...$<salt>$<key>.
+// Поля содержат версию и параметры алгоритма и разбираются реализацией алгоритма.
+type phcHash struct {
+	fields []string
+	salt   []byte
+	key    []byte
+}
+
+// encodePHC возвращает хэш в формате PHC. Соль и ключ кодируются в base64 без дополнения.
+func encodePHC(id string, fields []string, salt, key []byte) []byte {
+	parts := make([]string, 0, len(fields)+4)
+	parts = append(parts, "", id)
+	parts = append(parts, fields...)
+	parts = append(parts,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return []byte(strings.Join(parts, "$"))
+}
+
+// decodePHC разбирает хэш алгоритма id в формате PHC с fieldsCount полями между id и солью.
+// Если формат хэша неверный или ключ пустой, возвращает ошибку ErrInvalidHash.
+func decodePHC(hash []byte, id string, fieldsCount int) (phcHash, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != fieldsCount+4 || parts[0] != "" || parts[1] != id {
+		return phcHash{}, ErrInvalidHash
+	}
+
+	decoded := phcHash{
+		fields: parts[2 : 2+fieldsCount],
+	}
+
+	var err error
+	if decoded.salt, err = base64.RawStdEncoding.DecodeString(parts[2+fieldsCount]); err != nil {
+		return phcHash{}, ErrInvalidHash
+	}
+
+	if decoded.key, err = base64.RawStdEncoding.DecodeString(parts[3+fieldsCount]); err != nil || len(decoded.key) == 0 {
+		return phcHash{}, ErrInvalidHash
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,100 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	scryptSaltLength = 16
+	scryptKeyLength  = 32
+)
+
+// scryptHasher - реализация хэширования алгоритмом scrypt.
+// Формат хэша: $scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<key>.
+type scryptHasher struct {
+	logN int
+	r    int
+	p    int
+}
+
+// scryptHash - разобранный хэш scrypt.
+type scryptHash struct {
+	logN int
+	r    int
+	p    int
+	salt []byte
+	key  []byte
+}
+
+func (h *scryptHasher) hash(password []byte) ([]byte, error) {
+	salt := make([]byte, scryptSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key(password, salt, 1<<h.logN, h.r, h.p, scryptKeyLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePHC(AlgorithmScrypt, []string{
+		fmt.Sprintf("ln=%d,r=%d,p=%d", h.logN, h.r, h.p),
+	}, salt, key), nil
+}
+
+func (h *scryptHasher) compare(hash, password []byte) error {
+	decoded, err := decodeScrypt(hash)
+	if err != nil {
+		return err
+	}
+
+	key, err := scrypt.Key(password, decoded.salt, 1<<decoded.logN, decoded.r, decoded.p, len(decoded.key))
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(key, decoded.key) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+func (h *scryptHasher) needsRehash(hash []byte) bool {
+	decoded, err := decodeScrypt(hash)
+	if err != nil {
+		return true
+	}
+
+	return decoded.logN != h.logN ||
+		decoded.r != h.r ||
+		decoded.p != h.p ||
+		len(decoded.key) != scryptKeyLength
+}
+
+// decodeScrypt разбирает хэш scrypt. Если формат хэша неверный, возвращает ошибку ErrInvalidHash.
+func decodeScrypt(hash []byte) (scryptHash, error) {
+	phc, err := decodePHC(hash, AlgorithmScrypt, 1)
+	if err != nil {
+		return scryptHash{}, err
+	}
+
+	decoded := scryptHash{
+		salt: phc.salt,
+		key:  phc.key,
+	}
+
+	if _, err = fmt.Sscanf(phc.fields[0], "ln=%d,r=%d,p=%d", &decoded.logN, &decoded.r, &decoded.p); err != nil {
+		return scryptHash{}, ErrInvalidHash
+	}
+
+	if decoded.logN <= 0 || decoded.logN >= 32 {
+		return scryptHash{}, ErrInvalidHash
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// MemoryPublisher - структура реализует публикацию событий в память процесса.
+// Предназначена для тестов и локального запуска, когда брокер сообщений недоступен.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []models.Event
+	seen   map[string]struct{}
+}
+
+// NewMemoryPublisher - конструктор для типа *MemoryPublisher.
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{
+		seen: make(map[string]struct{}),
+	}
+}
+
+// Publish сохраняет событие. Повторная публикация события с тем же ключом идемпотентности игнорируется,
+// как это сделал бы потребитель.
+func (p *MemoryPublisher) Publish(ctx context.Context, event models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.seen[event.IdempotencyKey]; ok {
+		return nil
+	}
+
+	p.seen[event.IdempotencyKey] = struct{}{}
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+// Events возвращает опубликованные события в порядке публикации.
+func (p *MemoryPublisher) Events() []models.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]models.Event, len(p.events))
+	copy(events, p.events)
+
+	return events
+}
+
+// Close ничего не делает, MemoryPublisher не держит внешних ресурсов.
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
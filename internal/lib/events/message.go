@@ -0,0 +1,28 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// message - сообщение о событии, которое получают потребители.
+type message struct {
+	IdempotencyKey string          `json:"idempotency_key"`
+	Type           string          `json:"type"`
+	UserId         int64           `json:"user_id"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// encode кодирует событие в сообщение для потребителей в формате JSON.
+func encode(event models.Event) ([]byte, error) {
+	return json.Marshal(message{
+		IdempotencyKey: event.IdempotencyKey,
+		Type:           event.Type,
+		UserId:         event.UserId,
+		OccurredAt:     event.CreatedAt,
+		Data:           event.Payload,
+	})
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// clientName - имя клиента, под которым сервис виден в мониторинге NATS.
+const clientName = "messenger-users"
+
+// NATSParams - параметры публикации событий в NATS JetStream.
+type NATSParams struct {
+	// URL - адрес сервера вида nats://[user:password@]host:port или tls://host:port.
+	URL string
+	// SubjectPrefix - префикс subject, событие публикуется в <SubjectPrefix>.<тип события>.
+	SubjectPrefix string
+	// Stream - поток JetStream, который должен принять событие. Если пусто, принимает любой поток с этим subject.
+	Stream string
+	// Timeout ограничивает установку соединения и ожидание подтверждения одного события.
+	Timeout time.Duration
+	// Token - токен авторизации на сервере.
+	Token string
+	// CredsFile - файл с JWT и NKey пользователя.
+	CredsFile string
+	// TLSCAFile - сертификат центра, которым проверяется сертификат сервера. Если задан, соединение использует TLS.
+	TLSCAFile string
+	// TLSCertFile и TLSKeyFile - сертификат и ключ клиента для взаимной аутентификации по TLS.
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// NATSPublisher - структура реализует публикацию событий в NATS JetStream.
+// Событие публикуется в subject <prefix>.<тип события>, ключ идемпотентности передается в заголовке Nats-Msg-Id,
+// поэтому поток с окном дедупликации отбрасывает повторы.
+// Publish возвращает nil только после подтверждения (PubAck) от потока, то есть когда событие сохранено.
+type NATSPublisher struct {
+	nc      *nats.Conn
+	js      jetstream.JetStream
+	prefix  string
+	stream  string
+	timeout time.Duration
+}
+
+// NewNATSPublisher - конструктор для типа *NATSPublisher.
+// Если сервер недоступен, соединение устанавливается в фоне, а публикации до этого завершаются ошибкой по таймауту.
+// Возвращает ошибку, если адрес или параметры авторизации и TLS заданы неверно.
+func NewNATSPublisher(params NATSParams) (*NATSPublisher, error) {
+	const op = "events.NewNATSPublisher"
+
+	opts := []nats.Option{
+		nats.Name(clientName),
+		nats.Timeout(params.Timeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	}
+	if params.Token != "" {
+		opts = append(opts, nats.Token(params.Token))
+	}
+	if params.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(params.CredsFile))
+	}
+	if params.TLSCAFile != "" {
+		opts = append(opts, nats.RootCAs(params.TLSCAFile))
+	}
+	if params.TLSCertFile != "" || params.TLSKeyFile != "" {
+		opts = append(opts, nats.ClientCert(params.TLSCertFile, params.TLSKeyFile))
+	}
+
+	nc, err := nats.Connect(params.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return &NATSPublisher{
+		nc:      nc,
+		js:      js,
+		prefix:  params.SubjectPrefix,
+		stream:  params.Stream,
+		timeout: params.Timeout,
+	}, nil
+}
+
+// Publish публикует событие и дожидается подтверждения от потока JetStream.
+// Повтор события, уже сохраненного потоком, тоже подтверждается.
+func (p *NATSPublisher) Publish(ctx context.Context, event models.Event) error {
+	const op = "events.NATSPublisher.Publish"
+
+	data, err := encode(event)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	opts := []jetstream.PublishOpt{jetstream.WithMsgID(event.IdempotencyKey)}
+	if p.stream != "" {
+		opts = append(opts, jetstream.WithExpectStream(p.stream))
+	}
+
+	if _, err = p.js.Publish(ctx, p.prefix+"."+event.Type, data, opts...); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// Close закрывает соединение с сервером.
+func (p *NATSPublisher) Close() error {
+	p.nc.Close()
+
+	return nil
+}
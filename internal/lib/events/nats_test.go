@@ -0,0 +1,377 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+var (
+	TestEvent = models.Event{
+		Id:             1,
+		IdempotencyKey: "6f1c3a52-5d8e-4f43-9a51-2f1d5b0c7e11",
+		Type:           models.EventUserRegistered,
+		UserId:         1,
+		Payload:        []byte(`{"username":"username"}`),
+		CreatedAt:      time.Unix(1700000000, 0).UTC(),
+	}
+	TestNATSToken = "token"
+	TestPubAck    = `{"stream":"users","seq":1}`
+)
+
+// testNATSMsg - сообщение, опубликованное клиентом на тестовом сервере.
+type testNATSMsg struct {
+	Subject string
+	Header  textproto.MIMEHeader
+	Data    []byte
+}
+
+// testNATSServer - минимальный сервер NATS для тестов публикатора.
+// Отвечает на публикации с адресом ответа результатом ack. Если ack возвращает пустую строку, ответа нет.
+type testNATSServer struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	token     string
+	ack       func(msg testNATSMsg) string
+
+	mu   sync.Mutex
+	msgs []testNATSMsg
+}
+
+// newTestNATSServer запускает тестовый сервер. Если tlsConfig задан, сервер требует TLS,
+// если token не пуст - авторизацию по токену.
+func newTestNATSServer(t *testing.T, tlsConfig *tls.Config, token string, ack func(msg testNATSMsg) string) *testNATSServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testNATSServer{ln: ln, tlsConfig: tlsConfig, token: token, ack: ack}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+// url возвращает адрес тестового сервера.
+func (s *testNATSServer) url() string {
+	return "nats://" + s.ln.Addr().String()
+}
+
+// published возвращает сообщения, опубликованные на сервере.
+func (s *testNATSServer) published() []testNATSMsg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]testNATSMsg(nil), s.msgs...)
+}
+
+// serve обслуживает одно соединение клиента.
+func (s *testNATSServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	info := map[string]any{
+		"server_id":   "test",
+		"version":     "2.10.0",
+		"proto":       1,
+		"headers":     true,
+		"max_payload": 1 << 20,
+	}
+	if s.tlsConfig != nil {
+		info["tls_required"] = true
+	}
+	if s.token != "" {
+		info["auth_required"] = true
+	}
+
+	data, _ := json.Marshal(info)
+	if _, err := fmt.Fprintf(conn, "INFO %s\r\n", data); err != nil {
+		return
+	}
+
+	if s.tlsConfig != nil {
+		tlsConn := tls.Server(conn, s.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+
+		conn = tlsConn
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "CONNECT":
+			var opts struct {
+				Token string `json:"auth_token"`
+			}
+			_ = json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &opts)
+			if opts.Token != s.token {
+				_, _ = io.WriteString(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			_, _ = io.WriteString(conn, "PONG\r\n")
+		case "HPUB":
+			//HPUB <subject> <reply> <длина заголовков> <общая длина>
+			if len(fields) != 5 {
+				return
+			}
+
+			headerLen, _ := strconv.Atoi(fields[3])
+			totalLen, _ := strconv.Atoi(fields[4])
+			body := make([]byte, totalLen+2)
+			if _, err = io.ReadFull(r, body); err != nil {
+				return
+			}
+
+			header, _ := textproto.NewReader(bufio.NewReader(strings.NewReader(
+				strings.TrimPrefix(string(body[:headerLen]), "NATS/1.0\r\n")))).ReadMIMEHeader()
+			msg := testNATSMsg{Subject: fields[1], Header: header, Data: body[headerLen:totalLen]}
+
+			s.mu.Lock()
+			s.msgs = append(s.msgs, msg)
+			s.mu.Unlock()
+
+			if ack := s.ack(msg); ack != "" {
+				_, _ = fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", fields[2], len(ack), ack)
+			}
+		}
+	}
+}
+
+// newTestTLSConfig возвращает конфигурацию TLS сервера с самоподписанным сертификатом для 127.0.0.1
+// и путь к файлу этого сертификата, которым клиент проверяет сервер.
+func newTestTLSConfig(t *testing.T) (*tls.Config, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nats test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}, path
+}
+
+// newTestNATSPublisher возвращает публикатор, подключенный к тестовому серверу.
+func newTestNATSPublisher(t *testing.T, params NATSParams) *NATSPublisher {
+	t.Helper()
+
+	p, err := NewNATSPublisher(params)
+	if err != nil {
+		t.Fatalf("NewNATSPublisher() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+
+	return p
+}
+
+func TestNATSPublisher_Publish(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		ack     string
+		wantErr bool
+	}{
+		{
+			name: "OK",
+			ack:  TestPubAck,
+		},
+		{
+			name:   "ExpectedStream",
+			stream: "users",
+			ack:    TestPubAck,
+		},
+		{
+			name: "Duplicate",
+			ack:  `{"stream":"users","seq":1,"duplicate":true}`,
+		},
+		{
+			name:    "StreamError",
+			ack:     `{"error":{"code":503,"err_code":10077,"description":"maximum messages exceeded"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidAck",
+			ack:     `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "NoAck",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestNATSServer(t, nil, "", func(testNATSMsg) string { return tt.ack })
+			p := newTestNATSPublisher(t, NATSParams{
+				URL:           s.url(),
+				SubjectPrefix: "users",
+				Stream:        tt.stream,
+				Timeout:       200 * time.Millisecond,
+			})
+
+			err := p.Publish(context.Background(), TestEvent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NATSPublisher.Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			msgs := s.published()
+			if len(msgs) != 1 {
+				t.Fatalf("published %d messages, want 1", len(msgs))
+			}
+
+			msg := msgs[0]
+			if want := "users." + TestEvent.Type; msg.Subject != want {
+				t.Errorf("subject = %q, want %q", msg.Subject, want)
+			}
+
+			if got := msg.Header.Get("Nats-Msg-Id"); got != TestEvent.IdempotencyKey {
+				t.Errorf("Nats-Msg-Id = %q, want %q", got, TestEvent.IdempotencyKey)
+			}
+
+			if got := msg.Header.Get("Nats-Expected-Stream"); got != tt.stream {
+				t.Errorf("Nats-Expected-Stream = %q, want %q", got, tt.stream)
+			}
+
+			want, err := encode(TestEvent)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(msg.Data) != string(want) {
+				t.Errorf("data = %s, want %s", msg.Data, want)
+			}
+		})
+	}
+}
+
+func TestNATSPublisher_Publish_Token(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "OK",
+			token: TestNATSToken,
+		},
+		{
+			name:    "WrongToken",
+			token:   "wrong",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestNATSServer(t, nil, TestNATSToken, func(testNATSMsg) string { return TestPubAck })
+			p := newTestNATSPublisher(t, NATSParams{
+				URL:           s.url(),
+				SubjectPrefix: "users",
+				Timeout:       200 * time.Millisecond,
+				Token:         tt.token,
+			})
+
+			if err := p.Publish(context.Background(), TestEvent); (err != nil) != tt.wantErr {
+				t.Errorf("NATSPublisher.Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNATSPublisher_Publish_TLS(t *testing.T) {
+	tlsConfig, caFile := newTestTLSConfig(t)
+	s := newTestNATSServer(t, tlsConfig, "", func(testNATSMsg) string { return TestPubAck })
+	p := newTestNATSPublisher(t, NATSParams{
+		URL:           s.url(),
+		SubjectPrefix: "users",
+		Timeout:       time.Second,
+		TLSCAFile:     caFile,
+	})
+
+	if err := p.Publish(context.Background(), TestEvent); err != nil {
+		t.Fatalf("NATSPublisher.Publish() error = %v", err)
+	}
+
+	if got := len(s.published()); got != 1 {
+		t.Errorf("published %d messages, want 1", got)
+	}
+}
+
+func TestNewNATSPublisher_TLSCAFileNotFound(t *testing.T) {
+	_, err := NewNATSPublisher(NATSParams{
+		URL:       "nats://127.0.0.1:4222",
+		Timeout:   time.Second,
+		TLSCAFile: filepath.Join(t.TempDir(), "missing.pem"),
+	})
+	if err == nil {
+		t.Errorf("NewNATSPublisher() error = nil, want error for missing CA file")
+	}
+}
@@ -0,0 +1,87 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Размер refresh токена в байтах.
+const refreshTokenSize = 32
+
+// Claims - данные, которые содержит access токен.
+type Claims struct {
+	UserId    int64 `json:"uid"`
+	SessionId int64 `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// Manager - структура реализует методы выпуска access и refresh токенов.
+type Manager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// New - конструктор для типа *Manager.
+func New(secret string, accessTTL, refreshTTL time.Duration) *Manager {
+	return &Manager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// NewAccessToken возвращает подписанный access токен сессии пользователя и время его истечения.
+// Использует алгоритм подписи HS256.
+func (m *Manager) NewAccessToken(userId, sessionId int64) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.accessTTL)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserId:    userId,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+
+	signed, err := token.SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, expiresAt, nil
+}
+
+// NewRefreshToken возвращает случайный refresh токен и время его истечения.
+func (m *Manager) NewRefreshToken() (string, time.Time, error) {
+	b := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), time.Now().Add(m.refreshTTL), nil
+}
+
+// ParseAccessToken проверяет подпись и срок действия access токена, возвращает его данные.
+func (m *Manager) ParseAccessToken(token string) (models.TokenInfo, error) {
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return models.TokenInfo{}, err
+	}
+
+	return models.TokenInfo{
+		UserId:    claims.UserId,
+		SessionId: claims.SessionId,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
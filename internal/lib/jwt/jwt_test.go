@@ -0,0 +1,141 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TestSecret    = "secret"
+	TestUserId    = int64(1)
+	TestSessionId = int64(7)
+)
+
+// signed возвращает токен с данными тестовой сессии, подписанный методом method и ключом key.
+func signed(t *testing.T, method jwt.SigningMethod, key any, expiresAt time.Time) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, Claims{
+		UserId:    TestUserId,
+		SessionId: TestSessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}).SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	return token
+}
+
+func TestManager_NewAccessToken(t *testing.T) {
+	m := New(TestSecret, time.Minute, time.Hour)
+
+	token, expiresAt, err := m.NewAccessToken(TestUserId, TestSessionId)
+	if err != nil {
+		t.Fatalf("Manager.NewAccessToken() error = %v", err)
+	}
+	if d := time.Until(expiresAt); d <= 0 || d > time.Minute {
+		t.Errorf("Manager.NewAccessToken() expiresAt in %v, want within %v", d, time.Minute)
+	}
+
+	info, err := m.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("Manager.ParseAccessToken() error = %v", err)
+	}
+	if info.UserId != TestUserId || info.SessionId != TestSessionId {
+		t.Errorf("Manager.ParseAccessToken() = %+v, want user %d session %d", info, TestUserId, TestSessionId)
+	}
+	if !info.ExpiresAt.Equal(expiresAt.Truncate(time.Second)) {
+		t.Errorf("Manager.ParseAccessToken() expiresAt = %v, want %v", info.ExpiresAt, expiresAt.Truncate(time.Second))
+	}
+}
+
+func TestManager_ParseAccessToken(t *testing.T) {
+	m := New(TestSecret, time.Minute, time.Hour)
+	valid := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "OK",
+			token: signed(t, jwt.SigningMethodHS256, []byte(TestSecret), valid),
+		},
+		{
+			name:    "Expired",
+			token:   signed(t, jwt.SigningMethodHS256, []byte(TestSecret), time.Now().Add(-time.Minute)),
+			wantErr: true,
+		},
+		{
+			name:    "WrongKey",
+			token:   signed(t, jwt.SigningMethodHS256, []byte("other"), valid),
+			wantErr: true,
+		},
+		{
+			name:    "WrongAlg",
+			token:   signed(t, jwt.SigningMethodHS512, []byte(TestSecret), valid),
+			wantErr: true,
+		},
+		{
+			name:    "NoneAlg",
+			token:   signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+			wantErr: true,
+		},
+		{
+			name:    "Malformed",
+			token:   "not.a.token",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := m.ParseAccessToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Manager.ParseAccessToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (info.UserId != TestUserId || info.SessionId != TestSessionId) {
+				t.Errorf("Manager.ParseAccessToken() = %+v, want user %d session %d", info, TestUserId, TestSessionId)
+			}
+		})
+	}
+}
+
+func TestManager_ParseAccessToken_NoExpiry(t *testing.T) {
+	m := New(TestSecret, time.Minute, time.Hour)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserId: TestUserId}).SignedString([]byte(TestSecret))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	if _, err = m.ParseAccessToken(token); err == nil {
+		t.Errorf("Manager.ParseAccessToken() error = nil for token without exp")
+	}
+}
+
+func TestManager_NewRefreshToken(t *testing.T) {
+	m := New(TestSecret, time.Minute, time.Hour)
+
+	first, expiresAt, err := m.NewRefreshToken()
+	if err != nil {
+		t.Fatalf("Manager.NewRefreshToken() error = %v", err)
+	}
+	second, _, err := m.NewRefreshToken()
+	if err != nil {
+		t.Fatalf("Manager.NewRefreshToken() error = %v", err)
+	}
+
+	if first == second {
+		t.Errorf("Manager.NewRefreshToken() returned the same token twice")
+	}
+	if d := time.Until(expiresAt); d <= 0 || d > time.Hour {
+		t.Errorf("Manager.NewRefreshToken() expiresAt in %v, want within %v", d, time.Hour)
+	}
+}
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/al3ksus/messengerusers/internal/logger"
+)
+
+// LogNotifier - структура реализует доставку уведомлений в лог.
+// Предназначена для локального запуска, когда настоящий канал доставки недоступен.
+type LogNotifier struct {
+	log logger.Logger
+}
+
+// NewLogNotifier - конструктор для типа *LogNotifier.
+func NewLogNotifier(log logger.Logger) *LogNotifier {
+	return &LogNotifier{
+		log: log,
+	}
+}
+
+// SendPasswordResetCode записывает код сброса пароля пользователя в лог.
+func (n *LogNotifier) SendPasswordResetCode(ctx context.Context, user models.User, code string) error {
+	n.log.Infof("password reset code for user %s (id=%d): %s", user.Username, user.Id, code)
+
+	return nil
+}
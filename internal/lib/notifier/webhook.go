@@ -0,0 +1,87 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// EventPasswordResetCode - тип уведомления с кодом сброса пароля.
+const EventPasswordResetCode = "password_reset_code"
+
+var ErrInvalidWebhookURL = errors.New("invalid webhook url")
+
+// WebhookNotifier - структура реализует доставку уведомлений через HTTP вебхук сервиса рассылок.
+// Сервис рассылок сам выбирает канал доставки пользователю.
+type WebhookNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// webhookMessage - тело запроса к вебхуку.
+type webhookMessage struct {
+	Type     string `json:"type"`
+	UserId   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Code     string `json:"code"`
+}
+
+// NewWebhookNotifier - конструктор для типа *WebhookNotifier.
+// Если token не пустой, он передается в заголовке Authorization.
+// Если адрес вебхука не является абсолютным http(s) адресом, возвращает ошибку ErrInvalidWebhookURL.
+func NewWebhookNotifier(rawURL, token string, timeout time.Duration) (*WebhookNotifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidWebhookURL, rawURL)
+	}
+
+	return &WebhookNotifier{
+		url:    rawURL,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// SendPasswordResetCode отправляет код сброса пароля пользователя на вебхук.
+// Ответ со статусом вне диапазона 2xx считается ошибкой доставки.
+func (n *WebhookNotifier) SendPasswordResetCode(ctx context.Context, user models.User, code string) error {
+	body, err := json.Marshal(webhookMessage{
+		Type:     EventPasswordResetCode,
+		UserId:   user.Id,
+		Username: user.Username,
+		Code:     code,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+var (
+	TestUser = models.User{
+		Id:       1,
+		Username: "username",
+	}
+	TestCode  = "ABCDE23456"
+	TestToken = "token"
+)
+
+func TestNewWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawURL  string
+		wantErr error
+	}{
+		{
+			name:   "OK",
+			rawURL: "https://notify.local/reset",
+		},
+		{
+			name:    "NoScheme",
+			rawURL:  "notify.local/reset",
+			wantErr: ErrInvalidWebhookURL,
+		},
+		{
+			name:    "UnsupportedScheme",
+			rawURL:  "ftp://notify.local/reset",
+			wantErr: ErrInvalidWebhookURL,
+		},
+		{
+			name:    "Empty",
+			rawURL:  "",
+			wantErr: ErrInvalidWebhookURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWebhookNotifier(tt.rawURL, "", time.Second)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewWebhookNotifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_SendPasswordResetCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		status  int
+		wantErr bool
+	}{
+		{
+			name:   "OK",
+			token:  TestToken,
+			status: http.StatusAccepted,
+		},
+		{
+			name:   "NoToken",
+			status: http.StatusOK,
+		},
+		{
+			name:    "ErrorStatus",
+			token:   TestToken,
+			status:  http.StatusBadGateway,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got webhookMessage
+			var gotAuth string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth = r.Header.Get("Authorization")
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decode webhook body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			n, err := NewWebhookNotifier(srv.URL, tt.token, time.Second)
+			if err != nil {
+				t.Fatalf("NewWebhookNotifier() error = %v", err)
+			}
+
+			err = n.SendPasswordResetCode(context.Background(), TestUser, TestCode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WebhookNotifier.SendPasswordResetCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			want := webhookMessage{
+				Type:     EventPasswordResetCode,
+				UserId:   TestUser.Id,
+				Username: TestUser.Username,
+				Code:     TestCode,
+			}
+			if got != want {
+				t.Errorf("webhook body = %+v, want %+v", got, want)
+			}
+
+			wantAuth := ""
+			if tt.token != "" {
+				wantAuth = "Bearer " + tt.token
+			}
+			if gotAuth != wantAuth {
+				t.Errorf("Authorization = %q, want %q", gotAuth, wantAuth)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy - структура реализует проверку пароля на соответствие политике паролей.
+type Policy struct {
+	minLength  int
+	maxLength  int
+	minClasses int
+	maxBytes   int
+	denyList   map[string]struct{}
+}
+
+// NewPolicy возвращает новый объект Policy.
+// minClasses - минимальное количество классов символов (строчные и заглавные буквы, цифры, прочие символы) в пароле.
+// maxBytes - максимальная длина пароля в байтах, которую допускает алгоритм хэширования, 0 - без ограничения.
+// Пароли из denyList сравниваются без учета регистра.
+func NewPolicy(minLength, maxLength, minClasses, maxBytes int, denyList []string) *Policy {
+	deny := make(map[string]struct{}, len(denyList))
+	for _, pass := range denyList {
+		deny[strings.ToLower(pass)] = struct{}{}
+	}
+
+	return &Policy{
+		minLength:  minLength,
+		maxLength:  maxLength,
+		minClasses: minClasses,
+		maxBytes:   maxBytes,
+		denyList:   deny,
+	}
+}
+
+// Validate проверяет пароль на соответствие политике и возвращает список нарушений.
+// Пустой список означает, что пароль допустим. Если username пустой, проверка на совпадение с ним пропускается.
+func (p *Policy) Validate(username, password string) []string {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.minLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.minLength))
+	}
+
+	if p.maxLength > 0 && length > p.maxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.maxLength))
+	}
+
+	if p.maxBytes > 0 && len(password) > p.maxBytes {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", p.maxBytes))
+	}
+
+	if classes := countClasses(password); classes < p.minClasses {
+		violations = append(violations, fmt.Sprintf(
+			"must contain at least %d of: lowercase letters, uppercase letters, digits, other characters", p.minClasses))
+	}
+
+	if _, ok := p.denyList[strings.ToLower(password)]; ok {
+		violations = append(violations, "is too common")
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		violations = append(violations, "must not be equal to username")
+	}
+
+	return violations
+}
+
+// MustLoadDenyList читает список запрещенных паролей из файла, по одному паролю в строке.
+// Пустые строки и строки, начинающиеся с '#', пропускаются. Для пустого пути возвращает пустой список.
+// Вызывает панику в случае ошибки.
+func MustLoadDenyList(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		panic("error opening password deny list: " + err.Error())
+	}
+	defer file.Close()
+
+	var denyList []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		denyList = append(denyList, line)
+	}
+
+	if err = scanner.Err(); err != nil {
+		panic("error reading password deny list: " + err.Error())
+	}
+
+	return denyList
+}
+
+// countClasses возвращает количество классов символов, встречающихся в пароле.
+func countClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,200 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	TestUsername = "username"
+	TestDenyList = []string{"Password1", "qwerty123"}
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	p := NewPolicy(8, 20, 2, 0, TestDenyList)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     []string
+	}{
+		{
+			name:     "OK",
+			username: TestUsername,
+			password: "correct horse",
+		},
+		{
+			name:     "OKUnicode",
+			username: TestUsername,
+			password: "пароль123",
+		},
+		{
+			name:     "TooShort",
+			username: TestUsername,
+			password: "abc123",
+			want:     []string{"must be at least 8 characters long"},
+		},
+		{
+			name:     "TooLong",
+			username: TestUsername,
+			password: strings.Repeat("a1", 11),
+			want:     []string{"must be at most 20 characters long"},
+		},
+		{
+			name:     "LengthInRunes",
+			username: TestUsername,
+			password: "пароль12",
+		},
+		{
+			name:     "NotEnoughClasses",
+			username: TestUsername,
+			password: "abcdefghij",
+			want:     []string{"must contain at least 2 of: lowercase letters, uppercase letters, digits, other characters"},
+		},
+		{
+			name:     "DenyListIgnoreCase",
+			username: TestUsername,
+			password: "PASSWORD1",
+			want:     []string{"is too common"},
+		},
+		{
+			name:     "EqualsUsername",
+			username: "Username1",
+			password: "username1",
+			want:     []string{"must not be equal to username"},
+		},
+		{
+			name:     "EmptyUsernameSkipped",
+			username: "",
+			password: "username1",
+		},
+		{
+			name:     "Multiple",
+			username: TestUsername,
+			password: "aaa",
+			want: []string{
+				"must be at least 8 characters long",
+				"must contain at least 2 of: lowercase letters, uppercase letters, digits, other characters",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Validate(tt.username, tt.password); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Policy.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate_NoMaxLength(t *testing.T) {
+	p := NewPolicy(8, 0, 1, 0, nil)
+
+	if got := p.Validate(TestUsername, strings.Repeat("a", 64)); got != nil {
+		t.Errorf("Policy.Validate() = %v, want nil", got)
+	}
+}
+
+func TestPolicy_Validate_MaxBytes(t *testing.T) {
+	long := strings.Repeat("я", 40) + "1"
+
+	tests := []struct {
+		name     string
+		maxBytes int
+		want     []string
+	}{
+		{
+			name:     "Limited",
+			maxBytes: 72,
+			want:     []string{"must be at most 72 bytes long"},
+		},
+		{
+			name:     "Unlimited",
+			maxBytes: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy(8, 64, 2, tt.maxBytes, nil)
+			if got := p.Validate(TestUsername, long); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Policy.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_countClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     int
+	}{
+		{
+			name:     "Empty",
+			password: "",
+			want:     0,
+		},
+		{
+			name:     "Lower",
+			password: "abc",
+			want:     1,
+		},
+		{
+			name:     "LowerUpper",
+			password: "abcABC",
+			want:     2,
+		},
+		{
+			name:     "LowerUpperDigit",
+			password: "abcABC123",
+			want:     3,
+		},
+		{
+			name:     "All",
+			password: "aB1 ",
+			want:     4,
+		},
+		{
+			name:     "Unicode",
+			password: "пП٣",
+			want:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countClasses(tt.password); got != tt.want {
+				t.Errorf("countClasses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustLoadDenyList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deny.txt")
+	if err := os.WriteFile(path, []byte("# common passwords\n\npassword1\n  qwerty123  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"password1", "qwerty123"}
+	if got := MustLoadDenyList(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("MustLoadDenyList() = %v, want %v", got, want)
+	}
+
+	if got := MustLoadDenyList(""); got != nil {
+		t.Errorf("MustLoadDenyList(\"\") = %v, want nil", got)
+	}
+}
+
+func TestMustLoadDenyList_NotFound(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustLoadDenyList() must panic for missing file")
+		}
+	}()
+
+	MustLoadDenyList(filepath.Join(t.TempDir(), "missing.txt"))
+}
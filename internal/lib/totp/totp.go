@@ -0,0 +1,106 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Размер секрета в байтах, рекомендованный RFC 4226 для HMAC-SHA1.
+	secretSize = 20
+	digits     = 6
+	period     = 30 * time.Second
+)
+
+// encoding - base32 без выравнивания, в котором секрет принимают приложения-аутентификаторы.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Manager - структура реализует генерацию и проверку одноразовых кодов TOTP (RFC 6238).
+// Коды шестизначные, шаг 30 секунд, алгоритм HMAC-SHA1 - параметры, которые поддерживают все распространенные аутентификаторы.
+type Manager struct {
+	issuer string
+	skew   int
+	now    func() time.Time
+}
+
+// New - конструктор для типа *Manager.
+// issuer - название сервиса в приложении-аутентификаторе,
+// skew - количество соседних шагов, коды которых принимаются для компенсации рассинхронизации часов.
+func New(issuer string, skew int) *Manager {
+	return &Manager{
+		issuer: issuer,
+		skew:   skew,
+		now:    time.Now,
+	}
+}
+
+// GenerateSecret возвращает случайный секрет в кодировке base32.
+func (m *Manager) GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// URI возвращает otpauth:// URI для добавления секрета в приложение-аутентификатор (например, через QR код).
+func (m *Manager) URI(secret, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", m.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(int(period.Seconds())))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + m.issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+// Validate проверяет код по секрету для текущего времени с учетом допустимой рассинхронизации.
+// Возвращает номер шага, для которого код подошел, чтобы вызывающая сторона могла запретить его повторное использование.
+func (m *Manager) Validate(secret, code string) (int64, bool) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != digits {
+		return 0, false
+	}
+
+	current := m.now().Unix() / int64(period.Seconds())
+	for i := -m.skew; i <= m.skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(generateCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// generateCode вычисляет код для указанного шага по алгоритму HOTP (RFC 4226).
+func generateCode(key []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%1000000)
+}
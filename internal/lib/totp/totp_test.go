@@ -0,0 +1,169 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+var (
+	// TestSecret - секрет тестовых векторов RFC 6238 для HMAC-SHA1 ("12345678901234567890") в кодировке base32.
+	TestSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+	TestIssuer = "Messenger"
+)
+
+// newTestManager возвращает Manager, для которого текущее время равно unix.
+func newTestManager(skew int, unix int64) *Manager {
+	m := New(TestIssuer, skew)
+	m.now = func() time.Time {
+		return time.Unix(unix, 0)
+	}
+
+	return m
+}
+
+func TestManager_Validate_RFC6238(t *testing.T) {
+	// Векторы из приложения B RFC 6238, коды сокращены до шести младших цифр.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			m := newTestManager(0, tt.unix)
+
+			step, ok := m.Validate(TestSecret, tt.code)
+			if !ok {
+				t.Fatalf("Manager.Validate() = false, want true")
+			}
+
+			if want := tt.unix / 30; step != want {
+				t.Errorf("Manager.Validate() step = %v, want %v", step, want)
+			}
+		})
+	}
+}
+
+func TestManager_Validate_Skew(t *testing.T) {
+	const now = 1111111111
+	current := int64(now / 30)
+	key, err := encoding.DecodeString(TestSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		skew   int
+		offset int64
+		want   bool
+	}{
+		{name: "CurrentNoSkew", skew: 0, offset: 0, want: true},
+		{name: "PreviousNoSkew", skew: 0, offset: -1, want: false},
+		{name: "NextNoSkew", skew: 0, offset: 1, want: false},
+		{name: "Previous", skew: 1, offset: -1, want: true},
+		{name: "Next", skew: 1, offset: 1, want: true},
+		{name: "TooOld", skew: 1, offset: -2, want: false},
+		{name: "TooNew", skew: 1, offset: 2, want: false},
+		{name: "WideWindow", skew: 2, offset: -2, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(tt.skew, now)
+			step := current + tt.offset
+
+			got, ok := m.Validate(TestSecret, generateCode(key, step))
+			if ok != tt.want {
+				t.Fatalf("Manager.Validate() = %v, want %v", ok, tt.want)
+			}
+
+			if ok && got != step {
+				t.Errorf("Manager.Validate() step = %v, want %v", got, step)
+			}
+		})
+	}
+}
+
+func TestManager_Validate_Invalid(t *testing.T) {
+	m := newTestManager(1, 59)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		want   bool
+	}{
+		{name: "LowercaseSecret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "287082", want: true},
+		{name: "WrongCode", secret: TestSecret, code: "000000", want: false},
+		{name: "ShortCode", secret: TestSecret, code: "28708", want: false},
+		{name: "LongCode", secret: TestSecret, code: "94287082", want: false},
+		{name: "InvalidSecret", secret: "not base32!", code: "287082", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := m.Validate(tt.secret, tt.code); ok != tt.want {
+				t.Errorf("Manager.Validate() = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_GenerateSecret(t *testing.T) {
+	m := New(TestIssuer, 1)
+
+	secret, err := m.GenerateSecret()
+	if err != nil {
+		t.Fatalf("Manager.GenerateSecret() error = %v", err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("Manager.GenerateSecret() returned invalid base32: %v", err)
+	}
+
+	if len(key) != secretSize {
+		t.Errorf("Manager.GenerateSecret() key size = %v, want %v", len(key), secretSize)
+	}
+
+	other, err := m.GenerateSecret()
+	if err != nil {
+		t.Fatalf("Manager.GenerateSecret() error = %v", err)
+	}
+
+	if other == secret {
+		t.Errorf("Manager.GenerateSecret() returned the same secret twice")
+	}
+}
+
+func TestManager_URI(t *testing.T) {
+	m := New(TestIssuer, 1)
+
+	u, err := url.Parse(m.URI(TestSecret, "user1"))
+	if err != nil {
+		t.Fatalf("Manager.URI() returned invalid url: %v", err)
+	}
+
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/"+TestIssuer+":user1" {
+		t.Errorf("Manager.URI() = %v", u)
+	}
+
+	want := url.Values{
+		"secret":    {TestSecret},
+		"issuer":    {TestIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {"30"},
+	}
+	for key, value := range want {
+		if got := u.Query().Get(key); got != value[0] {
+			t.Errorf("Manager.URI() %s = %q, want %q", key, got, value[0])
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package username
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables - таблица замены визуально похожих символов других алфавитов на латинские.
+var confusables = map[rune]rune{
+	// Кириллица
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ї': 'i', 'ј': 'j',
+	'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ɡ': 'g',
+	// Греческий алфавит
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// Прочие символы, похожие на латинские буквы. Цифры не заменяются: username может содержать и цифры, и буквы
+	'ı': 'i', 'ℓ': 'l',
+}
+
+// Policy - структура реализует нормализацию username и проверку на соответствие правилам.
+type Policy struct {
+	minLength int
+	maxLength int
+	reserved  map[string]struct{}
+}
+
+// NewPolicy возвращает новый объект Policy.
+// Зарезервированные имена сравниваются в канонической форме.
+func NewPolicy(minLength, maxLength int, reserved []string) *Policy {
+	p := &Policy{
+		minLength: minLength,
+		maxLength: maxLength,
+		reserved:  make(map[string]struct{}, len(reserved)),
+	}
+
+	for _, name := range reserved {
+		p.reserved[p.Canonical(name)] = struct{}{}
+	}
+
+	return p
+}
+
+// Canonical возвращает каноническую форму username, по которой проверяется уникальность и выполняется поиск.
+// Удаляет пробелы по краям, приводит строку к форме NFKC и нижнему регистру, заменяет похожие символы латинскими.
+func (p *Policy) Canonical(username string) string {
+	username = norm.NFKC.String(strings.TrimSpace(username))
+
+	var b strings.Builder
+	b.Grow(len(username))
+	for _, r := range strings.ToLower(username) {
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Validate проверяет username на соответствие правилам и возвращает список нарушений.
+// Пустой список означает, что username допустим. Пробелы по краям не учитываются.
+func (p *Policy) Validate(username string) []string {
+	var violations []string
+
+	username = strings.TrimSpace(username)
+
+	length := utf8.RuneCountInString(username)
+	if length < p.minLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.minLength))
+	}
+
+	if length > p.maxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.maxLength))
+	}
+
+	if first, _ := utf8.DecodeRuneInString(username); length > 0 && !unicode.IsLetter(first) {
+		violations = append(violations, "must start with a letter")
+	}
+
+	for _, r := range username {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			violations = append(violations, "may contain only letters, digits, '_' and '.'")
+			break
+		}
+	}
+
+	if _, ok := p.reserved[p.Canonical(username)]; ok {
+		violations = append(violations, "is reserved")
+	}
+
+	return violations
+}
@@ -0,0 +1,151 @@
+package username
+
+import (
+	"reflect"
+	"testing"
+)
+
+var TestReserved = []string{"admin", "Support"}
+
+func TestPolicy_Canonical(t *testing.T) {
+	p := NewPolicy(3, 32, nil)
+
+	tests := []struct {
+		name     string
+		username string
+		want     string
+	}{
+		{
+			name:     "Lower",
+			username: "User_Name",
+			want:     "user_name",
+		},
+		{
+			name:     "TrimSpace",
+			username: "  user  ",
+			want:     "user",
+		},
+		{
+			name:     "NFKCFullWidth",
+			username: "ｕｓｅｒ１",
+			want:     "user1",
+		},
+		{
+			name:     "NFKCLigature",
+			username: "ﬁle",
+			want:     "file",
+		},
+		{
+			name:     "CyrillicConfusables",
+			username: "Аdmin",
+			want:     "admin",
+		},
+		{
+			name:     "GreekConfusables",
+			username: "ΑΒΕ",
+			want:     "abe",
+		},
+		{
+			name:     "DigitsKept",
+			username: "user0l",
+			want:     "user0l",
+		},
+		{
+			name:     "NonConfusableKept",
+			username: "Жук",
+			want:     "жyk",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Canonical(tt.username); got != tt.want {
+				t.Errorf("Policy.Canonical() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Canonical_Idempotent(t *testing.T) {
+	p := NewPolicy(3, 32, nil)
+
+	for _, username := range []string{"User", "ｕｓｅｒ", "Аdmin", "ℓogin", " ıd "} {
+		once := p.Canonical(username)
+		if twice := p.Canonical(once); twice != once {
+			t.Errorf("Policy.Canonical(%q) = %q, Canonical of it = %q", username, once, twice)
+		}
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	p := NewPolicy(3, 10, TestReserved)
+
+	tests := []struct {
+		name     string
+		username string
+		want     []string
+	}{
+		{
+			name:     "OK",
+			username: "user_1.x",
+		},
+		{
+			name:     "OKUnicodeLetters",
+			username: "пользов",
+		},
+		{
+			name:     "SpacesIgnored",
+			username: "  user  ",
+		},
+		{
+			name:     "TooShort",
+			username: "ab",
+			want:     []string{"must be at least 3 characters long"},
+		},
+		{
+			name:     "TooLong",
+			username: "abcdefghijk",
+			want:     []string{"must be at most 10 characters long"},
+		},
+		{
+			name:     "StartsWithDigit",
+			username: "1user",
+			want:     []string{"must start with a letter"},
+		},
+		{
+			name:     "ForbiddenCharacters",
+			username: "user-name",
+			want:     []string{"may contain only letters, digits, '_' and '.'"},
+		},
+		{
+			name:     "Reserved",
+			username: "ADMIN",
+			want:     []string{"is reserved"},
+		},
+		{
+			name:     "ReservedConfusable",
+			username: "suppоrt",
+			want:     []string{"is reserved"},
+		},
+		{
+			name:     "Empty",
+			username: "",
+			want:     []string{"must be at least 3 characters long"},
+		},
+		{
+			name:     "Multiple",
+			username: "_-",
+			want: []string{
+				"must be at least 3 characters long",
+				"must start with a letter",
+				"may contain only letters, digits, '_' and '.'",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Validate(tt.username); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Policy.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// attempt - состояние неудачных попыток входа по одному ключу.
+type attempt struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+}
+
+// AttemptTracker - хранилище неудачных попыток входа в памяти процесса.
+// Используется в тестах и при запуске без базы данных, состояние не переживает перезапуск.
+type AttemptTracker struct {
+	mu       sync.Mutex
+	attempts map[string]*attempt
+	now      func() time.Time
+}
+
+// NewAttemptTracker возвращает новый объект *AttemptTracker.
+func NewAttemptTracker() *AttemptTracker {
+	return &AttemptTracker{
+		attempts: make(map[string]*attempt),
+		now:      time.Now,
+	}
+}
+
+// GetLockout возвращает время окончания блокировки входа по ключу.
+// Если ключ не заблокирован, возвращает нулевое время.
+func (t *AttemptTracker) GetLockout(_ context.Context, key string) (time.Time, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if a, ok := t.attempts[key]; ok {
+		return a.lockedUntil, nil
+	}
+
+	return time.Time{}, nil
+}
+
+// RegisterFailedAttempt регистрирует неудачную попытку входа по ключу и возвращает количество неудачных попыток подряд.
+// Если предыдущая неудачная попытка была раньше windowStart, счетчик начинается заново.
+func (t *AttemptTracker) RegisterFailedAttempt(_ context.Context, key string, windowStart time.Time) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a, ok := t.attempts[key]
+	if !ok {
+		a = &attempt{}
+		t.attempts[key] = a
+	}
+
+	if a.lastFailureAt.Before(windowStart) {
+		a.failures = 0
+	}
+
+	a.failures++
+	a.lastFailureAt = t.now()
+
+	return a.failures, nil
+}
+
+// SetLockout блокирует вход по ключу до указанного времени.
+func (t *AttemptTracker) SetLockout(_ context.Context, key string, until time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if a, ok := t.attempts[key]; ok {
+		a.lockedUntil = until
+	}
+
+	return nil
+}
+
+// ResetFailedAttempts сбрасывает счетчик неудачных попыток и блокировку по ключу.
+func (t *AttemptTracker) ResetFailedAttempts(_ context.Context, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.attempts, key)
+
+	return nil
+}
+
+// DeleteExpiredAttempts удаляет не более limit ключей, последняя неудачная попытка по которым была раньше before
+// и блокировка по которым истекла. Возвращает число удаленных ключей.
+func (t *AttemptTracker) DeleteExpiredAttempts(_ context.Context, before time.Time, limit int) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+
+	var deleted int
+	for key, a := range t.attempts {
+		if deleted == limit {
+			break
+		}
+
+		if a.lastFailureAt.Before(before) && a.lockedUntil.Before(now) {
+			delete(t.attempts, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
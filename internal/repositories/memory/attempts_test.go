@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var (
+	TestAttemptKey = "user:user1"
+	TestNow        = time.Unix(1700000000, 0)
+)
+
+func TestAttemptTracker_RegisterFailedAttempt(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastFailure time.Time
+		failures    int
+		windowStart time.Time
+		want        int
+	}{
+		{
+			name:        "First",
+			windowStart: TestNow.Add(-time.Minute),
+			want:        1,
+		},
+		{
+			name:        "InWindow",
+			lastFailure: TestNow.Add(-time.Second),
+			failures:    2,
+			windowStart: TestNow.Add(-time.Minute),
+			want:        3,
+		},
+		{
+			name:        "WindowExpired",
+			lastFailure: TestNow.Add(-time.Hour),
+			failures:    2,
+			windowStart: TestNow.Add(-time.Minute),
+			want:        1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewAttemptTracker()
+			tracker.now = func() time.Time { return TestNow }
+			if tt.failures > 0 {
+				tracker.attempts[TestAttemptKey] = &attempt{failures: tt.failures, lastFailureAt: tt.lastFailure}
+			}
+
+			got, err := tracker.RegisterFailedAttempt(context.Background(), TestAttemptKey, tt.windowStart)
+			if err != nil {
+				t.Errorf("AttemptTracker.RegisterFailedAttempt() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("AttemptTracker.RegisterFailedAttempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttemptTracker_Lockout(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewAttemptTracker()
+	until := TestNow.Add(time.Minute)
+
+	if _, err := tracker.RegisterFailedAttempt(ctx, TestAttemptKey, TestNow); err != nil {
+		t.Fatalf("AttemptTracker.RegisterFailedAttempt() error = %v", err)
+	}
+
+	if err := tracker.SetLockout(ctx, TestAttemptKey, until); err != nil {
+		t.Fatalf("AttemptTracker.SetLockout() error = %v", err)
+	}
+
+	got, _ := tracker.GetLockout(ctx, TestAttemptKey)
+	if !got.Equal(until) {
+		t.Errorf("AttemptTracker.GetLockout() = %v, want %v", got, until)
+	}
+
+	if err := tracker.ResetFailedAttempts(ctx, TestAttemptKey); err != nil {
+		t.Fatalf("AttemptTracker.ResetFailedAttempts() error = %v", err)
+	}
+
+	got, _ = tracker.GetLockout(ctx, TestAttemptKey)
+	if !got.IsZero() {
+		t.Errorf("AttemptTracker.GetLockout() after reset = %v, want zero time", got)
+	}
+}
+
+func TestAttemptTracker_DeleteExpiredAttempts(t *testing.T) {
+	tracker := NewAttemptTracker()
+	tracker.now = func() time.Time { return TestNow }
+	tracker.attempts["expired"] = &attempt{failures: 1, lastFailureAt: TestNow.Add(-time.Hour)}
+	tracker.attempts["in_window"] = &attempt{failures: 1, lastFailureAt: TestNow.Add(-time.Second)}
+	tracker.attempts["locked"] = &attempt{failures: 9, lastFailureAt: TestNow.Add(-time.Hour), lockedUntil: TestNow.Add(time.Minute)}
+
+	got, err := tracker.DeleteExpiredAttempts(context.Background(), TestNow.Add(-time.Minute), 10)
+	if err != nil {
+		t.Fatalf("AttemptTracker.DeleteExpiredAttempts() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("AttemptTracker.DeleteExpiredAttempts() = %v, want 1", got)
+	}
+
+	if _, ok := tracker.attempts["expired"]; ok {
+		t.Errorf("AttemptTracker.DeleteExpiredAttempts() kept expired key")
+	}
+	if len(tracker.attempts) != 2 {
+		t.Errorf("AttemptTracker.DeleteExpiredAttempts() left %d keys, want 2", len(tracker.attempts))
+	}
+}
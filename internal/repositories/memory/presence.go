@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// presenceEventsBuffer - размер буфера событий одного подписчика.
+const presenceEventsBuffer = 16
+
+// presenceEntry - активность одного пользователя.
+type presenceEntry struct {
+	lastSeen  time.Time
+	flushedAt time.Time
+}
+
+// presenceShard - часть карты присутствия со своей блокировкой.
+type presenceShard struct {
+	mu      sync.Mutex
+	entries map[int64]*presenceEntry
+}
+
+// presenceSubscriber - подписчик на изменения присутствия пользователей.
+type presenceSubscriber struct {
+	userIds []int64
+	events  chan models.Presence
+}
+
+// PresenceTracker - карта присутствия пользователей в памяти процесса.
+// Карта разбита на части по id пользователя, чтобы частые отметки активности не конкурировали за одну блокировку.
+// Пользователь считается в сети, пока он есть в карте: запись удаляется методом Expire.
+// Состояние не переживает перезапуск и не разделяется между экземплярами сервиса.
+type PresenceTracker struct {
+	shards []*presenceShard
+
+	subMu       sync.RWMutex
+	subscribers map[int64]map[*presenceSubscriber]struct{}
+	closed      bool
+}
+
+// NewPresenceTracker возвращает новый объект *PresenceTracker, разбитый на shards частей.
+func NewPresenceTracker(shards int) *PresenceTracker {
+	if shards < 1 {
+		shards = 1
+	}
+
+	t := &PresenceTracker{
+		shards:      make([]*presenceShard, shards),
+		subscribers: make(map[int64]map[*presenceSubscriber]struct{}),
+	}
+	for i := range t.shards {
+		t.shards[i] = &presenceShard{entries: make(map[int64]*presenceEntry)}
+	}
+
+	return t
+}
+
+// Touch отмечает активность пользователя в момент at.
+// Если пользователя не было в сети, подписчики получают событие появления в сети.
+func (t *PresenceTracker) Touch(_ context.Context, userId int64, at time.Time) error {
+	s := t.shard(userId)
+
+	s.mu.Lock()
+	e, ok := s.entries[userId]
+	if !ok {
+		e = &presenceEntry{}
+		s.entries[userId] = e
+	}
+	if at.After(e.lastSeen) {
+		e.lastSeen = at
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		t.publish(models.Presence{UserId: userId, Online: true, LastSeenAt: at})
+	}
+
+	return nil
+}
+
+// GetLastSeen возвращает время последней активности пользователей, которые есть в карте.
+func (t *PresenceTracker) GetLastSeen(_ context.Context, userIds []int64) (map[int64]time.Time, error) {
+	lastSeen := make(map[int64]time.Time, len(userIds))
+	for _, id := range userIds {
+		s := t.shard(id)
+
+		s.mu.Lock()
+		if e, ok := s.entries[id]; ok {
+			lastSeen[id] = e.lastSeen
+		}
+		s.mu.Unlock()
+	}
+
+	return lastSeen, nil
+}
+
+// Pending возвращает время последней активности пользователей, которое еще не отмечено сохраненным.
+func (t *PresenceTracker) Pending(_ context.Context) (map[int64]time.Time, error) {
+	pending := make(map[int64]time.Time)
+	for _, s := range t.shards {
+		s.mu.Lock()
+		for id, e := range s.entries {
+			if e.lastSeen.After(e.flushedAt) {
+				pending[id] = e.lastSeen
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	return pending, nil
+}
+
+// MarkFlushed отмечает время активности пользователей сохраненным.
+// Активность, отмеченная после получения flushed, остается несохраненной.
+func (t *PresenceTracker) MarkFlushed(_ context.Context, flushed map[int64]time.Time) error {
+	for id, at := range flushed {
+		s := t.shard(id)
+
+		s.mu.Lock()
+		if e, ok := s.entries[id]; ok && at.After(e.flushedAt) {
+			e.flushedAt = at
+		}
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Expire удаляет из карты пользователей, не проявлявших активность с момента before.
+// Пользователи с несохраненной активностью не удаляются, чтобы время активности не потерялось.
+// Подписчики получают событие ухода из сети. Возвращает количество удаленных пользователей.
+func (t *PresenceTracker) Expire(_ context.Context, before time.Time) (int, error) {
+	var expired []models.Presence
+	for _, s := range t.shards {
+		s.mu.Lock()
+		for id, e := range s.entries {
+			if e.lastSeen.Before(before) && !e.lastSeen.After(e.flushedAt) {
+				delete(s.entries, id)
+				expired = append(expired, models.Presence{UserId: id, LastSeenAt: e.lastSeen})
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	for _, p := range expired {
+		t.publish(p)
+	}
+
+	return len(expired), nil
+}
+
+// Subscribe подписывает на изменения присутствия пользователей userIds до отмены ctx или закрытия карты.
+// После отмены ctx или закрытия карты канал событий закрывается.
+// Если подписчик не успевает читать события, новые события для него пропускаются.
+func (t *PresenceTracker) Subscribe(ctx context.Context, userIds []int64) (<-chan models.Presence, error) {
+	sub := &presenceSubscriber{
+		userIds: userIds,
+		events:  make(chan models.Presence, presenceEventsBuffer),
+	}
+
+	t.subMu.Lock()
+	if t.closed {
+		t.subMu.Unlock()
+		close(sub.events)
+		return sub.events, nil
+	}
+	for _, id := range userIds {
+		if t.subscribers[id] == nil {
+			t.subscribers[id] = make(map[*presenceSubscriber]struct{})
+		}
+		t.subscribers[id][sub] = struct{}{}
+	}
+	t.subMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		t.unsubscribe(sub)
+	}()
+
+	return sub.events, nil
+}
+
+// Close закрывает каналы событий всех подписчиков, чтобы потоки WatchPresence завершились.
+// Новые подписки после закрытия сразу получают закрытый канал.
+func (t *PresenceTracker) Close() error {
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	closed := make(map[*presenceSubscriber]struct{})
+	for _, subs := range t.subscribers {
+		for sub := range subs {
+			if _, ok := closed[sub]; !ok {
+				closed[sub] = struct{}{}
+				close(sub.events)
+			}
+		}
+	}
+	t.subscribers = make(map[int64]map[*presenceSubscriber]struct{})
+
+	return nil
+}
+
+// unsubscribe удаляет подписчика и закрывает его канал событий.
+func (t *PresenceTracker) unsubscribe(sub *presenceSubscriber) {
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+
+	//Каналы всех подписчиков уже закрыты методом Close
+	if t.closed {
+		return
+	}
+
+	for _, id := range sub.userIds {
+		delete(t.subscribers[id], sub)
+		if len(t.subscribers[id]) == 0 {
+			delete(t.subscribers, id)
+		}
+	}
+
+	//Канал закрывается под блокировкой, поэтому publish не отправит событие в закрытый канал
+	close(sub.events)
+}
+
+// publish отправляет событие подписчикам пользователя без ожидания.
+func (t *PresenceTracker) publish(p models.Presence) {
+	t.subMu.RLock()
+	defer t.subMu.RUnlock()
+
+	for sub := range t.subscribers[p.UserId] {
+		select {
+		case sub.events <- p:
+		default:
+		}
+	}
+}
+
+// shard возвращает часть карты, в которой хранится пользователь.
+func (t *PresenceTracker) shard(userId int64) *presenceShard {
+	return t.shards[uint64(userId)%uint64(len(t.shards))]
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+var TestPresenceUserId int64 = 1
+
+func TestPresenceTracker_Expire(t *testing.T) {
+	tests := []struct {
+		name      string
+		lastSeen  time.Time
+		flushedAt time.Time
+		before    time.Time
+		want      int
+	}{
+		{
+			name:      "Expired",
+			lastSeen:  TestNow,
+			flushedAt: TestNow,
+			before:    TestNow.Add(time.Minute),
+			want:      1,
+		},
+		{
+			name:      "Online",
+			lastSeen:  TestNow,
+			flushedAt: TestNow,
+			before:    TestNow.Add(-time.Minute),
+		},
+		{
+			name:     "NotFlushed",
+			lastSeen: TestNow,
+			before:   TestNow.Add(time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewPresenceTracker(4)
+			tracker.shard(TestPresenceUserId).entries[TestPresenceUserId] = &presenceEntry{
+				lastSeen:  tt.lastSeen,
+				flushedAt: tt.flushedAt,
+			}
+
+			got, err := tracker.Expire(context.Background(), tt.before)
+			if err != nil {
+				t.Errorf("PresenceTracker.Expire() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("PresenceTracker.Expire() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresenceTracker_Flush(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewPresenceTracker(4)
+
+	if err := tracker.Touch(ctx, TestPresenceUserId, TestNow); err != nil {
+		t.Fatalf("PresenceTracker.Touch() error = %v", err)
+	}
+
+	pending, _ := tracker.Pending(ctx)
+	want := map[int64]time.Time{TestPresenceUserId: TestNow}
+	if !reflect.DeepEqual(pending, want) {
+		t.Errorf("PresenceTracker.Pending() = %v, want %v", pending, want)
+	}
+
+	//Активность после получения несохраненных записей не должна считаться сохраненной
+	if err := tracker.Touch(ctx, TestPresenceUserId, TestNow.Add(time.Second)); err != nil {
+		t.Fatalf("PresenceTracker.Touch() error = %v", err)
+	}
+
+	if err := tracker.MarkFlushed(ctx, pending); err != nil {
+		t.Fatalf("PresenceTracker.MarkFlushed() error = %v", err)
+	}
+
+	pending, _ = tracker.Pending(ctx)
+	want = map[int64]time.Time{TestPresenceUserId: TestNow.Add(time.Second)}
+	if !reflect.DeepEqual(pending, want) {
+		t.Errorf("PresenceTracker.Pending() after flush = %v, want %v", pending, want)
+	}
+}
+
+func TestPresenceTracker_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := NewPresenceTracker(4)
+
+	events, err := tracker.Subscribe(ctx, []int64{TestPresenceUserId})
+	if err != nil {
+		t.Fatalf("PresenceTracker.Subscribe() error = %v", err)
+	}
+
+	_ = tracker.Touch(ctx, TestPresenceUserId, TestNow)
+	//Повторная активность пользователя в сети не порождает событие
+	_ = tracker.Touch(ctx, TestPresenceUserId, TestNow.Add(time.Second))
+	_ = tracker.MarkFlushed(ctx, map[int64]time.Time{TestPresenceUserId: TestNow.Add(time.Second)})
+	_, _ = tracker.Expire(ctx, TestNow.Add(time.Minute))
+
+	want := []models.Presence{
+		{UserId: TestPresenceUserId, Online: true, LastSeenAt: TestNow},
+		{UserId: TestPresenceUserId, LastSeenAt: TestNow.Add(time.Second)},
+	}
+	for _, w := range want {
+		if got := <-events; got != w {
+			t.Errorf("PresenceTracker.Subscribe() event = %v, want %v", got, w)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Errorf("PresenceTracker.Subscribe() channel not closed after cancel")
+	}
+}
+
+func TestPresenceTracker_Close(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker := NewPresenceTracker(4)
+
+	events, err := tracker.Subscribe(ctx, []int64{TestPresenceUserId, TestPresenceUserId + 1})
+	if err != nil {
+		t.Fatalf("PresenceTracker.Subscribe() error = %v", err)
+	}
+
+	if err = tracker.Close(); err != nil {
+		t.Fatalf("PresenceTracker.Close() error = %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("PresenceTracker.Subscribe() channel not closed after Close")
+	}
+
+	//Подписка после закрытия сразу получает закрытый канал
+	events, err = tracker.Subscribe(ctx, []int64{TestPresenceUserId})
+	if err != nil {
+		t.Fatalf("PresenceTracker.Subscribe() after Close error = %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("PresenceTracker.Subscribe() after Close channel not closed")
+	}
+
+	//Отмена контекста после закрытия не закрывает канал повторно
+	cancel()
+	_ = tracker.Touch(ctx, TestPresenceUserId, TestNow)
+}
@@ -0,0 +1,98 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GetLockout возвращает время окончания блокировки входа по ключу.
+// Если ключ не заблокирован, возвращает нулевое время.
+func (r *Repository) GetLockout(ctx context.Context, key string) (time.Time, error) {
+	const op = "psql.GetLockout"
+
+	row := r.db.QueryRowContext(ctx, "SELECT locked_until FROM login_attempts WHERE key = $1", key)
+
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&lockedUntil); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return lockedUntil.Time, nil
+}
+
+// RegisterFailedAttempt регистрирует неудачную попытку входа по ключу и возвращает количество неудачных попыток подряд.
+// Если предыдущая неудачная попытка была раньше windowStart, счетчик начинается заново.
+func (r *Repository) RegisterFailedAttempt(ctx context.Context, key string, windowStart time.Time) (int, error) {
+	const op = "psql.RegisterFailedAttempt"
+
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO login_attempts (key, failures, last_failure_at) 
+		VALUES ($1, 1, now()) 
+		ON CONFLICT (key) DO UPDATE SET 
+			failures = CASE WHEN login_attempts.last_failure_at < $2 THEN 1 ELSE login_attempts.failures + 1 END, 
+			last_failure_at = now() 
+		RETURNING failures`,
+		key, windowStart)
+
+	var failures int
+	if err := row.Scan(&failures); err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return failures, nil
+}
+
+// SetLockout блокирует вход по ключу до указанного времени.
+func (r *Repository) SetLockout(ctx context.Context, key string, until time.Time) error {
+	const op = "psql.SetLockout"
+
+	_, err := r.db.ExecContext(ctx, "UPDATE login_attempts SET locked_until = $1 WHERE key = $2", until, key)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ResetFailedAttempts сбрасывает счетчик неудачных попыток и блокировку по ключу.
+func (r *Repository) ResetFailedAttempts(ctx context.Context, key string) error {
+	const op = "psql.ResetFailedAttempts"
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredAttempts удаляет не более limit ключей, последняя неудачная попытка по которым была раньше before
+// и блокировка по которым истекла. Возвращает число удаленных ключей.
+func (r *Repository) DeleteExpiredAttempts(ctx context.Context, before time.Time, limit int) (int, error) {
+	const op = "psql.DeleteExpiredAttempts"
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM login_attempts WHERE key IN (
+			SELECT key FROM login_attempts 
+			WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < now()) 
+			LIMIT $2
+		)`,
+		before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return int(deleted), nil
+}
@@ -0,0 +1,341 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var (
+	TestAttemptKey  = "user:user1"
+	TestWindowStart = time.Unix(1700000000, 0)
+	TestLockedUntil = time.Unix(1700000900, 0)
+)
+
+func TestRepository_GetLockout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx context.Context
+		key string
+	}
+	type mockBehavior func(ctx context.Context, key string)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         time.Time
+		wantErr      bool
+	}{
+		{
+			name: "Locked",
+			args: args{
+				ctx: context.Background(),
+				key: TestAttemptKey,
+			},
+			mockBehavior: func(ctx context.Context, key string) {
+				rows := sqlmock.NewRows([]string{"locked_until"}).AddRow(TestLockedUntil)
+				mock.ExpectQuery("SELECT locked_until FROM login_attempts").WithArgs(key).WillReturnRows(rows)
+			},
+			want: TestLockedUntil,
+		},
+		{
+			name: "NotLocked",
+			args: args{
+				ctx: context.Background(),
+				key: TestAttemptKey,
+			},
+			mockBehavior: func(ctx context.Context, key string) {
+				rows := sqlmock.NewRows([]string{"locked_until"}).AddRow(nil)
+				mock.ExpectQuery("SELECT locked_until FROM login_attempts").WithArgs(key).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "NoAttempts",
+			args: args{
+				ctx: context.Background(),
+				key: TestAttemptKey,
+			},
+			mockBehavior: func(ctx context.Context, key string) {
+				rows := sqlmock.NewRows([]string{"locked_until"})
+				mock.ExpectQuery("SELECT locked_until FROM login_attempts").WithArgs(key).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx: context.Background(),
+				key: TestAttemptKey,
+			},
+			mockBehavior: func(ctx context.Context, key string) {
+				mock.ExpectQuery("SELECT locked_until FROM login_attempts").WithArgs(key).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.key)
+
+			got, err := rep.GetLockout(tt.args.ctx, tt.args.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetLockout() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Repository.GetLockout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_RegisterFailedAttempt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx         context.Context
+		key         string
+		windowStart time.Time
+	}
+	type mockBehavior func(ctx context.Context, key string, windowStart time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:         context.Background(),
+				key:         TestAttemptKey,
+				windowStart: TestWindowStart,
+			},
+			mockBehavior: func(ctx context.Context, key string, windowStart time.Time) {
+				rows := sqlmock.NewRows([]string{"failures"}).AddRow(3)
+				mock.ExpectQuery("INSERT INTO login_attempts").WithArgs(key, windowStart).WillReturnRows(rows)
+			},
+			want: 3,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:         context.Background(),
+				key:         TestAttemptKey,
+				windowStart: TestWindowStart,
+			},
+			mockBehavior: func(ctx context.Context, key string, windowStart time.Time) {
+				mock.ExpectQuery("INSERT INTO login_attempts").WithArgs(key, windowStart).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.key, tt.args.windowStart)
+
+			got, err := rep.RegisterFailedAttempt(tt.args.ctx, tt.args.key, tt.args.windowStart)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.RegisterFailedAttempt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.RegisterFailedAttempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_SetLockout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx   context.Context
+		key   string
+		until time.Time
+	}
+	type mockBehavior func(ctx context.Context, key string, until time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:   context.Background(),
+				key:   TestAttemptKey,
+				until: TestLockedUntil,
+			},
+			mockBehavior: func(ctx context.Context, key string, until time.Time) {
+				mock.ExpectExec("UPDATE login_attempts SET locked_until").
+					WithArgs(until, key).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:   context.Background(),
+				key:   TestAttemptKey,
+				until: TestLockedUntil,
+			},
+			mockBehavior: func(ctx context.Context, key string, until time.Time) {
+				mock.ExpectExec("UPDATE login_attempts SET locked_until").
+					WithArgs(until, key).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.key, tt.args.until)
+
+			if err := rep.SetLockout(tt.args.ctx, tt.args.key, tt.args.until); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SetLockout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_ResetFailedAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx context.Context
+		key string
+	}
+	type mockBehavior func(ctx context.Context, key string)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx: context.Background(),
+				key: TestAttemptKey,
+			},
+			mockBehavior: func(ctx context.Context, key string) {
+				mock.ExpectExec("DELETE FROM login_attempts").WithArgs(key).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx: context.Background(),
+				key: TestAttemptKey,
+			},
+			mockBehavior: func(ctx context.Context, key string) {
+				mock.ExpectExec("DELETE FROM login_attempts").WithArgs(key).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.key)
+
+			if err := rep.ResetFailedAttempts(tt.args.ctx, tt.args.key); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ResetFailedAttempts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteExpiredAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		before time.Time
+		limit  int
+	}
+	type mockBehavior func(ctx context.Context, before time.Time, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				before: TestWindowStart,
+				limit:  10,
+			},
+			mockBehavior: func(ctx context.Context, before time.Time, limit int) {
+				mock.ExpectExec("DELETE FROM login_attempts WHERE key IN (.+) last_failure_at < (.+) locked_until < now()").
+					WithArgs(before, limit).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			want: 3,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				before: TestWindowStart,
+				limit:  10,
+			},
+			mockBehavior: func(ctx context.Context, before time.Time, limit int) {
+				mock.ExpectExec("DELETE FROM login_attempts").WithArgs(before, limit).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.before, tt.args.limit)
+
+			got, err := rep.DeleteExpiredAttempts(tt.args.ctx, tt.args.before, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteExpiredAttempts() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.DeleteExpiredAttempts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+// SaveBlock сохраняет блокировку пользователя blockedId пользователем blockerId.
+// Вместе с блокировкой удаляются контакты пользователей и заявки в контакты между ними в обе стороны.
+// Если блокируемый пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+// Если пользователь уже заблокирован, возвращает ошибку repository.ErrAlreadyBlocked.
+func (r *Repository) SaveBlock(ctx context.Context, blockerId, blockedId int64) error {
+	const op = "psql.SaveBlock"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Блокировка строки блокирующего ждет завершения SaveContactRequest, адресованных ему,
+	//поэтому заявки, сохраненные параллельно, будут видны и удалены ниже
+	_, err = tx.ExecContext(ctx,
+		"SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE",
+		blockerId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT true FROM users WHERE id = $1 AND is_active = true FOR SHARE",
+		blockedId).Scan(&exists)
+	if err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)",
+		blockerId, blockedId)
+	if err != nil {
+		_ = tx.Rollback()
+		//Ошибка нарушения constraint unique
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code.Name() == repository.CodeConstraintUnique {
+			return fmt.Errorf("%s, %w", op, repository.ErrAlreadyBlocked)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM contacts 
+		WHERE (user_id = $1 AND contact_id = $2) OR (user_id = $2 AND contact_id = $1)`,
+		blockerId, blockedId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM contact_requests 
+		WHERE (from_user_id = $1 AND to_user_id = $2) OR (from_user_id = $2 AND to_user_id = $1)`,
+		blockerId, blockedId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteBlock удаляет блокировку пользователя blockedId пользователем blockerId.
+// Если блокировка не найдена, возвращает ошибку repository.ErrBlockNotFound.
+func (r *Repository) DeleteBlock(ctx context.Context, blockerId, blockedId int64) error {
+	const op = "psql.DeleteBlock"
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2",
+		blockerId, blockedId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrBlockNotFound)
+}
+
+// ListBlocked получает активных пользователей, заблокированных пользователем userId, упорядоченных по id.
+// Если afterId не равен 0, возвращаются пользователи, следующие после пользователя с этим id.
+// Возвращает не более limit пользователей.
+func (r *Repository) ListBlocked(ctx context.Context, userId, afterId int64, limit int) ([]models.BlockedUser, error) {
+	const op = "psql.ListBlocked"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userColumns+`, b.created_at 
+		FROM user_blocks b 
+		JOIN users u ON u.id = b.blocked_id 
+		WHERE b.blocker_id = $1 AND b.blocked_id > $2 AND u.is_active = true 
+		ORDER BY b.blocked_id 
+		LIMIT $3`,
+		userId, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var blocked []models.BlockedUser
+	for rows.Next() {
+		var user models.BlockedUser
+		if err = rows.Scan(append(userFields(&user.User), &user.BlockedAt)...); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		blocked = append(blocked, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return blocked, nil
+}
+
+// IsBlocked сообщает, заблокировал ли пользователь blockerId пользователя blockedId.
+func (r *Repository) IsBlocked(ctx context.Context, blockerId, blockedId int64) (bool, error) {
+	const op = "psql.IsBlocked"
+
+	var blocked bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2)",
+		blockerId, blockedId).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return blocked, nil
+}
@@ -0,0 +1,371 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/lib/pq"
+)
+
+var (
+	TestBlockedId   int64 = 2
+	TestBlockedUser       = models.BlockedUser{
+		User: models.User{
+			Id:       TestBlockedId,
+			Username: "user2",
+			IsActive: true,
+		},
+		BlockedAt: TestExpiresAt,
+	}
+)
+
+// newTestBlockedRows возвращает строки результата запроса заблокированных пользователей.
+func newTestBlockedRows(blocked ...models.BlockedUser) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "pass_hash", "is_active", "display_name", "bio",
+		"avatar_url", "locale", "time_zone", "created_at", "updated_at", "blocked_at",
+	})
+	for _, b := range blocked {
+		u := b.User
+		rows.AddRow(
+			u.Id, u.Username, u.PasswordHash, u.IsActive, u.DisplayName, u.Bio,
+			u.AvatarURL, u.Locale, u.TimeZone, u.CreatedAt, u.UpdatedAt, b.BlockedAt,
+		)
+	}
+
+	return rows
+}
+
+func TestRepository_SaveBlock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		blockerId int64
+		blockedId int64
+	}
+	type mockBehavior func(ctx context.Context, blockerId, blockedId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("SELECT 1 FROM users (.+) FOR NO KEY UPDATE").
+					WithArgs(blockerId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(blockedId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectExec("INSERT INTO user_blocks").
+					WithArgs(blockerId, blockedId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM contacts").
+					WithArgs(blockerId, blockedId).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(blockerId, blockedId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("SELECT 1 FROM users (.+) FOR NO KEY UPDATE").
+					WithArgs(blockerId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(blockedId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "AlreadyBlocked",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("SELECT 1 FROM users (.+) FOR NO KEY UPDATE").
+					WithArgs(blockerId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(blockedId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectExec("INSERT INTO user_blocks").
+					WithArgs(blockerId, blockedId).
+					WillReturnError(&pq.Error{Code: "23505"})
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("SELECT 1 FROM users (.+) FOR NO KEY UPDATE").
+					WithArgs(blockerId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(blockedId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectExec("INSERT INTO user_blocks").
+					WithArgs(blockerId, blockedId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM contacts").
+					WithArgs(blockerId, blockedId).
+					WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.blockerId, tt.args.blockedId)
+
+			if err := rep.SaveBlock(tt.args.ctx, tt.args.blockerId, tt.args.blockedId); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SaveBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteBlock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		blockerId int64
+		blockedId int64
+	}
+	type mockBehavior func(ctx context.Context, blockerId, blockedId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectExec("DELETE FROM user_blocks").
+					WithArgs(blockerId, blockedId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectExec("DELETE FROM user_blocks").
+					WithArgs(blockerId, blockedId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.blockerId, tt.args.blockedId)
+
+			if err := rep.DeleteBlock(tt.args.ctx, tt.args.blockerId, tt.args.blockedId); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_ListBlocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx     context.Context
+		userId  int64
+		afterId int64
+		limit   int
+	}
+	type mockBehavior func(ctx context.Context, userId, afterId int64, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.BlockedUser
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				limit:  10,
+			},
+			mockBehavior: func(ctx context.Context, userId, afterId int64, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM user_blocks b JOIN users u").
+					WithArgs(userId, afterId, limit).
+					WillReturnRows(newTestBlockedRows(TestBlockedUser))
+			},
+			want: []models.BlockedUser{TestBlockedUser},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				afterId: TestBlockedId,
+				limit:   10,
+			},
+			mockBehavior: func(ctx context.Context, userId, afterId int64, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM user_blocks b JOIN users u").
+					WithArgs(userId, afterId, limit).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.afterId, tt.args.limit)
+
+			got, err := rep.ListBlocked(tt.args.ctx, tt.args.userId, tt.args.afterId, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ListBlocked() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.ListBlocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_IsBlocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		blockerId int64
+		blockedId int64
+	}
+	type mockBehavior func(ctx context.Context, blockerId, blockedId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         bool
+		wantErr      bool
+	}{
+		{
+			name: "Blocked",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM user_blocks").
+					WithArgs(blockerId, blockedId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			want: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				blockerId: TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(ctx context.Context, blockerId, blockedId int64) {
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM user_blocks").
+					WithArgs(blockerId, blockedId).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.blockerId, tt.args.blockedId)
+
+			got, err := rep.IsBlocked(tt.args.ctx, tt.args.blockerId, tt.args.blockedId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.IsBlocked() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.IsBlocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/lib/pq"
+)
+
+// userChangesChannel - канал NOTIFY, в который триггер таблицы user_changes отправляет уведомление о новых изменениях.
+const userChangesChannel = "user_changes"
+
+// listenerPingInterval - интервал проверки соединения слушателя, если уведомлений не было.
+const listenerPingInterval = 90 * time.Second
+
+// GetUserChanges получает не более limit изменений пользователей, следующих после позиции (afterTxId, afterId),
+// в порядке позиции. Возвращаются только изменения завершенных транзакций с tx_id меньше горизонта журнала,
+// поэтому изменения, зафиксированные позже, всегда получают позицию после уже прочитанных.
+func (r *Repository) GetUserChanges(ctx context.Context, afterTxId, afterId int64, limit int) ([]models.UserChange, error) {
+	const op = "psql.GetUserChanges"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, tx_id, event_type, user_id, payload, created_at 
+		FROM user_changes 
+		WHERE (tx_id, id) > ($1::xid8, $2) AND tx_id < pg_snapshot_xmin(pg_current_snapshot()) 
+		ORDER BY tx_id, id 
+		LIMIT $3`,
+		afterTxId, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var changes []models.UserChange
+	for rows.Next() {
+		var c models.UserChange
+		if err = rows.Scan(&c.Id, &c.TxId, &c.Type, &c.UserId, &c.Payload, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		changes = append(changes, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return changes, nil
+}
+
+// GetChangeHorizon получает горизонт журнала изменений - наименьший id транзакции, которая еще может быть не завершена.
+// Изменения с tx_id меньше горизонта уже зафиксированы, новые изменения получат tx_id не меньше горизонта.
+func (r *Repository) GetChangeHorizon(ctx context.Context) (int64, error) {
+	const op = "psql.GetChangeHorizon"
+
+	var horizon int64
+	err := r.db.QueryRowContext(ctx, "SELECT pg_snapshot_xmin(pg_current_snapshot())").Scan(&horizon)
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return horizon, nil
+}
+
+// GetOldestChange получает позицию (tx_id, id) самого старого изменения, оставшегося в журнале изменений.
+// Если журнал пуст, возвращает нулевую позицию.
+func (r *Repository) GetOldestChange(ctx context.Context) (int64, int64, error) {
+	const op = "psql.GetOldestChange"
+
+	var txId, id int64
+	err := r.db.QueryRowContext(ctx, "SELECT tx_id, id FROM user_changes ORDER BY tx_id, id LIMIT 1").Scan(&txId, &id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, nil
+		}
+
+		return 0, 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return txId, id, nil
+}
+
+// DeleteUserChanges удаляет не более limit изменений пользователей, добавленных раньше before.
+// Возвращает число удаленных изменений.
+func (r *Repository) DeleteUserChanges(ctx context.Context, before time.Time, limit int) (int, error) {
+	const op = "psql.DeleteUserChanges"
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM user_changes WHERE id IN (
+			SELECT id FROM user_changes WHERE created_at < $1 ORDER BY id LIMIT $2
+		)`,
+		before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return int(deleted), nil
+}
+
+// ChangeListener слушает уведомления о новых изменениях пользователей через LISTEN/NOTIFY
+// и раздает их подписчикам. Для всех подписчиков используется одно соединение с базой данных.
+type ChangeListener struct {
+	listener    *pq.Listener
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+	closed      bool
+}
+
+// NewChangeListener создает слушателя уведомлений об изменениях пользователей, принимает на вход строку подключения.
+// При потере соединения слушатель переподключается с интервалом от minReconnect до maxReconnect.
+func NewChangeListener(conn string, minReconnect, maxReconnect time.Duration) (*ChangeListener, error) {
+	const op = "psql.NewChangeListener"
+
+	listener := pq.NewListener(conn, minReconnect, maxReconnect, nil)
+	if err := listener.Listen(userChangesChannel); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	l := &ChangeListener{
+		listener:    listener,
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+	go l.run()
+
+	return l, nil
+}
+
+// Subscribe подписывает на уведомления о новых изменениях до отмены ctx.
+// Уведомления не накапливаются: пока подписчик не прочитал уведомление, следующие отбрасываются.
+// После отмены ctx или закрытия слушателя канал уведомлений закрывается.
+func (l *ChangeListener) Subscribe(ctx context.Context) (<-chan struct{}, error) {
+	const op = "psql.ChangeListener.Subscribe"
+
+	notify := make(chan struct{}, 1)
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, fmt.Errorf("%s, listener closed", op)
+	}
+	l.subscribers[notify] = struct{}{}
+	l.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.unsubscribe(notify)
+	}()
+
+	return notify, nil
+}
+
+// Close закрывает соединение слушателя и каналы уведомлений всех подписчиков.
+func (l *ChangeListener) Close() error {
+	return l.listener.Close()
+}
+
+// run раздает уведомления подписчикам, пока не будет закрыт слушатель.
+// После переподключения уведомления, отправленные во время разрыва, потеряны,
+// поэтому подписчики тоже уведомляются и перечитывают журнал изменений.
+func (l *ChangeListener) run() {
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case _, ok := <-l.listener.Notify:
+			if !ok {
+				l.closeSubscribers()
+				return
+			}
+
+			l.notify()
+		case <-ticker.C:
+			go func() {
+				_ = l.listener.Ping()
+			}()
+		}
+	}
+}
+
+// notify отправляет уведомление подписчикам без ожидания.
+func (l *ChangeListener) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for notify := range l.subscribers {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// unsubscribe удаляет подписчика и закрывает его канал уведомлений, если он еще не закрыт.
+func (l *ChangeListener) unsubscribe(notify chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.subscribers[notify]; ok {
+		delete(l.subscribers, notify)
+		close(notify)
+	}
+}
+
+// closeSubscribers закрывает каналы уведомлений всех подписчиков и запрещает новые подписки.
+func (l *ChangeListener) closeSubscribers() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	for notify := range l.subscribers {
+		delete(l.subscribers, notify)
+		close(notify)
+	}
+}
@@ -0,0 +1,271 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+var TestUserChange = models.UserChange{
+	Id:        12,
+	TxId:      740,
+	Type:      models.EventUserProfileUpdated,
+	UserId:    TestUserId,
+	Payload:   []byte(`{"user_id":1,"changes":{"bio":"Bio"}}`),
+	CreatedAt: TestExpiresAt,
+}
+
+func TestRepository_GetUserChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	columns := []string{"id", "tx_id", "event_type", "user_id", "payload", "created_at"}
+
+	type args struct {
+		ctx       context.Context
+		afterTxId int64
+		afterId   int64
+		limit     int
+	}
+	type mockBehavior func(ctx context.Context, afterTxId, afterId int64, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.UserChange
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				afterTxId: 740,
+				afterId:   11,
+				limit:     10,
+			},
+			mockBehavior: func(ctx context.Context, afterTxId, afterId int64, limit int) {
+				c := TestUserChange
+				mock.ExpectQuery("SELECT (.+) FROM user_changes WHERE (.+) pg_snapshot_xmin(.+) ORDER BY tx_id, id").
+					WithArgs(afterTxId, afterId, limit).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(c.Id, c.TxId, c.Type, c.UserId, c.Payload, c.CreatedAt))
+			},
+			want: []models.UserChange{TestUserChange},
+		},
+		{
+			name: "Empty",
+			args: args{
+				ctx:       context.Background(),
+				afterTxId: 740,
+				afterId:   12,
+				limit:     10,
+			},
+			mockBehavior: func(ctx context.Context, afterTxId, afterId int64, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM user_changes").WithArgs(afterTxId, afterId, limit).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				afterTxId: 740,
+				afterId:   11,
+				limit:     10,
+			},
+			mockBehavior: func(ctx context.Context, afterTxId, afterId int64, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM user_changes").WithArgs(afterTxId, afterId, limit).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.afterTxId, tt.args.afterId, tt.args.limit)
+
+			got, err := rep.GetUserChanges(tt.args.ctx, tt.args.afterTxId, tt.args.afterId, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetUserChanges() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetUserChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_GetChangeHorizon(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	tests := []struct {
+		name         string
+		mockBehavior func()
+		want         int64
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			mockBehavior: func() {
+				mock.ExpectQuery("SELECT pg_snapshot_xmin").WillReturnRows(sqlmock.NewRows([]string{"xmin"}).AddRow(741))
+			},
+			want: 741,
+		},
+		{
+			name: "Error",
+			mockBehavior: func() {
+				mock.ExpectQuery("SELECT pg_snapshot_xmin").WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior()
+
+			got, err := rep.GetChangeHorizon(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetChangeHorizon() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.GetChangeHorizon() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_GetOldestChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	tests := []struct {
+		name         string
+		mockBehavior func()
+		wantTxId     int64
+		wantId       int64
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			mockBehavior: func() {
+				mock.ExpectQuery("SELECT tx_id, id FROM user_changes ORDER BY tx_id, id LIMIT 1").
+					WillReturnRows(sqlmock.NewRows([]string{"tx_id", "id"}).AddRow(741, 12))
+			},
+			wantTxId: 741,
+			wantId:   12,
+		},
+		{
+			name: "EmptyJournal",
+			mockBehavior: func() {
+				mock.ExpectQuery("SELECT tx_id, id FROM user_changes").WillReturnRows(sqlmock.NewRows([]string{"tx_id", "id"}))
+			},
+		},
+		{
+			name: "Error",
+			mockBehavior: func() {
+				mock.ExpectQuery("SELECT tx_id, id FROM user_changes").WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior()
+
+			gotTxId, gotId, err := rep.GetOldestChange(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetOldestChange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotTxId != tt.wantTxId || gotId != tt.wantId {
+				t.Errorf("Repository.GetOldestChange() = (%v, %v), want (%v, %v)", gotTxId, gotId, tt.wantTxId, tt.wantId)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteUserChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		before time.Time
+		limit  int
+	}
+	type mockBehavior func(ctx context.Context, before time.Time, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				before: TestExpiresAt,
+				limit:  10,
+			},
+			mockBehavior: func(ctx context.Context, before time.Time, limit int) {
+				mock.ExpectExec("DELETE FROM user_changes WHERE id IN (.+) created_at < (.+) LIMIT").
+					WithArgs(before, limit).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			want: 3,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				before: TestExpiresAt,
+				limit:  10,
+			},
+			mockBehavior: func(ctx context.Context, before time.Time, limit int) {
+				mock.ExpectExec("DELETE FROM user_changes").WithArgs(before, limit).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.before, tt.args.limit)
+
+			got, err := rep.DeleteUserChanges(tt.args.ctx, tt.args.before, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteUserChanges() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.DeleteUserChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
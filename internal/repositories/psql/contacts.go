@@ -0,0 +1,308 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+// SaveContactRequest сохраняет заявку пользователя fromUserId на добавление в контакты пользователя toUserId.
+// Если встречная заявка от toUserId уже есть, заявка не сохраняется: пользователи сразу становятся контактами
+// и возвращается accepted = true.
+// Если получатель не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+// Если получатель заблокировал отправителя, возвращает ошибку repository.ErrContactRequestBlocked.
+// Если пользователи уже состоят в контактах, возвращает ошибку repository.ErrAlreadyContacts.
+// Если такая заявка уже есть, возвращает ошибку repository.ErrContactRequestExists.
+func (r *Repository) SaveContactRequest(ctx context.Context, fromUserId, toUserId int64) (bool, error) {
+	const op = "psql.SaveContactRequest"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Блокировка строки получателя не дает удалить или деактивировать его до конца транзакции,
+	//а также конфликтует с блокировкой строки в SaveBlock, поэтому проверка блокировки ниже не устареет до коммита
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT true FROM users WHERE id = $1 AND is_active = true FOR SHARE",
+		toUserId).Scan(&exists)
+	if err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	var blocked bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2)",
+		toUserId, fromUserId).Scan(&blocked)
+	if err != nil {
+		_ = tx.Rollback()
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if blocked {
+		_ = tx.Rollback()
+		return false, fmt.Errorf("%s, %w", op, repository.ErrContactRequestBlocked)
+	}
+
+	var contacts bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM contacts WHERE user_id = $1 AND contact_id = $2)",
+		fromUserId, toUserId).Scan(&contacts)
+	if err != nil {
+		_ = tx.Rollback()
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if contacts {
+		_ = tx.Rollback()
+		return false, fmt.Errorf("%s, %w", op, repository.ErrAlreadyContacts)
+	}
+
+	//Встречная заявка означает согласие обеих сторон
+	res, err := tx.ExecContext(ctx,
+		"DELETE FROM contact_requests WHERE from_user_id = $1 AND to_user_id = $2",
+		toUserId, fromUserId)
+	if err != nil {
+		_ = tx.Rollback()
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if affected > 0 {
+		if err = saveContactPair(ctx, tx, fromUserId, toUserId); err != nil {
+			_ = tx.Rollback()
+			return false, fmt.Errorf("%s, %w", op, err)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO contact_requests (from_user_id, to_user_id) VALUES ($1, $2)",
+			fromUserId, toUserId)
+		if err != nil {
+			_ = tx.Rollback()
+			//Ошибка нарушения constraint unique
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code.Name() == repository.CodeConstraintUnique {
+				return false, fmt.Errorf("%s, %w", op, repository.ErrContactRequestExists)
+			}
+
+			return false, fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return affected > 0, nil
+}
+
+// AcceptContactRequest принимает заявку пользователя fromUserId, адресованную пользователю userId,
+// и делает пользователей контактами друг друга.
+// Если заявка не найдена, возвращает ошибку repository.ErrContactRequestNotFound.
+func (r *Repository) AcceptContactRequest(ctx context.Context, userId, fromUserId int64) error {
+	const op = "psql.AcceptContactRequest"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"DELETE FROM contact_requests WHERE from_user_id = $1 AND to_user_id = $2",
+		fromUserId, userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = checkAffected(op, res, repository.ErrContactRequestNotFound); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err = saveContactPair(ctx, tx, userId, fromUserId); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteContactRequest удаляет заявку пользователя fromUserId, адресованную пользователю toUserId.
+// Используется как для отклонения заявки получателем, так и для ее отзыва отправителем.
+// Если заявка не найдена, возвращает ошибку repository.ErrContactRequestNotFound.
+func (r *Repository) DeleteContactRequest(ctx context.Context, fromUserId, toUserId int64) error {
+	const op = "psql.DeleteContactRequest"
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM contact_requests WHERE from_user_id = $1 AND to_user_id = $2",
+		fromUserId, toUserId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrContactRequestNotFound)
+}
+
+// DeleteContact удаляет пользователей userId и contactId из контактов друг друга.
+// Если пользователи не состоят в контактах, возвращает ошибку repository.ErrContactNotFound.
+func (r *Repository) DeleteContact(ctx context.Context, userId, contactId int64) error {
+	const op = "psql.DeleteContact"
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM contacts 
+		WHERE (user_id = $1 AND contact_id = $2) OR (user_id = $2 AND contact_id = $1)`,
+		userId, contactId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrContactNotFound)
+}
+
+// ListContacts получает активных контактов пользователя, упорядоченных по id.
+// Если afterId не равен 0, возвращаются контакты, следующие после контакта с этим id.
+// Возвращает не более limit контактов.
+func (r *Repository) ListContacts(ctx context.Context, userId, afterId int64, limit int) ([]models.Contact, error) {
+	const op = "psql.ListContacts"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userColumns+`, c.created_at 
+		FROM contacts c 
+		JOIN users u ON u.id = c.contact_id 
+		WHERE c.user_id = $1 AND c.contact_id > $2 AND u.is_active = true 
+		ORDER BY c.contact_id 
+		LIMIT $3`,
+		userId, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var contacts []models.Contact
+	for rows.Next() {
+		var contact models.Contact
+		if err = rows.Scan(append(userFields(&contact.User), &contact.Since)...); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		contacts = append(contacts, contact)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return contacts, nil
+}
+
+// ListContactRequests получает заявки в контакты, адресованные пользователю, если incoming = true,
+// или отправленные им, если incoming = false, начиная с последней.
+func (r *Repository) ListContactRequests(ctx context.Context, userId int64, incoming bool) ([]models.ContactRequest, error) {
+	const op = "psql.ListContactRequests"
+
+	column := "from_user_id"
+	if incoming {
+		column = "to_user_id"
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT from_user_id, to_user_id, created_at 
+		FROM contact_requests 
+		WHERE `+column+` = $1 
+		ORDER BY created_at DESC`,
+		userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var requests []models.ContactRequest
+	for rows.Next() {
+		var request models.ContactRequest
+		if err = rows.Scan(&request.FromUserId, &request.ToUserId, &request.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		requests = append(requests, request)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return requests, nil
+}
+
+// IsContact сообщает, состоят ли пользователи userId и contactId в контактах друг друга.
+func (r *Repository) IsContact(ctx context.Context, userId, contactId int64) (bool, error) {
+	const op = "psql.IsContact"
+
+	var contacts bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM contacts WHERE user_id = $1 AND contact_id = $2)",
+		userId, contactId).Scan(&contacts)
+	if err != nil {
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return contacts, nil
+}
+
+// FilterContacts возвращает id из candidateIds, которые состоят в контактах пользователя userId.
+func (r *Repository) FilterContacts(ctx context.Context, userId int64, candidateIds []int64) ([]int64, error) {
+	const op = "psql.FilterContacts"
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT contact_id FROM contacts WHERE user_id = $1 AND contact_id = ANY($2)",
+		userId, pq.Array(candidateIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var contactIds []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		contactIds = append(contactIds, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return contactIds, nil
+}
+
+// saveContactPair сохраняет пользователей в контактах друг друга в рамках транзакции tx.
+func saveContactPair(ctx context.Context, tx *sql.Tx, userId, contactId int64) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO contacts (user_id, contact_id) VALUES ($1, $2), ($2, $1) 
+		ON CONFLICT DO NOTHING`,
+		userId, contactId)
+
+	return err
+}
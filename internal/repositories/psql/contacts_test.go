@@ -0,0 +1,725 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/lib/pq"
+)
+
+var (
+	TestContactId int64 = 2
+	TestContact         = models.Contact{
+		User: models.User{
+			Id:       TestContactId,
+			Username: "user2",
+			IsActive: true,
+		},
+		Since: TestExpiresAt,
+	}
+)
+
+// newTestContactRows возвращает строки результата запроса контактов.
+func newTestContactRows(contacts ...models.Contact) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "pass_hash", "is_active", "display_name", "bio",
+		"avatar_url", "locale", "time_zone", "created_at", "updated_at", "since",
+	})
+	for _, c := range contacts {
+		u := c.User
+		rows.AddRow(
+			u.Id, u.Username, u.PasswordHash, u.IsActive, u.DisplayName, u.Bio,
+			u.AvatarURL, u.Locale, u.TimeZone, u.CreatedAt, u.UpdatedAt, c.Since,
+		)
+	}
+
+	return rows
+}
+
+func TestRepository_SaveContactRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx        context.Context
+		fromUserId int64
+		toUserId   int64
+	}
+	type mockBehavior func(ctx context.Context, fromUserId, toUserId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         bool
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM user_blocks").
+					WithArgs(toUserId, fromUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM contacts").
+					WithArgs(fromUserId, toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(toUserId, fromUserId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO contact_requests").
+					WithArgs(fromUserId, toUserId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "MutualRequest",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM user_blocks").
+					WithArgs(toUserId, fromUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM contacts").
+					WithArgs(fromUserId, toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(toUserId, fromUserId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO contacts").
+					WithArgs(fromUserId, toUserId).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+
+				mock.ExpectCommit()
+			},
+			want: true,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "Blocked",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM user_blocks").
+					WithArgs(toUserId, fromUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "AlreadyContacts",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM user_blocks").
+					WithArgs(toUserId, fromUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM contacts").
+					WithArgs(fromUserId, toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "RequestExists",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT true FROM users").
+					WithArgs(toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM user_blocks").
+					WithArgs(toUserId, fromUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM contacts").
+					WithArgs(fromUserId, toUserId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(toUserId, fromUserId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO contact_requests").
+					WithArgs(fromUserId, toUserId).
+					WillReturnError(&pq.Error{Code: "23505"})
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.fromUserId, tt.args.toUserId)
+
+			got, err := rep.SaveContactRequest(tt.args.ctx, tt.args.fromUserId, tt.args.toUserId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SaveContactRequest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.SaveContactRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_AcceptContactRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx        context.Context
+		userId     int64
+		fromUserId int64
+	}
+	type mockBehavior func(ctx context.Context, userId, fromUserId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, userId, fromUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(fromUserId, userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO contacts").
+					WithArgs(userId, fromUserId).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "RequestNotFound",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, userId, fromUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(fromUserId, userId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "ErrorInsert",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, userId, fromUserId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(fromUserId, userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO contacts").
+					WithArgs(userId, fromUserId).
+					WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.fromUserId)
+
+			if err := rep.AcceptContactRequest(tt.args.ctx, tt.args.userId, tt.args.fromUserId); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.AcceptContactRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteContactRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx        context.Context
+		fromUserId int64
+		toUserId   int64
+	}
+	type mockBehavior func(ctx context.Context, fromUserId, toUserId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(fromUserId, toUserId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:        context.Background(),
+				fromUserId: TestUserId,
+				toUserId:   TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, fromUserId, toUserId int64) {
+				mock.ExpectExec("DELETE FROM contact_requests").
+					WithArgs(fromUserId, toUserId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.fromUserId, tt.args.toUserId)
+
+			if err := rep.DeleteContactRequest(tt.args.ctx, tt.args.fromUserId, tt.args.toUserId); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteContactRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteContact(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		contactId int64
+	}
+	type mockBehavior func(ctx context.Context, userId, contactId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, userId, contactId int64) {
+				mock.ExpectExec("DELETE FROM contacts").
+					WithArgs(userId, contactId).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, userId, contactId int64) {
+				mock.ExpectExec("DELETE FROM contacts").
+					WithArgs(userId, contactId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.contactId)
+
+			if err := rep.DeleteContact(tt.args.ctx, tt.args.userId, tt.args.contactId); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteContact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_ListContacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx     context.Context
+		userId  int64
+		afterId int64
+		limit   int
+	}
+	type mockBehavior func(ctx context.Context, userId, afterId int64, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.Contact
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				limit:  10,
+			},
+			mockBehavior: func(ctx context.Context, userId, afterId int64, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM contacts c JOIN users u").
+					WithArgs(userId, afterId, limit).
+					WillReturnRows(newTestContactRows(TestContact))
+			},
+			want: []models.Contact{TestContact},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				afterId: TestContactId,
+				limit:   10,
+			},
+			mockBehavior: func(ctx context.Context, userId, afterId int64, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM contacts c JOIN users u").
+					WithArgs(userId, afterId, limit).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.afterId, tt.args.limit)
+
+			got, err := rep.ListContacts(tt.args.ctx, tt.args.userId, tt.args.afterId, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ListContacts() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.ListContacts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_ListContactRequests(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	request := models.ContactRequest{FromUserId: TestContactId, ToUserId: TestUserId, CreatedAt: TestExpiresAt}
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		incoming bool
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.ContactRequest
+		wantErr      bool
+	}{
+		{
+			name: "Incoming",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				incoming: true,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"from_user_id", "to_user_id", "created_at"}).
+					AddRow(request.FromUserId, request.ToUserId, request.CreatedAt)
+				mock.ExpectQuery("SELECT (.+) FROM contact_requests WHERE to_user_id").WithArgs(userId).WillReturnRows(rows)
+			},
+			want: []models.ContactRequest{request},
+		},
+		{
+			name: "Outgoing",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"from_user_id", "to_user_id", "created_at"})
+				mock.ExpectQuery("SELECT (.+) FROM contact_requests WHERE from_user_id").WithArgs(userId).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				incoming: true,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectQuery("SELECT (.+) FROM contact_requests").WithArgs(userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.ListContactRequests(tt.args.ctx, tt.args.userId, tt.args.incoming)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ListContactRequests() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.ListContactRequests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_IsContact(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		contactId int64
+	}
+	type mockBehavior func(ctx context.Context, userId, contactId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         bool
+		wantErr      bool
+	}{
+		{
+			name: "Contacts",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, userId, contactId int64) {
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM contacts").
+					WithArgs(userId, contactId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			want: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(ctx context.Context, userId, contactId int64) {
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM contacts").
+					WithArgs(userId, contactId).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.contactId)
+
+			got, err := rep.IsContact(tt.args.ctx, tt.args.userId, tt.args.contactId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.IsContact() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.IsContact() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_FilterContacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx          context.Context
+		userId       int64
+		candidateIds []int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64, candidateIds []int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []int64
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:          context.Background(),
+				userId:       TestUserId,
+				candidateIds: []int64{TestContactId, 3},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, candidateIds []int64) {
+				mock.ExpectQuery("SELECT contact_id FROM contacts").
+					WithArgs(userId, pq.Array(candidateIds)).
+					WillReturnRows(sqlmock.NewRows([]string{"contact_id"}).AddRow(TestContactId))
+			},
+			want: []int64{TestContactId},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:          context.Background(),
+				userId:       TestUserId,
+				candidateIds: []int64{TestContactId},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, candidateIds []int64) {
+				mock.ExpectQuery("SELECT contact_id FROM contacts").
+					WithArgs(userId, pq.Array(candidateIds)).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.candidateIds)
+
+			got, err := rep.FilterContacts(tt.args.ctx, tt.args.userId, tt.args.candidateIds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.FilterContacts() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.FilterContacts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// События журнала erasure_audit.
+const (
+	erasureEventScheduled = "scheduled"
+	erasureEventCancelled = "cancelled"
+	erasureEventErased    = "erased"
+)
+
+// ScheduleUserDeletion переводит пользователя в статус 'неактивен', завершает все его сессии, удаляет устройства
+// и назначает окончательное удаление на deleteAfter. Запрос фиксируется в журнале erasure_audit,
+// в outbox сохраняется событие деактивации, если пользователь был активен.
+// Если пользователь с таким id не найден, возвращает ошибку repository.ErrUserNotFound.
+// Если удаление уже назначено, возвращает ошибку repository.ErrDeletionScheduled.
+func (r *Repository) ScheduleUserDeletion(ctx context.Context, userId int64, deleteAfter time.Time) error {
+	const op = "psql.ScheduleUserDeletion"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	var scheduled, isActive bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT delete_after IS NOT NULL, is_active FROM users WHERE id = $1 FOR UPDATE",
+		userId).Scan(&scheduled, &isActive)
+	if err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if scheduled {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, repository.ErrDeletionScheduled)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET is_active = FALSE, delete_after = $1, updated_at = now() WHERE id = $2",
+		deleteAfter, userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM devices WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO erasure_audit (user_id, event, delete_after) VALUES ($1, $2, $3)",
+		userId, erasureEventScheduled, deleteAfter)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Пользователь, уже переведенный в статус 'неактивен', не порождает повторного события деактивации
+	if isActive {
+		if err = saveEvent(ctx, tx, models.EventUserDeactivated, userEventPayload{UserId: userId}); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// CancelUserDeletion отменяет назначенное удаление пользователя и возвращает ему статус 'активен'.
+// Отмена фиксируется в журнале erasure_audit, в outbox сохраняется событие активации.
+// Если пользователь не найден или его удаление не назначено, возвращает ошибку repository.ErrDeletionNotScheduled.
+func (r *Repository) CancelUserDeletion(ctx context.Context, userId int64) error {
+	const op = "psql.CancelUserDeletion"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE users SET is_active = TRUE, delete_after = NULL, updated_at = now() 
+		WHERE id = $1 AND delete_after IS NOT NULL`,
+		userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = checkAffected(op, res, repository.ErrDeletionNotScheduled); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO erasure_audit (user_id, event) VALUES ($1, $2)",
+		userId, erasureEventCancelled)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = saveEvent(ctx, tx, models.EventUserActivated, userEventPayload{UserId: userId}); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeDeletedUsers окончательно удаляет не более limit пользователей, срок удаления которых наступил,
+// и фиксирует удаление в журнале erasure_audit, а событие удаления - в outbox и журнале изменений user_changes.
+// Зависимые данные удаляются каскадно, прежние изменения пользователя удаляются из журнала user_changes.
+// Строки, заблокированные параллельной очисткой или отменой удаления, пропускаются.
+// Возвращает удаленных пользователей.
+func (r *Repository) PurgeDeletedUsers(ctx context.Context, limit int) ([]models.ErasedUser, error) {
+	const op = "psql.PurgeDeletedUsers"
+
+	//Удаление и записи в журналы и outbox выполняются одним запросом, поэтому они не расходятся с таблицей users
+	rows, err := r.db.QueryContext(ctx,
+		`WITH erased AS (
+			DELETE FROM users WHERE id IN (
+				SELECT id FROM users 
+				WHERE delete_after <= now() AND is_active = false 
+				ORDER BY delete_after 
+				LIMIT $1 
+				FOR UPDATE SKIP LOCKED
+			) RETURNING id, username_canonical, delete_after
+		), audit AS (
+			INSERT INTO erasure_audit (user_id, event, delete_after) 
+			SELECT id, $2, delete_after FROM erased
+		), events AS (
+			INSERT INTO outbox (event_type, user_id, payload) 
+			SELECT $3, id, json_build_object('user_id', id) FROM erased
+		), forgotten AS (
+			DELETE FROM user_changes WHERE user_id IN (SELECT id FROM erased)
+		), changes AS (
+			INSERT INTO user_changes (event_type, user_id, payload) 
+			SELECT $3, id, json_build_object('user_id', id) FROM erased
+		) SELECT id, username_canonical FROM erased`,
+		limit, erasureEventErased, models.EventUserDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var erased []models.ErasedUser
+	for rows.Next() {
+		var user models.ErasedUser
+		if err = rows.Scan(&user.UserId, &user.Canonical); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		erased = append(erased, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return erased, nil
+}
+
+// GetErasureEvents получает записи журнала удаления пользователя в порядке их появления.
+func (r *Repository) GetErasureEvents(ctx context.Context, userId int64) ([]models.ErasureEvent, error) {
+	const op = "psql.GetErasureEvents"
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT event, delete_after, created_at FROM erasure_audit WHERE user_id = $1 ORDER BY id",
+		userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.ErasureEvent
+	for rows.Next() {
+		var event models.ErasureEvent
+		var deleteAfter sql.NullTime
+		if err = rows.Scan(&event.Event, &deleteAfter, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		event.DeleteAfter = deleteAfter.Time
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return events, nil
+}
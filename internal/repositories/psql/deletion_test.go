@@ -0,0 +1,391 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+func TestRepository_ScheduleUserDeletion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx         context.Context
+		userId      int64
+		deleteAfter time.Time
+	}
+	type mockBehavior func(ctx context.Context, userId int64, deleteAfter time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				deleteAfter: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, deleteAfter time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"scheduled", "is_active"}).AddRow(false, true)
+				mock.ExpectQuery("SELECT delete_after IS NOT NULL, is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec("UPDATE users SET is_active = FALSE, delete_after").
+					WithArgs(deleteAfter, userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM devices").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO erasure_audit").
+					WithArgs(userId, erasureEventScheduled, deleteAfter).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserDeactivated, userId, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "AlreadyInactive",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				deleteAfter: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, deleteAfter time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"scheduled", "is_active"}).AddRow(false, false)
+				mock.ExpectQuery("SELECT delete_after IS NOT NULL, is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec("UPDATE users SET is_active = FALSE, delete_after").
+					WithArgs(deleteAfter, userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM devices").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO erasure_audit").
+					WithArgs(userId, erasureEventScheduled, deleteAfter).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				deleteAfter: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, deleteAfter time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"scheduled", "is_active"})
+				mock.ExpectQuery("SELECT delete_after IS NOT NULL, is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "AlreadyScheduled",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				deleteAfter: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, deleteAfter time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"scheduled", "is_active"}).AddRow(true, false)
+				mock.ExpectQuery("SELECT delete_after IS NOT NULL, is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "ErrorAudit",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				deleteAfter: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, deleteAfter time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"scheduled", "is_active"}).AddRow(false, true)
+				mock.ExpectQuery("SELECT delete_after IS NOT NULL, is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec("UPDATE users SET is_active = FALSE, delete_after").
+					WithArgs(deleteAfter, userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM devices").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO erasure_audit").
+					WithArgs(userId, erasureEventScheduled, deleteAfter).
+					WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.deleteAfter)
+
+			if err := rep.ScheduleUserDeletion(tt.args.ctx, tt.args.userId, tt.args.deleteAfter); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ScheduleUserDeletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_CancelUserDeletion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("UPDATE users SET is_active = TRUE, delete_after = NULL").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO erasure_audit").
+					WithArgs(userId, erasureEventCancelled).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserActivated, userId, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "NotScheduled",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("UPDATE users SET is_active = TRUE, delete_after = NULL").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "ErrorAudit",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("UPDATE users SET is_active = TRUE, delete_after = NULL").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO erasure_audit").
+					WithArgs(userId, erasureEventCancelled).
+					WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			if err := rep.CancelUserDeletion(tt.args.ctx, tt.args.userId); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.CancelUserDeletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_PurgeDeletedUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx   context.Context
+		limit int
+	}
+	type mockBehavior func(ctx context.Context, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.ErasedUser
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				rows := sqlmock.NewRows([]string{"id", "username_canonical"}).
+					AddRow(TestUserId, TestCanonical).
+					AddRow(TestUserId+1, "user2")
+				mock.ExpectQuery("DELETE FROM users (.+) DELETE FROM user_changes (.+) INSERT INTO user_changes").WithArgs(limit, erasureEventErased, models.EventUserDeleted).WillReturnRows(rows)
+			},
+			want: []models.ErasedUser{
+				{UserId: TestUserId, Canonical: TestCanonical},
+				{UserId: TestUserId + 1, Canonical: "user2"},
+			},
+		},
+		{
+			name: "Empty",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				rows := sqlmock.NewRows([]string{"id", "username_canonical"})
+				mock.ExpectQuery("DELETE FROM users (.+) DELETE FROM user_changes (.+) INSERT INTO user_changes").WithArgs(limit, erasureEventErased, models.EventUserDeleted).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				mock.ExpectQuery("DELETE FROM users").WithArgs(limit, erasureEventErased, models.EventUserDeleted).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.limit)
+
+			got, err := rep.PurgeDeletedUsers(tt.args.ctx, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.PurgeDeletedUsers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.PurgeDeletedUsers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_GetErasureEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	createdAt := TestExpiresAt.AddDate(0, -1, 0)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.ErasureEvent
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"event", "delete_after", "created_at"}).
+					AddRow(erasureEventScheduled, TestExpiresAt, createdAt).
+					AddRow(erasureEventCancelled, nil, createdAt)
+				mock.ExpectQuery("SELECT (.+) FROM erasure_audit").WithArgs(userId).WillReturnRows(rows)
+			},
+			want: []models.ErasureEvent{
+				{Event: erasureEventScheduled, DeleteAfter: TestExpiresAt, CreatedAt: createdAt},
+				{Event: erasureEventCancelled, CreatedAt: createdAt},
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectQuery("SELECT (.+) FROM erasure_audit").WithArgs(userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.GetErasureEvents(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetErasureEvents() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetErasureEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// SaveDevice сохраняет устройство активного пользователя и возвращает его с заполненными id и временем.
+// Если устройство с таким push токеном уже есть, оно переходит к пользователю device.UserId и обновляется,
+// при смене владельца время создания устройства сбрасывается.
+// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+func (r *Repository) SaveDevice(ctx context.Context, device models.Device) (models.Device, error) {
+	const op = "psql.SaveDevice"
+
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO devices (user_id, platform, push_token, app_version) 
+		SELECT id, $2, $3, $4 FROM users WHERE id = $1 AND is_active = true 
+		ON CONFLICT (push_token) DO UPDATE 
+		SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, app_version = EXCLUDED.app_version, 
+		created_at = CASE WHEN devices.user_id = EXCLUDED.user_id THEN devices.created_at ELSE now() END, 
+		last_active_at = now() 
+		RETURNING id, created_at, last_active_at`,
+		device.UserId, device.Platform, device.PushToken, device.AppVersion).
+		Scan(&device.Id, &device.CreatedAt, &device.LastActiveAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Device{}, fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return models.Device{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return device, nil
+}
+
+// DeleteDevice удаляет устройство deviceId пользователя userId.
+// Если устройство не найдено, возвращает ошибку repository.ErrDeviceNotFound.
+func (r *Repository) DeleteDevice(ctx context.Context, userId, deviceId int64) error {
+	const op = "psql.DeleteDevice"
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM devices WHERE id = $1 AND user_id = $2", deviceId, userId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrDeviceNotFound)
+}
+
+// ListDevices возвращает все устройства пользователя, начиная с последнего активного.
+func (r *Repository) ListDevices(ctx context.Context, userId int64) ([]models.Device, error) {
+	const op = "psql.ListDevices"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, platform, push_token, app_version, created_at, last_active_at 
+		FROM devices 
+		WHERE user_id = $1 
+		ORDER BY last_active_at DESC`,
+		userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		var d models.Device
+		err = rows.Scan(&d.Id, &d.UserId, &d.Platform, &d.PushToken, &d.AppVersion, &d.CreatedAt, &d.LastActiveAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		devices = append(devices, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return devices, nil
+}
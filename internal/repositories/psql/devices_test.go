@@ -0,0 +1,251 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+var TestDevice = models.Device{
+	Id:           3,
+	UserId:       TestUserId,
+	Platform:     models.DevicePlatformIOS,
+	PushToken:    "push-token",
+	AppVersion:   "1.2.0",
+	CreatedAt:    TestExpiresAt,
+	LastActiveAt: TestExpiresAt,
+}
+
+func TestRepository_SaveDevice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	input := models.Device{
+		UserId:     TestDevice.UserId,
+		Platform:   TestDevice.Platform,
+		PushToken:  TestDevice.PushToken,
+		AppVersion: TestDevice.AppVersion,
+	}
+
+	type args struct {
+		ctx    context.Context
+		device models.Device
+	}
+	type mockBehavior func(ctx context.Context, device models.Device)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.Device
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				device: input,
+			},
+			mockBehavior: func(ctx context.Context, device models.Device) {
+				mock.ExpectQuery("INSERT INTO devices (.+) ON CONFLICT \\(push_token\\) DO UPDATE").
+					WithArgs(device.UserId, device.Platform, device.PushToken, device.AppVersion).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "last_active_at"}).
+						AddRow(TestDevice.Id, TestDevice.CreatedAt, TestDevice.LastActiveAt))
+			},
+			want: TestDevice,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:    context.Background(),
+				device: input,
+			},
+			mockBehavior: func(ctx context.Context, device models.Device) {
+				mock.ExpectQuery("INSERT INTO devices").
+					WithArgs(device.UserId, device.Platform, device.PushToken, device.AppVersion).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				device: input,
+			},
+			mockBehavior: func(ctx context.Context, device models.Device) {
+				mock.ExpectQuery("INSERT INTO devices").
+					WithArgs(device.UserId, device.Platform, device.PushToken, device.AppVersion).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.device)
+
+			got, err := rep.SaveDevice(tt.args.ctx, tt.args.device)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SaveDevice() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.SaveDevice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteDevice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		deviceId int64
+	}
+	type mockBehavior func(ctx context.Context, userId, deviceId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				deviceId: TestDevice.Id,
+			},
+			mockBehavior: func(ctx context.Context, userId, deviceId int64) {
+				mock.ExpectExec("DELETE FROM devices").WithArgs(deviceId, userId).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				deviceId: TestDevice.Id,
+			},
+			mockBehavior: func(ctx context.Context, userId, deviceId int64) {
+				mock.ExpectExec("DELETE FROM devices").WithArgs(deviceId, userId).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				deviceId: TestDevice.Id,
+			},
+			mockBehavior: func(ctx context.Context, userId, deviceId int64) {
+				mock.ExpectExec("DELETE FROM devices").WithArgs(deviceId, userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.deviceId)
+
+			if err := rep.DeleteDevice(tt.args.ctx, tt.args.userId, tt.args.deviceId); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteDevice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_ListDevices(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	columns := []string{"id", "user_id", "platform", "push_token", "app_version", "created_at", "last_active_at"}
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.Device
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				d := TestDevice
+				mock.ExpectQuery("SELECT (.+) FROM devices").
+					WithArgs(userId).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(d.Id, d.UserId, d.Platform, d.PushToken, d.AppVersion, d.CreatedAt, d.LastActiveAt))
+			},
+			want: []models.Device{TestDevice},
+		},
+		{
+			name: "Empty",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectQuery("SELECT (.+) FROM devices").WithArgs(userId).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectQuery("SELECT (.+) FROM devices").WithArgs(userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.ListDevices(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ListDevices() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.ListDevices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
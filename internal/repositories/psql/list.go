@@ -0,0 +1,67 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// ListUsers получает пользователей, подходящих под условия filter, упорядоченных по id.
+// Если desc = true, пользователи упорядочены по убыванию id.
+// Если afterId не равен 0, возвращаются пользователи, следующие после пользователя с этим id.
+// Возвращает не более limit пользователей.
+func (r *Repository) ListUsers(ctx context.Context, filter models.UserFilter, desc bool, afterId int64, limit int) ([]models.User, error) {
+	const op = "psql.ListUsers"
+
+	var conditions []string
+	var args []any
+	addCondition := func(format string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.IsActive != nil {
+		addCondition("u.is_active = $%d", *filter.IsActive)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		addCondition("u.created_at >= $%d", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		addCondition("u.created_at < $%d", filter.CreatedBefore)
+	}
+	if filter.UsernamePrefix != "" {
+		addCondition("u.username_canonical LIKE $%d", likeEscaper.Replace(filter.UsernamePrefix)+"%")
+	}
+
+	order, next := "ASC", "u.id > $%d"
+	if desc {
+		order, next = "DESC", "u.id < $%d"
+	}
+	if afterId != 0 {
+		addCondition(next, afterId)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+
+	//Выборка по условию на id вместо OFFSET, чтобы время запроса не зависело от номера страницы
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT %s FROM users u %s ORDER BY u.id %s LIMIT $%d", userColumns, where, order, len(args)),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return users, nil
+}
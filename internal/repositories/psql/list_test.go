@@ -0,0 +1,135 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+func TestRepository_ListUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	active := true
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type args struct {
+		ctx     context.Context
+		filter  models.UserFilter
+		desc    bool
+		afterId int64
+		limit   int
+	}
+	type mockBehavior func(ctx context.Context)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.User
+		wantErr      bool
+	}{
+		{
+			name: "NoFilter",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context) {
+				mock.ExpectQuery(`SELECT (.+) FROM users u\s+ORDER BY u.id ASC LIMIT \$1`).
+					WithArgs(10).
+					WillReturnRows(newTestUserRows(TestUser))
+			},
+			want: []models.User{TestUser},
+		},
+		{
+			name: "AllFilters",
+			args: args{
+				ctx: context.Background(),
+				filter: models.UserFilter{
+					IsActive:       &active,
+					CreatedAfter:   createdAfter,
+					CreatedBefore:  createdBefore,
+					UsernamePrefix: "us_",
+				},
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context) {
+				mock.ExpectQuery(`WHERE u.is_active = \$1 AND u.created_at >= \$2 AND u.created_at < \$3 `+
+					`AND u.username_canonical LIKE \$4 ORDER BY u.id ASC LIMIT \$5`).
+					WithArgs(active, createdAfter, createdBefore, `us\_%`, 10).
+					WillReturnRows(newTestUserRows(TestUser))
+			},
+			want: []models.User{TestUser},
+		},
+		{
+			name: "NextPageAsc",
+			args: args{
+				ctx:     context.Background(),
+				afterId: 5,
+				limit:   10,
+			},
+			mockBehavior: func(ctx context.Context) {
+				mock.ExpectQuery(`WHERE u.id > \$1 ORDER BY u.id ASC LIMIT \$2`).
+					WithArgs(int64(5), 10).
+					WillReturnRows(newTestUserRows())
+			},
+		},
+		{
+			name: "NextPageDesc",
+			args: args{
+				ctx:     context.Background(),
+				filter:  models.UserFilter{IsActive: &active},
+				desc:    true,
+				afterId: 5,
+				limit:   10,
+			},
+			mockBehavior: func(ctx context.Context) {
+				mock.ExpectQuery(`WHERE u.is_active = \$1 AND u.id < \$2 ORDER BY u.id DESC LIMIT \$3`).
+					WithArgs(active, int64(5), 10).
+					WillReturnRows(newTestUserRows(TestUser))
+			},
+			want: []models.User{TestUser},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context) {
+				mock.ExpectQuery("SELECT (.+) FROM users u").
+					WithArgs(10).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx)
+
+			got, err := rep.ListUsers(tt.args.ctx, tt.args.filter, tt.args.desc, tt.args.afterId, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ListUsers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.ListUsers() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+// SaveTOTPSecret сохраняет зашифрованный секрет TOTP пользователя, ожидающий подтверждения.
+// Неподтвержденный секрет, сохраненный ранее, заменяется.
+// Если у пользователя уже подтвержден TOTP, возвращает ошибку repository.ErrTOTPAlreadyConfirmed.
+func (r *Repository) SaveTOTPSecret(ctx context.Context, userId int64, secret []byte) error {
+	const op = "psql.SaveTOTPSecret"
+
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_totp (user_id, secret) VALUES ($1, $2) 
+		ON CONFLICT (user_id) DO UPDATE 
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = now() 
+		WHERE user_totp.confirmed_at IS NULL`,
+		userId, secret)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrTOTPAlreadyConfirmed)
+}
+
+// GetTOTP получает второй фактор пользователя.
+// Если TOTP не настроен, возвращает ошибку repository.ErrTOTPNotFound.
+func (r *Repository) GetTOTP(ctx context.Context, userId int64) (models.TOTP, error) {
+	const op = "psql.GetTOTP"
+
+	row := r.db.QueryRowContext(ctx,
+		"SELECT user_id, secret, confirmed_at IS NOT NULL, last_used_step FROM user_totp WHERE user_id = $1",
+		userId)
+
+	var totp models.TOTP
+	err := row.Scan(&totp.UserId, &totp.Secret, &totp.Confirmed, &totp.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTP{}, fmt.Errorf("%s, %w", op, repository.ErrTOTPNotFound)
+		}
+
+		return models.TOTP{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return totp, nil
+}
+
+// TOTPEnabled сообщает, подтвержден ли у пользователя TOTP.
+func (r *Repository) TOTPEnabled(ctx context.Context, userId int64) (bool, error) {
+	const op = "psql.TOTPEnabled"
+
+	row := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM user_totp WHERE user_id = $1 AND confirmed_at IS NOT NULL)",
+		userId)
+
+	var enabled bool
+	if err := row.Scan(&enabled); err != nil {
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return enabled, nil
+}
+
+// ConfirmTOTP подтверждает TOTP пользователя, запоминает использованный шаг и заменяет коды восстановления.
+// Если неподтвержденный TOTP не найден, возвращает ошибку repository.ErrTOTPNotFound.
+func (r *Repository) ConfirmTOTP(ctx context.Context, userId int64, step int64, recoveryCodeHashes [][]byte) error {
+	const op = "psql.ConfirmTOTP"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE user_totp SET confirmed_at = now(), last_used_step = $1 WHERE user_id = $2 AND confirmed_at IS NULL",
+		step, userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = checkAffected(op, res, repository.ErrTOTPNotFound); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, unnest($2::bytea[])",
+		userId, pq.Array(recoveryCodeHashes))
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep запоминает шаг, код которого принят при входе.
+// Если подтвержденный TOTP не найден или код этого шага уже использован,
+// возвращает ошибку repository.ErrTOTPNotFound.
+func (r *Repository) UseTOTPStep(ctx context.Context, userId int64, step int64) error {
+	const op = "psql.UseTOTPStep"
+
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_totp SET last_used_step = $1 
+		WHERE user_id = $2 
+			AND confirmed_at IS NOT NULL 
+			AND last_used_step < $1`,
+		step, userId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrTOTPNotFound)
+}
+
+// UseRecoveryCode погашает код восстановления пользователя.
+// Если код не найден или уже использован, возвращает ошибку repository.ErrRecoveryCodeNotFound.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userId int64, codeHash []byte) error {
+	const op = "psql.UseRecoveryCode"
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+		userId, codeHash)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrRecoveryCodeNotFound)
+}
+
+// DeleteTOTP отключает второй фактор пользователя: удаляет секрет, коды восстановления и незавершенные входы.
+// Если TOTP не настроен, возвращает ошибку repository.ErrTOTPNotFound.
+func (r *Repository) DeleteTOTP(ctx context.Context, userId int64) error {
+	const op = "psql.DeleteTOTP"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = checkAffected(op, res, repository.ErrTOTPNotFound); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM mfa_challenges WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveChallenge сохраняет хэш токена незавершенного входа, ожидающего второй фактор.
+func (r *Repository) SaveChallenge(ctx context.Context, userId int64, tokenHash []byte, expiresAt time.Time) error {
+	const op = "psql.SaveChallenge"
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO mfa_challenges (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		userId, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// GetChallengeOwner получает активного пользователя, которому принадлежит действующий токен незавершенного входа.
+// Если токен не найден, уже использован или истек, возвращает ошибку repository.ErrChallengeNotFound.
+func (r *Repository) GetChallengeOwner(ctx context.Context, tokenHash []byte) (models.User, error) {
+	const op = "psql.GetChallengeOwner"
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` 
+		FROM mfa_challenges c 
+		JOIN users u ON u.id = c.user_id 
+		WHERE c.token_hash = $1 
+			AND c.used_at IS NULL 
+			AND c.expires_at > now() 
+			AND u.is_active = true`,
+		tokenHash)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s, %w", op, repository.ErrChallengeNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return user, nil
+}
+
+// ConsumeChallenge погашает токен незавершенного входа.
+// Если токен не найден, уже использован или истек, возвращает ошибку repository.ErrChallengeNotFound.
+func (r *Repository) ConsumeChallenge(ctx context.Context, tokenHash []byte) error {
+	const op = "psql.ConsumeChallenge"
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE mfa_challenges SET used_at = now() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()",
+		tokenHash)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrChallengeNotFound)
+}
@@ -0,0 +1,667 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+var (
+	TestTOTPSecret               = []byte("encrypted_secret")
+	TestTOTPStep           int64 = 100
+	TestRecoveryCodeHashes       = [][]byte{[]byte("code_hash_1"), []byte("code_hash_2")}
+	TestChallengeHash            = []byte("challenge_hash")
+	TestTOTP                     = models.TOTP{
+		UserId:       TestUserId,
+		Secret:       TestTOTPSecret,
+		Confirmed:    true,
+		LastUsedStep: TestTOTPStep,
+	}
+)
+
+func TestRepository_SaveTOTPSecret(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+		secret []byte
+	}
+	type mockBehavior func(ctx context.Context, userId int64, secret []byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				secret: TestTOTPSecret,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, secret []byte) {
+				mock.ExpectExec("INSERT INTO user_totp").
+					WithArgs(userId, secret).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "AlreadyConfirmed",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				secret: TestTOTPSecret,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, secret []byte) {
+				mock.ExpectExec("INSERT INTO user_totp").
+					WithArgs(userId, secret).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: repository.ErrTOTPAlreadyConfirmed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.secret)
+
+			err := rep.SaveTOTPSecret(tt.args.ctx, tt.args.userId, tt.args.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.SaveTOTPSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_GetTOTP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.TOTP
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"user_id", "secret", "confirmed", "last_used_step"}).
+					AddRow(TestTOTP.UserId, TestTOTP.Secret, TestTOTP.Confirmed, TestTOTP.LastUsedStep)
+				mock.ExpectQuery("SELECT (.+) FROM user_totp").WithArgs(userId).WillReturnRows(rows)
+			},
+			want: TestTOTP,
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"user_id", "secret", "confirmed", "last_used_step"})
+				mock.ExpectQuery("SELECT (.+) FROM user_totp").WithArgs(userId).WillReturnRows(rows)
+			},
+			wantErr: repository.ErrTOTPNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.GetTOTP(tt.args.ctx, tt.args.userId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.GetTOTP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetTOTP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_TOTPEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         bool
+		wantErr      bool
+	}{
+		{
+			name: "Enabled",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"exists"}).AddRow(true)
+				mock.ExpectQuery("SELECT EXISTS").WithArgs(userId).WillReturnRows(rows)
+			},
+			want: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectQuery("SELECT EXISTS").WithArgs(userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.TOTPEnabled(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.TOTPEnabled() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.TOTPEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_ConfirmTOTP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx        context.Context
+		userId     int64
+		step       int64
+		codeHashes [][]byte
+	}
+	type mockBehavior func(ctx context.Context, userId int64, step int64, codeHashes [][]byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				step:       TestTOTPStep,
+				codeHashes: TestRecoveryCodeHashes,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, step int64, codeHashes [][]byte) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("UPDATE user_totp SET confirmed_at").
+					WithArgs(step, userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM recovery_codes").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO recovery_codes").
+					WithArgs(userId, pq.Array(codeHashes)).
+					WillReturnResult(sqlmock.NewResult(0, int64(len(codeHashes))))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				step:       TestTOTPStep,
+				codeHashes: TestRecoveryCodeHashes,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, step int64, codeHashes [][]byte) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("UPDATE user_totp SET confirmed_at").
+					WithArgs(step, userId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrTOTPNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.step, tt.args.codeHashes)
+
+			err := rep.ConfirmTOTP(tt.args.ctx, tt.args.userId, tt.args.step, tt.args.codeHashes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.ConfirmTOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_UseTOTPStep(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+		step   int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64, step int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				step:   TestTOTPStep,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, step int64) {
+				mock.ExpectExec("UPDATE user_totp SET last_used_step").
+					WithArgs(step, userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "StepAlreadyUsed",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				step:   TestTOTPStep,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, step int64) {
+				mock.ExpectExec("UPDATE user_totp SET last_used_step").
+					WithArgs(step, userId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: repository.ErrTOTPNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.step)
+
+			err := rep.UseTOTPStep(tt.args.ctx, tt.args.userId, tt.args.step)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.UseTOTPStep() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_UseRecoveryCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		codeHash []byte
+	}
+	type mockBehavior func(ctx context.Context, userId int64, codeHash []byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				codeHash: TestRecoveryCodeHashes[0],
+			},
+			mockBehavior: func(ctx context.Context, userId int64, codeHash []byte) {
+				mock.ExpectExec("UPDATE recovery_codes SET used_at").
+					WithArgs(userId, codeHash).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				codeHash: TestRecoveryCodeHashes[0],
+			},
+			mockBehavior: func(ctx context.Context, userId int64, codeHash []byte) {
+				mock.ExpectExec("UPDATE recovery_codes SET used_at").
+					WithArgs(userId, codeHash).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: repository.ErrRecoveryCodeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.codeHash)
+
+			err := rep.UseRecoveryCode(tt.args.ctx, tt.args.userId, tt.args.codeHash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.UseRecoveryCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteTOTP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("DELETE FROM user_totp").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM recovery_codes").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectExec("DELETE FROM mfa_challenges").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectExec("DELETE FROM user_totp").
+					WithArgs(userId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrTOTPNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			err := rep.DeleteTOTP(tt.args.ctx, tt.args.userId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.DeleteTOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_SaveChallenge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		tokenHash []byte
+		expiresAt time.Time
+	}
+	type mockBehavior func(ctx context.Context, userId int64, tokenHash []byte, expiresAt time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				tokenHash: TestChallengeHash,
+				expiresAt: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, tokenHash []byte, expiresAt time.Time) {
+				mock.ExpectExec("INSERT INTO mfa_challenges").
+					WithArgs(userId, tokenHash, expiresAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				tokenHash: TestChallengeHash,
+				expiresAt: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, tokenHash []byte, expiresAt time.Time) {
+				mock.ExpectExec("INSERT INTO mfa_challenges").
+					WithArgs(userId, tokenHash, expiresAt).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.tokenHash, tt.args.expiresAt)
+
+			if err := rep.SaveChallenge(tt.args.ctx, tt.args.userId, tt.args.tokenHash, tt.args.expiresAt); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SaveChallenge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_GetChallengeOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		tokenHash []byte
+	}
+	type mockBehavior func(ctx context.Context, tokenHash []byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: TestChallengeHash,
+			},
+			mockBehavior: func(ctx context.Context, tokenHash []byte) {
+				rows := newTestUserRows(TestUser)
+				mock.ExpectQuery("SELECT (.+) FROM mfa_challenges").WithArgs(tokenHash).WillReturnRows(rows)
+			},
+			want: TestUser,
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: TestChallengeHash,
+			},
+			mockBehavior: func(ctx context.Context, tokenHash []byte) {
+				rows := newTestUserRows()
+				mock.ExpectQuery("SELECT (.+) FROM mfa_challenges").WithArgs(tokenHash).WillReturnRows(rows)
+			},
+			wantErr: repository.ErrChallengeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.tokenHash)
+
+			got, err := rep.GetChallengeOwner(tt.args.ctx, tt.args.tokenHash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.GetChallengeOwner() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetChallengeOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_ConsumeChallenge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		tokenHash []byte
+	}
+	type mockBehavior func(ctx context.Context, tokenHash []byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: TestChallengeHash,
+			},
+			mockBehavior: func(ctx context.Context, tokenHash []byte) {
+				mock.ExpectExec("UPDATE mfa_challenges SET used_at").
+					WithArgs(tokenHash).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: TestChallengeHash,
+			},
+			mockBehavior: func(ctx context.Context, tokenHash []byte) {
+				mock.ExpectExec("UPDATE mfa_challenges SET used_at").
+					WithArgs(tokenHash).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: repository.ErrChallengeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.tokenHash)
+
+			err := rep.ConsumeChallenge(tt.args.ctx, tt.args.tokenHash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.ConsumeChallenge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
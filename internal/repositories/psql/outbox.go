@@ -0,0 +1,81 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/lib/pq"
+)
+
+// userEventPayload - данные события жизненного цикла пользователя.
+type userEventPayload struct {
+	UserId   int64  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	// Changes - новые значения измененных полей профиля.
+	Changes map[string]string `json:"changes,omitempty"`
+}
+
+// GetOutboxEvents получает не более limit неопубликованных событий в порядке их появления.
+func (r *Repository) GetOutboxEvents(ctx context.Context, limit int) ([]models.Event, error) {
+	const op = "psql.GetOutboxEvents"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, idempotency_key, event_type, user_id, payload, created_at 
+		FROM outbox 
+		ORDER BY id 
+		LIMIT $1`,
+		limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		if err = rows.Scan(&e.Id, &e.IdempotencyKey, &e.Type, &e.UserId, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return events, nil
+}
+
+// DeleteOutboxEvents удаляет опубликованные события с указанными id.
+func (r *Repository) DeleteOutboxEvents(ctx context.Context, eventIds []int64) error {
+	const op = "psql.DeleteOutboxEvents"
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM outbox WHERE id = ANY($1)", pq.Array(eventIds))
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// saveEvent сохраняет событие eventType с данными payload в outbox и журнал изменений user_changes
+// в рамках транзакции tx.
+func saveEvent(ctx context.Context, tx *sql.Tx, eventType string, payload userEventPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	//JSON передается строкой: []byte драйвер передает как bytea
+	_, err = tx.ExecContext(ctx,
+		`WITH event AS (
+			INSERT INTO outbox (event_type, user_id, payload) VALUES ($1, $2, $3)
+		) INSERT INTO user_changes (event_type, user_id, payload) VALUES ($1, $2, $3)`,
+		eventType, payload.UserId, string(data))
+
+	return err
+}
@@ -0,0 +1,152 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/lib/pq"
+)
+
+var TestEvent = models.Event{
+	Id:             5,
+	IdempotencyKey: "3f2c1b8e-6a4d-4f0e-9b1a-2d7c5e8f9a01",
+	Type:           models.EventUserRegistered,
+	UserId:         TestUserId,
+	Payload:        []byte(`{"user_id":1,"username":"User"}`),
+	CreatedAt:      TestExpiresAt,
+}
+
+func TestRepository_GetOutboxEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	columns := []string{"id", "idempotency_key", "event_type", "user_id", "payload", "created_at"}
+
+	type args struct {
+		ctx   context.Context
+		limit int
+	}
+	type mockBehavior func(ctx context.Context, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.Event
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				e := TestEvent
+				mock.ExpectQuery("SELECT (.+) FROM outbox ORDER BY id").
+					WithArgs(limit).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(e.Id, e.IdempotencyKey, e.Type, e.UserId, e.Payload, e.CreatedAt))
+			},
+			want: []models.Event{TestEvent},
+		},
+		{
+			name: "Empty",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM outbox").WithArgs(limit).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM outbox").WithArgs(limit).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.limit)
+
+			got, err := rep.GetOutboxEvents(tt.args.ctx, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetOutboxEvents() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetOutboxEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteOutboxEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		eventIds []int64
+	}
+	type mockBehavior func(ctx context.Context, eventIds []int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				eventIds: []int64{5, 6},
+			},
+			mockBehavior: func(ctx context.Context, eventIds []int64) {
+				mock.ExpectExec("DELETE FROM outbox").
+					WithArgs(pq.Array(eventIds)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				eventIds: []int64{5},
+			},
+			mockBehavior: func(ctx context.Context, eventIds []int64) {
+				mock.ExpectExec("DELETE FROM outbox").WithArgs(pq.Array(eventIds)).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.eventIds)
+
+			if err := rep.DeleteOutboxEvents(tt.args.ctx, tt.args.eventIds); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteOutboxEvents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SaveLastSeen сохраняет время последней активности пользователей одним запросом.
+// Более раннее время не перезаписывает уже сохраненное, несуществующие пользователи пропускаются.
+func (r *Repository) SaveLastSeen(ctx context.Context, lastSeen map[int64]time.Time) error {
+	const op = "psql.SaveLastSeen"
+
+	userIds := make([]int64, 0, len(lastSeen))
+	seenAt := make([]string, 0, len(lastSeen))
+	for id, at := range lastSeen {
+		userIds = append(userIds, id)
+		seenAt = append(seenAt, at.UTC().Format(time.RFC3339Nano))
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users u SET last_seen_at = v.seen_at 
+		FROM unnest($1::bigint[], $2::timestamptz[]) AS v(id, seen_at) 
+		WHERE u.id = v.id AND (u.last_seen_at IS NULL OR u.last_seen_at < v.seen_at)`,
+		pq.Array(userIds), pq.Array(seenAt))
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLastSeen получает сохраненное время последней активности активных пользователей с указанными id.
+// Пользователи, которые еще не были в сети, в результат не попадают.
+func (r *Repository) GetLastSeen(ctx context.Context, userIds []int64) (map[int64]time.Time, error) {
+	const op = "psql.GetLastSeen"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, last_seen_at FROM users 
+		WHERE id = ANY($1) AND is_active = true AND last_seen_at IS NOT NULL`,
+		pq.Array(userIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	lastSeen := make(map[int64]time.Time, len(userIds))
+	for rows.Next() {
+		var (
+			id int64
+			at time.Time
+		)
+		if err = rows.Scan(&id, &at); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		lastSeen[id] = at
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return lastSeen, nil
+}
@@ -0,0 +1,133 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestRepository_SaveLastSeen(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		lastSeen map[int64]time.Time
+	}
+	type mockBehavior func(ctx context.Context, lastSeen map[int64]time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				lastSeen: map[int64]time.Time{TestUserId: TestExpiresAt},
+			},
+			mockBehavior: func(ctx context.Context, lastSeen map[int64]time.Time) {
+				mock.ExpectExec("UPDATE users u SET last_seen_at").
+					WithArgs(pq.Array([]int64{TestUserId}), pq.Array([]string{TestExpiresAt.UTC().Format(time.RFC3339Nano)})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				lastSeen: map[int64]time.Time{TestUserId: TestExpiresAt},
+			},
+			mockBehavior: func(ctx context.Context, lastSeen map[int64]time.Time) {
+				mock.ExpectExec("UPDATE users u SET last_seen_at").
+					WithArgs(pq.Array([]int64{TestUserId}), pq.Array([]string{TestExpiresAt.UTC().Format(time.RFC3339Nano)})).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.lastSeen)
+
+			if err := rep.SaveLastSeen(tt.args.ctx, tt.args.lastSeen); (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SaveLastSeen() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_GetLastSeen(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx     context.Context
+		userIds []int64
+	}
+	type mockBehavior func(ctx context.Context, userIds []int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         map[int64]time.Time
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId, 2},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				mock.ExpectQuery("SELECT id, last_seen_at FROM users").
+					WithArgs(pq.Array(userIds)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "last_seen_at"}).AddRow(TestUserId, TestExpiresAt))
+			},
+			want: map[int64]time.Time{TestUserId: TestExpiresAt},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				mock.ExpectQuery("SELECT id, last_seen_at FROM users").
+					WithArgs(pq.Array(userIds)).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userIds)
+
+			got, err := rep.GetLastSeen(tt.args.ctx, tt.args.userIds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetLastSeen() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetLastSeen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+// UpdatePrivacySettings изменяет указанные настройки приватности активного пользователя и возвращает настройки после изменения.
+// fields - имена полей из models.PrivacyField*, остальные настройки не изменяются.
+// Если пользователь еще не изменял настройки, не указанные в fields настройки получают значения по умолчанию.
+// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+func (r *Repository) UpdatePrivacySettings(ctx context.Context, userId int64, settings models.PrivacySettings, fields []string) (models.PrivacySettings, error) {
+	const op = "psql.UpdatePrivacySettings"
+
+	columns := make([]string, 0, len(fields))
+	values := make([]string, 0, len(fields))
+	set := make([]string, 0, len(fields)+1)
+	args := make([]any, 0, len(fields)+1)
+	args = append(args, userId)
+	for _, field := range fields {
+		value, err := privacyValue(settings, field)
+		if err != nil {
+			return models.PrivacySettings{}, fmt.Errorf("%s, %w", op, err)
+		}
+
+		args = append(args, value)
+		//Имя столбца совпадает с именем поля и берется из белого списка privacyValue
+		columns = append(columns, ", "+field)
+		values = append(values, fmt.Sprintf(", $%d", len(args)))
+		set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", field, field))
+	}
+	set = append(set, "updated_at = now()")
+
+	//Изменяются только указанные столбцы, поэтому параллельные изменения других настроек не теряются
+	var updated models.PrivacySettings
+	err := r.db.QueryRowContext(ctx,
+		fmt.Sprintf(
+			`INSERT INTO user_privacy_settings (user_id%s) 
+			SELECT id%s FROM users WHERE id = $1 AND is_active = true 
+			ON CONFLICT (user_id) DO UPDATE SET %s 
+			RETURNING last_seen, avatar, search`,
+			strings.Join(columns, ""), strings.Join(values, ""), strings.Join(set, ", "),
+		),
+		args...).Scan(&updated.LastSeen, &updated.Avatar, &updated.Search)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PrivacySettings{}, fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return models.PrivacySettings{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return updated, nil
+}
+
+// GetPrivacySettings получает сохраненные настройки приватности пользователей с указанными id.
+// Пользователи, которые не изменяли настройки, в результат не попадают.
+func (r *Repository) GetPrivacySettings(ctx context.Context, userIds []int64) (map[int64]models.PrivacySettings, error) {
+	const op = "psql.GetPrivacySettings"
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT user_id, last_seen, avatar, search FROM user_privacy_settings WHERE user_id = ANY($1)",
+		pq.Array(userIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	settings := make(map[int64]models.PrivacySettings, len(userIds))
+	for rows.Next() {
+		var (
+			userId int64
+			s      models.PrivacySettings
+		)
+		if err = rows.Scan(&userId, &s.LastSeen, &s.Avatar, &s.Search); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		settings[userId] = s
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return settings, nil
+}
+
+// privacyValue возвращает значение настройки приватности по имени поля.
+func privacyValue(settings models.PrivacySettings, field string) (models.Visibility, error) {
+	switch field {
+	case models.PrivacyFieldLastSeen:
+		return settings.LastSeen, nil
+	case models.PrivacyFieldAvatar:
+		return settings.Avatar, nil
+	case models.PrivacyFieldSearch:
+		return settings.Search, nil
+	default:
+		return "", fmt.Errorf("unknown privacy field %q", field)
+	}
+}
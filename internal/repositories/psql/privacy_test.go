@@ -0,0 +1,208 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+var TestPrivacySettings = models.PrivacySettings{
+	LastSeen: models.VisibilityContacts,
+	Avatar:   models.VisibilityEveryone,
+	Search:   models.VisibilityNobody,
+}
+
+func TestRepository_UpdatePrivacySettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		settings models.PrivacySettings
+		fields   []string
+	}
+	type mockBehavior func(ctx context.Context, userId int64, settings models.PrivacySettings)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.PrivacySettings
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				settings: TestPrivacySettings,
+				fields:   []string{models.PrivacyFieldLastSeen, models.PrivacyFieldSearch},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, settings models.PrivacySettings) {
+				rows := sqlmock.NewRows([]string{"last_seen", "avatar", "search"}).
+					AddRow(settings.LastSeen, settings.Avatar, settings.Search)
+				mock.ExpectQuery(`INSERT INTO user_privacy_settings \(user_id, last_seen, search\)\s+SELECT id, \$2, \$3 FROM users WHERE id = \$1 AND is_active = true\s+`+
+					`ON CONFLICT \(user_id\) DO UPDATE SET last_seen = EXCLUDED.last_seen, search = EXCLUDED.search, updated_at = now\(\)`).
+					WithArgs(userId, settings.LastSeen, settings.Search).
+					WillReturnRows(rows)
+			},
+			want: TestPrivacySettings,
+		},
+		{
+			name: "EmptyFields",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				settings: TestPrivacySettings,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, settings models.PrivacySettings) {
+				rows := sqlmock.NewRows([]string{"last_seen", "avatar", "search"}).
+					AddRow(settings.LastSeen, settings.Avatar, settings.Search)
+				mock.ExpectQuery(`INSERT INTO user_privacy_settings \(user_id\)\s+SELECT id FROM users WHERE id = \$1 AND is_active = true\s+` +
+					`ON CONFLICT \(user_id\) DO UPDATE SET updated_at = now\(\)`).
+					WithArgs(userId).
+					WillReturnRows(rows)
+			},
+			want: TestPrivacySettings,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				settings: TestPrivacySettings,
+				fields:   []string{models.PrivacyFieldAvatar},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, settings models.PrivacySettings) {
+				rows := sqlmock.NewRows([]string{"last_seen", "avatar", "search"})
+				mock.ExpectQuery("INSERT INTO user_privacy_settings").
+					WithArgs(userId, settings.Avatar).
+					WillReturnRows(rows)
+			},
+			wantErr: repository.ErrUserNotFound,
+		},
+		{
+			name: "UnknownField",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				settings: TestPrivacySettings,
+				fields:   []string{"user_id"},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, settings models.PrivacySettings) {},
+			wantErr:      errors.New(""),
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				settings: TestPrivacySettings,
+				fields:   []string{models.PrivacyFieldAvatar},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, settings models.PrivacySettings) {
+				mock.ExpectQuery("INSERT INTO user_privacy_settings").
+					WithArgs(userId, settings.Avatar).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.settings)
+
+			got, err := rep.UpdatePrivacySettings(tt.args.ctx, tt.args.userId, tt.args.settings, tt.args.fields)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("Repository.UpdatePrivacySettings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if errors.Is(tt.wantErr, repository.ErrUserNotFound) && !errors.Is(err, repository.ErrUserNotFound) {
+				t.Errorf("Repository.UpdatePrivacySettings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.UpdatePrivacySettings() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestRepository_GetPrivacySettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx     context.Context
+		userIds []int64
+	}
+	type mockBehavior func(ctx context.Context, userIds []int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         map[int64]models.PrivacySettings
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId, 2},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				mock.ExpectQuery("SELECT user_id, last_seen, avatar, search FROM user_privacy_settings").
+					WithArgs(pq.Array(userIds)).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "last_seen", "avatar", "search"}).
+						AddRow(TestUserId, "contacts", "everyone", "nobody"))
+			},
+			want: map[int64]models.PrivacySettings{TestUserId: TestPrivacySettings},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				mock.ExpectQuery("SELECT user_id, last_seen, avatar, search FROM user_privacy_settings").
+					WithArgs(pq.Array(userIds)).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userIds)
+
+			got, err := rep.GetPrivacySettings(tt.args.ctx, tt.args.userIds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetPrivacySettings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetPrivacySettings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// UpdateProfile изменяет указанные поля профиля активного пользователя и возвращает пользователя после изменения.
+// fields - имена полей из models.ProfileField*, остальные поля профиля не изменяются.
+// Вместе с изменением в outbox сохраняется событие изменения профиля с новыми значениями полей.
+// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+func (r *Repository) UpdateProfile(ctx context.Context, userId int64, profile models.Profile, fields []string) (models.User, error) {
+	const op = "psql.UpdateProfile"
+
+	set := make([]string, 0, len(fields)+1)
+	args := make([]any, 0, len(fields)+1)
+	changes := make(map[string]string, len(fields))
+	for _, field := range fields {
+		value, err := profileValue(profile, field)
+		if err != nil {
+			return models.User{}, fmt.Errorf("%s, %w", op, err)
+		}
+
+		args = append(args, value)
+		changes[field] = value
+		//Имя столбца совпадает с именем поля и берется из белого списка profileValue
+		set = append(set, fmt.Sprintf("%s = $%d", field, len(args)))
+	}
+	set = append(set, "updated_at = now()")
+	args = append(args, userId)
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	row := tx.QueryRowContext(ctx,
+		fmt.Sprintf(
+			"UPDATE users u SET %s WHERE u.id = $%d AND u.is_active = true RETURNING %s",
+			strings.Join(set, ", "), len(args), userColumns,
+		),
+		args...)
+
+	user, err := scanUser(row)
+	if err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	err = saveEvent(ctx, tx, models.EventUserProfileUpdated, userEventPayload{
+		UserId:   user.Id,
+		Username: user.Username,
+		Changes:  changes,
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return user, nil
+}
+
+// profileValue возвращает значение поля профиля по его имени.
+func profileValue(profile models.Profile, field string) (string, error) {
+	switch field {
+	case models.ProfileFieldDisplayName:
+		return profile.DisplayName, nil
+	case models.ProfileFieldBio:
+		return profile.Bio, nil
+	case models.ProfileFieldAvatarURL:
+		return profile.AvatarURL, nil
+	case models.ProfileFieldLocale:
+		return profile.Locale, nil
+	case models.ProfileFieldTimeZone:
+		return profile.TimeZone, nil
+	default:
+		return "", fmt.Errorf("unknown profile field %q", field)
+	}
+}
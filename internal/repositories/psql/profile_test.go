@@ -0,0 +1,174 @@
+package psql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+func TestRepository_UpdateProfile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx     context.Context
+		userId  int64
+		profile models.Profile
+		fields  []string
+	}
+	type mockBehavior func(ctx context.Context, userId int64, profile models.Profile)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestUser.Profile,
+				fields:  []string{models.ProfileFieldDisplayName, models.ProfileFieldTimeZone},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, profile models.Profile) {
+				rows := newTestUserRows(TestUser)
+				payload, _ := json.Marshal(userEventPayload{
+					UserId:   TestUser.Id,
+					Username: TestUser.Username,
+					Changes: map[string]string{
+						models.ProfileFieldDisplayName: profile.DisplayName,
+						models.ProfileFieldTimeZone:    profile.TimeZone,
+					},
+				})
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE users u SET display_name = \$1, time_zone = \$2, updated_at = now\(\) WHERE u.id = \$3`).
+					WithArgs(profile.DisplayName, profile.TimeZone, userId).
+					WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserProfileUpdated, TestUser.Id, string(payload)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: TestUser,
+		},
+		{
+			name: "EmptyFields",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestUser.Profile,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, profile models.Profile) {
+				rows := newTestUserRows(TestUser)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE users u SET updated_at = now\(\) WHERE u.id = \$1`).
+					WithArgs(userId).
+					WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserProfileUpdated, TestUser.Id, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: TestUser,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestUser.Profile,
+				fields:  []string{models.ProfileFieldBio},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, profile models.Profile) {
+				rows := newTestUserRows()
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE users u SET bio").
+					WithArgs(profile.Bio, userId).
+					WillReturnRows(rows)
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrUserNotFound,
+		},
+		{
+			name: "UnknownField",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestUser.Profile,
+				fields:  []string{"pass_hash"},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, profile models.Profile) {},
+			wantErr:      errors.New(""),
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestUser.Profile,
+				fields:  []string{models.ProfileFieldLocale},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, profile models.Profile) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE users u SET locale").
+					WithArgs(profile.Locale, userId).
+					WillReturnError(errors.New(""))
+				mock.ExpectRollback()
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "ErrorOutbox",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestUser.Profile,
+				fields:  []string{models.ProfileFieldLocale},
+			},
+			mockBehavior: func(ctx context.Context, userId int64, profile models.Profile) {
+				rows := newTestUserRows(TestUser)
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE users u SET locale").
+					WithArgs(profile.Locale, userId).
+					WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO outbox").WillReturnError(errors.New(""))
+				mock.ExpectRollback()
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.profile)
+
+			got, err := rep.UpdateProfile(tt.args.ctx, tt.args.userId, tt.args.profile, tt.args.fields)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("Repository.UpdateProfile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if errors.Is(tt.wantErr, repository.ErrUserNotFound) && !errors.Is(err, repository.ErrUserNotFound) {
+				t.Errorf("Repository.UpdateProfile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.UpdateProfile() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -16,6 +16,17 @@ type Repository struct {
 	db *sql.DB
 }
 
+// userColumns - столбцы таблицы users, из которых собирается models.User.
+// В запросах таблица users должна иметь псевдоним u.
+const userColumns = `u.id, u.username, u.pass_hash, u.is_active, 
+	u.display_name, u.bio, u.avatar_url, u.locale, u.time_zone, 
+	u.created_at, u.updated_at`
+
+// scanner - общий интерфейс *sql.Row и *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
 // Connect создает подключение к базе данных PostgresSQL, принимает на вход строку подключения.
 func Connect(conn string) (*sql.DB, error) {
 	const op = "psql.New"
@@ -41,19 +52,36 @@ func New(db *sql.DB) *Repository {
 }
 
 // SaveUser сохраняет нового пользователя в базу данных, возвращает id нового пользователя.
+// canonical - каноническая форма username, уникальная среди всех пользователей.
+// Вместе с пользователем в outbox сохраняется событие регистрации.
 // В случае нарушения constraint unique, возвращает ошибку repository.ErrUserAlredyExists.
-func (r *Repository) SaveUser(ctx context.Context, username string, password []byte) (int64, error) {
+// Если username зарезервирован после смены другим пользователем, возвращает ошибку repository.ErrUsernameReserved.
+func (r *Repository) SaveUser(ctx context.Context, username, canonical string, password []byte) (int64, error) {
 	const op = "psql.SaveUser"
 
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
 	var id int64
-	row := r.db.QueryRowContext(ctx,
+	row := tx.QueryRowContext(ctx,
 		`INSERT INTO users (
 			username, 
+			username_canonical, 
 			pass_hash, 
 			is_active
-		) VALUES ($1, $2, true) RETURNING id`,
-		username, password)
-	if err := row.Scan(&id); err != nil {
+		) SELECT $1, $2, $3, true 
+		WHERE NOT EXISTS (
+			SELECT 1 FROM username_history WHERE username_canonical = $2 AND reserved_until > now()
+		) RETURNING id`,
+		username, canonical, password)
+	if err = row.Scan(&id); err != nil {
+		_ = tx.Rollback()
+		//Строка не вставлена из-за резерва username
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s, %w", op, repository.ErrUsernameReserved)
+		}
 		//Ошибка нарушения constraint unique
 		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code.Name() == repository.CodeConstraintUnique {
 			return 0, fmt.Errorf("%s, %w", op, repository.ErrUserAlredyExists)
@@ -62,17 +90,47 @@ func (r *Repository) SaveUser(ctx context.Context, username string, password []b
 		return 0, fmt.Errorf("%s, %w", op, err)
 	}
 
+	if err = saveEvent(ctx, tx, models.EventUserRegistered, userEventPayload{UserId: id, Username: username}); err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
 	return id, nil
 }
 
-// GetUser получает пользователя по username. Если пользователь не найден, возвращает ошибку repository.ErrUserNotFound.
-func (r *Repository) GetUser(ctx context.Context, username string) (models.User, error) {
+// GetUser получает активного пользователя по канонической форме username.
+// Если пользователь не найден, возвращает ошибку repository.ErrUserNotFound.
+func (r *Repository) GetUser(ctx context.Context, canonical string) (models.User, error) {
 	const op = "psql.GetUser"
 
-	row := r.db.QueryRowContext(ctx, "SELECT * FROM users WHERE username = $1 AND is_active = true", username)
+	row := r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users u WHERE u.username_canonical = $1 AND u.is_active = true",
+		canonical)
 
-	var user models.User
-	err := row.Scan(&user.Id, &user.Username, &user.PasswordHash, &user.IsActive)
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return user, nil
+}
+
+// GetUserById получает пользователя по id вне зависимости от статуса активности.
+// Если пользователь не найден, возвращает ошибку repository.ErrUserNotFound.
+func (r *Repository) GetUserById(ctx context.Context, userId int64) (models.User, error) {
+	const op = "psql.GetUserById"
+
+	row := r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users u WHERE u.id = $1", userId)
+
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
@@ -84,7 +142,29 @@ func (r *Repository) GetUser(ctx context.Context, username string) (models.User,
 	return user, nil
 }
 
-// SetInactive устанавливает пользователю с указанным id значение is_active = false.
+// GetUsersByIds получает активных пользователей с указанными id одним запросом.
+// Ненайденные и неактивные пользователи в результат не попадают, порядок результата не определен.
+func (r *Repository) GetUsersByIds(ctx context.Context, userIds []int64) ([]models.User, error) {
+	const op = "psql.GetUsersByIds"
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM users u WHERE u.id = ANY($1) AND u.is_active = true",
+		pq.Array(userIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return users, nil
+}
+
+// SetInactive устанавливает пользователю с указанным id значение is_active = false
+// и удаляет его сессии и устройства. В outbox сохраняется событие деактивации.
 // Если пользователь с таким id не найден, возвращает ошибку repository.ErrUserNotFound.
 // Если пользователь уже неактивен, возвращает ошибку repository.ErrUserAlreadyInactive.
 func (r *Repository) SetInactive(ctx context.Context, userId int64) error {
@@ -95,7 +175,8 @@ func (r *Repository) SetInactive(ctx context.Context, userId int64) error {
 		return fmt.Errorf("%s, %w", op, err)
 	}
 
-	row := tx.QueryRowContext(ctx, "SELECT is_active FROM users WHERE id = $1", userId)
+	//Блокировка строки не дает параллельной деактивации пройти проверку и повторить событие деактивации
+	row := tx.QueryRowContext(ctx, "SELECT is_active FROM users WHERE id = $1 FOR UPDATE", userId)
 
 	var isActive bool
 	err = row.Scan(&isActive)
@@ -109,11 +190,79 @@ func (r *Repository) SetInactive(ctx context.Context, userId int64) error {
 	}
 
 	if !isActive {
+		_ = tx.Rollback()
 		return fmt.Errorf("%s, %w", op, repository.ErrUserAlreadyInactive)
 	}
 
-	_, err = tx.ExecContext(ctx, "UPDATE users SET is_active = FALSE WHERE id = $1", userId)
+	_, err = tx.ExecContext(ctx, "UPDATE users SET is_active = FALSE, updated_at = now() WHERE id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Неактивный пользователь теряет все сессии вместе с refresh токенами
+	_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Неактивному пользователю не доставляются push уведомления
+	_, err = tx.ExecContext(ctx, "DELETE FROM devices WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = saveEvent(ctx, tx, models.EventUserDeactivated, userEventPayload{UserId: userId}); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// SetActive устанавливает пользователю с указанным id значение is_active = true.
+// В outbox сохраняется событие активации.
+// Если пользователь с таким id не найден или ожидает удаления, возвращает ошибку repository.ErrUserNotFound.
+// Если пользователь уже активен, возвращает ошибку repository.ErrUserAlreadyActive.
+func (r *Repository) SetActive(ctx context.Context, userId int64) error {
+	const op = "psql.SetActive"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT is_active FROM users WHERE id = $1 AND delete_after IS NULL FOR UPDATE", userId)
+
+	var isActive bool
+	err = row.Scan(&isActive)
 	if err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if isActive {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, repository.ErrUserAlreadyActive)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET is_active = TRUE, updated_at = now() WHERE id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = saveEvent(ctx, tx, models.EventUserActivated, userEventPayload{UserId: userId}); err != nil {
 		_ = tx.Rollback()
 		return fmt.Errorf("%s, %w", op, err)
 	}
@@ -124,3 +273,74 @@ func (r *Repository) SetInactive(ctx context.Context, userId int64) error {
 
 	return nil
 }
+
+// UpdatePasswordHash заменяет хэш пароля пользователя с указанным id.
+// Если пользователь с таким id не найден, возвращает ошибку repository.ErrUserNotFound.
+func (r *Repository) UpdatePasswordHash(ctx context.Context, userId int64, passHash []byte) error {
+	const op = "psql.UpdatePasswordHash"
+
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET pass_hash = $1, updated_at = now() WHERE id = $2", passHash, userId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrUserNotFound)
+}
+
+// scanUser собирает models.User из строки, полученной запросом по столбцам userColumns.
+func scanUser(row scanner) (models.User, error) {
+	var user models.User
+	err := row.Scan(userFields(&user)...)
+
+	return user, err
+}
+
+// userFields возвращает указатели на поля пользователя в порядке столбцов userColumns.
+func userFields(user *models.User) []any {
+	return []any{
+		&user.Id,
+		&user.Username,
+		&user.PasswordHash,
+		&user.IsActive,
+		&user.DisplayName,
+		&user.Bio,
+		&user.AvatarURL,
+		&user.Locale,
+		&user.TimeZone,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	}
+}
+
+// scanUsers считывает всех пользователей из результата запроса столбцов userColumns.
+func scanUsers(rows *sql.Rows) ([]models.User, error) {
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// checkAffected возвращает ошибку notFound, если запрос не затронул ни одной строки.
+func checkAffected(op string, res sql.Result, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s, %w", op, notFound)
+	}
+
+	return nil
+}
@@ -5,16 +5,19 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/al3ksus/messengerusers/internal/domain/models"
+	"github.com/lib/pq"
 )
 
 var (
-	TestUsername       = "user1"
-	TestPass           = []byte("qwerty")
-	TestUserId   int64 = 1
-	EmptyUserId  int64 = 0
+	TestUsername        = "user1"
+	TestCanonical       = "user1"
+	TestPass            = []byte("qwerty")
+	TestUserId    int64 = 1
+	EmptyUserId   int64 = 0
 )
 
 var TestUser = models.User{
@@ -22,6 +25,31 @@ var TestUser = models.User{
 	Username:     TestUsername,
 	PasswordHash: TestPass,
 	IsActive:     true,
+	Profile: models.Profile{
+		DisplayName: "User One",
+		Bio:         "bio",
+		AvatarURL:   "https://example.com/avatar.png",
+		Locale:      "en-US",
+		TimeZone:    "Europe/Moscow",
+	},
+	CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+}
+
+// newTestUserRows возвращает строки результата запроса userColumns для переданных пользователей.
+func newTestUserRows(users ...models.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "pass_hash", "is_active", "display_name", "bio",
+		"avatar_url", "locale", "time_zone", "created_at", "updated_at",
+	})
+	for _, u := range users {
+		rows.AddRow(
+			u.Id, u.Username, u.PasswordHash, u.IsActive, u.DisplayName, u.Bio,
+			u.AvatarURL, u.Locale, u.TimeZone, u.CreatedAt, u.UpdatedAt,
+		)
+	}
+
+	return rows
 }
 
 func TestRepository_SaveUser(t *testing.T) {
@@ -34,11 +62,12 @@ func TestRepository_SaveUser(t *testing.T) {
 	rep := New(db)
 
 	type args struct {
-		ctx      context.Context
-		username string
-		password []byte
+		ctx       context.Context
+		username  string
+		canonical string
+		password  []byte
 	}
-	type mockBehavior func(ctx context.Context, username string, pass []byte)
+	type mockBehavior func(ctx context.Context, username, canonical string, pass []byte)
 	tests := []struct {
 		name         string
 		args         args
@@ -49,35 +78,95 @@ func TestRepository_SaveUser(t *testing.T) {
 		{
 			name: "OK",
 			args: args{
-				ctx:      context.Background(),
-				username: TestUsername,
-				password: TestPass,
+				ctx:       context.Background(),
+				username:  TestUsername,
+				canonical: TestCanonical,
+				password:  TestPass,
 			},
-			mockBehavior: func(ctx context.Context, username string, pass []byte) {
+			mockBehavior: func(ctx context.Context, username, canonical string, pass []byte) {
 				rows := sqlmock.NewRows([]string{"id"}).AddRow(TestUserId)
-				mock.ExpectQuery("INSERT INTO users").WithArgs(username, pass).WillReturnRows(rows)
-
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users").WithArgs(username, canonical, pass).WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserRegistered, TestUserId, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 			},
 			want: TestUserId,
 		},
+		{
+			name: "UsernameTaken",
+			args: args{
+				ctx:       context.Background(),
+				username:  TestUsername,
+				canonical: TestCanonical,
+				password:  TestPass,
+			},
+			mockBehavior: func(ctx context.Context, username, canonical string, pass []byte) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users").
+					WithArgs(username, canonical, pass).
+					WillReturnError(&pq.Error{Code: "23505"})
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "UsernameReserved",
+			args: args{
+				ctx:       context.Background(),
+				username:  TestUsername,
+				canonical: TestCanonical,
+				password:  TestPass,
+			},
+			mockBehavior: func(ctx context.Context, username, canonical string, pass []byte) {
+				rows := sqlmock.NewRows([]string{"id"})
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users (.+) FROM username_history").
+					WithArgs(username, canonical, pass).
+					WillReturnRows(rows)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
 		{
 			name: "Error",
 			args: args{
-				ctx:      context.Background(),
-				username: TestUsername,
-				password: TestPass,
+				ctx:       context.Background(),
+				username:  TestUsername,
+				canonical: TestCanonical,
+				password:  TestPass,
 			},
-			mockBehavior: func(ctx context.Context, username string, pass []byte) {
-				mock.ExpectQuery("INSERT INTO users").WithArgs(username, pass).WillReturnError(errors.New(""))
+			mockBehavior: func(ctx context.Context, username, canonical string, pass []byte) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users").WithArgs(username, canonical, pass).WillReturnError(errors.New(""))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "ErrorOutbox",
+			args: args{
+				ctx:       context.Background(),
+				username:  TestUsername,
+				canonical: TestCanonical,
+				password:  TestPass,
+			},
+			mockBehavior: func(ctx context.Context, username, canonical string, pass []byte) {
+				rows := sqlmock.NewRows([]string{"id"}).AddRow(TestUserId)
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users").WithArgs(username, canonical, pass).WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO outbox").WillReturnError(errors.New(""))
+				mock.ExpectRollback()
 			},
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.mockBehavior(tt.args.ctx, tt.args.username, tt.args.password)
+			tt.mockBehavior(tt.args.ctx, tt.args.username, tt.args.canonical, tt.args.password)
 
-			got, err := rep.SaveUser(tt.args.ctx, tt.args.username, tt.args.password)
+			got, err := rep.SaveUser(tt.args.ctx, tt.args.username, tt.args.canonical, tt.args.password)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Repository.SaveUser() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -117,9 +206,7 @@ func TestRepository_GetUser(t *testing.T) {
 				username: TestUsername,
 			},
 			mockBehavior: func(ctx context.Context, username string) {
-				rows := sqlmock.
-					NewRows([]string{"id", "username", "pass_hash", "is_active"}).
-					AddRow(TestUser.Id, TestUser.Username, TestUser.PasswordHash, TestUser.IsActive)
+				rows := newTestUserRows(TestUser)
 
 				mock.ExpectQuery("SELECT (.+) FROM users").WithArgs(username).WillReturnRows(rows)
 			},
@@ -153,6 +240,169 @@ func TestRepository_GetUser(t *testing.T) {
 	}
 }
 
+func TestRepository_GetUserById(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.User
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := newTestUserRows(TestUser)
+
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id").WithArgs(userId).WillReturnRows(rows)
+			},
+			want: TestUser,
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := newTestUserRows()
+
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id").WithArgs(userId).WillReturnRows(rows)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.GetUserById(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetUserById() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetUserById() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_GetUsersByIds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	secondUser := TestUser
+	secondUser.Id = 2
+	secondUser.Username = "user2"
+
+	type args struct {
+		ctx     context.Context
+		userIds []int64
+	}
+	type mockBehavior func(ctx context.Context, userIds []int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.User
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId, 2, 3},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				rows := newTestUserRows(TestUser, secondUser)
+
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id = ANY").
+					WithArgs(pq.Array(userIds)).
+					WillReturnRows(rows)
+			},
+			want: []models.User{TestUser, secondUser},
+		},
+		{
+			name: "NoneFound",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{3},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				rows := newTestUserRows()
+
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id = ANY").
+					WithArgs(pq.Array(userIds)).
+					WillReturnRows(rows)
+			},
+		},
+		{
+			name: "ScanError",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				rows := newTestUserRows(TestUser).RowError(0, errors.New(""))
+
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id = ANY").
+					WithArgs(pq.Array(userIds)).
+					WillReturnRows(rows)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId},
+			},
+			mockBehavior: func(ctx context.Context, userIds []int64) {
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id = ANY").
+					WithArgs(pq.Array(userIds)).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userIds)
+
+			got, err := rep.GetUsersByIds(tt.args.ctx, tt.args.userIds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetUsersByIds() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetUsersByIds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRepository_SetInactive(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -186,12 +436,62 @@ func TestRepository_SetInactive(t *testing.T) {
 					NewRows([]string{"is_active"}).
 					AddRow(true)
 
-				mock.ExpectQuery("SELECT is_active FROM users").WithArgs(userId).WillReturnRows(rows)
-				mock.ExpectExec("UPDATE users SET is_active = FALSE").WithArgs(userId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("SELECT is_active FROM users WHERE id = (.+) FOR UPDATE").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec(`UPDATE users SET is_active = FALSE, updated_at = now\(\)`).WithArgs(userId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM devices").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserDeactivated, userId, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectCommit()
 			},
 		},
+		{
+			name: "ErrorOutbox",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.
+					NewRows([]string{"is_active"}).
+					AddRow(true)
+
+				mock.ExpectQuery("SELECT is_active FROM users WHERE id = (.+) FOR UPDATE").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec(`UPDATE users SET is_active = FALSE, updated_at = now\(\)`).WithArgs(userId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM devices").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO outbox").WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "ErrorDeleteDevices",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.
+					NewRows([]string{"is_active"}).
+					AddRow(true)
+
+				mock.ExpectQuery("SELECT is_active FROM users WHERE id = (.+) FOR UPDATE").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec(`UPDATE users SET is_active = FALSE, updated_at = now\(\)`).WithArgs(userId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM devices").WithArgs(userId).WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
 		{
 			name: "ErrorSelect",
 			args: args{
@@ -201,7 +501,7 @@ func TestRepository_SetInactive(t *testing.T) {
 			mockBehavior: func(ctx context.Context, userId int64) {
 				mock.ExpectBegin()
 
-				mock.ExpectQuery("SELECT is_active FROM users").WithArgs(userId).WillReturnError(errors.New(""))
+				mock.ExpectQuery("SELECT is_active FROM users WHERE id = (.+) FOR UPDATE").WithArgs(userId).WillReturnError(errors.New(""))
 
 				mock.ExpectRollback()
 			},
@@ -220,8 +520,8 @@ func TestRepository_SetInactive(t *testing.T) {
 					NewRows([]string{"is_active"}).
 					AddRow(true)
 
-				mock.ExpectQuery("SELECT is_active FROM users").WithArgs(userId).WillReturnRows(rows)
-				mock.ExpectExec("UPDATE users SET is_active = FALSE").WithArgs(userId).WillReturnError(errors.New(""))
+				mock.ExpectQuery("SELECT is_active FROM users WHERE id = (.+) FOR UPDATE").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec(`UPDATE users SET is_active = FALSE, updated_at = now\(\)`).WithArgs(userId).WillReturnError(errors.New(""))
 
 				mock.ExpectRollback()
 			},
@@ -240,3 +540,182 @@ func TestRepository_SetInactive(t *testing.T) {
 		})
 	}
 }
+
+func TestRepository_SetActive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.
+					NewRows([]string{"is_active"}).
+					AddRow(false)
+
+				mock.ExpectQuery("SELECT is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec("UPDATE users SET is_active = TRUE").WithArgs(userId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserActivated, userId, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "ErrorSelect",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT is_active FROM users").WithArgs(userId).WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "AlreadyActive",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.
+					NewRows([]string{"is_active"}).
+					AddRow(true)
+
+				mock.ExpectQuery("SELECT is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "ErrorUpdate",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.
+					NewRows([]string{"is_active"}).
+					AddRow(false)
+
+				mock.ExpectQuery("SELECT is_active FROM users").WithArgs(userId).WillReturnRows(rows)
+				mock.ExpectExec("UPDATE users SET is_active = TRUE").WithArgs(userId).WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			err := rep.SetActive(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SetActive() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
+
+func TestRepository_UpdatePasswordHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		passHash []byte
+	}
+	type mockBehavior func(ctx context.Context, userId int64, passHash []byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				passHash: TestPass,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, passHash []byte) {
+				mock.ExpectExec("UPDATE users SET pass_hash = (.+), updated_at = now()").WithArgs(passHash, userId).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				passHash: TestPass,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, passHash []byte) {
+				mock.ExpectExec("UPDATE users SET pass_hash").WithArgs(passHash, userId).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				passHash: TestPass,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, passHash []byte) {
+				mock.ExpectExec("UPDATE users SET pass_hash").WithArgs(passHash, userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.passHash)
+
+			err := rep.UpdatePasswordHash(tt.args.ctx, tt.args.userId, tt.args.passHash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.UpdatePasswordHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
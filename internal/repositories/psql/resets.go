@@ -0,0 +1,153 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// SaveResetCode сохраняет хэш одноразового кода сброса пароля пользователя.
+// Если у пользователя уже maxActive неиспользованных и неистекших кодов, возвращает ошибку repository.ErrTooManyResetCodes.
+// Если пользователь не найден, возвращает ошибку repository.ErrUserNotFound.
+func (r *Repository) SaveResetCode(ctx context.Context, userId int64, codeHash []byte, expiresAt time.Time, maxActive int) error {
+	const op = "psql.SaveResetCode"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Блокировка строки пользователя не дает параллельным запросам одновременно пройти проверку числа кодов
+	row := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userId)
+	if err = row.Scan(&userId); err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	row = tx.QueryRowContext(ctx,
+		"SELECT count(*) FROM password_resets WHERE user_id = $1 AND used_at IS NULL AND expires_at > now()",
+		userId)
+
+	var active int
+	if err = row.Scan(&active); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if active >= maxActive {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, repository.ErrTooManyResetCodes)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO password_resets (user_id, code_hash, expires_at) VALUES ($1, $2, $3)",
+		userId, codeHash, expiresAt)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// GetResetCodeOwner получает активного пользователя, которому принадлежит действующий код сброса пароля.
+// Если код не найден, уже использован, истек или принадлежит неактивному пользователю,
+// возвращает ошибку repository.ErrResetCodeNotFound.
+func (r *Repository) GetResetCodeOwner(ctx context.Context, codeHash []byte) (models.User, error) {
+	const op = "psql.GetResetCodeOwner"
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` 
+		FROM password_resets r 
+		JOIN users u ON u.id = r.user_id 
+		WHERE r.code_hash = $1 
+			AND r.used_at IS NULL 
+			AND r.expires_at > now() 
+			AND u.is_active = true`,
+		codeHash)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s, %w", op, repository.ErrResetCodeNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return user, nil
+}
+
+// ResetPassword атомарно погашает код сброса и устанавливает новый хэш пароля его владельцу.
+// Остальные коды пользователя аннулируются, а все его сессии завершаются. Возвращает id пользователя.
+// Если код не найден, уже использован, истек или принадлежит неактивному пользователю,
+// возвращает ошибку repository.ErrResetCodeNotFound.
+func (r *Repository) ResetPassword(ctx context.Context, codeHash []byte, passHash []byte) (int64, error) {
+	const op = "psql.ResetPassword"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	row := tx.QueryRowContext(ctx,
+		`UPDATE password_resets 
+		SET used_at = now() 
+		WHERE code_hash = $1 
+			AND used_at IS NULL 
+			AND expires_at > now() 
+		RETURNING user_id`,
+		codeHash)
+
+	var userId int64
+	if err = row.Scan(&userId); err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s, %w", op, repository.ErrResetCodeNotFound)
+		}
+
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE users SET pass_hash = $1, updated_at = now() WHERE id = $2 AND is_active = true", passHash, userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = checkAffected(op, res, repository.ErrResetCodeNotFound); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE password_resets SET used_at = now() WHERE user_id = $1 AND used_at IS NULL", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userId)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return userId, nil
+}
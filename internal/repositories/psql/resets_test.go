@@ -0,0 +1,309 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+var (
+	TestCodeHash = []byte("code_hash")
+)
+
+func TestRepository_SaveResetCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+	errDB := errors.New("db error")
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		codeHash  []byte
+		expiresAt time.Time
+		maxActive int
+	}
+	type mockBehavior func(ctx context.Context, userId int64, codeHash []byte, expiresAt time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				codeHash:  TestCodeHash,
+				expiresAt: TestExpiresAt,
+				maxActive: 3,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, codeHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM users WHERE id = (.+) FOR UPDATE").
+					WithArgs(userId).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(userId))
+				mock.ExpectQuery("SELECT count(.+) FROM password_resets").
+					WithArgs(userId).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectExec("INSERT INTO password_resets").
+					WithArgs(userId, codeHash, expiresAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "TooManyResetCodes",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				codeHash:  TestCodeHash,
+				expiresAt: TestExpiresAt,
+				maxActive: 3,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, codeHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM users WHERE id = (.+) FOR UPDATE").
+					WithArgs(userId).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(userId))
+				mock.ExpectQuery("SELECT count(.+) FROM password_resets").
+					WithArgs(userId).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrTooManyResetCodes,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				codeHash:  TestCodeHash,
+				expiresAt: TestExpiresAt,
+				maxActive: 3,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, codeHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM users WHERE id = (.+) FOR UPDATE").
+					WithArgs(userId).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrUserNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				codeHash:  TestCodeHash,
+				expiresAt: TestExpiresAt,
+				maxActive: 3,
+			},
+			mockBehavior: func(ctx context.Context, userId int64, codeHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM users WHERE id = (.+) FOR UPDATE").
+					WithArgs(userId).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(userId))
+				mock.ExpectQuery("SELECT count(.+) FROM password_resets").
+					WithArgs(userId).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec("INSERT INTO password_resets").
+					WithArgs(userId, codeHash, expiresAt).
+					WillReturnError(errDB)
+				mock.ExpectRollback()
+			},
+			wantErr: errDB,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.codeHash, tt.args.expiresAt)
+
+			err := rep.SaveResetCode(tt.args.ctx, tt.args.userId, tt.args.codeHash, tt.args.expiresAt, tt.args.maxActive)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.SaveResetCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestRepository_GetResetCodeOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		codeHash []byte
+	}
+	type mockBehavior func(ctx context.Context, codeHash []byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.User
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				codeHash: TestCodeHash,
+			},
+			mockBehavior: func(ctx context.Context, codeHash []byte) {
+				rows := newTestUserRows(TestUser)
+				mock.ExpectQuery("SELECT (.+) FROM password_resets").WithArgs(codeHash).WillReturnRows(rows)
+			},
+			want: TestUser,
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:      context.Background(),
+				codeHash: TestCodeHash,
+			},
+			mockBehavior: func(ctx context.Context, codeHash []byte) {
+				rows := newTestUserRows()
+				mock.ExpectQuery("SELECT (.+) FROM password_resets").WithArgs(codeHash).WillReturnRows(rows)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.codeHash)
+
+			got, err := rep.GetResetCodeOwner(tt.args.ctx, tt.args.codeHash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetResetCodeOwner() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetResetCodeOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_ResetPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx      context.Context
+		codeHash []byte
+		passHash []byte
+	}
+	type mockBehavior func(ctx context.Context, codeHash, passHash []byte)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int64
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				codeHash: TestCodeHash,
+				passHash: TestPass,
+			},
+			mockBehavior: func(ctx context.Context, codeHash, passHash []byte) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"user_id"}).AddRow(TestUserId)
+				mock.ExpectQuery("UPDATE password_resets").WithArgs(codeHash).WillReturnRows(rows)
+				mock.ExpectExec("UPDATE users SET pass_hash = (.+), updated_at = now()").
+					WithArgs(passHash, TestUserId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE password_resets SET used_at").
+					WithArgs(TestUserId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM sessions").
+					WithArgs(TestUserId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
+			},
+			want: TestUserId,
+		},
+		{
+			name: "CodeNotFound",
+			args: args{
+				ctx:      context.Background(),
+				codeHash: TestCodeHash,
+				passHash: TestPass,
+			},
+			mockBehavior: func(ctx context.Context, codeHash, passHash []byte) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"user_id"})
+				mock.ExpectQuery("UPDATE password_resets").WithArgs(codeHash).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "UserInactive",
+			args: args{
+				ctx:      context.Background(),
+				codeHash: TestCodeHash,
+				passHash: TestPass,
+			},
+			mockBehavior: func(ctx context.Context, codeHash, passHash []byte) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"user_id"}).AddRow(TestUserId)
+				mock.ExpectQuery("UPDATE password_resets").WithArgs(codeHash).WillReturnRows(rows)
+				mock.ExpectExec("UPDATE users SET pass_hash").
+					WithArgs(passHash, TestUserId).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.codeHash, tt.args.passHash)
+
+			got, err := rep.ResetPassword(tt.args.ctx, tt.args.codeHash, tt.args.passHash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ResetPassword() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.ResetPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
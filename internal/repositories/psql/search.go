@@ -0,0 +1,74 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// likeEscaper экранирует специальные символы шаблона LIKE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers ищет активных пользователей по канонической форме username.
+// Пользователь находится, если его username начинается с query или похож на него по триграммам pg_trgm.
+// Совпадения по префиксу идут первыми, затем результаты упорядочены по убыванию схожести и по id.
+// Пользователи, скрывшие себя из поиска от viewerId настройками приватности, в результат не попадают.
+// Если after не nil, возвращаются результаты, следующие после указанной позиции. Возвращает не более limit результатов.
+func (r *Repository) SearchUsers(ctx context.Context, viewerId int64, query string, after *models.UserSearchCursor, limit int) ([]models.UserSearchHit, error) {
+	const op = "psql.SearchUsers"
+
+	args := []any{query, likeEscaper.Replace(query) + "%", viewerId}
+	var cursorFilter string
+	if after != nil {
+		args = append(args, after.PrefixMatch, after.Score, after.UserId)
+		//id сравнивается с обратным знаком, так как упорядочен по возрастанию, а остальные ключи по убыванию
+		cursorFilter = "AND (s.prefix_match, s.score, -u.id) < ($4, $5::real, -$6::bigint)"
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf(
+			`SELECT %s, s.prefix_match, s.score 
+			FROM users u 
+			CROSS JOIN LATERAL (
+				SELECT u.username_canonical LIKE $2 AS prefix_match, similarity(u.username_canonical, $1) AS score
+			) s 
+			WHERE u.is_active = true 
+			AND (u.username_canonical LIKE $2 OR u.username_canonical %% $1) 
+			AND (u.id = $3 OR NOT EXISTS (
+				SELECT 1 FROM user_privacy_settings p 
+				WHERE p.user_id = u.id AND (p.search = 'nobody' OR (p.search = 'contacts' AND NOT EXISTS (
+					SELECT 1 FROM contacts c WHERE c.user_id = u.id AND c.contact_id = $3
+				)))
+			)) 
+			%s 
+			ORDER BY s.prefix_match DESC, s.score DESC, u.id 
+			LIMIT $%d`,
+			userColumns, cursorFilter, len(args),
+		),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var hits []models.UserSearchHit
+	for rows.Next() {
+		var hit models.UserSearchHit
+		dest := append(userFields(&hit.User), &hit.Cursor.PrefixMatch, &hit.Cursor.Score)
+		if err = rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		hit.Cursor.UserId = hit.User.Id
+		hits = append(hits, hit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return hits, nil
+}
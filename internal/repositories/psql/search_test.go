@@ -0,0 +1,140 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// newTestSearchRows возвращает строки результата поиска для переданных результатов.
+func newTestSearchRows(hits ...models.UserSearchHit) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "pass_hash", "is_active", "display_name", "bio",
+		"avatar_url", "locale", "time_zone", "created_at", "updated_at",
+		"prefix_match", "score",
+	})
+	for _, h := range hits {
+		u := h.User
+		rows.AddRow(
+			u.Id, u.Username, u.PasswordHash, u.IsActive, u.DisplayName, u.Bio,
+			u.AvatarURL, u.Locale, u.TimeZone, u.CreatedAt, u.UpdatedAt,
+			h.Cursor.PrefixMatch, h.Cursor.Score,
+		)
+	}
+
+	return rows
+}
+
+func TestRepository_SearchUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	hit := models.UserSearchHit{
+		User:   TestUser,
+		Cursor: models.UserSearchCursor{PrefixMatch: true, Score: 0.5, UserId: TestUserId},
+	}
+	after := &models.UserSearchCursor{PrefixMatch: true, Score: 0.75, UserId: 7}
+
+	type args struct {
+		ctx      context.Context
+		viewerId int64
+		query    string
+		after    *models.UserSearchCursor
+		limit    int
+	}
+	type mockBehavior func(ctx context.Context, viewerId int64, query string, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.UserSearchHit
+		wantErr      bool
+	}{
+		{
+			name: "FirstPage",
+			args: args{
+				ctx:      context.Background(),
+				viewerId: TestUserId,
+				query:    "user",
+				limit:    10,
+			},
+			mockBehavior: func(ctx context.Context, viewerId int64, query string, limit int) {
+				mock.ExpectQuery(`SELECT (.+) FROM users u (.+) ORDER BY s.prefix_match DESC, s.score DESC, u.id\s+LIMIT \$4`).
+					WithArgs(query, "user%", viewerId, limit).
+					WillReturnRows(newTestSearchRows(hit))
+			},
+			want: []models.UserSearchHit{hit},
+		},
+		{
+			name: "NextPage",
+			args: args{
+				ctx:      context.Background(),
+				viewerId: TestUserId,
+				query:    "user",
+				after:    after,
+				limit:    10,
+			},
+			mockBehavior: func(ctx context.Context, viewerId int64, query string, limit int) {
+				mock.ExpectQuery(`SELECT (.+) \(s.prefix_match, s.score, -u.id\) < \(\$4, \$5::real, -\$6::bigint\) (.+) LIMIT \$7`).
+					WithArgs(query, "user%", viewerId, after.PrefixMatch, after.Score, after.UserId, limit).
+					WillReturnRows(newTestSearchRows(hit))
+			},
+			want: []models.UserSearchHit{hit},
+		},
+		{
+			name: "EscapesLikePattern",
+			args: args{
+				ctx:      context.Background(),
+				viewerId: TestUserId,
+				query:    `us_er%\`,
+				limit:    10,
+			},
+			mockBehavior: func(ctx context.Context, viewerId int64, query string, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM users u").
+					WithArgs(query, `us\_er\%\\%`, viewerId, limit).
+					WillReturnRows(newTestSearchRows())
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				viewerId: TestUserId,
+				query:    "user",
+				limit:    10,
+			},
+			mockBehavior: func(ctx context.Context, viewerId int64, query string, limit int) {
+				mock.ExpectQuery("SELECT (.+) FROM users u").
+					WithArgs(query, "user%", viewerId, limit).
+					WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.viewerId, tt.args.query, tt.args.limit)
+
+			got, err := rep.SearchUsers(tt.args.ctx, tt.args.viewerId, tt.args.query, tt.args.after, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SearchUsers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.SearchUsers() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// SaveSession создает новую сессию пользователя вместе с ее refresh токеном, возвращает id сессии.
+func (r *Repository) SaveSession(ctx context.Context, session models.Session, tokenHash []byte, expiresAt time.Time) (int64, error) {
+	const op = "psql.SaveSession"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	row := tx.QueryRowContext(ctx,
+		`INSERT INTO sessions (
+			user_id, 
+			device_name, 
+			ip, 
+			user_agent
+		) VALUES ($1, $2, $3, $4) RETURNING id`,
+		session.UserId, session.DeviceName, session.IP, session.UserAgent)
+
+	var sessionId int64
+	if err = row.Scan(&sessionId); err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		session.UserId, sessionId, tokenHash, expiresAt)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return sessionId, nil
+}
+
+// RotateRefreshToken атомарно заменяет refresh токен новым, возвращает id владельца токена и id сессии.
+// Если токен не найден, истек или принадлежит неактивному пользователю, возвращает ошибку repository.ErrTokenNotFound.
+func (r *Repository) RotateRefreshToken(ctx context.Context, oldHash, newHash []byte, expiresAt time.Time) (int64, int64, error) {
+	const op = "psql.RotateRefreshToken"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	row := tx.QueryRowContext(ctx,
+		`DELETE FROM refresh_tokens t 
+		USING users u 
+		WHERE t.token_hash = $1 
+			AND t.expires_at > now() 
+			AND u.id = t.user_id 
+			AND u.is_active = true 
+		RETURNING t.user_id, t.session_id`,
+		oldHash)
+
+	var userId, sessionId int64
+	if err = row.Scan(&userId, &sessionId); err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, fmt.Errorf("%s, %w", op, repository.ErrTokenNotFound)
+		}
+
+		return 0, 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		userId, sessionId, newHash, expiresAt)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET last_seen_at = now() WHERE id = $1", sessionId)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return userId, sessionId, nil
+}
+
+// TouchSession обновляет время последней активности сессии, если оно раньше touchBefore.
+// Более позднее время не перезаписывается, поэтому частая проверка токенов не порождает запись на каждый вызов.
+// Если сессия не найдена, возвращает ошибку repository.ErrSessionNotFound.
+func (r *Repository) TouchSession(ctx context.Context, sessionId int64, touchBefore time.Time) error {
+	const op = "psql.TouchSession"
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`WITH session AS (
+			SELECT id, last_seen_at FROM sessions WHERE id = $1
+		), touched AS (
+			UPDATE sessions SET last_seen_at = now() 
+			WHERE id = (SELECT id FROM session WHERE last_seen_at < $2)
+		) SELECT true FROM session`,
+		sessionId, touchBefore).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", op, repository.ErrSessionNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteSession удаляет сессию пользователя вместе с ее refresh токенами.
+// Если сессия не найдена, возвращает ошибку repository.ErrSessionNotFound.
+func (r *Repository) DeleteSession(ctx context.Context, userId, sessionId int64) error {
+	const op = "psql.DeleteSession"
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1 AND user_id = $2", sessionId, userId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return checkAffected(op, res, repository.ErrSessionNotFound)
+}
+
+// DeleteSessions удаляет все сессии пользователя вместе с их refresh токенами.
+func (r *Repository) DeleteSessions(ctx context.Context, userId int64) error {
+	const op = "psql.DeleteSessions"
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ListSessions возвращает действующие сессии пользователя, начиная с последней активной.
+// Сессия действует, пока у нее есть неистекший refresh токен.
+func (r *Repository) ListSessions(ctx context.Context, userId int64) ([]models.Session, error) {
+	const op = "psql.ListSessions"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT s.id, s.user_id, s.device_name, s.ip, s.user_agent, s.created_at, s.last_seen_at 
+		FROM sessions s 
+		WHERE s.user_id = $1 
+			AND EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.session_id = s.id AND t.expires_at > now()) 
+		ORDER BY s.last_seen_at DESC`,
+		userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var s models.Session
+		err = rows.Scan(&s.Id, &s.UserId, &s.DeviceName, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// DeleteExpiredSessions удаляет не более limit сессий, у которых не осталось неистекших refresh токенов,
+// вместе с их истекшими токенами. Возвращает число удаленных сессий.
+func (r *Repository) DeleteExpiredSessions(ctx context.Context, limit int) (int, error) {
+	const op = "psql.DeleteExpiredSessions"
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM sessions WHERE id IN (
+			SELECT s.id FROM sessions s 
+			WHERE NOT EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.session_id = s.id AND t.expires_at > now()) 
+			ORDER BY s.id LIMIT $1
+		)`,
+		limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return int(deleted), nil
+}
@@ -0,0 +1,555 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+var (
+	TestSessionId    int64 = 2
+	TestTokenHash          = []byte("hash")
+	TestNewTokenHash       = []byte("new_hash")
+	TestExpiresAt          = time.Unix(1800000000, 0)
+)
+
+var TestSession = models.Session{
+	Id:     TestSessionId,
+	UserId: TestUserId,
+	ClientInfo: models.ClientInfo{
+		DeviceName: "phone",
+		IP:         "127.0.0.1",
+		UserAgent:  "grpc-go",
+	},
+	CreatedAt:  time.Unix(1700000000, 0),
+	LastSeenAt: time.Unix(1700000100, 0),
+}
+
+func TestRepository_SaveSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		session   models.Session
+		tokenHash []byte
+		expiresAt time.Time
+	}
+	type mockBehavior func(ctx context.Context, session models.Session, tokenHash []byte, expiresAt time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int64
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				session:   TestSession,
+				tokenHash: TestTokenHash,
+				expiresAt: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, session models.Session, tokenHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"id"}).AddRow(TestSessionId)
+				mock.ExpectQuery("INSERT INTO sessions").
+					WithArgs(session.UserId, session.DeviceName, session.IP, session.UserAgent).
+					WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO refresh_tokens").
+					WithArgs(session.UserId, TestSessionId, tokenHash, expiresAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectCommit()
+			},
+			want: TestSessionId,
+		},
+		{
+			name: "ErrorInsertToken",
+			args: args{
+				ctx:       context.Background(),
+				session:   TestSession,
+				tokenHash: TestTokenHash,
+				expiresAt: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, session models.Session, tokenHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"id"}).AddRow(TestSessionId)
+				mock.ExpectQuery("INSERT INTO sessions").
+					WithArgs(session.UserId, session.DeviceName, session.IP, session.UserAgent).
+					WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO refresh_tokens").
+					WithArgs(session.UserId, TestSessionId, tokenHash, expiresAt).
+					WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.session, tt.args.tokenHash, tt.args.expiresAt)
+
+			got, err := rep.SaveSession(tt.args.ctx, tt.args.session, tt.args.tokenHash, tt.args.expiresAt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.SaveSession() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.SaveSession() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_RotateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		oldHash   []byte
+		newHash   []byte
+		expiresAt time.Time
+	}
+	type mockBehavior func(ctx context.Context, oldHash, newHash []byte, expiresAt time.Time)
+	tests := []struct {
+		name          string
+		args          args
+		mockBehavior  mockBehavior
+		wantUserId    int64
+		wantSessionId int64
+		wantErr       bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				oldHash:   TestTokenHash,
+				newHash:   TestNewTokenHash,
+				expiresAt: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, oldHash, newHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"user_id", "session_id"}).AddRow(TestUserId, TestSessionId)
+				mock.ExpectQuery("DELETE FROM refresh_tokens").WithArgs(oldHash).WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO refresh_tokens").
+					WithArgs(TestUserId, TestSessionId, newHash, expiresAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE sessions SET last_seen_at").
+					WithArgs(TestSessionId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
+			},
+			wantUserId:    TestUserId,
+			wantSessionId: TestSessionId,
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:       context.Background(),
+				oldHash:   TestTokenHash,
+				newHash:   TestNewTokenHash,
+				expiresAt: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, oldHash, newHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"user_id", "session_id"})
+				mock.ExpectQuery("DELETE FROM refresh_tokens").WithArgs(oldHash).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "ErrorInsert",
+			args: args{
+				ctx:       context.Background(),
+				oldHash:   TestTokenHash,
+				newHash:   TestNewTokenHash,
+				expiresAt: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, oldHash, newHash []byte, expiresAt time.Time) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"user_id", "session_id"}).AddRow(TestUserId, TestSessionId)
+				mock.ExpectQuery("DELETE FROM refresh_tokens").WithArgs(oldHash).WillReturnRows(rows)
+				mock.ExpectExec("INSERT INTO refresh_tokens").
+					WithArgs(TestUserId, TestSessionId, newHash, expiresAt).
+					WillReturnError(errors.New(""))
+
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.oldHash, tt.args.newHash, tt.args.expiresAt)
+
+			userId, sessionId, err := rep.RotateRefreshToken(tt.args.ctx, tt.args.oldHash, tt.args.newHash, tt.args.expiresAt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.RotateRefreshToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if userId != tt.wantUserId || sessionId != tt.wantSessionId {
+				t.Errorf("Repository.RotateRefreshToken() = %v, %v, want %v, %v", userId, sessionId, tt.wantUserId, tt.wantSessionId)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestRepository_TouchSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx         context.Context
+		sessionId   int64
+		touchBefore time.Time
+	}
+	type mockBehavior func(ctx context.Context, sessionId int64, touchBefore time.Time)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:         context.Background(),
+				sessionId:   TestSessionId,
+				touchBefore: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, sessionId int64, touchBefore time.Time) {
+				mock.ExpectQuery("UPDATE sessions SET last_seen_at").
+					WithArgs(sessionId, touchBefore).
+					WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:         context.Background(),
+				sessionId:   TestSessionId,
+				touchBefore: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, sessionId int64, touchBefore time.Time) {
+				mock.ExpectQuery("UPDATE sessions SET last_seen_at").
+					WithArgs(sessionId, touchBefore).
+					WillReturnRows(sqlmock.NewRows([]string{"bool"}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:         context.Background(),
+				sessionId:   TestSessionId,
+				touchBefore: TestExpiresAt,
+			},
+			mockBehavior: func(ctx context.Context, sessionId int64, touchBefore time.Time) {
+				mock.ExpectQuery("UPDATE sessions SET last_seen_at").WithArgs(sessionId, touchBefore).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.sessionId, tt.args.touchBefore)
+
+			err := rep.TouchSession(tt.args.ctx, tt.args.sessionId, tt.args.touchBefore)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.TouchSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		sessionId int64
+	}
+	type mockBehavior func(ctx context.Context, userId, sessionId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				sessionId: TestSessionId,
+			},
+			mockBehavior: func(ctx context.Context, userId, sessionId int64) {
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(sessionId, userId).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "NotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				sessionId: TestSessionId,
+			},
+			mockBehavior: func(ctx context.Context, userId, sessionId int64) {
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(sessionId, userId).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				sessionId: TestSessionId,
+			},
+			mockBehavior: func(ctx context.Context, userId, sessionId int64) {
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(sessionId, userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId, tt.args.sessionId)
+
+			err := rep.DeleteSession(tt.args.ctx, tt.args.userId, tt.args.sessionId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			err := rep.DeleteSessions(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteSessions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_ListSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.Session
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.
+					NewRows([]string{"id", "user_id", "device_name", "ip", "user_agent", "created_at", "last_seen_at"}).
+					AddRow(
+						TestSession.Id,
+						TestSession.UserId,
+						TestSession.DeviceName,
+						TestSession.IP,
+						TestSession.UserAgent,
+						TestSession.CreatedAt,
+						TestSession.LastSeenAt,
+					)
+
+				mock.ExpectQuery("SELECT (.+) FROM sessions s (.+) t.expires_at > now()").WithArgs(userId).WillReturnRows(rows)
+			},
+			want: []models.Session{TestSession},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectQuery("SELECT (.+) FROM sessions").WithArgs(userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.ListSessions(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ListSessions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.ListSessions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteExpiredSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	type args struct {
+		ctx   context.Context
+		limit int
+	}
+	type mockBehavior func(ctx context.Context, limit int)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				mock.ExpectExec("DELETE FROM sessions WHERE id IN (.+) NOT EXISTS (.+) LIMIT").
+					WithArgs(limit).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			want: 3,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			mockBehavior: func(ctx context.Context, limit int) {
+				mock.ExpectExec("DELETE FROM sessions").WithArgs(limit).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.limit)
+
+			got, err := rep.DeleteExpiredSessions(tt.args.ctx, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.DeleteExpiredSessions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Repository.DeleteExpiredSessions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+// ChangeUsername заменяет username активного пользователя и сохраняет прежний username в истории.
+// canonical - каноническая форма нового username. Прежний username резервируется за пользователем до reservedUntil.
+// В outbox сохраняется событие изменения профиля. Если username не изменился, ничего не делает.
+// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+// Если новый username зарезервирован за другим пользователем, возвращает ошибку repository.ErrUsernameReserved.
+// В случае нарушения constraint unique, возвращает ошибку repository.ErrUserAlredyExists.
+func (r *Repository) ChangeUsername(ctx context.Context, userId int64, username, canonical string, reservedUntil time.Time) error {
+	const op = "psql.ChangeUsername"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Блокировка строки не дает параллельной смене записать в историю неактуальный username
+	var oldUsername, oldCanonical string
+	err = tx.QueryRowContext(ctx,
+		"SELECT username, username_canonical FROM users WHERE id = $1 AND is_active = true FOR UPDATE",
+		userId).Scan(&oldUsername, &oldCanonical)
+	if err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s, %w", op, repository.ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if oldUsername == username {
+		_ = tx.Rollback()
+		return nil
+	}
+
+	var reserved bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM username_history 
+			WHERE username_canonical = $1 AND reserved_until > now() AND user_id <> $2
+		)`,
+		canonical, userId).Scan(&reserved)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if reserved {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, repository.ErrUsernameReserved)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET username = $1, username_canonical = $2, updated_at = now() WHERE id = $3",
+		username, canonical, userId)
+	if err != nil {
+		_ = tx.Rollback()
+		//Ошибка нарушения constraint unique
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code.Name() == repository.CodeConstraintUnique {
+			return fmt.Errorf("%s, %w", op, repository.ErrUserAlredyExists)
+		}
+
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO username_history (user_id, username, username_canonical, reserved_until) VALUES ($1, $2, $3, $4)",
+		userId, oldUsername, oldCanonical, reservedUntil)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	err = saveEvent(ctx, tx, models.EventUserProfileUpdated, userEventPayload{
+		UserId:   userId,
+		Username: username,
+		Changes:  map[string]string{"username": username},
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// GetUsernameHistory получает историю смены username пользователя, начиная с последней смены.
+func (r *Repository) GetUsernameHistory(ctx context.Context, userId int64) ([]models.UsernameChange, error) {
+	const op = "psql.GetUsernameHistory"
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT username, changed_at, reserved_until 
+		FROM username_history 
+		WHERE user_id = $1 
+		ORDER BY changed_at DESC, id DESC`,
+		userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+	defer rows.Close()
+
+	var history []models.UsernameChange
+	for rows.Next() {
+		var change models.UsernameChange
+		if err = rows.Scan(&change.Username, &change.ChangedAt, &change.ReservedUntil); err != nil {
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		history = append(history, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return history, nil
+}
+
+// CanonicalizeUsernames пересчитывает канонические формы username всех пользователей функцией canonical.
+// Если форма совпала с формой более раннего пользователя, пользователю назначается служебная форма
+// <каноническая форма>#<id>, а конфликт сохраняется в username_conflicts. Разрешенные конфликты удаляются.
+// Возвращает число пользователей, у которых изменилась каноническая форма, и id пользователей с конфликтом.
+func (r *Repository) CanonicalizeUsernames(ctx context.Context, canonical func(string) string) (int, []int64, error) {
+	const op = "psql.CanonicalizeUsernames"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Блокировка не дает регистрации или смене username занять форму во время пересчета, чтение не блокируется
+	if _, err = tx.ExecContext(ctx, "LOCK TABLE users IN EXCLUSIVE MODE"); err != nil {
+		_ = tx.Rollback()
+		return 0, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, username, username_canonical FROM users ORDER BY id")
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Форму получает пользователь с наименьшим id, остальные с той же формой считаются конфликтом
+	owners := make(map[string]int64)
+	var ids []int64
+	var forms, conflictUsernames, conflictForms []string
+	//Пустой, а не nil срез: иначе в запрос передается NULL и разрешенные конфликты не удаляются
+	conflicts := []int64{}
+	for rows.Next() {
+		var id int64
+		var name, current string
+		if err = rows.Scan(&id, &name, &current); err != nil {
+			_ = rows.Close()
+			_ = tx.Rollback()
+			return 0, nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		form := canonical(name)
+		if _, ok := owners[form]; ok {
+			conflicts = append(conflicts, id)
+			conflictUsernames = append(conflictUsernames, name)
+			conflictForms = append(conflictForms, form)
+			form = fmt.Sprintf("%s#%d", form, id)
+		} else {
+			owners[form] = id
+		}
+
+		if form != current {
+			ids = append(ids, id)
+			forms = append(forms, form)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		_ = tx.Rollback()
+		return 0, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if len(ids) > 0 {
+		//Уникальный индекс проверяется для каждой строки, поэтому сначала формы освобождаются временными значениями
+		_, err = tx.ExecContext(ctx,
+			"UPDATE users SET username_canonical = '#' || id WHERE id = ANY($1)",
+			pq.Array(ids))
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE users u SET username_canonical = c.canonical 
+			FROM unnest($1::bigint[], $2::text[]) AS c (id, canonical) 
+			WHERE u.id = c.id`,
+			pq.Array(ids), pq.Array(forms))
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, nil, fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM username_conflicts WHERE user_id <> ALL($1)", pq.Array(conflicts))
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO username_conflicts (user_id, username, username_canonical) 
+		SELECT * FROM unnest($1::bigint[], $2::text[], $3::text[]) 
+		ON CONFLICT (user_id) DO UPDATE SET username = excluded.username, username_canonical = excluded.username_canonical`,
+		pq.Array(conflicts), pq.Array(conflictUsernames), pq.Array(conflictForms))
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return len(ids), conflicts, nil
+}
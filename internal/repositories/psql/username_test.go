@@ -0,0 +1,386 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/lib/pq"
+)
+
+var (
+	TestNewUsername   = "User2"
+	TestNewCanonical  = "user2"
+	TestReservedUntil = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestRepository_ChangeUsername(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	errDB := errors.New("db error")
+
+	type args struct {
+		ctx           context.Context
+		userId        int64
+		username      string
+		canonical     string
+		reservedUntil time.Time
+	}
+	type mockBehavior func(a args)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:           context.Background(),
+				userId:        TestUserId,
+				username:      TestNewUsername,
+				canonical:     TestNewCanonical,
+				reservedUntil: TestReservedUntil,
+			},
+			mockBehavior: func(a args) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT username, username_canonical FROM users (.+) FOR UPDATE").
+					WithArgs(a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"username", "username_canonical"}).AddRow(TestUsername, TestCanonical))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM username_history").
+					WithArgs(a.canonical, a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec("UPDATE users SET username").
+					WithArgs(a.username, a.canonical, a.userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO username_history").
+					WithArgs(a.userId, TestUsername, TestCanonical, a.reservedUntil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO outbox").
+					WithArgs(models.EventUserProfileUpdated, a.userId, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "SameUsername",
+			args: args{
+				ctx:           context.Background(),
+				userId:        TestUserId,
+				username:      TestUsername,
+				canonical:     TestCanonical,
+				reservedUntil: TestReservedUntil,
+			},
+			mockBehavior: func(a args) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT username, username_canonical FROM users").
+					WithArgs(a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"username", "username_canonical"}).AddRow(TestUsername, TestCanonical))
+
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:           context.Background(),
+				userId:        TestUserId,
+				username:      TestNewUsername,
+				canonical:     TestNewCanonical,
+				reservedUntil: TestReservedUntil,
+			},
+			mockBehavior: func(a args) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT username, username_canonical FROM users").
+					WithArgs(a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"username", "username_canonical"}))
+
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrUserNotFound,
+		},
+		{
+			name: "UsernameReserved",
+			args: args{
+				ctx:           context.Background(),
+				userId:        TestUserId,
+				username:      TestNewUsername,
+				canonical:     TestNewCanonical,
+				reservedUntil: TestReservedUntil,
+			},
+			mockBehavior: func(a args) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT username, username_canonical FROM users").
+					WithArgs(a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"username", "username_canonical"}).AddRow(TestUsername, TestCanonical))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM username_history").
+					WithArgs(a.canonical, a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrUsernameReserved,
+		},
+		{
+			name: "UsernameTaken",
+			args: args{
+				ctx:           context.Background(),
+				userId:        TestUserId,
+				username:      TestNewUsername,
+				canonical:     TestNewCanonical,
+				reservedUntil: TestReservedUntil,
+			},
+			mockBehavior: func(a args) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT username, username_canonical FROM users").
+					WithArgs(a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"username", "username_canonical"}).AddRow(TestUsername, TestCanonical))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM username_history").
+					WithArgs(a.canonical, a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec("UPDATE users SET username").
+					WithArgs(a.username, a.canonical, a.userId).
+					WillReturnError(&pq.Error{Code: "23505"})
+
+				mock.ExpectRollback()
+			},
+			wantErr: repository.ErrUserAlredyExists,
+		},
+		{
+			name: "ErrorHistory",
+			args: args{
+				ctx:           context.Background(),
+				userId:        TestUserId,
+				username:      TestNewUsername,
+				canonical:     TestNewCanonical,
+				reservedUntil: TestReservedUntil,
+			},
+			mockBehavior: func(a args) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery("SELECT username, username_canonical FROM users").
+					WithArgs(a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"username", "username_canonical"}).AddRow(TestUsername, TestCanonical))
+				mock.ExpectQuery("SELECT EXISTS (.+) FROM username_history").
+					WithArgs(a.canonical, a.userId).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec("UPDATE users SET username").
+					WithArgs(a.username, a.canonical, a.userId).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO username_history").
+					WithArgs(a.userId, TestUsername, TestCanonical, a.reservedUntil).
+					WillReturnError(errDB)
+
+				mock.ExpectRollback()
+			},
+			wantErr: errDB,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args)
+
+			err := rep.ChangeUsername(tt.args.ctx, tt.args.userId, tt.args.username, tt.args.canonical, tt.args.reservedUntil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.ChangeUsername() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestRepository_GetUsernameHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+
+	changedAt := TestReservedUntil.AddDate(0, -1, 0)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	type mockBehavior func(ctx context.Context, userId int64)
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.UsernameChange
+		wantErr      bool
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"username", "changed_at", "reserved_until"}).
+					AddRow(TestUsername, changedAt, TestReservedUntil)
+				mock.ExpectQuery("SELECT (.+) FROM username_history").WithArgs(userId).WillReturnRows(rows)
+			},
+			want: []models.UsernameChange{
+				{Username: TestUsername, ChangedAt: changedAt, ReservedUntil: TestReservedUntil},
+			},
+		},
+		{
+			name: "Empty",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				rows := sqlmock.NewRows([]string{"username", "changed_at", "reserved_until"})
+				mock.ExpectQuery("SELECT (.+) FROM username_history").WithArgs(userId).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(ctx context.Context, userId int64) {
+				mock.ExpectQuery("SELECT (.+) FROM username_history").WithArgs(userId).WillReturnError(errors.New(""))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior(tt.args.ctx, tt.args.userId)
+
+			got, err := rep.GetUsernameHistory(tt.args.ctx, tt.args.userId)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.GetUsernameHistory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.GetUsernameHistory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_CanonicalizeUsernames(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	rep := New(db)
+	errDB := errors.New("db error")
+	columns := []string{"id", "username", "username_canonical"}
+
+	tests := []struct {
+		name          string
+		mockBehavior  func()
+		wantUpdated   int
+		wantConflicts []int64
+		wantErr       error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("LOCK TABLE users IN EXCLUSIVE MODE").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, username, username_canonical FROM users ORDER BY id").
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "Admin", "admin").
+						AddRow(2, "ADMIN", "admin#2").
+						AddRow(3, "Bob", "bob_old").
+						AddRow(4, "Carol", "carol#4"))
+				mock.ExpectExec("UPDATE users SET username_canonical = '#' (.+) WHERE id = ANY").
+					WithArgs(pq.Array([]int64{3, 4})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE users u SET username_canonical = c.canonical").
+					WithArgs(pq.Array([]int64{3, 4}), pq.Array([]string{"bob", "carol"})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM username_conflicts").
+					WithArgs(pq.Array([]int64{2})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO username_conflicts").
+					WithArgs(pq.Array([]int64{2}), pq.Array([]string{"ADMIN"}), pq.Array([]string{"admin"})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantUpdated:   2,
+			wantConflicts: []int64{2},
+		},
+		{
+			name: "NoChanges",
+			mockBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("LOCK TABLE users IN EXCLUSIVE MODE").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, username, username_canonical FROM users ORDER BY id").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Admin", "admin"))
+				mock.ExpectExec("DELETE FROM username_conflicts").
+					WithArgs(pq.Array([]int64{})).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO username_conflicts").
+					WithArgs(pq.Array([]int64{}), pq.Array([]string(nil)), pq.Array([]string(nil))).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			wantConflicts: []int64{},
+		},
+		{
+			name: "Error",
+			mockBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("LOCK TABLE users IN EXCLUSIVE MODE").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id, username, username_canonical FROM users ORDER BY id").
+					WillReturnError(errDB)
+				mock.ExpectRollback()
+			},
+			wantErr: errDB,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockBehavior()
+
+			updated, conflicts, err := rep.CanonicalizeUsernames(context.Background(), strings.ToLower)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Repository.CanonicalizeUsernames() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if updated != tt.wantUpdated {
+				t.Errorf("Repository.CanonicalizeUsernames() updated = %v, want %v", updated, tt.wantUpdated)
+			}
+
+			if !reflect.DeepEqual(conflicts, tt.wantConflicts) {
+				t.Errorf("Repository.CanonicalizeUsernames() conflicts = %v, want %v", conflicts, tt.wantConflicts)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -3,9 +3,29 @@ package repository
 import "errors"
 
 var (
-	ErrUserNotFound        = errors.New("user not found")
-	ErrUserAlredyExists    = errors.New("user already exists")
-	ErrUserAlreadyInactive = errors.New("user already inactive")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrUserAlredyExists       = errors.New("user already exists")
+	ErrUserAlreadyInactive    = errors.New("user already inactive")
+	ErrUserAlreadyActive      = errors.New("user already active")
+	ErrTokenNotFound          = errors.New("token not found")
+	ErrSessionNotFound        = errors.New("session not found")
+	ErrResetCodeNotFound      = errors.New("reset code not found")
+	ErrTooManyResetCodes      = errors.New("too many active reset codes")
+	ErrTOTPNotFound           = errors.New("totp not found")
+	ErrTOTPAlreadyConfirmed   = errors.New("totp already confirmed")
+	ErrRecoveryCodeNotFound   = errors.New("recovery code not found")
+	ErrChallengeNotFound      = errors.New("challenge not found")
+	ErrUsernameReserved       = errors.New("username reserved")
+	ErrDeletionScheduled      = errors.New("deletion already scheduled")
+	ErrDeletionNotScheduled   = errors.New("deletion not scheduled")
+	ErrAlreadyContacts        = errors.New("already contacts")
+	ErrContactRequestExists   = errors.New("contact request already exists")
+	ErrContactRequestNotFound = errors.New("contact request not found")
+	ErrContactRequestBlocked  = errors.New("contact request blocked")
+	ErrContactNotFound        = errors.New("contact not found")
+	ErrAlreadyBlocked         = errors.New("user already blocked")
+	ErrBlockNotFound          = errors.New("block not found")
+	ErrDeviceNotFound         = errors.New("device not found")
 )
 
 //Код ошибки PostgreSQL
@@ -0,0 +1,163 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// BlockSaver предоставляет методы изменения блокировок пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=BlockSaver
+type BlockSaver interface {
+	// SaveBlock сохраняет блокировку пользователя blockedId пользователем blockerId.
+	// Вместе с блокировкой удаляет контакты пользователей и заявки в контакты между ними.
+	// Если блокируемый пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+	// Если пользователь уже заблокирован, возвращает ошибку repository.ErrAlreadyBlocked.
+	SaveBlock(ctx context.Context, blockerId, blockedId int64) error
+
+	// DeleteBlock удаляет блокировку пользователя blockedId пользователем blockerId.
+	// Если блокировка не найдена, возвращает ошибку repository.ErrBlockNotFound.
+	DeleteBlock(ctx context.Context, blockerId, blockedId int64) error
+}
+
+// BlockProvider предоставляет методы получения блокировок пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=BlockProvider
+type BlockProvider interface {
+	// ListBlocked получает активных пользователей, заблокированных пользователем userId, упорядоченных по id.
+	// Если afterId не равен 0, возвращает пользователей, следующих после пользователя с этим id. Возвращает не более limit пользователей.
+	ListBlocked(ctx context.Context, userId, afterId int64, limit int) ([]models.BlockedUser, error)
+
+	// IsBlocked сообщает, заблокировал ли пользователь blockerId пользователя blockedId.
+	IsBlocked(ctx context.Context, blockerId, blockedId int64) (bool, error)
+}
+
+// blockedPageToken - содержимое токена страницы списка заблокированных пользователей.
+// Токен привязан к владельцу списка, чтобы его нельзя было применить к чужому списку.
+type blockedPageToken struct {
+	UserId  int64 `json:"u"`
+	AfterId int64 `json:"id"`
+}
+
+// BlockUser реализует логику блокировки пользователем userId пользователя blockedId.
+// Пользователи удаляются из контактов друг друга, заявки в контакты между ними удаляются.
+// Если userId и blockedId совпадают, возвращает users.ErrSelfBlock.
+// Если блокируемый пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+// Если пользователь уже заблокирован, возвращает users.ErrAlreadyBlocked.
+func (u *Users) BlockUser(ctx context.Context, userId, blockedId int64) error {
+	const op = "users.BlockUser"
+
+	if userId == blockedId {
+		u.log.Warnf("block of self. user_id=%d", userId)
+		return fmt.Errorf("%s, %w", op, ErrSelfBlock)
+	}
+
+	if err := u.blockSaver.SaveBlock(ctx, userId, blockedId); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, repository.ErrAlreadyBlocked) {
+			u.log.Warnf("user already blocked. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrAlreadyBlocked)
+		}
+
+		u.log.Errorf("error saving block. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// UnblockUser реализует логику снятия пользователем userId блокировки с пользователя blockedId.
+// Если пользователь не заблокирован, возвращает users.ErrBlockNotFound.
+func (u *Users) UnblockUser(ctx context.Context, userId, blockedId int64) error {
+	const op = "users.UnblockUser"
+
+	if err := u.blockSaver.DeleteBlock(ctx, userId, blockedId); err != nil {
+		if errors.Is(err, repository.ErrBlockNotFound) {
+			u.log.Warnf("block not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrBlockNotFound)
+		}
+
+		u.log.Errorf("error deleting block. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ListBlocked реализует логику постраничного получения пользователей, заблокированных пользователем userId, упорядоченных по id.
+// pageToken - токен страницы из предыдущего ответа, пустой для первой страницы.
+// Возвращает пользователей и токен следующей страницы, пустой, если пользователей больше нет.
+// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+func (u *Users) ListBlocked(ctx context.Context, userId int64, pageToken string, limit int) ([]models.BlockedUser, string, error) {
+	const op = "users.ListBlocked"
+
+	var afterId int64
+	if pageToken != "" {
+		var token blockedPageToken
+		if err := decodePageToken(pageToken, &token); err != nil {
+			u.log.Warnf("invalid page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+		if token.UserId != userId || token.AfterId == 0 {
+			u.log.Warnf("page token belongs to another list. user_id=%d", token.UserId)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+
+		afterId = token.AfterId
+	}
+
+	limit = u.pageSize.pageSize(limit)
+	//Лишний пользователь запрашивается, чтобы узнать, есть ли следующая страница
+	blocked, err := u.blockProvider.ListBlocked(ctx, userId, afterId, limit+1)
+	if err != nil {
+		u.log.Errorf("error listing blocked users. %w", err)
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	var nextPageToken string
+	if len(blocked) > limit {
+		blocked = blocked[:limit]
+		nextPageToken, err = encodePageToken(blockedPageToken{UserId: userId, AfterId: blocked[limit-1].User.Id})
+		if err != nil {
+			u.log.Errorf("error encoding page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	ids := make([]int64, 0, len(blocked))
+	for _, b := range blocked {
+		ids = append(ids, b.User.Id)
+	}
+
+	view, err := u.privacyView(ctx, userId, ids)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	for i := range blocked {
+		view.applyProfile(&blocked[i].User)
+	}
+
+	return blocked, nextPageToken, nil
+}
+
+// IsBlocked реализует логику проверки, заблокировал ли пользователь userId пользователя targetId.
+// Используется другими сервисами перед доставкой сообщения от targetId пользователю userId.
+func (u *Users) IsBlocked(ctx context.Context, userId, targetId int64) (bool, error) {
+	const op = "users.IsBlocked"
+
+	blocked, err := u.blockProvider.IsBlocked(ctx, userId, targetId)
+	if err != nil {
+		u.log.Errorf("error checking block. %w", err)
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return blocked, nil
+}
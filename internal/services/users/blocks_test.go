@@ -0,0 +1,421 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestBlockedId   int64 = 2
+	TestBlockedUser       = models.BlockedUser{
+		User: models.User{
+			Id:       TestBlockedId,
+			Username: "user2",
+			IsActive: true,
+		},
+		BlockedAt: time.Unix(1700000000, 0).UTC(),
+	}
+)
+
+// newTestBlockedPageToken возвращает токен страницы заблокированных пользователей userId, следующей после пользователя afterId.
+func newTestBlockedPageToken(userId, afterId int64) string {
+	token, err := encodePageToken(blockedPageToken{UserId: userId, AfterId: afterId})
+	if err != nil {
+		panic(err)
+	}
+
+	return token
+}
+
+func TestUsers_BlockUser(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		blockSaver *mocks.BlockSaver,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		blockedId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockSaver.On("SaveBlock", ctx, TestUserId, TestBlockedId).Return(nil)
+			},
+		},
+		{
+			name: "Self",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrSelfBlock,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockSaver.On("SaveBlock", ctx, TestUserId, TestBlockedId).Return(repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "AlreadyBlocked",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockSaver.On("SaveBlock", ctx, TestUserId, TestBlockedId).Return(repository.ErrAlreadyBlocked)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrAlreadyBlocked,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockSaver.On("SaveBlock", ctx, TestUserId, TestBlockedId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			blockSaver := mocks.NewBlockSaver(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, blockSaver, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				blockSaver:      blockSaver,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			err := u.BlockUser(tt.args.ctx, tt.args.userId, tt.args.blockedId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.BlockUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.BlockUser, "+tt.wantErr.Error(), fmt.Sprintf("users.BlockUser() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_UnblockUser(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		blockSaver *mocks.BlockSaver,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		blockedId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockSaver.On("DeleteBlock", ctx, TestUserId, TestBlockedId).Return(nil)
+			},
+		},
+		{
+			name: "BlockNotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockSaver.On("DeleteBlock", ctx, TestUserId, TestBlockedId).Return(repository.ErrBlockNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrBlockNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				blockedId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockSaver.On("DeleteBlock", ctx, TestUserId, TestBlockedId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			blockSaver := mocks.NewBlockSaver(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, blockSaver, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				blockSaver:      blockSaver,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			err := u.UnblockUser(tt.args.ctx, tt.args.userId, tt.args.blockedId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.UnblockUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.UnblockUser, "+tt.wantErr.Error(), fmt.Sprintf("users.UnblockUser() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_ListBlocked(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		blockSaver *mocks.BlockSaver,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	blocked := make([]models.BlockedUser, 0, 3)
+	for i := int64(2); i <= 4; i++ {
+		user := TestBlockedUser
+		user.User.Id = i
+		blocked = append(blocked, user)
+	}
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		pageToken string
+		limit     int
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.BlockedUser
+		wantToken    string
+		wantErr      error
+	}{
+		{
+			name: "FirstPage",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockProvider.On("ListBlocked", ctx, TestUserId, int64(0), 3).Return(blocked, nil)
+			},
+			want:      blocked[:2],
+			wantToken: newTestBlockedPageToken(TestUserId, 3),
+		},
+		{
+			name: "LastPage",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				pageToken: newTestBlockedPageToken(TestUserId, 1),
+				limit:     10,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockProvider.On("ListBlocked", ctx, TestUserId, int64(1), 4).Return(blocked, nil)
+			},
+			want: blocked,
+		},
+		{
+			name: "PageTokenOfAnotherUser",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				pageToken: newTestBlockedPageToken(TestBlockedId, 3),
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockProvider.On("ListBlocked", ctx, TestUserId, int64(0), 3).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			blockSaver := mocks.NewBlockSaver(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, blockSaver, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				blockSaver:      blockSaver,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			got, token, err := u.ListBlocked(tt.args.ctx, tt.args.userId, tt.args.pageToken, tt.args.limit)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ListBlocked() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ListBlocked, "+tt.wantErr.Error(), fmt.Sprintf("users.ListBlocked() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
+
+func TestUsers_IsBlocked(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		blockSaver *mocks.BlockSaver,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		targetId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         bool
+		wantErr      error
+	}{
+		{
+			name: "Blocked",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				targetId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockProvider.On("IsBlocked", ctx, TestUserId, TestBlockedId).Return(true, nil)
+			},
+			want: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				targetId: TestBlockedId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, blockSaver *mocks.BlockSaver, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				blockProvider.On("IsBlocked", ctx, TestUserId, TestBlockedId).Return(false, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			blockSaver := mocks.NewBlockSaver(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, blockSaver, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				blockSaver:      blockSaver,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			got, err := u.IsBlocked(tt.args.ctx, tt.args.userId, tt.args.targetId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.IsBlocked() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.IsBlocked, "+tt.wantErr.Error(), fmt.Sprintf("users.IsBlocked() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// UserChangeProvider предоставляет методы чтения и очистки журнала изменений пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=UserChangeProvider
+type UserChangeProvider interface {
+	// GetUserChanges получает не более limit изменений пользователей, следующих после позиции (afterTxId, afterId),
+	// в порядке позиции. Изменения, зафиксированные позже, всегда получают позицию после уже прочитанных.
+	GetUserChanges(ctx context.Context, afterTxId, afterId int64, limit int) ([]models.UserChange, error)
+
+	// GetChangeHorizon получает горизонт журнала изменений: изменения с TxId меньше горизонта уже зафиксированы,
+	// новые изменения получат TxId не меньше горизонта.
+	GetChangeHorizon(ctx context.Context) (int64, error)
+
+	// GetOldestChange получает позицию (TxId, Id) самого старого изменения, оставшегося в журнале.
+	// Если журнал пуст, возвращает нулевую позицию.
+	GetOldestChange(ctx context.Context) (int64, int64, error)
+
+	// DeleteUserChanges удаляет не более limit изменений пользователей, добавленных раньше before.
+	// Возвращает число удаленных изменений.
+	DeleteUserChanges(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// UserChangeListener предоставляет метод подписки на уведомления о новых изменениях пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=UserChangeListener
+type UserChangeListener interface {
+	// Subscribe подписывает на уведомления о новых изменениях до отмены ctx.
+	// Уведомления не накапливаются, одно уведомление может означать несколько изменений.
+	// После отмены ctx или остановки слушателя канал уведомлений закрывается.
+	Subscribe(ctx context.Context) (<-chan struct{}, error)
+}
+
+// changeCursor - содержимое курсора потока изменений пользователей.
+type changeCursor struct {
+	TxId    int64 `json:"tx"`
+	AfterId int64 `json:"id"`
+}
+
+// WatchUsers реализует логику потока изменений пользователей.
+// Сначала в send передаются изменения, следующие после sinceCursor, затем новые изменения, пока не будет отменен ctx.
+// Вместе с каждым изменением передается его курсор, с которого поток можно продолжить после разрыва.
+// Если sinceCursor пустой, передаются только изменения, зафиксированные после начала потока.
+// Если курсор недействителен, возвращает users.ErrInvalidCursor.
+// Если курсор раньше самого старого изменения, оставшегося в журнале, изменения после него могли быть удалены
+// по сроку хранения, и возвращается users.ErrCursorExpired.
+// Если send возвращает ошибку, поток прекращается и ошибка возвращается.
+func (u *Users) WatchUsers(ctx context.Context, sinceCursor string, send func(change models.UserChange, cursor string) error) error {
+	const op = "users.WatchUsers"
+
+	var cursor changeCursor
+	if sinceCursor != "" {
+		if err := decodePageToken(sinceCursor, &cursor); err != nil || cursor.TxId <= 0 || cursor.AfterId <= 0 {
+			u.log.Warnf("invalid change cursor. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrInvalidCursor)
+		}
+
+		oldestTxId, oldestId, err := u.changeProvider.GetOldestChange(ctx)
+		if err != nil {
+			u.log.Errorf("error getting oldest user change. %w", err)
+			return fmt.Errorf("%s, %w", op, err)
+		}
+
+		//Журнал очищается с самых старых изменений, поэтому если курсор раньше самого старого оставшегося изменения
+		//или журнал пуст, изменения после курсора могли быть удалены
+		if oldestTxId == 0 || cursor.TxId < oldestTxId || (cursor.TxId == oldestTxId && cursor.AfterId < oldestId) {
+			u.log.Warnf("change cursor expired. tx_id=%d, id=%d", cursor.TxId, cursor.AfterId)
+			return fmt.Errorf("%s, %w", op, ErrCursorExpired)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	//Подписка оформляется до чтения журнала, чтобы не пропустить изменения, добавленные во время чтения
+	notify, err := u.changeListener.Subscribe(ctx)
+	if err != nil {
+		u.log.Errorf("error subscribing to user changes. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Без курсора поток начинается с горизонта журнала: изменения до него уже зафиксированы и не передаются
+	if sinceCursor == "" {
+		if cursor.TxId, err = u.changeProvider.GetChangeHorizon(ctx); err != nil {
+			u.log.Errorf("error getting change horizon. %w", err)
+			return fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	//Изменения становятся видны, когда завершатся все более ранние транзакции, а не только добавившая их,
+	//поэтому журнал перечитывается и без уведомлений
+	ticker := time.NewTicker(u.changePollInterval)
+	defer ticker.Stop()
+
+	for {
+		changes, err := u.changeProvider.GetUserChanges(ctx, cursor.TxId, cursor.AfterId, u.pageSize.Max)
+		if err != nil {
+			u.log.Errorf("error getting user changes. %w", err)
+			return fmt.Errorf("%s, %w", op, err)
+		}
+
+		for _, change := range changes {
+			cursor = changeCursor{TxId: change.TxId, AfterId: change.Id}
+			token, err := encodePageToken(cursor)
+			if err != nil {
+				u.log.Errorf("error encoding change cursor. %w", err)
+				return fmt.Errorf("%s, %w", op, err)
+			}
+
+			if err = send(change, token); err != nil {
+				u.log.Warnf("error sending user change. %w", err)
+				return fmt.Errorf("%s, %w", op, err)
+			}
+		}
+
+		//Полная страница означает, что в журнале могут оставаться изменения, и они читаются без ожидания
+		if len(changes) == u.pageSize.Max {
+			continue
+		}
+
+		select {
+		case _, ok := <-notify:
+			if !ok {
+				return nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeUserChanges удаляет из журнала изменения пользователей старше срока хранения пачками по batchSize.
+// Возвращает число удаленных изменений.
+func (u *Users) PurgeUserChanges(ctx context.Context, batchSize int) (int, error) {
+	const op = "users.PurgeUserChanges"
+
+	before := time.Now().Add(-u.changeRetention)
+
+	var purged int
+	for {
+		deleted, err := u.changeProvider.DeleteUserChanges(ctx, before, batchSize)
+		if err != nil {
+			u.log.Errorf("error deleting user changes. %w", err)
+			return purged, fmt.Errorf("%s, %w", op, err)
+		}
+
+		purged += deleted
+		if deleted < batchSize {
+			return purged, nil
+		}
+	}
+}
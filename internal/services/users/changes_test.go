@@ -0,0 +1,302 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	TestChangePageSize     = 2
+	TestChangeHorizon      = int64(100)
+	TestChangePollInterval = time.Hour
+	TestChangeRetention    = 24 * time.Hour
+)
+
+var (
+	TestChangeCreatedAt = time.Now().Truncate(time.Second)
+	TestUserChanges     = []models.UserChange{
+		{Id: 1, TxId: 101, Type: models.EventUserRegistered, UserId: TestUserId, Payload: []byte(`{"user_id":1}`), CreatedAt: TestChangeCreatedAt},
+		{Id: 3, TxId: 102, Type: models.EventUserProfileUpdated, UserId: TestUserId, Payload: []byte(`{"user_id":1}`), CreatedAt: TestChangeCreatedAt},
+		{Id: 2, TxId: 103, Type: models.EventUserDeactivated, UserId: TestUserId, Payload: []byte(`{"user_id":1}`), CreatedAt: TestChangeCreatedAt},
+	}
+)
+
+// testChangeCursor возвращает курсор изменения change.
+func testChangeCursor(change models.UserChange) string {
+	cursor, _ := encodePageToken(changeCursor{TxId: change.TxId, AfterId: change.Id})
+	return cursor
+}
+
+func TestUsers_WatchUsers(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		changeProvider *mocks.UserChangeProvider,
+		changeListener *mocks.UserChangeListener,
+	)
+
+	// newNotify возвращает закрытый канал с notifications уведомлениями, как после отмены подписки.
+	newNotify := func(notifications int) <-chan struct{} {
+		notify := make(chan struct{}, notifications)
+		for i := 0; i < notifications; i++ {
+			notify <- struct{}{}
+		}
+		close(notify)
+
+		return notify
+	}
+
+	tests := []struct {
+		name         string
+		sinceCursor  string
+		sendErr      error
+		mockBehavior mockBehavior
+		want         []models.UserChange
+		wantCursors  []string
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeListener.On("Subscribe", mock.Anything).Return(newNotify(1), nil)
+				changeProvider.On("GetChangeHorizon", mock.Anything).Return(TestChangeHorizon, nil)
+				changeProvider.On("GetUserChanges", mock.Anything, TestChangeHorizon, int64(0), TestChangePageSize).Return(TestUserChanges[:1], nil)
+				changeProvider.On("GetUserChanges", mock.Anything, int64(101), int64(1), TestChangePageSize).Return(nil, nil)
+			},
+			want:        TestUserChanges[:1],
+			wantCursors: []string{testChangeCursor(TestUserChanges[0])},
+		},
+		{
+			name:        "FullPage",
+			sinceCursor: testChangeCursor(TestUserChanges[0]),
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeProvider.On("GetOldestChange", mock.Anything).Return(int64(101), int64(1), nil)
+				changeListener.On("Subscribe", mock.Anything).Return(newNotify(0), nil)
+				changeProvider.On("GetUserChanges", mock.Anything, int64(101), int64(1), TestChangePageSize).Return(TestUserChanges[1:], nil)
+				changeProvider.On("GetUserChanges", mock.Anything, int64(103), int64(2), TestChangePageSize).Return(nil, nil)
+			},
+			want:        TestUserChanges[1:],
+			wantCursors: []string{testChangeCursor(TestUserChanges[1]), testChangeCursor(TestUserChanges[2])},
+		},
+		{
+			name:        "InvalidCursor",
+			sinceCursor: "!",
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:        "ZeroCursor",
+			sinceCursor: testChangeCursor(models.UserChange{}),
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:        "ExpiredCursor",
+			sinceCursor: testChangeCursor(TestUserChanges[0]),
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				//Изменение курсора и следующие за ним удалены из журнала
+				changeProvider.On("GetOldestChange", mock.Anything).Return(int64(102), int64(3), nil)
+				log.On("Warnf", mock.Anything, mock.Anything, mock.Anything)
+			},
+			wantErr: ErrCursorExpired,
+		},
+		{
+			name:        "ExpiredCursorEmptyJournal",
+			sinceCursor: testChangeCursor(TestUserChanges[0]),
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeProvider.On("GetOldestChange", mock.Anything).Return(int64(0), int64(0), nil)
+				log.On("Warnf", mock.Anything, mock.Anything, mock.Anything)
+			},
+			wantErr: ErrCursorExpired,
+		},
+		{
+			name:        "OldestChangeError",
+			sinceCursor: testChangeCursor(TestUserChanges[0]),
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeProvider.On("GetOldestChange", mock.Anything).Return(int64(0), int64(0), errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name:    "SendError",
+			sendErr: errors.New(""),
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeListener.On("Subscribe", mock.Anything).Return(newNotify(0), nil)
+				changeProvider.On("GetChangeHorizon", mock.Anything).Return(TestChangeHorizon, nil)
+				changeProvider.On("GetUserChanges", mock.Anything, TestChangeHorizon, int64(0), TestChangePageSize).Return(TestUserChanges[:2], nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			want:        TestUserChanges[:1],
+			wantCursors: []string{testChangeCursor(TestUserChanges[0])},
+			wantErr:     errors.New(""),
+		},
+		{
+			name: "SubscribeError",
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeListener.On("Subscribe", mock.Anything).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "HorizonError",
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeListener.On("Subscribe", mock.Anything).Return(newNotify(0), nil)
+				changeProvider.On("GetChangeHorizon", mock.Anything).Return(int64(0), errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name:        "GetChangesError",
+			sinceCursor: testChangeCursor(TestUserChanges[0]),
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeProvider.On("GetOldestChange", mock.Anything).Return(int64(101), int64(1), nil)
+				changeListener.On("Subscribe", mock.Anything).Return(newNotify(0), nil)
+				changeProvider.On("GetUserChanges", mock.Anything, int64(101), int64(1), TestChangePageSize).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			changeProvider := mocks.NewUserChangeProvider(t)
+			changeListener := mocks.NewUserChangeListener(t)
+
+			tt.mockBehavior(log, changeProvider, changeListener)
+			u := &Users{
+				log:                log,
+				changeProvider:     changeProvider,
+				changeListener:     changeListener,
+				pageSize:           PageSizeParams{Default: TestChangePageSize, Max: TestChangePageSize},
+				changePollInterval: TestChangePollInterval,
+				changeRetention:    TestChangeRetention,
+			}
+
+			var (
+				got     []models.UserChange
+				cursors []string
+			)
+			err := u.WatchUsers(context.Background(), tt.sinceCursor, func(change models.UserChange, cursor string) error {
+				got = append(got, change)
+				cursors = append(cursors, cursor)
+				return tt.sendErr
+			})
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.WatchUsers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.WatchUsers, "+tt.wantErr.Error(), fmt.Sprintf("users.WatchUsers() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantCursors, cursors)
+		})
+	}
+}
+
+func TestUsers_WatchUsers_Poll(t *testing.T) {
+	sendErr := errors.New("")
+	log := loggermocks.NewLogger(t)
+	changeProvider := mocks.NewUserChangeProvider(t)
+	changeListener := mocks.NewUserChangeListener(t)
+
+	//Уведомлений нет, изменение становится видно при перечитывании журнала по таймеру
+	changeListener.On("Subscribe", mock.Anything).Return(make(<-chan struct{}), nil)
+	changeProvider.On("GetChangeHorizon", mock.Anything).Return(TestChangeHorizon, nil)
+	changeProvider.On("GetUserChanges", mock.Anything, TestChangeHorizon, int64(0), TestChangePageSize).Return(nil, nil).Once()
+	changeProvider.On("GetUserChanges", mock.Anything, TestChangeHorizon, int64(0), TestChangePageSize).Return(TestUserChanges[:1], nil).Once()
+	log.On("Warnf", mock.Anything, mock.Anything)
+	u := &Users{
+		log:                log,
+		changeProvider:     changeProvider,
+		changeListener:     changeListener,
+		pageSize:           PageSizeParams{Default: TestChangePageSize, Max: TestChangePageSize},
+		changePollInterval: time.Millisecond,
+		changeRetention:    TestChangeRetention,
+	}
+
+	var got []models.UserChange
+	err := u.WatchUsers(context.Background(), "", func(change models.UserChange, cursor string) error {
+		got = append(got, change)
+		return sendErr
+	})
+
+	assert.ErrorIs(t, err, sendErr)
+	assert.Equal(t, TestUserChanges[:1], got)
+}
+
+func TestUsers_PurgeUserChanges(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		changeProvider *mocks.UserChangeProvider,
+		changeListener *mocks.UserChangeListener,
+	)
+
+	tests := []struct {
+		name         string
+		batchSize    int
+		mockBehavior mockBehavior
+		want         int
+		wantErr      error
+	}{
+		{
+			name:      "OK",
+			batchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeProvider.On("DeleteUserChanges", mock.Anything, mock.AnythingOfType("time.Time"), 2).Return(2, nil).Once()
+				changeProvider.On("DeleteUserChanges", mock.Anything, mock.AnythingOfType("time.Time"), 2).Return(1, nil).Once()
+			},
+			want: 3,
+		},
+		{
+			name:      "Error",
+			batchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, changeProvider *mocks.UserChangeProvider, changeListener *mocks.UserChangeListener) {
+				changeProvider.On("DeleteUserChanges", mock.Anything, mock.AnythingOfType("time.Time"), 2).Return(0, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			changeProvider := mocks.NewUserChangeProvider(t)
+			changeListener := mocks.NewUserChangeListener(t)
+
+			tt.mockBehavior(log, changeProvider, changeListener)
+			u := &Users{
+				log:                log,
+				changeProvider:     changeProvider,
+				changeListener:     changeListener,
+				pageSize:           PageSizeParams{Default: TestChangePageSize, Max: TestChangePageSize},
+				changePollInterval: TestChangePollInterval,
+				changeRetention:    TestChangeRetention,
+			}
+			got, err := u.PurgeUserChanges(context.Background(), tt.batchSize)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.PurgeUserChanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.PurgeUserChanges, "+tt.wantErr.Error(), fmt.Sprintf("users.PurgeUserChanges() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
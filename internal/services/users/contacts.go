@@ -0,0 +1,260 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// ContactSaver предоставляет методы изменения контактов пользователей и заявок в контакты.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=ContactSaver
+type ContactSaver interface {
+	// SaveContactRequest сохраняет заявку пользователя fromUserId на добавление в контакты пользователя toUserId.
+	// Если встречная заявка уже есть, пользователи сразу становятся контактами и возвращается accepted = true.
+	// Если получатель не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+	// Если получатель заблокировал отправителя, возвращает ошибку repository.ErrContactRequestBlocked.
+	// Если пользователи уже состоят в контактах, возвращает ошибку repository.ErrAlreadyContacts.
+	// Если такая заявка уже есть, возвращает ошибку repository.ErrContactRequestExists.
+	SaveContactRequest(ctx context.Context, fromUserId, toUserId int64) (accepted bool, err error)
+
+	// AcceptContactRequest принимает заявку пользователя fromUserId, адресованную пользователю userId.
+	// Если заявка не найдена, возвращает ошибку repository.ErrContactRequestNotFound.
+	AcceptContactRequest(ctx context.Context, userId, fromUserId int64) error
+
+	// DeleteContactRequest удаляет заявку пользователя fromUserId, адресованную пользователю toUserId.
+	// Если заявка не найдена, возвращает ошибку repository.ErrContactRequestNotFound.
+	DeleteContactRequest(ctx context.Context, fromUserId, toUserId int64) error
+
+	// DeleteContact удаляет пользователей userId и contactId из контактов друг друга.
+	// Если пользователи не состоят в контактах, возвращает ошибку repository.ErrContactNotFound.
+	DeleteContact(ctx context.Context, userId, contactId int64) error
+}
+
+// ContactProvider предоставляет методы получения контактов пользователей и заявок в контакты.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=ContactProvider
+type ContactProvider interface {
+	// ListContacts получает активных контактов пользователя, упорядоченных по id.
+	// Если afterId не равен 0, возвращает контакты, следующие после контакта с этим id. Возвращает не более limit контактов.
+	ListContacts(ctx context.Context, userId, afterId int64, limit int) ([]models.Contact, error)
+
+	// ListContactRequests получает входящие, если incoming = true, или исходящие заявки пользователя, начиная с последней.
+	ListContactRequests(ctx context.Context, userId int64, incoming bool) ([]models.ContactRequest, error)
+
+	// IsContact сообщает, состоят ли пользователи userId и contactId в контактах друг друга.
+	IsContact(ctx context.Context, userId, contactId int64) (bool, error)
+
+	// FilterContacts возвращает id из candidateIds, которые состоят в контактах пользователя userId.
+	FilterContacts(ctx context.Context, userId int64, candidateIds []int64) ([]int64, error)
+}
+
+// contactsPageToken - содержимое токена страницы списка контактов.
+// Токен привязан к владельцу списка, чтобы его нельзя было применить к чужому списку.
+type contactsPageToken struct {
+	UserId  int64 `json:"u"`
+	AfterId int64 `json:"id"`
+}
+
+// SendContactRequest реализует логику отправки заявки в контакты от пользователя userId пользователю contactId.
+// Если contactId уже отправил заявку userId, пользователи сразу становятся контактами и возвращается accepted = true.
+// Если userId и contactId совпадают, возвращает users.ErrSelfContact.
+// Если получатель заблокировал отправителя, возвращает users.ErrContactRequestBlocked.
+// Если получатель не найден или неактивен, возвращает users.ErrUserNotFound.
+// Если пользователи уже состоят в контактах, возвращает users.ErrAlreadyContacts.
+// Если заявка уже отправлена, возвращает users.ErrContactRequestExists.
+func (u *Users) SendContactRequest(ctx context.Context, userId, contactId int64) (bool, error) {
+	const op = "users.SendContactRequest"
+
+	if userId == contactId {
+		u.log.Warnf("contact request to self. user_id=%d", userId)
+		return false, fmt.Errorf("%s, %w", op, ErrSelfContact)
+	}
+
+	//Блокировка проверяется в той же транзакции, что и сохранение заявки
+	accepted, err := u.contactSaver.SaveContactRequest(ctx, userId, contactId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return false, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, repository.ErrContactRequestBlocked) {
+			u.log.Warnf("contact request from blocked user. user_id=%d", userId)
+			return false, fmt.Errorf("%s, %w", op, ErrContactRequestBlocked)
+		}
+		if errors.Is(err, repository.ErrAlreadyContacts) {
+			u.log.Warnf("already contacts. %w", err)
+			return false, fmt.Errorf("%s, %w", op, ErrAlreadyContacts)
+		}
+		if errors.Is(err, repository.ErrContactRequestExists) {
+			u.log.Warnf("contact request already exists. %w", err)
+			return false, fmt.Errorf("%s, %w", op, ErrContactRequestExists)
+		}
+
+		u.log.Errorf("error saving contact request. %w", err)
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return accepted, nil
+}
+
+// AcceptContactRequest реализует логику принятия пользователем userId заявки в контакты от пользователя fromUserId.
+// Если заявка не найдена, возвращает users.ErrContactRequestNotFound.
+func (u *Users) AcceptContactRequest(ctx context.Context, userId, fromUserId int64) error {
+	const op = "users.AcceptContactRequest"
+
+	if err := u.contactSaver.AcceptContactRequest(ctx, userId, fromUserId); err != nil {
+		if errors.Is(err, repository.ErrContactRequestNotFound) {
+			u.log.Warnf("contact request not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrContactRequestNotFound)
+		}
+
+		u.log.Errorf("error accepting contact request. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// DeclineContactRequest реализует логику отклонения пользователем userId заявки в контакты от пользователя fromUserId.
+// Если заявка не найдена, возвращает users.ErrContactRequestNotFound.
+func (u *Users) DeclineContactRequest(ctx context.Context, userId, fromUserId int64) error {
+	const op = "users.DeclineContactRequest"
+
+	if err := u.deleteContactRequest(ctx, fromUserId, userId); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// CancelContactRequest реализует логику отзыва пользователем userId своей заявки в контакты пользователю toUserId.
+// Если заявка не найдена, возвращает users.ErrContactRequestNotFound.
+func (u *Users) CancelContactRequest(ctx context.Context, userId, toUserId int64) error {
+	const op = "users.CancelContactRequest"
+
+	if err := u.deleteContactRequest(ctx, userId, toUserId); err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveContact реализует логику удаления пользователей userId и contactId из контактов друг друга.
+// Если пользователи не состоят в контактах, возвращает users.ErrContactNotFound.
+func (u *Users) RemoveContact(ctx context.Context, userId, contactId int64) error {
+	const op = "users.RemoveContact"
+
+	if err := u.contactSaver.DeleteContact(ctx, userId, contactId); err != nil {
+		if errors.Is(err, repository.ErrContactNotFound) {
+			u.log.Warnf("contact not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrContactNotFound)
+		}
+
+		u.log.Errorf("error deleting contact. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ListContacts реализует логику постраничного получения активных контактов пользователя, упорядоченных по id.
+// pageToken - токен страницы из предыдущего ответа, пустой для первой страницы.
+// Возвращает контакты и токен следующей страницы, пустой, если контактов больше нет.
+// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+func (u *Users) ListContacts(ctx context.Context, userId int64, pageToken string, limit int) ([]models.Contact, string, error) {
+	const op = "users.ListContacts"
+
+	var afterId int64
+	if pageToken != "" {
+		var token contactsPageToken
+		if err := decodePageToken(pageToken, &token); err != nil {
+			u.log.Warnf("invalid page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+		if token.UserId != userId || token.AfterId == 0 {
+			u.log.Warnf("page token belongs to another list. user_id=%d", token.UserId)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+
+		afterId = token.AfterId
+	}
+
+	limit = u.pageSize.pageSize(limit)
+	//Лишний контакт запрашивается, чтобы узнать, есть ли следующая страница
+	contacts, err := u.contactProvider.ListContacts(ctx, userId, afterId, limit+1)
+	if err != nil {
+		u.log.Errorf("error listing contacts. %w", err)
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	var nextPageToken string
+	if len(contacts) > limit {
+		contacts = contacts[:limit]
+		nextPageToken, err = encodePageToken(contactsPageToken{UserId: userId, AfterId: contacts[limit-1].User.Id})
+		if err != nil {
+			u.log.Errorf("error encoding page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	ids := make([]int64, 0, len(contacts))
+	for _, contact := range contacts {
+		ids = append(ids, contact.User.Id)
+	}
+
+	view, err := u.privacyView(ctx, userId, ids)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	for i := range contacts {
+		view.applyProfile(&contacts[i].User)
+	}
+
+	return contacts, nextPageToken, nil
+}
+
+// ListContactRequests реализует логику получения входящих, если incoming = true, или исходящих заявок пользователя в контакты.
+func (u *Users) ListContactRequests(ctx context.Context, userId int64, incoming bool) ([]models.ContactRequest, error) {
+	const op = "users.ListContactRequests"
+
+	requests, err := u.contactProvider.ListContactRequests(ctx, userId, incoming)
+	if err != nil {
+		u.log.Errorf("error listing contact requests. %w", err)
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return requests, nil
+}
+
+// AreContacts реализует логику проверки, состоят ли пользователи userId и contactId в контактах друг друга.
+func (u *Users) AreContacts(ctx context.Context, userId, contactId int64) (bool, error) {
+	const op = "users.AreContacts"
+
+	contacts, err := u.contactProvider.IsContact(ctx, userId, contactId)
+	if err != nil {
+		u.log.Errorf("error checking contact. %w", err)
+		return false, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return contacts, nil
+}
+
+// deleteContactRequest удаляет заявку пользователя fromUserId, адресованную пользователю toUserId.
+func (u *Users) deleteContactRequest(ctx context.Context, fromUserId, toUserId int64) error {
+	if err := u.contactSaver.DeleteContactRequest(ctx, fromUserId, toUserId); err != nil {
+		if errors.Is(err, repository.ErrContactRequestNotFound) {
+			u.log.Warnf("contact request not found. %w", err)
+			return ErrContactRequestNotFound
+		}
+
+		u.log.Errorf("error deleting contact request. %w", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,796 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestContactId int64 = 2
+	TestContact         = models.Contact{
+		User: models.User{
+			Id:       TestContactId,
+			Username: "user2",
+			IsActive: true,
+		},
+		Since: time.Unix(1700000000, 0).UTC(),
+	}
+	TestContactRequest = models.ContactRequest{
+		FromUserId: TestContactId,
+		ToUserId:   TestUserId,
+		CreatedAt:  time.Unix(1700000000, 0).UTC(),
+	}
+)
+
+// newTestContactsPageToken возвращает токен страницы контактов пользователя userId, следующей после контакта afterId.
+func newTestContactsPageToken(userId, afterId int64) string {
+	token, err := encodePageToken(contactsPageToken{UserId: userId, AfterId: afterId})
+	if err != nil {
+		panic(err)
+	}
+
+	return token
+}
+
+func TestUsers_SendContactRequest(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		contactId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         bool
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("SaveContactRequest", ctx, TestUserId, TestContactId).Return(false, nil)
+			},
+		},
+		{
+			name: "MutualRequest",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("SaveContactRequest", ctx, TestUserId, TestContactId).Return(true, nil)
+			},
+			want: true,
+		},
+		{
+			name: "Self",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrSelfContact,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("SaveContactRequest", ctx, TestUserId, TestContactId).Return(false, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "AlreadyContacts",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("SaveContactRequest", ctx, TestUserId, TestContactId).Return(false, repository.ErrAlreadyContacts)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrAlreadyContacts,
+		},
+		{
+			name: "RequestExists",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("SaveContactRequest", ctx, TestUserId, TestContactId).Return(false, repository.ErrContactRequestExists)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrContactRequestExists,
+		},
+		{
+			name: "Blocked",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("SaveContactRequest", ctx, TestUserId, TestContactId).Return(false, repository.ErrContactRequestBlocked)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrContactRequestBlocked,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("SaveContactRequest", ctx, TestUserId, TestContactId).Return(false, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			got, err := u.SendContactRequest(tt.args.ctx, tt.args.userId, tt.args.contactId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.SendContactRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.SendContactRequest, "+tt.wantErr.Error(), fmt.Sprintf("users.SendContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_AcceptContactRequest(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx        context.Context
+		userId     int64
+		fromUserId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("AcceptContactRequest", ctx, TestUserId, TestContactId).Return(nil)
+			},
+		},
+		{
+			name: "RequestNotFound",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("AcceptContactRequest", ctx, TestUserId, TestContactId).Return(repository.ErrContactRequestNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrContactRequestNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("AcceptContactRequest", ctx, TestUserId, TestContactId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			err := u.AcceptContactRequest(tt.args.ctx, tt.args.userId, tt.args.fromUserId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.AcceptContactRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.AcceptContactRequest, "+tt.wantErr.Error(), fmt.Sprintf("users.AcceptContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_DeclineContactRequest(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx        context.Context
+		userId     int64
+		fromUserId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("DeleteContactRequest", ctx, TestContactId, TestUserId).Return(nil)
+			},
+		},
+		{
+			name: "RequestNotFound",
+			args: args{
+				ctx:        context.Background(),
+				userId:     TestUserId,
+				fromUserId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("DeleteContactRequest", ctx, TestContactId, TestUserId).Return(repository.ErrContactRequestNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrContactRequestNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			err := u.DeclineContactRequest(tt.args.ctx, tt.args.userId, tt.args.fromUserId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.DeclineContactRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.DeclineContactRequest, "+tt.wantErr.Error(), fmt.Sprintf("users.DeclineContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_CancelContactRequest(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		toUserId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				toUserId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("DeleteContactRequest", ctx, TestUserId, TestContactId).Return(nil)
+			},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				toUserId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("DeleteContactRequest", ctx, TestUserId, TestContactId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			err := u.CancelContactRequest(tt.args.ctx, tt.args.userId, tt.args.toUserId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.CancelContactRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.CancelContactRequest, "+tt.wantErr.Error(), fmt.Sprintf("users.CancelContactRequest() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_RemoveContact(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		contactId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("DeleteContact", ctx, TestUserId, TestContactId).Return(nil)
+			},
+		},
+		{
+			name: "ContactNotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactSaver.On("DeleteContact", ctx, TestUserId, TestContactId).Return(repository.ErrContactNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrContactNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			err := u.RemoveContact(tt.args.ctx, tt.args.userId, tt.args.contactId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.RemoveContact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.RemoveContact, "+tt.wantErr.Error(), fmt.Sprintf("users.RemoveContact() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_ListContacts(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	contacts := make([]models.Contact, 0, 3)
+	for i := int64(2); i <= 4; i++ {
+		contact := TestContact
+		contact.User.Id = i
+		contacts = append(contacts, contact)
+	}
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		pageToken string
+		limit     int
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.Contact
+		wantToken    string
+		wantErr      error
+	}{
+		{
+			name: "FirstPage",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactProvider.On("ListContacts", ctx, TestUserId, int64(0), 3).Return(contacts, nil)
+			},
+			want:      contacts[:2],
+			wantToken: newTestContactsPageToken(TestUserId, 3),
+		},
+		{
+			name: "LastPage",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				pageToken: newTestContactsPageToken(TestUserId, 1),
+				limit:     10,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactProvider.On("ListContacts", ctx, TestUserId, int64(1), 4).Return(contacts, nil)
+			},
+			want: contacts,
+		},
+		{
+			name: "MalformedPageToken",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				pageToken: "!",
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name: "PageTokenOfAnotherUser",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				pageToken: newTestContactsPageToken(TestContactId, 3),
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactProvider.On("ListContacts", ctx, TestUserId, int64(0), 3).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			got, token, err := u.ListContacts(tt.args.ctx, tt.args.userId, tt.args.pageToken, tt.args.limit)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ListContacts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ListContacts, "+tt.wantErr.Error(), fmt.Sprintf("users.ListContacts() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
+
+func TestUsers_ListContactRequests(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		incoming bool
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.ContactRequest
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				incoming: true,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactProvider.On("ListContactRequests", ctx, TestUserId, true).Return([]models.ContactRequest{TestContactRequest}, nil)
+			},
+			want: []models.ContactRequest{TestContactRequest},
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactProvider.On("ListContactRequests", ctx, TestUserId, false).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			got, err := u.ListContactRequests(tt.args.ctx, tt.args.userId, tt.args.incoming)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ListContactRequests() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ListContactRequests, "+tt.wantErr.Error(), fmt.Sprintf("users.ListContactRequests() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_AreContacts(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		contactSaver *mocks.ContactSaver,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		contactId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         bool
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactProvider.On("IsContact", ctx, TestUserId, TestContactId).Return(true, nil)
+			},
+			want: true,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				contactId: TestContactId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, contactSaver *mocks.ContactSaver, contactProvider *mocks.ContactProvider, blockProvider *mocks.BlockProvider, ctx context.Context) {
+				contactProvider.On("IsContact", ctx, TestUserId, TestContactId).Return(false, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			contactSaver := mocks.NewContactSaver(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, contactSaver, contactProvider, blockProvider, tt.args.ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				contactSaver:    contactSaver,
+				contactProvider: contactProvider,
+				blockProvider:   blockProvider,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			got, err := u.AreContacts(tt.args.ctx, tt.args.userId, tt.args.contactId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.AreContacts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.AreContacts, "+tt.wantErr.Error(), fmt.Sprintf("users.AreContacts() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
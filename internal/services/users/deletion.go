@@ -0,0 +1,82 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// DeleteUser реализует логику запроса на удаление пользователя.
+// Пользователь сразу становится неактивным и теряет все сессии, а окончательное удаление
+// назначается по истечении льготного периода, в течение которого его можно отменить. Возвращает время удаления.
+// Если пользователь не найден, возвращает users.ErrUserNotFound.
+// Если удаление уже назначено, возвращает users.ErrDeletionScheduled.
+func (u *Users) DeleteUser(ctx context.Context, userId int64) (time.Time, error) {
+	const op = "users.DeleteUser"
+
+	deleteAfter := time.Now().Add(u.deletionGracePeriod)
+	if err := u.userSaver.ScheduleUserDeletion(ctx, userId, deleteAfter); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return time.Time{}, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, repository.ErrDeletionScheduled) {
+			u.log.Warnf("deletion already scheduled. %w", err)
+			return time.Time{}, fmt.Errorf("%s, %w", op, ErrDeletionScheduled)
+		}
+
+		u.log.Errorf("error scheduling user deletion. %w", err)
+		return time.Time{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return deleteAfter, nil
+}
+
+// CancelUserDeletion реализует логику отмены удаления пользователя в течение льготного периода.
+// Пользователь возвращается в статус 'активен'.
+// Если пользователь не найден или его удаление не назначено, возвращает users.ErrDeletionNotScheduled.
+func (u *Users) CancelUserDeletion(ctx context.Context, userId int64) error {
+	const op = "users.CancelUserDeletion"
+
+	if err := u.userSaver.CancelUserDeletion(ctx, userId); err != nil {
+		if errors.Is(err, repository.ErrDeletionNotScheduled) {
+			u.log.Warnf("deletion not scheduled. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrDeletionNotScheduled)
+		}
+
+		u.log.Errorf("error cancelling user deletion. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeDeletedUsers окончательно удаляет пользователей, льготный период которых истек, пачками по batchSize.
+// Вместе с пользователем удаляются счетчики неудачных попыток входа по его username. Возвращает число удаленных пользователей.
+func (u *Users) PurgeDeletedUsers(ctx context.Context, batchSize int) (int, error) {
+	const op = "users.PurgeDeletedUsers"
+
+	var purged int
+	for {
+		erased, err := u.userSaver.PurgeDeletedUsers(ctx, batchSize)
+		if err != nil {
+			u.log.Errorf("error purging deleted users. %w", err)
+			return purged, fmt.Errorf("%s, %w", op, err)
+		}
+
+		for _, user := range erased {
+			//Счетчик хранит username в ключе и не связан с таблицей users, поэтому не удаляется каскадно
+			if err = u.attemptTracker.ResetFailedAttempts(ctx, userAttemptKey(user.Canonical)); err != nil {
+				u.log.Errorf("error resetting failed attempts of erased user. user_id=%d", user.UserId)
+			}
+		}
+
+		purged += len(erased)
+		if len(erased) < batchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestDeletionGracePeriod = 30 * 24 * time.Hour
+)
+
+func TestUsers_DeleteUser(t *testing.T) {
+	type mockBehavior func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64)
+
+	// deleteAfter проверяет, что удаление назначено по истечении льготного периода из конфигурации.
+	deleteAfter := mock.MatchedBy(func(t time.Time) bool {
+		return time.Until(t) > TestDeletionGracePeriod-time.Minute && time.Until(t) <= TestDeletionGracePeriod
+	})
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64) {
+				userSaver.On("ScheduleUserDeletion", ctx, userId, deleteAfter).Return(nil)
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64) {
+				userSaver.On("ScheduleUserDeletion", ctx, userId, deleteAfter).Return(repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "AlreadyScheduled",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64) {
+				userSaver.On("ScheduleUserDeletion", ctx, userId, deleteAfter).Return(repository.ErrDeletionScheduled)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrDeletionScheduled,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64) {
+				userSaver.On("ScheduleUserDeletion", ctx, userId, deleteAfter).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userSaver := mocks.NewUserSaver(t)
+
+			tt.mockBehavior(log, userSaver, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:                 log,
+				userSaver:           userSaver,
+				deletionGracePeriod: TestDeletionGracePeriod,
+			}
+			got, err := u.DeleteUser(tt.args.ctx, tt.args.userId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.DeleteUser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.DeleteUser, "+tt.wantErr.Error(), fmt.Sprintf("users.DeleteUser() error = %v, wantErr %v", err, tt.wantErr))
+				assert.True(t, got.IsZero())
+				return
+			}
+
+			assert.WithinDuration(t, time.Now().Add(TestDeletionGracePeriod), got, time.Minute)
+		})
+	}
+}
+
+func TestUsers_CancelUserDeletion(t *testing.T) {
+	type mockBehavior func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64) {
+				userSaver.On("CancelUserDeletion", ctx, userId).Return(nil)
+			},
+		},
+		{
+			name: "NotScheduled",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64) {
+				userSaver.On("CancelUserDeletion", ctx, userId).Return(repository.ErrDeletionNotScheduled)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrDeletionNotScheduled,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userSaver *mocks.UserSaver, ctx context.Context, userId int64) {
+				userSaver.On("CancelUserDeletion", ctx, userId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userSaver := mocks.NewUserSaver(t)
+
+			tt.mockBehavior(log, userSaver, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:       log,
+				userSaver: userSaver,
+			}
+			err := u.CancelUserDeletion(tt.args.ctx, tt.args.userId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.CancelUserDeletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.CancelUserDeletion, "+tt.wantErr.Error(), fmt.Sprintf("users.CancelUserDeletion() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_PurgeDeletedUsers(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userSaver *mocks.UserSaver,
+		attemptTracker *mocks.AttemptTracker,
+		ctx context.Context,
+		batchSize int,
+	)
+
+	erasedUser := models.ErasedUser{UserId: TestUserId, Canonical: TestCanonical}
+
+	type args struct {
+		ctx       context.Context
+		batchSize int
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				batchSize: 1,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				batchSize int,
+			) {
+				userSaver.On("PurgeDeletedUsers", ctx, batchSize).Return([]models.ErasedUser{erasedUser}, nil).Once()
+				userSaver.On("PurgeDeletedUsers", ctx, batchSize).Return(nil, nil).Once()
+				attemptTracker.On("ResetFailedAttempts", ctx, userAttemptKey(TestCanonical)).Return(nil)
+			},
+			want: 1,
+		},
+		{
+			name: "NothingToPurge",
+			args: args{
+				ctx:       context.Background(),
+				batchSize: 10,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				batchSize int,
+			) {
+				userSaver.On("PurgeDeletedUsers", ctx, batchSize).Return(nil, nil)
+			},
+		},
+		{
+			name: "ErrorResetAttempts",
+			args: args{
+				ctx:       context.Background(),
+				batchSize: 10,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				batchSize int,
+			) {
+				userSaver.On("PurgeDeletedUsers", ctx, batchSize).Return([]models.ErasedUser{erasedUser}, nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, userAttemptKey(TestCanonical)).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			want: 1,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:       context.Background(),
+				batchSize: 1,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				batchSize int,
+			) {
+				userSaver.On("PurgeDeletedUsers", ctx, batchSize).Return([]models.ErasedUser{erasedUser}, nil).Once()
+				userSaver.On("PurgeDeletedUsers", ctx, batchSize).Return(nil, errors.New("")).Once()
+				attemptTracker.On("ResetFailedAttempts", ctx, userAttemptKey(TestCanonical)).Return(nil)
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			want:    1,
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userSaver := mocks.NewUserSaver(t)
+			attemptTracker := mocks.NewAttemptTracker(t)
+
+			tt.mockBehavior(log, userSaver, attemptTracker, tt.args.ctx, tt.args.batchSize)
+			u := &Users{
+				log:            log,
+				userSaver:      userSaver,
+				attemptTracker: attemptTracker,
+			}
+			got, err := u.PurgeDeletedUsers(tt.args.ctx, tt.args.batchSize)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.PurgeDeletedUsers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.PurgeDeletedUsers, "+tt.wantErr.Error(), fmt.Sprintf("users.PurgeDeletedUsers() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
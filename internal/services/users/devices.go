@@ -0,0 +1,131 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+const (
+	maxPushTokenLength  = 4096
+	maxAppVersionLength = 32
+)
+
+// DeviceSaver предоставляет методы сохранения и удаления устройств пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=DeviceSaver
+type DeviceSaver interface {
+	// SaveDevice сохраняет устройство активного пользователя и возвращает его с заполненными id и временем.
+	// Если устройство с таким push токеном уже есть, оно переходит к пользователю device.UserId.
+	// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+	SaveDevice(ctx context.Context, device models.Device) (models.Device, error)
+
+	// DeleteDevice удаляет устройство deviceId пользователя userId.
+	// Если устройство не найдено, возвращает ошибку repository.ErrDeviceNotFound.
+	DeleteDevice(ctx context.Context, userId, deviceId int64) error
+}
+
+// DeviceProvider предоставляет метод получения устройств пользователя.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=DeviceProvider
+type DeviceProvider interface {
+	// ListDevices возвращает все устройства пользователя, начиная с последнего активного.
+	ListDevices(ctx context.Context, userId int64) ([]models.Device, error)
+}
+
+// RegisterDevice реализует логику регистрации устройства пользователя для push уведомлений.
+// Повторная регистрация того же push токена обновляет устройство, а если токен был у другого пользователя,
+// устройство переходит к userId.
+// Если платформа неизвестна или токен и версия приложения не соответствуют правилам, возвращает *users.ValidationError.
+// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+func (u *Users) RegisterDevice(ctx context.Context, userId int64, platform, pushToken, appVersion string) (models.Device, error) {
+	const op = "users.RegisterDevice"
+
+	if err := validateDevice(platform, pushToken, appVersion); err != nil {
+		u.log.Warnf("invalid device. %w", err)
+		return models.Device{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	device, err := u.deviceSaver.SaveDevice(ctx, models.Device{
+		UserId:     userId,
+		Platform:   platform,
+		PushToken:  pushToken,
+		AppVersion: appVersion,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return models.Device{}, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+
+		u.log.Errorf("error saving device. %w", err)
+		return models.Device{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return device, nil
+}
+
+// UnregisterDevice реализует логику удаления устройства пользователя.
+// Если у пользователя нет такого устройства, возвращает users.ErrDeviceNotFound.
+func (u *Users) UnregisterDevice(ctx context.Context, userId, deviceId int64) error {
+	const op = "users.UnregisterDevice"
+
+	if err := u.deviceSaver.DeleteDevice(ctx, userId, deviceId); err != nil {
+		if errors.Is(err, repository.ErrDeviceNotFound) {
+			u.log.Warnf("device not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrDeviceNotFound)
+		}
+
+		u.log.Errorf("error deleting device. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ListDevices реализует логику получения устройств пользователя, начиная с последнего активного.
+func (u *Users) ListDevices(ctx context.Context, userId int64) ([]models.Device, error) {
+	const op = "users.ListDevices"
+
+	devices, err := u.deviceProvider.ListDevices(ctx, userId)
+	if err != nil {
+		u.log.Errorf("error listing devices. %w", err)
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return devices, nil
+}
+
+// validateDevice проверяет данные регистрируемого устройства.
+// Если данные недопустимы, возвращает *ValidationError.
+func validateDevice(platform, pushToken, appVersion string) error {
+	var violations []FieldViolation
+
+	switch platform {
+	case models.DevicePlatformIOS, models.DevicePlatformAndroid, models.DevicePlatformWeb:
+	default:
+		violations = append(violations, FieldViolation{Field: "platform", Description: "must be ios, android or web"})
+	}
+
+	if pushToken == "" {
+		violations = append(violations, FieldViolation{Field: "push_token", Description: "must not be empty"})
+	} else if len(pushToken) > maxPushTokenLength {
+		violations = append(violations, FieldViolation{
+			Field:       "push_token",
+			Description: fmt.Sprintf("must be at most %d bytes long", maxPushTokenLength),
+		})
+	}
+
+	if utf8.RuneCountInString(appVersion) > maxAppVersionLength {
+		violations = append(violations, FieldViolation{
+			Field:       "app_version",
+			Description: fmt.Sprintf("must be at most %d characters long", maxAppVersionLength),
+		})
+	}
+
+	return newValidationError(violations...)
+}
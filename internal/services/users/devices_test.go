@@ -0,0 +1,265 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var TestDevice = models.Device{
+	Id:           3,
+	UserId:       TestUserId,
+	Platform:     models.DevicePlatformAndroid,
+	PushToken:    "push-token",
+	AppVersion:   "1.2.0",
+	CreatedAt:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	LastActiveAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+}
+
+func TestUsers_RegisterDevice(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		deviceSaver *mocks.DeviceSaver,
+		deviceProvider *mocks.DeviceProvider,
+		ctx context.Context,
+	)
+
+	input := models.Device{
+		UserId:     TestDevice.UserId,
+		Platform:   TestDevice.Platform,
+		PushToken:  TestDevice.PushToken,
+		AppVersion: TestDevice.AppVersion,
+	}
+
+	type args struct {
+		platform   string
+		pushToken  string
+		appVersion string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.Device
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				platform:   TestDevice.Platform,
+				pushToken:  TestDevice.PushToken,
+				appVersion: TestDevice.AppVersion,
+			},
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceSaver.On("SaveDevice", ctx, input).Return(TestDevice, nil)
+			},
+			want: TestDevice,
+		},
+		{
+			name: "InvalidDevice",
+			args: args{
+				platform:   "symbian",
+				pushToken:  "",
+				appVersion: strings.Repeat("1", maxAppVersionLength+1),
+			},
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{
+				{Field: "platform", Description: "must be ios, android or web"},
+				{Field: "push_token", Description: "must not be empty"},
+				{Field: "app_version", Description: fmt.Sprintf("must be at most %d characters long", maxAppVersionLength)},
+			}},
+		},
+		{
+			name: "PushTokenTooLong",
+			args: args{
+				platform:  TestDevice.Platform,
+				pushToken: strings.Repeat("a", maxPushTokenLength+1),
+			},
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{
+				{Field: "push_token", Description: fmt.Sprintf("must be at most %d bytes long", maxPushTokenLength)},
+			}},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				platform:   TestDevice.Platform,
+				pushToken:  TestDevice.PushToken,
+				appVersion: TestDevice.AppVersion,
+			},
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceSaver.On("SaveDevice", ctx, input).Return(models.Device{}, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				platform:   TestDevice.Platform,
+				pushToken:  TestDevice.PushToken,
+				appVersion: TestDevice.AppVersion,
+			},
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceSaver.On("SaveDevice", ctx, input).Return(models.Device{}, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			deviceSaver := mocks.NewDeviceSaver(t)
+			deviceProvider := mocks.NewDeviceProvider(t)
+
+			tt.mockBehavior(log, deviceSaver, deviceProvider, ctx)
+			u := &Users{
+				log:            log,
+				deviceSaver:    deviceSaver,
+				deviceProvider: deviceProvider,
+			}
+			got, err := u.RegisterDevice(ctx, TestUserId, tt.args.platform, tt.args.pushToken, tt.args.appVersion)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.RegisterDevice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.RegisterDevice, "+tt.wantErr.Error(), fmt.Sprintf("users.RegisterDevice() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_UnregisterDevice(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		deviceSaver *mocks.DeviceSaver,
+		deviceProvider *mocks.DeviceProvider,
+		ctx context.Context,
+	)
+
+	tests := []struct {
+		name         string
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceSaver.On("DeleteDevice", ctx, TestUserId, TestDevice.Id).Return(nil)
+			},
+		},
+		{
+			name: "DeviceNotFound",
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceSaver.On("DeleteDevice", ctx, TestUserId, TestDevice.Id).Return(repository.ErrDeviceNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrDeviceNotFound,
+		},
+		{
+			name: "Error",
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceSaver.On("DeleteDevice", ctx, TestUserId, TestDevice.Id).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			deviceSaver := mocks.NewDeviceSaver(t)
+			deviceProvider := mocks.NewDeviceProvider(t)
+
+			tt.mockBehavior(log, deviceSaver, deviceProvider, ctx)
+			u := &Users{
+				log:            log,
+				deviceSaver:    deviceSaver,
+				deviceProvider: deviceProvider,
+			}
+			err := u.UnregisterDevice(ctx, TestUserId, TestDevice.Id)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.UnregisterDevice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.UnregisterDevice, "+tt.wantErr.Error(), fmt.Sprintf("users.UnregisterDevice() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_ListDevices(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		deviceSaver *mocks.DeviceSaver,
+		deviceProvider *mocks.DeviceProvider,
+		ctx context.Context,
+	)
+
+	tests := []struct {
+		name         string
+		mockBehavior mockBehavior
+		want         []models.Device
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceProvider.On("ListDevices", ctx, TestUserId).Return([]models.Device{TestDevice}, nil)
+			},
+			want: []models.Device{TestDevice},
+		},
+		{
+			name: "Error",
+			mockBehavior: func(log *loggermocks.Logger, deviceSaver *mocks.DeviceSaver, deviceProvider *mocks.DeviceProvider, ctx context.Context) {
+				deviceProvider.On("ListDevices", ctx, TestUserId).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			deviceSaver := mocks.NewDeviceSaver(t)
+			deviceProvider := mocks.NewDeviceProvider(t)
+
+			tt.mockBehavior(log, deviceSaver, deviceProvider, ctx)
+			u := &Users{
+				log:            log,
+				deviceSaver:    deviceSaver,
+				deviceProvider: deviceProvider,
+			}
+			got, err := u.ListDevices(ctx, TestUserId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ListDevices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ListDevices, "+tt.wantErr.Error(), fmt.Sprintf("users.ListDevices() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// EventOutbox предоставляет методы работы с событиями, ожидающими публикации.
+// События сохраняются в outbox репозиторием в одной транзакции с изменением пользователя.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=EventOutbox
+type EventOutbox interface {
+	// GetOutboxEvents получает не более limit неопубликованных событий в порядке их появления.
+	GetOutboxEvents(ctx context.Context, limit int) ([]models.Event, error)
+
+	// DeleteOutboxEvents удаляет опубликованные события с указанными id.
+	DeleteOutboxEvents(ctx context.Context, eventIds []int64) error
+}
+
+// EventPublisher - интерфейс публикации событий жизненного цикла пользователей для других сервисов.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=EventPublisher
+type EventPublisher interface {
+	// Publish публикует событие. Событие может быть опубликовано повторно,
+	// потребители отбрасывают повторы по event.IdempotencyKey.
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// RelayEvents реализует логику публикации не более batchSize событий из outbox в порядке их появления.
+// Опубликованные события удаляются из outbox. Если публикация события не удалась, следующие события не публикуются,
+// чтобы сохранить порядок, а опубликованные до него удаляются.
+// Если опубликованные события удалить не удалось, они будут опубликованы повторно.
+// Возвращает количество опубликованных событий.
+func (u *Users) RelayEvents(ctx context.Context, batchSize int) (int, error) {
+	const op = "users.RelayEvents"
+
+	events, err := u.eventOutbox.GetOutboxEvents(ctx, batchSize)
+	if err != nil {
+		u.log.Errorf("error getting outbox events. %w", err)
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	published := make([]int64, 0, len(events))
+	var publishErr error
+	for _, event := range events {
+		if publishErr = u.eventPublisher.Publish(ctx, event); publishErr != nil {
+			u.log.Errorf("error publishing event. id=%d. %w", event.Id, publishErr)
+			break
+		}
+
+		published = append(published, event.Id)
+	}
+
+	if len(published) > 0 {
+		if err = u.eventOutbox.DeleteOutboxEvents(ctx, published); err != nil {
+			u.log.Errorf("error deleting outbox events. %w", err)
+			return 0, fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	if publishErr != nil {
+		return len(published), fmt.Errorf("%s, %w", op, publishErr)
+	}
+
+	return len(published), nil
+}
@@ -0,0 +1,117 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const TestRelayBatch = 10
+
+var TestEvents = []models.Event{
+	{Id: 1, IdempotencyKey: "key-1", Type: models.EventUserRegistered, UserId: TestUserId},
+	{Id: 2, IdempotencyKey: "key-2", Type: models.EventUserDeactivated, UserId: TestUserId},
+}
+
+func TestUsers_RelayEvents(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		eventOutbox *mocks.EventOutbox,
+		eventPublisher *mocks.EventPublisher,
+		ctx context.Context,
+	)
+
+	tests := []struct {
+		name         string
+		mockBehavior mockBehavior
+		want         int
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(log *loggermocks.Logger, eventOutbox *mocks.EventOutbox, eventPublisher *mocks.EventPublisher, ctx context.Context) {
+				eventOutbox.On("GetOutboxEvents", ctx, TestRelayBatch).Return(TestEvents, nil)
+				eventPublisher.On("Publish", ctx, TestEvents[0]).Return(nil).Once()
+				eventPublisher.On("Publish", ctx, TestEvents[1]).Return(nil).Once()
+				eventOutbox.On("DeleteOutboxEvents", ctx, []int64{1, 2}).Return(nil)
+			},
+			want: 2,
+		},
+		{
+			name: "Empty",
+			mockBehavior: func(log *loggermocks.Logger, eventOutbox *mocks.EventOutbox, eventPublisher *mocks.EventPublisher, ctx context.Context) {
+				eventOutbox.On("GetOutboxEvents", ctx, TestRelayBatch).Return(nil, nil)
+			},
+		},
+		{
+			name: "PublishError",
+			mockBehavior: func(log *loggermocks.Logger, eventOutbox *mocks.EventOutbox, eventPublisher *mocks.EventPublisher, ctx context.Context) {
+				eventOutbox.On("GetOutboxEvents", ctx, TestRelayBatch).Return(TestEvents, nil)
+				eventPublisher.On("Publish", ctx, TestEvents[0]).Return(nil).Once()
+				eventPublisher.On("Publish", ctx, TestEvents[1]).Return(errors.New("")).Once()
+				log.On("Errorf", mock.Anything, mock.Anything, mock.Anything)
+				eventOutbox.On("DeleteOutboxEvents", ctx, []int64{1}).Return(nil)
+			},
+			want:    1,
+			wantErr: errors.New(""),
+		},
+		{
+			name: "FirstPublishError",
+			mockBehavior: func(log *loggermocks.Logger, eventOutbox *mocks.EventOutbox, eventPublisher *mocks.EventPublisher, ctx context.Context) {
+				eventOutbox.On("GetOutboxEvents", ctx, TestRelayBatch).Return(TestEvents, nil)
+				eventPublisher.On("Publish", ctx, TestEvents[0]).Return(errors.New("")).Once()
+				log.On("Errorf", mock.Anything, mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "DeleteError",
+			mockBehavior: func(log *loggermocks.Logger, eventOutbox *mocks.EventOutbox, eventPublisher *mocks.EventPublisher, ctx context.Context) {
+				eventOutbox.On("GetOutboxEvents", ctx, TestRelayBatch).Return(TestEvents, nil)
+				eventPublisher.On("Publish", ctx, mock.Anything).Return(nil).Twice()
+				eventOutbox.On("DeleteOutboxEvents", ctx, []int64{1, 2}).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "GetError",
+			mockBehavior: func(log *loggermocks.Logger, eventOutbox *mocks.EventOutbox, eventPublisher *mocks.EventPublisher, ctx context.Context) {
+				eventOutbox.On("GetOutboxEvents", ctx, TestRelayBatch).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			eventOutbox := mocks.NewEventOutbox(t)
+			eventPublisher := mocks.NewEventPublisher(t)
+
+			tt.mockBehavior(log, eventOutbox, eventPublisher, ctx)
+			u := &Users{
+				log:            log,
+				eventOutbox:    eventOutbox,
+				eventPublisher: eventPublisher,
+			}
+			got, err := u.RelayEvents(ctx, TestRelayBatch)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.RelayEvents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.RelayEvents, "+tt.wantErr.Error(), fmt.Sprintf("users.RelayEvents() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// exportVersion - версия формата документа с данными пользователя.
+// Увеличивается при любом несовместимом изменении exportDocument.
+const exportVersion = 1
+
+// exportContactsBatch - число контактов и заблокированных пользователей, получаемых из репозитория за один запрос при выгрузке.
+const exportContactsBatch = 1000
+
+// exportDocument - документ со всеми данными, которые сервис хранит о пользователе.
+// Секреты (хэш пароля, секрет TOTP, коды восстановления, хэши токенов) в документ не попадают.
+type exportDocument struct {
+	Version         int                    `json:"version"`
+	ExportedAt      time.Time              `json:"exported_at"`
+	Profile         exportProfile          `json:"profile"`
+	TOTPEnabled     bool                   `json:"totp_enabled"`
+	Privacy         exportPrivacy          `json:"privacy"`
+	Sessions        []exportSession        `json:"sessions"`
+	Devices         []exportDevice         `json:"devices"`
+	UsernameHistory []exportUsernameChange `json:"username_history"`
+	AuditEvents     []exportAuditEvent     `json:"audit_events"`
+	Contacts        []exportContact        `json:"contacts"`
+	ContactRequests []exportContactRequest `json:"contact_requests"`
+	Blocked         []exportBlockedUser    `json:"blocked"`
+}
+
+type exportProfile struct {
+	Id          int64      `json:"id"`
+	Username    string     `json:"username"`
+	IsActive    bool       `json:"is_active"`
+	DisplayName string     `json:"display_name"`
+	Bio         string     `json:"bio"`
+	AvatarURL   string     `json:"avatar_url"`
+	Locale      string     `json:"locale"`
+	TimeZone    string     `json:"time_zone"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
+}
+
+type exportPrivacy struct {
+	LastSeen string `json:"last_seen"`
+	Avatar   string `json:"avatar"`
+	Search   string `json:"search"`
+}
+
+type exportSession struct {
+	Id         int64     `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+type exportDevice struct {
+	Id           int64     `json:"id"`
+	Platform     string    `json:"platform"`
+	PushToken    string    `json:"push_token"`
+	AppVersion   string    `json:"app_version"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+}
+
+type exportUsernameChange struct {
+	Username      string    `json:"username"`
+	ChangedAt     time.Time `json:"changed_at"`
+	ReservedUntil time.Time `json:"reserved_until"`
+}
+
+type exportAuditEvent struct {
+	Event       string     `json:"event"`
+	DeleteAfter *time.Time `json:"delete_after,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type exportContact struct {
+	UserId int64     `json:"user_id"`
+	Since  time.Time `json:"since"`
+}
+
+type exportContactRequest struct {
+	FromUserId int64     `json:"from_user_id"`
+	ToUserId   int64     `json:"to_user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type exportBlockedUser struct {
+	UserId    int64     `json:"user_id"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// ExportUserData реализует логику выгрузки всех данных пользователя для запросов на переносимость данных.
+// Данные собираются целиком до начала записи, после чего документ JSON версии exportVersion записывается в w.
+// Выгрузка доступна и для неактивных пользователей. Контакты и заблокированные пользователи выгружаются без неактивных пользователей.
+// Время последней активности берется из репозитория и выгружается только для активных пользователей, которые уже были в сети.
+// Если пользователь не найден, возвращает users.ErrUserNotFound.
+func (u *Users) ExportUserData(ctx context.Context, userId int64, w io.Writer) error {
+	const op = "users.ExportUserData"
+
+	doc, err := u.collectUserData(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = json.NewEncoder(w).Encode(doc); err != nil {
+		u.log.Errorf("error writing user data. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// collectUserData собирает документ с данными пользователя из репозитория.
+func (u *Users) collectUserData(ctx context.Context, userId int64) (exportDocument, error) {
+	user, err := u.userProvider.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return exportDocument{}, ErrUserNotFound
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return exportDocument{}, err
+	}
+
+	totpEnabled, err := u.secondFactorProvider.TOTPEnabled(ctx, userId)
+	if err != nil {
+		u.log.Errorf("error checking totp. %w", err)
+		return exportDocument{}, err
+	}
+
+	sessions, err := u.sessionProvider.ListSessions(ctx, userId)
+	if err != nil {
+		u.log.Errorf("error listing sessions. %w", err)
+		return exportDocument{}, err
+	}
+
+	devices, err := u.deviceProvider.ListDevices(ctx, userId)
+	if err != nil {
+		u.log.Errorf("error listing devices. %w", err)
+		return exportDocument{}, err
+	}
+
+	history, err := u.userProvider.GetUsernameHistory(ctx, userId)
+	if err != nil {
+		u.log.Errorf("error getting username history. %w", err)
+		return exportDocument{}, err
+	}
+
+	events, err := u.userProvider.GetErasureEvents(ctx, userId)
+	if err != nil {
+		u.log.Errorf("error getting erasure events. %w", err)
+		return exportDocument{}, err
+	}
+
+	contacts, err := u.collectContacts(ctx, userId)
+	if err != nil {
+		return exportDocument{}, err
+	}
+
+	requests, err := u.collectContactRequests(ctx, userId)
+	if err != nil {
+		return exportDocument{}, err
+	}
+
+	blocked, err := u.collectBlocked(ctx, userId)
+	if err != nil {
+		return exportDocument{}, err
+	}
+
+	privacy, err := u.privacyProvider.GetPrivacySettings(ctx, []int64{userId})
+	if err != nil {
+		u.log.Errorf("error getting privacy settings. %w", err)
+		return exportDocument{}, err
+	}
+
+	settings, ok := privacy[userId]
+	if !ok {
+		settings = models.DefaultPrivacySettings()
+	}
+
+	lastSeen, err := u.presenceProvider.GetLastSeen(ctx, []int64{userId})
+	if err != nil {
+		u.log.Errorf("error getting last seen. %w", err)
+		return exportDocument{}, err
+	}
+
+	//Пустые списки выгружаются как [], а не null, чтобы формат документа не зависел от наличия данных
+	doc := exportDocument{
+		Version:    exportVersion,
+		ExportedAt: time.Now().UTC(),
+		Profile: exportProfile{
+			Id:          user.Id,
+			Username:    user.Username,
+			IsActive:    user.IsActive,
+			DisplayName: user.DisplayName,
+			Bio:         user.Bio,
+			AvatarURL:   user.AvatarURL,
+			Locale:      user.Locale,
+			TimeZone:    user.TimeZone,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+		},
+		TOTPEnabled: totpEnabled,
+		Privacy: exportPrivacy{
+			LastSeen: string(settings.LastSeen),
+			Avatar:   string(settings.Avatar),
+			Search:   string(settings.Search),
+		},
+		Sessions:        make([]exportSession, 0, len(sessions)),
+		Devices:         make([]exportDevice, 0, len(devices)),
+		UsernameHistory: make([]exportUsernameChange, 0, len(history)),
+		AuditEvents:     make([]exportAuditEvent, 0, len(events)),
+		Contacts:        make([]exportContact, 0, len(contacts)),
+		ContactRequests: make([]exportContactRequest, 0, len(requests)),
+		Blocked:         make([]exportBlockedUser, 0, len(blocked)),
+	}
+
+	if at, ok := lastSeen[userId]; ok {
+		doc.Profile.LastSeenAt = &at
+	}
+
+	for _, s := range sessions {
+		doc.Sessions = append(doc.Sessions, exportSession{
+			Id:         s.Id,
+			DeviceName: s.DeviceName,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+		})
+	}
+
+	for _, d := range devices {
+		doc.Devices = append(doc.Devices, exportDevice{
+			Id:           d.Id,
+			Platform:     d.Platform,
+			PushToken:    d.PushToken,
+			AppVersion:   d.AppVersion,
+			CreatedAt:    d.CreatedAt,
+			LastActiveAt: d.LastActiveAt,
+		})
+	}
+
+	for _, change := range history {
+		doc.UsernameHistory = append(doc.UsernameHistory, exportUsernameChange{
+			Username:      change.Username,
+			ChangedAt:     change.ChangedAt,
+			ReservedUntil: change.ReservedUntil,
+		})
+	}
+
+	for _, event := range events {
+		auditEvent := exportAuditEvent{
+			Event:     event.Event,
+			CreatedAt: event.CreatedAt,
+		}
+		if !event.DeleteAfter.IsZero() {
+			deleteAfter := event.DeleteAfter
+			auditEvent.DeleteAfter = &deleteAfter
+		}
+
+		doc.AuditEvents = append(doc.AuditEvents, auditEvent)
+	}
+
+	for _, contact := range contacts {
+		doc.Contacts = append(doc.Contacts, exportContact{
+			UserId: contact.User.Id,
+			Since:  contact.Since,
+		})
+	}
+
+	for _, request := range requests {
+		doc.ContactRequests = append(doc.ContactRequests, exportContactRequest{
+			FromUserId: request.FromUserId,
+			ToUserId:   request.ToUserId,
+			CreatedAt:  request.CreatedAt,
+		})
+	}
+
+	for _, user := range blocked {
+		doc.Blocked = append(doc.Blocked, exportBlockedUser{
+			UserId:    user.User.Id,
+			BlockedAt: user.BlockedAt,
+		})
+	}
+
+	return doc, nil
+}
+
+// collectContacts получает все активные контакты пользователя пачками по exportContactsBatch.
+func (u *Users) collectContacts(ctx context.Context, userId int64) ([]models.Contact, error) {
+	var contacts []models.Contact
+	var afterId int64
+	for {
+		batch, err := u.contactProvider.ListContacts(ctx, userId, afterId, exportContactsBatch)
+		if err != nil {
+			u.log.Errorf("error listing contacts. %w", err)
+			return nil, err
+		}
+
+		contacts = append(contacts, batch...)
+		if len(batch) < exportContactsBatch {
+			return contacts, nil
+		}
+
+		afterId = batch[len(batch)-1].User.Id
+	}
+}
+
+// collectBlocked получает всех активных пользователей, заблокированных пользователем, пачками по exportContactsBatch.
+func (u *Users) collectBlocked(ctx context.Context, userId int64) ([]models.BlockedUser, error) {
+	var blocked []models.BlockedUser
+	var afterId int64
+	for {
+		batch, err := u.blockProvider.ListBlocked(ctx, userId, afterId, exportContactsBatch)
+		if err != nil {
+			u.log.Errorf("error listing blocked users. %w", err)
+			return nil, err
+		}
+
+		blocked = append(blocked, batch...)
+		if len(batch) < exportContactsBatch {
+			return blocked, nil
+		}
+
+		afterId = batch[len(batch)-1].User.Id
+	}
+}
+
+// collectContactRequests получает входящие и исходящие заявки пользователя в контакты.
+func (u *Users) collectContactRequests(ctx context.Context, userId int64) ([]models.ContactRequest, error) {
+	incoming, err := u.contactProvider.ListContactRequests(ctx, userId, true)
+	if err != nil {
+		u.log.Errorf("error listing contact requests. %w", err)
+		return nil, err
+	}
+
+	outgoing, err := u.contactProvider.ListContactRequests(ctx, userId, false)
+	if err != nil {
+		u.log.Errorf("error listing contact requests. %w", err)
+		return nil, err
+	}
+
+	return append(incoming, outgoing...), nil
+}
@@ -0,0 +1,268 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestUsernameChange = models.UsernameChange{
+		Username:      "old_user1",
+		ChangedAt:     time.Unix(1700000000, 0).UTC(),
+		ReservedUntil: time.Unix(1702592000, 0).UTC(),
+	}
+	TestErasureEvent = models.ErasureEvent{
+		Event:     "cancelled",
+		CreatedAt: time.Unix(1700000000, 0).UTC(),
+	}
+)
+
+// errWriter - io.Writer, который всегда возвращает ошибку.
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("")
+}
+
+func TestUsers_ExportUserData(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		sessionProvider *mocks.SessionProvider,
+		secondFactorProvider *mocks.SecondFactorProvider,
+		contactProvider *mocks.ContactProvider,
+		blockProvider *mocks.BlockProvider,
+		privacyProvider *mocks.PrivacyProvider,
+		presenceProvider *mocks.PresenceProvider,
+		deviceProvider *mocks.DeviceProvider,
+		ctx context.Context,
+		userId int64,
+	)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+		w      io.Writer
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				w:      &bytes.Buffer{},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				sessionProvider *mocks.SessionProvider,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				contactProvider *mocks.ContactProvider,
+				blockProvider *mocks.BlockProvider,
+				privacyProvider *mocks.PrivacyProvider,
+				presenceProvider *mocks.PresenceProvider,
+				deviceProvider *mocks.DeviceProvider,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				secondFactorProvider.On("TOTPEnabled", ctx, userId).Return(true, nil)
+				sessionProvider.On("ListSessions", ctx, userId).Return([]models.Session{TestSession}, nil)
+				userProvider.On("GetUsernameHistory", ctx, userId).Return([]models.UsernameChange{TestUsernameChange}, nil)
+				userProvider.On("GetErasureEvents", ctx, userId).Return([]models.ErasureEvent{TestErasureEvent}, nil)
+				contactProvider.On("ListContacts", ctx, userId, int64(0), exportContactsBatch).Return([]models.Contact{TestContact}, nil)
+				contactProvider.On("ListContactRequests", ctx, userId, true).Return([]models.ContactRequest{TestContactRequest}, nil)
+				contactProvider.On("ListContactRequests", ctx, userId, false).Return(nil, nil)
+				blockProvider.On("ListBlocked", ctx, userId, int64(0), exportContactsBatch).Return([]models.BlockedUser{TestBlockedUser}, nil)
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{userId}).Return(map[int64]models.PrivacySettings{userId: TestPrivacySettings}, nil)
+				presenceProvider.On("GetLastSeen", ctx, []int64{userId}).Return(map[int64]time.Time{userId: TestLastSeen}, nil)
+				deviceProvider.On("ListDevices", ctx, userId).Return([]models.Device{TestDevice}, nil)
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				w:      &bytes.Buffer{},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				sessionProvider *mocks.SessionProvider,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				contactProvider *mocks.ContactProvider,
+				blockProvider *mocks.BlockProvider,
+				privacyProvider *mocks.PrivacyProvider,
+				presenceProvider *mocks.PresenceProvider,
+				deviceProvider *mocks.DeviceProvider,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "ErrorSessions",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				w:      &bytes.Buffer{},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				sessionProvider *mocks.SessionProvider,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				contactProvider *mocks.ContactProvider,
+				blockProvider *mocks.BlockProvider,
+				privacyProvider *mocks.PrivacyProvider,
+				presenceProvider *mocks.PresenceProvider,
+				deviceProvider *mocks.DeviceProvider,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				secondFactorProvider.On("TOTPEnabled", ctx, userId).Return(false, nil)
+				sessionProvider.On("ListSessions", ctx, userId).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "ErrorWrite",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				w:      errWriter{},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				sessionProvider *mocks.SessionProvider,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				contactProvider *mocks.ContactProvider,
+				blockProvider *mocks.BlockProvider,
+				privacyProvider *mocks.PrivacyProvider,
+				presenceProvider *mocks.PresenceProvider,
+				deviceProvider *mocks.DeviceProvider,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				secondFactorProvider.On("TOTPEnabled", ctx, userId).Return(false, nil)
+				sessionProvider.On("ListSessions", ctx, userId).Return(nil, nil)
+				userProvider.On("GetUsernameHistory", ctx, userId).Return(nil, nil)
+				userProvider.On("GetErasureEvents", ctx, userId).Return(nil, nil)
+				contactProvider.On("ListContacts", ctx, userId, int64(0), exportContactsBatch).Return(nil, nil)
+				contactProvider.On("ListContactRequests", ctx, userId, true).Return(nil, nil)
+				contactProvider.On("ListContactRequests", ctx, userId, false).Return(nil, nil)
+				blockProvider.On("ListBlocked", ctx, userId, int64(0), exportContactsBatch).Return(nil, nil)
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{userId}).Return(map[int64]models.PrivacySettings{}, nil)
+				presenceProvider.On("GetLastSeen", ctx, []int64{userId}).Return(map[int64]time.Time{}, nil)
+				deviceProvider.On("ListDevices", ctx, userId).Return(nil, nil)
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			sessionProvider := mocks.NewSessionProvider(t)
+			secondFactorProvider := mocks.NewSecondFactorProvider(t)
+			contactProvider := mocks.NewContactProvider(t)
+			blockProvider := mocks.NewBlockProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+			presenceProvider := mocks.NewPresenceProvider(t)
+			deviceProvider := mocks.NewDeviceProvider(t)
+
+			tt.mockBehavior(log, userProvider, sessionProvider, secondFactorProvider, contactProvider, blockProvider, privacyProvider, presenceProvider, deviceProvider, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:                  log,
+				userProvider:         userProvider,
+				sessionProvider:      sessionProvider,
+				secondFactorProvider: secondFactorProvider,
+				contactProvider:      contactProvider,
+				blockProvider:        blockProvider,
+				privacyProvider:      privacyProvider,
+				presenceProvider:     presenceProvider,
+				deviceProvider:       deviceProvider,
+			}
+			err := u.ExportUserData(tt.args.ctx, tt.args.userId, tt.args.w)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ExportUserData() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ExportUserData, "+tt.wantErr.Error(), fmt.Sprintf("users.ExportUserData() error = %v, wantErr %v", err, tt.wantErr))
+				return
+			}
+
+			raw := tt.args.w.(*bytes.Buffer).Bytes()
+			assert.NotContains(t, string(raw), "pass")
+
+			var doc exportDocument
+			if err = json.Unmarshal(raw, &doc); err != nil {
+				t.Fatalf("users.ExportUserData() wrote invalid json: %v", err)
+			}
+			assert.Equal(t, exportVersion, doc.Version)
+			assert.Equal(t, TestUser.Id, doc.Profile.Id)
+			assert.Equal(t, TestUser.Username, doc.Profile.Username)
+			assert.Equal(t, &TestLastSeen, doc.Profile.LastSeenAt)
+			assert.True(t, doc.TOTPEnabled)
+			assert.Equal(t, exportPrivacy{
+				LastSeen: string(TestPrivacySettings.LastSeen),
+				Avatar:   string(TestPrivacySettings.Avatar),
+				Search:   string(TestPrivacySettings.Search),
+			}, doc.Privacy)
+			assert.Len(t, doc.Sessions, 1)
+			assert.Equal(t, []exportDevice{{
+				Id:           TestDevice.Id,
+				Platform:     TestDevice.Platform,
+				PushToken:    TestDevice.PushToken,
+				AppVersion:   TestDevice.AppVersion,
+				CreatedAt:    TestDevice.CreatedAt,
+				LastActiveAt: TestDevice.LastActiveAt,
+			}}, doc.Devices)
+			assert.Equal(t, []exportUsernameChange{{
+				Username:      TestUsernameChange.Username,
+				ChangedAt:     TestUsernameChange.ChangedAt,
+				ReservedUntil: TestUsernameChange.ReservedUntil,
+			}}, doc.UsernameHistory)
+			assert.Equal(t, []exportAuditEvent{{
+				Event:     TestErasureEvent.Event,
+				CreatedAt: TestErasureEvent.CreatedAt,
+			}}, doc.AuditEvents)
+			assert.Equal(t, []exportContact{{UserId: TestContact.User.Id, Since: TestContact.Since}}, doc.Contacts)
+			assert.Equal(t, []exportContactRequest{{
+				FromUserId: TestContactRequest.FromUserId,
+				ToUserId:   TestContactRequest.ToUserId,
+				CreatedAt:  TestContactRequest.CreatedAt,
+			}}, doc.ContactRequests)
+			assert.Equal(t, []exportBlockedUser{{UserId: TestBlockedUser.User.Id, BlockedAt: TestBlockedUser.BlockedAt}}, doc.Blocked)
+		})
+	}
+}
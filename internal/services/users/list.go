@@ -0,0 +1,94 @@
+package users
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+// listPageToken - содержимое токена страницы списка пользователей.
+// Токен привязан к условиям отбора и порядку, чтобы его нельзя было применить к другому списку.
+type listPageToken struct {
+	Filter  string `json:"f"`
+	AfterId int64  `json:"id"`
+}
+
+// ListUsers реализует логику постраничного получения пользователей для администрирования.
+// Пользователи упорядочены по id, по убыванию, если desc = true.
+// pageToken - токен страницы из предыдущего ответа, пустой для первой страницы.
+// Возвращает пользователей и токен следующей страницы, пустой, если пользователей больше нет.
+// Если период создания задан неверно, возвращает *users.ValidationError.
+// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+func (u *Users) ListUsers(ctx context.Context, filter models.UserFilter, desc bool, pageToken string, limit int) ([]models.User, string, error) {
+	const op = "users.ListUsers"
+
+	if !filter.CreatedAfter.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedBefore.After(filter.CreatedAfter) {
+		err := newValidationError(FieldViolation{Field: "created_before", Description: "must be after created_after"})
+		u.log.Warnf("invalid user filter. %w", err)
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	if filter.UsernamePrefix != "" {
+		filter.UsernamePrefix = u.usernamePolicy.Canonical(filter.UsernamePrefix)
+	}
+
+	fingerprint, err := listFingerprint(filter, desc)
+	if err != nil {
+		u.log.Errorf("error encoding user filter. %w", err)
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	var afterId int64
+	if pageToken != "" {
+		var token listPageToken
+		if err = decodePageToken(pageToken, &token); err != nil {
+			u.log.Warnf("invalid page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+		if token.Filter != fingerprint || token.AfterId == 0 {
+			u.log.Warnf("page token belongs to another list. after_id=%d", token.AfterId)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+
+		afterId = token.AfterId
+	}
+
+	limit = u.pageSize.pageSize(limit)
+	//Лишний пользователь запрашивается, чтобы узнать, есть ли следующая страница
+	users, err := u.userProvider.ListUsers(ctx, filter, desc, afterId, limit+1)
+	if err != nil {
+		u.log.Errorf("error listing users. %w", err)
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	var nextPageToken string
+	if len(users) > limit {
+		users = users[:limit]
+		nextPageToken, err = encodePageToken(listPageToken{Filter: fingerprint, AfterId: users[limit-1].Id})
+		if err != nil {
+			u.log.Errorf("error encoding page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	return users, nextPageToken, nil
+}
+
+// listFingerprint возвращает отпечаток условий отбора и порядка списка пользователей.
+func listFingerprint(filter models.UserFilter, desc bool) (string, error) {
+	filter.CreatedAfter = filter.CreatedAfter.UTC()
+	filter.CreatedBefore = filter.CreatedBefore.UTC()
+
+	data, err := json.Marshal(struct {
+		Filter models.UserFilter
+		Desc   bool
+	}{filter, desc})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(hashToken(string(data))), nil
+}
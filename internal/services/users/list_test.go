@@ -0,0 +1,193 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestListPageToken возвращает токен страницы списка, следующей после пользователя afterId.
+func newTestListPageToken(filter models.UserFilter, desc bool, afterId int64) string {
+	fingerprint, err := listFingerprint(filter, desc)
+	if err != nil {
+		panic(err)
+	}
+
+	token, err := encodePageToken(listPageToken{Filter: fingerprint, AfterId: afterId})
+	if err != nil {
+		panic(err)
+	}
+
+	return token
+}
+
+func TestUsers_ListUsers(t *testing.T) {
+	active := true
+	filter := models.UserFilter{IsActive: &active, UsernamePrefix: TestCanonical}
+	users := make([]models.User, 0, 3)
+	for i := int64(1); i <= 3; i++ {
+		user := TestUser
+		user.Id = i
+		users = append(users, user)
+	}
+
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		usernamePolicy *mocks.UsernamePolicy,
+		ctx context.Context,
+	)
+
+	type args struct {
+		ctx       context.Context
+		filter    models.UserFilter
+		desc      bool
+		pageToken string
+		limit     int
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.User
+		wantToken    string
+		wantErr      error
+	}{
+		{
+			name: "FirstPage",
+			args: args{
+				ctx:    context.Background(),
+				filter: models.UserFilter{IsActive: &active, UsernamePrefix: TestUsername},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+			) {
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				userProvider.On("ListUsers", ctx, filter, false, int64(0), 3).Return(users, nil)
+			},
+			want:      users[:2],
+			wantToken: newTestListPageToken(filter, false, 2),
+		},
+		{
+			name: "LastPage",
+			args: args{
+				ctx:       context.Background(),
+				desc:      true,
+				pageToken: newTestListPageToken(models.UserFilter{}, true, 4),
+				limit:     10,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+			) {
+				userProvider.On("ListUsers", ctx, models.UserFilter{}, true, int64(4), 4).Return(users, nil)
+			},
+			want: users,
+		},
+		{
+			name: "InvalidCreatedRange",
+			args: args{
+				ctx: context.Background(),
+				filter: models.UserFilter{
+					CreatedAfter:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					CreatedBefore: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+			) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{{Field: "created_before", Description: "must be after created_after"}}},
+		},
+		{
+			name: "MalformedPageToken",
+			args: args{
+				ctx:       context.Background(),
+				pageToken: "!",
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+			) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name: "PageTokenOfAnotherOrder",
+			args: args{
+				ctx:       context.Background(),
+				pageToken: newTestListPageToken(models.UserFilter{}, true, 4),
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+			) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx: context.Background(),
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+			) {
+				userProvider.On("ListUsers", ctx, models.UserFilter{}, false, int64(0), 3).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+
+			tt.mockBehavior(log, userProvider, usernamePolicy, tt.args.ctx)
+			u := &Users{
+				log:            log,
+				userProvider:   userProvider,
+				usernamePolicy: usernamePolicy,
+				pageSize:       TestSearchPageSize,
+			}
+			got, token, err := u.ListUsers(tt.args.ctx, tt.args.filter, tt.args.desc, tt.args.pageToken, tt.args.limit)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ListUsers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ListUsers, "+tt.wantErr.Error(), fmt.Sprintf("users.ListUsers() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// Размер токена незавершенного входа в байтах.
+	challengeTokenSize = 32
+	totpCodeLength     = 6
+)
+
+// SecondFactorSaver предоставляет методы хранения второго фактора и незавершенных входов.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=SecondFactorSaver
+type SecondFactorSaver interface {
+	// SaveTOTPSecret сохраняет зашифрованный секрет TOTP пользователя, ожидающий подтверждения.
+	// Если у пользователя уже подтвержден TOTP, возвращает ошибку repository.ErrTOTPAlreadyConfirmed.
+	SaveTOTPSecret(ctx context.Context, userId int64, secret []byte) error
+
+	// ConfirmTOTP подтверждает TOTP пользователя, запоминает использованный шаг и заменяет коды восстановления.
+	// Если неподтвержденный TOTP не найден, возвращает ошибку repository.ErrTOTPNotFound.
+	ConfirmTOTP(ctx context.Context, userId int64, step int64, recoveryCodeHashes [][]byte) error
+
+	// UseTOTPStep запоминает шаг, код которого принят при входе.
+	// Если подтвержденный TOTP не найден или код этого шага уже использован, возвращает ошибку repository.ErrTOTPNotFound.
+	UseTOTPStep(ctx context.Context, userId int64, step int64) error
+
+	// UseRecoveryCode погашает код восстановления пользователя.
+	// Если код не найден или уже использован, возвращает ошибку repository.ErrRecoveryCodeNotFound.
+	UseRecoveryCode(ctx context.Context, userId int64, codeHash []byte) error
+
+	// DeleteTOTP отключает второй фактор пользователя вместе с кодами восстановления.
+	// Если TOTP не настроен, возвращает ошибку repository.ErrTOTPNotFound.
+	DeleteTOTP(ctx context.Context, userId int64) error
+
+	// SaveChallenge сохраняет хэш токена незавершенного входа, ожидающего второй фактор.
+	SaveChallenge(ctx context.Context, userId int64, tokenHash []byte, expiresAt time.Time) error
+
+	// ConsumeChallenge погашает токен незавершенного входа.
+	// Если токен не найден, уже использован или истек, возвращает ошибку repository.ErrChallengeNotFound.
+	ConsumeChallenge(ctx context.Context, tokenHash []byte) error
+}
+
+// SecondFactorProvider предоставляет методы получения второго фактора и незавершенных входов.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=SecondFactorProvider
+type SecondFactorProvider interface {
+	// GetTOTP получает второй фактор пользователя.
+	// Если TOTP не настроен, возвращает ошибку repository.ErrTOTPNotFound.
+	GetTOTP(ctx context.Context, userId int64) (models.TOTP, error)
+
+	// TOTPEnabled сообщает, подтвержден ли у пользователя TOTP.
+	TOTPEnabled(ctx context.Context, userId int64) (bool, error)
+
+	// GetChallengeOwner получает активного пользователя, которому принадлежит действующий токен незавершенного входа.
+	// Если токен не найден, уже использован или истек, возвращает ошибку repository.ErrChallengeNotFound.
+	GetChallengeOwner(ctx context.Context, tokenHash []byte) (models.User, error)
+}
+
+// TOTPManager - интерфейс генерации и проверки одноразовых кодов TOTP.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=TOTPManager
+type TOTPManager interface {
+	// GenerateSecret возвращает случайный секрет в кодировке base32.
+	GenerateSecret() (string, error)
+
+	// URI возвращает otpauth:// URI для добавления секрета в приложение-аутентификатор.
+	URI(secret, account string) string
+
+	// Validate проверяет код по секрету для текущего времени, возвращает номер шага, для которого код подошел.
+	Validate(secret, code string) (int64, bool)
+}
+
+// SecretEncrypter - интерфейс шифрования секретов, хранящихся в базе данных.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=SecretEncrypter
+type SecretEncrypter interface {
+	// Encrypt шифрует данные.
+	Encrypt(plaintext []byte) ([]byte, error)
+
+	// Decrypt расшифровывает данные, полученные методом Encrypt.
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// SecondFactorRequiredError - ошибка входа, для завершения которого нужен второй фактор.
+// Содержит токен незавершенного входа, который передается в VerifySecondFactor вместе с кодом.
+type SecondFactorRequiredError struct {
+	Challenge string
+	ExpiresAt time.Time
+}
+
+func (e *SecondFactorRequiredError) Error() string {
+	return ErrSecondFactorRequired.Error()
+}
+
+func (e *SecondFactorRequiredError) Unwrap() error {
+	return ErrSecondFactorRequired
+}
+
+// EnableTOTP реализует логику начала подключения TOTP.
+// Возвращает секрет и otpauth:// URI для приложения-аутентификатора. TOTP начинает действовать после ConfirmTOTP.
+// Если пользователь не найден или неактивен, возвращает users.ErrInvalidCredentials.
+// Если TOTP уже подключен, возвращает users.ErrTOTPAlreadyEnabled.
+func (u *Users) EnableTOTP(ctx context.Context, userId int64) (string, string, error) {
+	const op = "users.EnableTOTP"
+
+	user, err := u.userProvider.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return "", "", fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return "", "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	if !user.IsActive {
+		u.log.Warnf("user is inactive. user_id=%d", userId)
+		return "", "", fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+	}
+
+	secret, err := u.totpManager.GenerateSecret()
+	if err != nil {
+		u.log.Errorf("error generating totp secret. %w", err)
+		return "", "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	encrypted, err := u.secretEncrypter.Encrypt([]byte(secret))
+	if err != nil {
+		u.log.Errorf("error encrypting totp secret. %w", err)
+		return "", "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.secondFactorSaver.SaveTOTPSecret(ctx, userId, encrypted); err != nil {
+		if errors.Is(err, repository.ErrTOTPAlreadyConfirmed) {
+			u.log.Warnf("totp already enabled. %w", err)
+			return "", "", fmt.Errorf("%s, %w", op, ErrTOTPAlreadyEnabled)
+		}
+
+		u.log.Errorf("error saving totp secret. %w", err)
+		return "", "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	return secret, u.totpManager.URI(secret, user.Username), nil
+}
+
+// ConfirmTOTP реализует логику подтверждения подключения TOTP первым кодом из приложения-аутентификатора.
+// Возвращает коды восстановления, каждый из которых можно один раз использовать вместо кода TOTP.
+// Если подключение TOTP не начато, возвращает users.ErrTOTPNotEnabled.
+// Если TOTP уже подключен, возвращает users.ErrTOTPAlreadyEnabled.
+// Если код неверный, возвращает users.ErrInvalidSecondFactor.
+func (u *Users) ConfirmTOTP(ctx context.Context, userId int64, code string) ([]string, error) {
+	const op = "users.ConfirmTOTP"
+
+	totp, err := u.secondFactorProvider.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrTOTPNotFound) {
+			u.log.Warnf("totp enrollment not started. %w", err)
+			return nil, fmt.Errorf("%s, %w", op, ErrTOTPNotEnabled)
+		}
+
+		u.log.Errorf("error getting totp. %w", err)
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if totp.Confirmed {
+		u.log.Warnf("totp already enabled. user_id=%d", userId)
+		return nil, fmt.Errorf("%s, %w", op, ErrTOTPAlreadyEnabled)
+	}
+
+	step, err := u.validateTOTP(totp, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSecondFactor) {
+			u.log.Warnf("invalid totp code. user_id=%d", userId)
+			return nil, fmt.Errorf("%s, %w", op, err)
+		}
+
+		u.log.Errorf("error validating totp code. %w", err)
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		u.log.Errorf("error generating recovery codes. %w", err)
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.secondFactorSaver.ConfirmTOTP(ctx, userId, step, recoveryCodeHashes); err != nil {
+		if errors.Is(err, repository.ErrTOTPNotFound) {
+			u.log.Warnf("totp confirmed concurrently. %w", err)
+			return nil, fmt.Errorf("%s, %w", op, ErrTOTPAlreadyEnabled)
+		}
+
+		u.log.Errorf("error confirming totp. %w", err)
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTOTP реализует логику отключения TOTP с проверкой пароля.
+// Коды восстановления и незавершенные входы пользователя аннулируются.
+// Если пользователь не найден, неактивен или пароль неверный, возвращает users.ErrInvalidCredentials.
+// Если TOTP не подключен, возвращает users.ErrTOTPNotEnabled.
+func (u *Users) DisableTOTP(ctx context.Context, userId int64, password string) error {
+	const op = "users.DisableTOTP"
+
+	user, err := u.userProvider.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if !user.IsActive {
+		u.log.Warnf("user is inactive. user_id=%d", userId)
+		return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+	}
+
+	if err = u.crypter.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
+		u.log.Warnf("invalid credentials. %w", err)
+		return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+	}
+
+	if err = u.secondFactorSaver.DeleteTOTP(ctx, userId); err != nil {
+		if errors.Is(err, repository.ErrTOTPNotFound) {
+			u.log.Warnf("totp not enabled. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrTOTPNotEnabled)
+		}
+
+		u.log.Errorf("error deleting totp. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// VerifySecondFactor реализует логику завершения входа кодом TOTP или кодом восстановления.
+// Создает новую сессию для клиента и возвращает ее пару токенов.
+// Если токен незавершенного входа недействителен, возвращает users.ErrInvalidChallenge.
+// Если код неверный, возвращает users.ErrInvalidSecondFactor.
+// Неверные коды учитываются вместе с неудачными попытками входа, при превышении лимита возвращает *users.LockoutError.
+func (u *Users) VerifySecondFactor(ctx context.Context, challenge, code string, client models.ClientInfo) (models.Tokens, error) {
+	const op = "users.VerifySecondFactor"
+
+	challengeHash := hashToken(challenge)
+
+	user, err := u.secondFactorProvider.GetChallengeOwner(ctx, challengeHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrChallengeNotFound) {
+			u.log.Warnf("challenge not found. %w", err)
+			return models.Tokens{}, fmt.Errorf("%s, %w", op, ErrInvalidChallenge)
+		}
+
+		u.log.Errorf("error getting challenge owner. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	canonical := u.usernamePolicy.Canonical(user.Username)
+	attempts := u.loginAttempts(canonical, client.IP)
+	if err = u.checkLockout(ctx, attempts); err != nil {
+		u.log.Warnf("second factor rejected. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.verifySecondFactor(ctx, user.Id, code); err != nil {
+		if errors.Is(err, ErrInvalidSecondFactor) {
+			u.log.Warnf("invalid second factor. user_id=%d", user.Id)
+			u.registerAttempts(ctx, u.throttle, attempts)
+			return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+		}
+
+		u.log.Errorf("error verifying second factor. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.secondFactorSaver.ConsumeChallenge(ctx, challengeHash); err != nil {
+		if errors.Is(err, repository.ErrChallengeNotFound) {
+			u.log.Warnf("challenge already consumed. %w", err)
+			return models.Tokens{}, fmt.Errorf("%s, %w", op, ErrInvalidChallenge)
+		}
+
+		u.log.Errorf("error consuming challenge. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.attemptTracker.ResetFailedAttempts(ctx, userAttemptKey(canonical)); err != nil {
+		u.log.Errorf("error resetting failed attempts. %w", err)
+	}
+
+	tokens, err := u.startSession(ctx, user.Id, client)
+	if err != nil {
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// newSecondFactorChallenge создает токен незавершенного входа пользователя.
+func (u *Users) newSecondFactorChallenge(ctx context.Context, userId int64) (*SecondFactorRequiredError, error) {
+	b := make([]byte, challengeTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		u.log.Errorf("error generating challenge token. %w", err)
+		return nil, err
+	}
+
+	challenge := base64.RawURLEncoding.EncodeToString(b)
+	expiresAt := time.Now().Add(u.challengeTTL)
+
+	if err := u.secondFactorSaver.SaveChallenge(ctx, userId, hashToken(challenge), expiresAt); err != nil {
+		u.log.Errorf("error saving challenge. %w", err)
+		return nil, err
+	}
+
+	return &SecondFactorRequiredError{
+		Challenge: challenge,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// verifySecondFactor проверяет код TOTP или погашает код восстановления пользователя.
+// Если код не подошел, возвращает users.ErrInvalidSecondFactor.
+func (u *Users) verifySecondFactor(ctx context.Context, userId int64, code string) error {
+	code = strings.TrimSpace(code)
+
+	if !isTOTPCode(code) {
+		err := u.secondFactorSaver.UseRecoveryCode(ctx, userId, hashToken(normalizeRecoveryCode(code)))
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return ErrInvalidSecondFactor
+		}
+
+		return err
+	}
+
+	totp, err := u.secondFactorProvider.GetTOTP(ctx, userId)
+	if err != nil {
+		//TOTP мог быть отключен после ввода пароля
+		if errors.Is(err, repository.ErrTOTPNotFound) {
+			return ErrInvalidSecondFactor
+		}
+
+		return err
+	}
+
+	if !totp.Confirmed {
+		return ErrInvalidSecondFactor
+	}
+
+	step, err := u.validateTOTP(totp, code)
+	if err != nil {
+		return err
+	}
+
+	//Условное обновление шага защищает от повторного использования кода параллельными запросами
+	if err = u.secondFactorSaver.UseTOTPStep(ctx, userId, step); err != nil {
+		if errors.Is(err, repository.ErrTOTPNotFound) {
+			return ErrInvalidSecondFactor
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// validateTOTP расшифровывает секрет и проверяет код, возвращает номер шага принятого кода.
+// Коды уже использованных шагов не принимаются. Если код не подошел, возвращает users.ErrInvalidSecondFactor.
+func (u *Users) validateTOTP(totp models.TOTP, code string) (int64, error) {
+	secret, err := u.secretEncrypter.Decrypt(totp.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := u.totpManager.Validate(string(secret), code)
+	if !ok || step <= totp.LastUsedStep {
+		return 0, ErrInvalidSecondFactor
+	}
+
+	return step, nil
+}
+
+// generateRecoveryCodes возвращает коды восстановления в виде для пользователя и их хэши для хранения.
+func generateRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomCode(recoveryCodeLength)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode приводит введенный код восстановления к виду, в котором хранится его хэш.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// isTOTPCode сообщает, что код похож на код TOTP, а не на код восстановления.
+func isTOTPCode(code string) bool {
+	if len(code) != totpCodeLength {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,848 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestTOTPSecret            = "JBSWY3DPEHPK3PXP"
+	TestEncryptedSecret       = []byte("encrypted")
+	TestTOTPURI               = "otpauth://totp/Messenger:user1?secret=JBSWY3DPEHPK3PXP"
+	TestTOTPCode              = "123456"
+	TestTOTPStep        int64 = 100
+	TestRecoveryCode          = "abcde-23456"
+	TestChallenge             = "challenge"
+	TestChallengeTTL          = 5 * time.Minute
+)
+
+var (
+	TestTOTP = models.TOTP{
+		UserId:       TestUserId,
+		Secret:       TestEncryptedSecret,
+		Confirmed:    true,
+		LastUsedStep: TestTOTPStep - 1,
+	}
+	TestPendingTOTP = models.TOTP{
+		UserId: TestUserId,
+		Secret: TestEncryptedSecret,
+	}
+)
+
+func TestUsers_EnableTOTP(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		secondFactorSaver *mocks.SecondFactorSaver,
+		totpManager *mocks.TOTPManager,
+		secretEncrypter *mocks.SecretEncrypter,
+		ctx context.Context,
+		userId int64,
+	)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantSecret   string
+		wantURI      string
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				totpManager.On("GenerateSecret").Return(TestTOTPSecret, nil)
+				secretEncrypter.On("Encrypt", []byte(TestTOTPSecret)).Return(TestEncryptedSecret, nil)
+				secondFactorSaver.On("SaveTOTPSecret", ctx, userId, TestEncryptedSecret).Return(nil)
+				totpManager.On("URI", TestTOTPSecret, TestUsername).Return(TestTOTPURI)
+			},
+			wantSecret: TestTOTPSecret,
+			wantURI:    TestTOTPURI,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "InactiveUser",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestInactiveUser, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "AlreadyEnabled",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				totpManager.On("GenerateSecret").Return(TestTOTPSecret, nil)
+				secretEncrypter.On("Encrypt", []byte(TestTOTPSecret)).Return(TestEncryptedSecret, nil)
+				secondFactorSaver.On("SaveTOTPSecret", ctx, userId, TestEncryptedSecret).Return(repository.ErrTOTPAlreadyConfirmed)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrTOTPAlreadyEnabled,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				totpManager.On("GenerateSecret").Return(TestTOTPSecret, nil)
+				secretEncrypter.On("Encrypt", []byte(TestTOTPSecret)).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			secondFactorSaver := mocks.NewSecondFactorSaver(t)
+			totpManager := mocks.NewTOTPManager(t)
+			secretEncrypter := mocks.NewSecretEncrypter(t)
+
+			tt.mockBehavior(log, userProvider, secondFactorSaver, totpManager, secretEncrypter, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:               log,
+				userProvider:      userProvider,
+				secondFactorSaver: secondFactorSaver,
+				totpManager:       totpManager,
+				secretEncrypter:   secretEncrypter,
+			}
+			secret, uri, err := u.EnableTOTP(tt.args.ctx, tt.args.userId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.EnableTOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.EnableTOTP, "+tt.wantErr.Error(), fmt.Sprintf("users.EnableTOTP() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, secret, tt.wantSecret, fmt.Sprintf("users.EnableTOTP() secret = %v, want %v", secret, tt.wantSecret))
+			assert.Equal(t, uri, tt.wantURI, fmt.Sprintf("users.EnableTOTP() uri = %v, want %v", uri, tt.wantURI))
+		})
+	}
+}
+
+func TestUsers_ConfirmTOTP(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		secondFactorSaver *mocks.SecondFactorSaver,
+		secondFactorProvider *mocks.SecondFactorProvider,
+		totpManager *mocks.TOTPManager,
+		secretEncrypter *mocks.SecretEncrypter,
+		ctx context.Context,
+		userId int64,
+		code string,
+	)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+		code   string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantCodes    int
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				code:   TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+				code string,
+			) {
+				secondFactorProvider.On("GetTOTP", ctx, userId).Return(TestPendingTOTP, nil)
+				secretEncrypter.On("Decrypt", TestEncryptedSecret).Return([]byte(TestTOTPSecret), nil)
+				totpManager.On("Validate", TestTOTPSecret, code).Return(TestTOTPStep, true)
+				secondFactorSaver.On("ConfirmTOTP", ctx, userId, TestTOTPStep, mock.AnythingOfType("[][]uint8")).Return(nil)
+			},
+			wantCodes: recoveryCodeCount,
+		},
+		{
+			name: "NotEnrolled",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				code:   TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+				code string,
+			) {
+				secondFactorProvider.On("GetTOTP", ctx, userId).Return(models.TOTP{}, repository.ErrTOTPNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrTOTPNotEnabled,
+		},
+		{
+			name: "AlreadyEnabled",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				code:   TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+				code string,
+			) {
+				secondFactorProvider.On("GetTOTP", ctx, userId).Return(TestTOTP, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrTOTPAlreadyEnabled,
+		},
+		{
+			name: "InvalidCode",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				code:   TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+				code string,
+			) {
+				secondFactorProvider.On("GetTOTP", ctx, userId).Return(TestPendingTOTP, nil)
+				secretEncrypter.On("Decrypt", TestEncryptedSecret).Return([]byte(TestTOTPSecret), nil)
+				totpManager.On("Validate", TestTOTPSecret, code).Return(int64(0), false)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidSecondFactor,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+				code:   TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				userId int64,
+				code string,
+			) {
+				secondFactorProvider.On("GetTOTP", ctx, userId).Return(TestPendingTOTP, nil)
+				secretEncrypter.On("Decrypt", TestEncryptedSecret).Return([]byte(TestTOTPSecret), nil)
+				totpManager.On("Validate", TestTOTPSecret, code).Return(TestTOTPStep, true)
+				secondFactorSaver.On("ConfirmTOTP", ctx, userId, TestTOTPStep, mock.AnythingOfType("[][]uint8")).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			secondFactorSaver := mocks.NewSecondFactorSaver(t)
+			secondFactorProvider := mocks.NewSecondFactorProvider(t)
+			totpManager := mocks.NewTOTPManager(t)
+			secretEncrypter := mocks.NewSecretEncrypter(t)
+
+			tt.mockBehavior(log, secondFactorSaver, secondFactorProvider, totpManager, secretEncrypter, tt.args.ctx, tt.args.userId, tt.args.code)
+			u := &Users{
+				log:                  log,
+				secondFactorSaver:    secondFactorSaver,
+				secondFactorProvider: secondFactorProvider,
+				totpManager:          totpManager,
+				secretEncrypter:      secretEncrypter,
+			}
+			got, err := u.ConfirmTOTP(tt.args.ctx, tt.args.userId, tt.args.code)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ConfirmTOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ConfirmTOTP, "+tt.wantErr.Error(), fmt.Sprintf("users.ConfirmTOTP() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Len(t, got, tt.wantCodes, fmt.Sprintf("users.ConfirmTOTP() = %v, want %d codes", got, tt.wantCodes))
+		})
+	}
+}
+
+func TestUsers_DisableTOTP(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		crypter *mocks.Crypter,
+		secondFactorSaver *mocks.SecondFactorSaver,
+		ctx context.Context,
+		userId int64,
+		password string,
+	)
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		password string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				ctx context.Context,
+				userId int64,
+				password string,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(password)).Return(nil)
+				secondFactorSaver.On("DeleteTOTP", ctx, userId).Return(nil)
+			},
+		},
+		{
+			name: "WrongPassword",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				password: TestWrongPassword,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				ctx context.Context,
+				userId int64,
+				password string,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(password)).Return(errors.New(""))
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "NotEnabled",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				ctx context.Context,
+				userId int64,
+				password string,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(password)).Return(nil)
+				secondFactorSaver.On("DeleteTOTP", ctx, userId).Return(repository.ErrTOTPNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrTOTPNotEnabled,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				ctx context.Context,
+				userId int64,
+				password string,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(EmptyUser, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			crypter := mocks.NewCrypter(t)
+			secondFactorSaver := mocks.NewSecondFactorSaver(t)
+
+			tt.mockBehavior(log, userProvider, crypter, secondFactorSaver, tt.args.ctx, tt.args.userId, tt.args.password)
+			u := &Users{
+				log:               log,
+				userProvider:      userProvider,
+				crypter:           crypter,
+				secondFactorSaver: secondFactorSaver,
+			}
+			err := u.DisableTOTP(tt.args.ctx, tt.args.userId, tt.args.password)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.DisableTOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.DisableTOTP, "+tt.wantErr.Error(), fmt.Sprintf("users.DisableTOTP() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_VerifySecondFactor(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		tokenManager *mocks.TokenManager,
+		sessionSaver *mocks.SessionSaver,
+		usernamePolicy *mocks.UsernamePolicy,
+		attemptTracker *mocks.AttemptTracker,
+		secondFactorSaver *mocks.SecondFactorSaver,
+		secondFactorProvider *mocks.SecondFactorProvider,
+		totpManager *mocks.TOTPManager,
+		secretEncrypter *mocks.SecretEncrypter,
+		ctx context.Context,
+		challenge string,
+		code string,
+	)
+
+	type args struct {
+		ctx       context.Context
+		challenge string
+		code      string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.Tokens
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(TestUser, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				secondFactorProvider.On("GetTOTP", ctx, TestUserId).Return(TestTOTP, nil)
+				secretEncrypter.On("Decrypt", TestEncryptedSecret).Return([]byte(TestTOTPSecret), nil)
+				totpManager.On("Validate", TestTOTPSecret, code).Return(TestTOTPStep, true)
+				secondFactorSaver.On("UseTOTPStep", ctx, TestUserId, TestTOTPStep).Return(nil)
+				secondFactorSaver.On("ConsumeChallenge", ctx, hashToken(challenge)).Return(nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(nil)
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.On("SaveSession", ctx, TestSession, hashToken(TestRefreshToken), TestRefreshExpiresAt).Return(TestSessionId, nil)
+				tokenManager.On("NewAccessToken", TestUserId, TestSessionId).Return(TestAccessToken, TestAccessExpiresAt, nil)
+			},
+			want: TestTokens,
+		},
+		{
+			name: "RecoveryCode",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestRecoveryCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(TestUser, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				secondFactorSaver.On("UseRecoveryCode", ctx, TestUserId, hashToken("ABCDE23456")).Return(nil)
+				secondFactorSaver.On("ConsumeChallenge", ctx, hashToken(challenge)).Return(nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(nil)
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.On("SaveSession", ctx, TestSession, hashToken(TestRefreshToken), TestRefreshExpiresAt).Return(TestSessionId, nil)
+				tokenManager.On("NewAccessToken", TestUserId, TestSessionId).Return(TestAccessToken, TestAccessExpiresAt, nil)
+			},
+			want: TestTokens,
+		},
+		{
+			name: "InvalidChallenge",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(EmptyUser, repository.ErrChallengeNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidChallenge,
+		},
+		{
+			name: "InvalidCode",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(TestUser, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				secondFactorProvider.On("GetTOTP", ctx, TestUserId).Return(TestTOTP, nil)
+				secretEncrypter.On("Decrypt", TestEncryptedSecret).Return([]byte(TestTOTPSecret), nil)
+				totpManager.On("Validate", TestTOTPSecret, code).Return(int64(0), false)
+				log.On("Warnf", mock.Anything, mock.Anything)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestIPAttemptKey, mock.Anything).Return(1, nil)
+			},
+			wantErr: ErrInvalidSecondFactor,
+		},
+		{
+			name: "ReusedCode",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(TestUser, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				secondFactorProvider.On("GetTOTP", ctx, TestUserId).Return(TestTOTP, nil)
+				secretEncrypter.On("Decrypt", TestEncryptedSecret).Return([]byte(TestTOTPSecret), nil)
+				totpManager.On("Validate", TestTOTPSecret, code).Return(TestTOTP.LastUsedStep, true)
+				log.On("Warnf", mock.Anything, mock.Anything)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestIPAttemptKey, mock.Anything).Return(1, nil)
+			},
+			wantErr: ErrInvalidSecondFactor,
+		},
+		{
+			name: "InvalidRecoveryCode",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestRecoveryCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(TestUser, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				secondFactorSaver.On("UseRecoveryCode", ctx, TestUserId, hashToken("ABCDE23456")).Return(repository.ErrRecoveryCodeNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestIPAttemptKey, mock.Anything).Return(1, nil)
+			},
+			wantErr: ErrInvalidSecondFactor,
+		},
+		{
+			name: "LockedOut",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestTOTPCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(TestUser, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(TestLockedUntil, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &LockoutError{Until: TestLockedUntil},
+		},
+		{
+			name: "ChallengeConsumed",
+			args: args{
+				ctx:       context.Background(),
+				challenge: TestChallenge,
+				code:      TestRecoveryCode,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				totpManager *mocks.TOTPManager,
+				secretEncrypter *mocks.SecretEncrypter,
+				ctx context.Context,
+				challenge string,
+				code string,
+			) {
+				secondFactorProvider.On("GetChallengeOwner", ctx, hashToken(challenge)).Return(TestUser, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				secondFactorSaver.On("UseRecoveryCode", ctx, TestUserId, hashToken("ABCDE23456")).Return(nil)
+				secondFactorSaver.On("ConsumeChallenge", ctx, hashToken(challenge)).Return(repository.ErrChallengeNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidChallenge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			tokenManager := mocks.NewTokenManager(t)
+			sessionSaver := mocks.NewSessionSaver(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+			attemptTracker := mocks.NewAttemptTracker(t)
+			secondFactorSaver := mocks.NewSecondFactorSaver(t)
+			secondFactorProvider := mocks.NewSecondFactorProvider(t)
+			totpManager := mocks.NewTOTPManager(t)
+			secretEncrypter := mocks.NewSecretEncrypter(t)
+
+			tt.mockBehavior(
+				log,
+				tokenManager,
+				sessionSaver,
+				usernamePolicy,
+				attemptTracker,
+				secondFactorSaver,
+				secondFactorProvider,
+				totpManager,
+				secretEncrypter,
+				tt.args.ctx,
+				tt.args.challenge,
+				tt.args.code,
+			)
+			u := &Users{
+				log:                  log,
+				tokenManager:         tokenManager,
+				sessionSaver:         sessionSaver,
+				usernamePolicy:       usernamePolicy,
+				attemptTracker:       attemptTracker,
+				throttle:             TestThrottle,
+				secondFactorSaver:    secondFactorSaver,
+				secondFactorProvider: secondFactorProvider,
+				totpManager:          totpManager,
+				secretEncrypter:      secretEncrypter,
+				challengeTTL:         TestChallengeTTL,
+			}
+			got, err := u.VerifySecondFactor(tt.args.ctx, tt.args.challenge, tt.args.code, TestClient)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.VerifySecondFactor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.VerifySecondFactor, "+tt.wantErr.Error(), fmt.Sprintf("users.VerifySecondFactor() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, got, tt.want, fmt.Sprintf("users.VerifySecondFactor() = %v, want %v", got, tt.want))
+		})
+	}
+}
@@ -27,9 +27,9 @@ func (_m *Crypter) CompareHashAndPassword(hashedPassword []byte, password []byte
 	return r0
 }
 
-// GenerateFromPassword provides a mock function with given fields: pass, cost
-func (_m *Crypter) GenerateFromPassword(pass []byte, cost int) ([]byte, error) {
-	ret := _m.Called(pass, cost)
+// GenerateFromPassword provides a mock function with given fields: pass
+func (_m *Crypter) GenerateFromPassword(pass []byte) ([]byte, error) {
+	ret := _m.Called(pass)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GenerateFromPassword")
@@ -37,19 +37,19 @@ func (_m *Crypter) GenerateFromPassword(pass []byte, cost int) ([]byte, error) {
 
 	var r0 []byte
 	var r1 error
-	if rf, ok := ret.Get(0).(func([]byte, int) ([]byte, error)); ok {
-		return rf(pass, cost)
+	if rf, ok := ret.Get(0).(func([]byte) ([]byte, error)); ok {
+		return rf(pass)
 	}
-	if rf, ok := ret.Get(0).(func([]byte, int) []byte); ok {
-		r0 = rf(pass, cost)
+	if rf, ok := ret.Get(0).(func([]byte) []byte); ok {
+		r0 = rf(pass)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func([]byte, int) error); ok {
-		r1 = rf(pass, cost)
+	if rf, ok := ret.Get(1).(func([]byte) error); ok {
+		r1 = rf(pass)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -57,6 +57,24 @@ func (_m *Crypter) GenerateFromPassword(pass []byte, cost int) ([]byte, error) {
 	return r0, r1
 }
 
+// NeedsRehash provides a mock function with given fields: hashedPassword
+func (_m *Crypter) NeedsRehash(hashedPassword []byte) bool {
+	ret := _m.Called(hashedPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NeedsRehash")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func([]byte) bool); ok {
+		r0 = rf(hashedPassword)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // NewCrypter creates a new instance of Crypter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCrypter(t interface {
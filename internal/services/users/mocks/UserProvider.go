@@ -14,9 +14,39 @@ type UserProvider struct {
 	mock.Mock
 }
 
-// GetUser provides a mock function with given fields: ctx, username
-func (_m *UserProvider) GetUser(ctx context.Context, username string) (models.User, error) {
-	ret := _m.Called(ctx, username)
+// GetErasureEvents provides a mock function with given fields: ctx, userId
+func (_m *UserProvider) GetErasureEvents(ctx context.Context, userId int64) ([]models.ErasureEvent, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetErasureEvents")
+	}
+
+	var r0 []models.ErasureEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.ErasureEvent, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.ErasureEvent); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ErasureEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, canonical
+func (_m *UserProvider) GetUser(ctx context.Context, canonical string) (models.User, error) {
+	ret := _m.Called(ctx, canonical)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetUser")
@@ -25,16 +55,164 @@ func (_m *UserProvider) GetUser(ctx context.Context, username string) (models.Us
 	var r0 models.User
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, string) (models.User, error)); ok {
-		return rf(ctx, username)
+		return rf(ctx, canonical)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, string) models.User); ok {
-		r0 = rf(ctx, username)
+		r0 = rf(ctx, canonical)
 	} else {
 		r0 = ret.Get(0).(models.User)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, username)
+		r1 = rf(ctx, canonical)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserById provides a mock function with given fields: ctx, userId
+func (_m *UserProvider) GetUserById(ctx context.Context, userId int64) (models.User, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserById")
+	}
+
+	var r0 models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (models.User, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) models.User); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsernameHistory provides a mock function with given fields: ctx, userId
+func (_m *UserProvider) GetUsernameHistory(ctx context.Context, userId int64) ([]models.UsernameChange, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsernameHistory")
+	}
+
+	var r0 []models.UsernameChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]models.UsernameChange, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.UsernameChange); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.UsernameChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsersByIds provides a mock function with given fields: ctx, userIds
+func (_m *UserProvider) GetUsersByIds(ctx context.Context, userIds []int64) ([]models.User, error) {
+	ret := _m.Called(ctx, userIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsersByIds")
+	}
+
+	var r0 []models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]models.User, error)); ok {
+		return rf(ctx, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []models.User); ok {
+		r0 = rf(ctx, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, userIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsers provides a mock function with given fields: ctx, filter, desc, afterId, limit
+func (_m *UserProvider) ListUsers(ctx context.Context, filter models.UserFilter, desc bool, afterId int64, limit int) ([]models.User, error) {
+	ret := _m.Called(ctx, filter, desc, afterId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsers")
+	}
+
+	var r0 []models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserFilter, bool, int64, int) ([]models.User, error)); ok {
+		return rf(ctx, filter, desc, afterId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.UserFilter, bool, int64, int) []models.User); ok {
+		r0 = rf(ctx, filter, desc, afterId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.UserFilter, bool, int64, int) error); ok {
+		r1 = rf(ctx, filter, desc, afterId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchUsers provides a mock function with given fields: ctx, viewerId, query, after, limit
+func (_m *UserProvider) SearchUsers(ctx context.Context, viewerId int64, query string, after *models.UserSearchCursor, limit int) ([]models.UserSearchHit, error) {
+	ret := _m.Called(ctx, viewerId, query, after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchUsers")
+	}
+
+	var r0 []models.UserSearchHit
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, *models.UserSearchCursor, int) ([]models.UserSearchHit, error)); ok {
+		return rf(ctx, viewerId, query, after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, *models.UserSearchCursor, int) []models.UserSearchHit); ok {
+		r0 = rf(ctx, viewerId, query, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.UserSearchHit)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, *models.UserSearchCursor, int) error); ok {
+		r1 = rf(ctx, viewerId, query, after, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
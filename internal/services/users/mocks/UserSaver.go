@@ -4,7 +4,9 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
+	models "github.com/al3ksus/messengerusers/internal/domain/models"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,9 +15,75 @@ type UserSaver struct {
 	mock.Mock
 }
 
-// SaveUser provides a mock function with given fields: ctx, username, password
-func (_m *UserSaver) SaveUser(ctx context.Context, username string, password []byte) (int64, error) {
-	ret := _m.Called(ctx, username, password)
+// CancelUserDeletion provides a mock function with given fields: ctx, userId
+func (_m *UserSaver) CancelUserDeletion(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelUserDeletion")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ChangeUsername provides a mock function with given fields: ctx, userId, username, canonical, reservedUntil
+func (_m *UserSaver) ChangeUsername(ctx context.Context, userId int64, username string, canonical string, reservedUntil time.Time) error {
+	ret := _m.Called(ctx, userId, username, canonical, reservedUntil)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangeUsername")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, time.Time) error); ok {
+		r0 = rf(ctx, userId, username, canonical, reservedUntil)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeDeletedUsers provides a mock function with given fields: ctx, limit
+func (_m *UserSaver) PurgeDeletedUsers(ctx context.Context, limit int) ([]models.ErasedUser, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeDeletedUsers")
+	}
+
+	var r0 []models.ErasedUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.ErasedUser, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.ErasedUser); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ErasedUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveUser provides a mock function with given fields: ctx, username, canonical, password
+func (_m *UserSaver) SaveUser(ctx context.Context, username string, canonical string, password []byte) (int64, error) {
+	ret := _m.Called(ctx, username, canonical, password)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveUser")
@@ -23,17 +91,17 @@ func (_m *UserSaver) SaveUser(ctx context.Context, username string, password []b
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) (int64, error)); ok {
-		return rf(ctx, username, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte) (int64, error)); ok {
+		return rf(ctx, username, canonical, password)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) int64); ok {
-		r0 = rf(ctx, username, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte) int64); ok {
+		r0 = rf(ctx, username, canonical, password)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, []byte) error); ok {
-		r1 = rf(ctx, username, password)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []byte) error); ok {
+		r1 = rf(ctx, username, canonical, password)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,6 +109,42 @@ func (_m *UserSaver) SaveUser(ctx context.Context, username string, password []b
 	return r0, r1
 }
 
+// ScheduleUserDeletion provides a mock function with given fields: ctx, userId, deleteAfter
+func (_m *UserSaver) ScheduleUserDeletion(ctx context.Context, userId int64, deleteAfter time.Time) error {
+	ret := _m.Called(ctx, userId, deleteAfter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScheduleUserDeletion")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) error); ok {
+		r0 = rf(ctx, userId, deleteAfter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetActive provides a mock function with given fields: ctx, userId
+func (_m *UserSaver) SetActive(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetActive")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetInactive provides a mock function with given fields: ctx, userId
 func (_m *UserSaver) SetInactive(ctx context.Context, userId int64) error {
 	ret := _m.Called(ctx, userId)
@@ -59,6 +163,52 @@ func (_m *UserSaver) SetInactive(ctx context.Context, userId int64) error {
 	return r0
 }
 
+// UpdatePasswordHash provides a mock function with given fields: ctx, userId, passHash
+func (_m *UserSaver) UpdatePasswordHash(ctx context.Context, userId int64, passHash []byte) error {
+	ret := _m.Called(ctx, userId, passHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePasswordHash")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []byte) error); ok {
+		r0 = rf(ctx, userId, passHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, userId, profile, fields
+func (_m *UserSaver) UpdateProfile(ctx context.Context, userId int64, profile models.Profile, fields []string) (models.User, error) {
+	ret := _m.Called(ctx, userId, profile, fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, models.Profile, []string) (models.User, error)); ok {
+		return rf(ctx, userId, profile, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, models.Profile, []string) models.User); ok {
+		r0 = rf(ctx, userId, profile, fields)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, models.Profile, []string) error); ok {
+		r1 = rf(ctx, userId, profile, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewUserSaver creates a new instance of UserSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewUserSaver(t interface {
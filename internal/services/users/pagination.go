@@ -0,0 +1,46 @@
+package users
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// PageSizeParams - размер страницы для методов, возвращающих результаты постранично.
+type PageSizeParams struct {
+	// Default - размер страницы, если клиент его не указал.
+	Default int
+	// Max - наибольший размер страницы, больший размер уменьшается до него.
+	Max int
+}
+
+// pageSize возвращает размер страницы для запрошенного лимита.
+func (p PageSizeParams) pageSize(limit int) int {
+	if limit <= 0 {
+		return p.Default
+	}
+	if limit > p.Max {
+		return p.Max
+	}
+
+	return limit
+}
+
+// encodePageToken возвращает непрозрачный для клиента токен страницы, содержащий позицию v.
+func encodePageToken(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodePageToken считывает позицию из токена страницы в v.
+func decodePageToken(token string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
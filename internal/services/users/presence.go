@@ -0,0 +1,244 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// PresenceTracker предоставляет методы работы с картой присутствия пользователей в сети.
+// Пользователь считается в сети, пока он есть в карте.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=PresenceTracker
+type PresenceTracker interface {
+	// Touch отмечает активность пользователя в момент at.
+	// Если пользователя не было в сети, подписчики получают событие появления в сети.
+	Touch(ctx context.Context, userId int64, at time.Time) error
+
+	// GetLastSeen возвращает время последней активности пользователей, которые есть в карте.
+	GetLastSeen(ctx context.Context, userIds []int64) (map[int64]time.Time, error)
+
+	// Pending возвращает время последней активности пользователей, которое еще не отмечено сохраненным.
+	Pending(ctx context.Context) (map[int64]time.Time, error)
+
+	// MarkFlushed отмечает время активности пользователей сохраненным.
+	MarkFlushed(ctx context.Context, flushed map[int64]time.Time) error
+
+	// Expire удаляет из карты пользователей, не проявлявших активность с момента before, если их активность сохранена.
+	// Подписчики получают событие ухода из сети. Возвращает количество удаленных пользователей.
+	Expire(ctx context.Context, before time.Time) (int, error)
+
+	// Subscribe подписывает на изменения присутствия пользователей userIds до отмены ctx.
+	// После отмены ctx канал событий закрывается.
+	Subscribe(ctx context.Context, userIds []int64) (<-chan models.Presence, error)
+}
+
+// PresenceSaver предоставляет метод сохранения времени последней активности пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=PresenceSaver
+type PresenceSaver interface {
+	// SaveLastSeen сохраняет время последней активности пользователей. Более раннее время не перезаписывает сохраненное.
+	SaveLastSeen(ctx context.Context, lastSeen map[int64]time.Time) error
+}
+
+// PresenceProvider предоставляет метод получения сохраненного времени последней активности пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=PresenceProvider
+type PresenceProvider interface {
+	// GetLastSeen получает сохраненное время последней активности активных пользователей.
+	// Пользователи, которые еще не были в сети, в результат не попадают.
+	GetLastSeen(ctx context.Context, userIds []int64) (map[int64]time.Time, error)
+}
+
+// Heartbeat реализует логику отметки активности пользователя.
+// Пользователь считается в сети в течение presenceTTL после последней отметки.
+// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+func (u *Users) Heartbeat(ctx context.Context, userId int64) error {
+	const op = "users.Heartbeat"
+
+	user, err := u.userProvider.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if !user.IsActive {
+		u.log.Warnf("user is inactive. user_id=%d", userId)
+		return fmt.Errorf("%s, %w", op, ErrUserNotFound)
+	}
+
+	if err = u.presenceTracker.Touch(ctx, userId, time.Now()); err != nil {
+		u.log.Errorf("error touching presence. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// GetPresence реализует логику получения присутствия пользователей в сети по списку id пользователем viewerId.
+// Повторяющиеся id учитываются один раз, порядок результата совпадает с порядком первого появления id.
+// Для пользователей, которые еще не были в сети или скрыли присутствие от viewerId, время последней активности нулевое.
+// Если различных id больше, чем допускает конфигурация, возвращает users.ErrBatchTooLarge.
+func (u *Users) GetPresence(ctx context.Context, viewerId int64, userIds []int64) ([]models.Presence, error) {
+	const op = "users.GetPresence"
+
+	unique := uniqueIds(userIds)
+	if len(unique) > u.maxBatchSize {
+		u.log.Warnf("batch too large. size=%d", len(unique))
+		return nil, fmt.Errorf("%s, %w", op, ErrBatchTooLarge)
+	}
+
+	view, err := u.privacyView(ctx, viewerId, unique)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	presences, err := u.presence(ctx, unique)
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	for i := range presences {
+		view.applyPresence(&presences[i])
+	}
+
+	return presences, nil
+}
+
+// WatchPresence реализует логику наблюдения за присутствием пользователей в сети пользователем viewerId.
+// Сначала в send передается текущее состояние всех пользователей, затем изменения, пока не будет отменен ctx.
+// Для пользователей, скрывших присутствие от viewerId, передается только скрытое текущее состояние.
+// Настройки приватности проверяются один раз при начале наблюдения.
+// События ухода из сети приходят с задержкой до интервала сохранения активности.
+// Если различных id больше, чем допускает конфигурация, возвращает users.ErrBatchTooLarge.
+// Если send возвращает ошибку, наблюдение прекращается и ошибка возвращается.
+func (u *Users) WatchPresence(ctx context.Context, viewerId int64, userIds []int64, send func(models.Presence) error) error {
+	const op = "users.WatchPresence"
+
+	unique := uniqueIds(userIds)
+	if len(unique) > u.maxBatchSize {
+		u.log.Warnf("batch too large. size=%d", len(unique))
+		return fmt.Errorf("%s, %w", op, ErrBatchTooLarge)
+	}
+
+	view, err := u.privacyView(ctx, viewerId, unique)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	//Подписка оформляется до получения текущего состояния, чтобы не пропустить изменения между ними
+	events, err := u.presenceTracker.Subscribe(ctx, unique)
+	if err != nil {
+		u.log.Errorf("error subscribing to presence. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	presences, err := u.presence(ctx, unique)
+	if err != nil {
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	for _, p := range presences {
+		view.applyPresence(&p)
+		if err = send(p); err != nil {
+			u.log.Warnf("error sending presence. %w", err)
+			return fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	for p := range events {
+		if !view.allows(p.UserId, view.of(p.UserId).LastSeen) {
+			continue
+		}
+
+		if err = send(p); err != nil {
+			u.log.Warnf("error sending presence. %w", err)
+			return fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// FlushPresence реализует логику сохранения несохраненной активности пользователей в репозиторий
+// и удаления из карты присутствия пользователей, не проявлявших активность дольше presenceTTL.
+// Возвращает количество пользователей, активность которых сохранена.
+func (u *Users) FlushPresence(ctx context.Context) (int, error) {
+	const op = "users.FlushPresence"
+
+	pending, err := u.presenceTracker.Pending(ctx)
+	if err != nil {
+		u.log.Errorf("error getting pending presence. %w", err)
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if len(pending) > 0 {
+		if err = u.presenceSaver.SaveLastSeen(ctx, pending); err != nil {
+			u.log.Errorf("error saving last seen. %w", err)
+			return 0, fmt.Errorf("%s, %w", op, err)
+		}
+
+		if err = u.presenceTracker.MarkFlushed(ctx, pending); err != nil {
+			u.log.Errorf("error marking presence flushed. %w", err)
+			return 0, fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	if _, err = u.presenceTracker.Expire(ctx, time.Now().Add(-u.presenceTTL)); err != nil {
+		u.log.Errorf("error expiring presence. %w", err)
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return len(pending), nil
+}
+
+// presence собирает присутствие пользователей из карты присутствия,
+// а для пользователей, которых нет в карте, - из сохраненного времени последней активности.
+func (u *Users) presence(ctx context.Context, userIds []int64) ([]models.Presence, error) {
+	lastSeen, err := u.presenceTracker.GetLastSeen(ctx, userIds)
+	if err != nil {
+		u.log.Errorf("error getting presence. %w", err)
+		return nil, err
+	}
+
+	var missing []int64
+	for _, id := range userIds {
+		if _, ok := lastSeen[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	var stored map[int64]time.Time
+	if len(missing) > 0 {
+		stored, err = u.presenceProvider.GetLastSeen(ctx, missing)
+		if err != nil {
+			u.log.Errorf("error getting last seen. %w", err)
+			return nil, err
+		}
+	}
+
+	onlineSince := time.Now().Add(-u.presenceTTL)
+	presences := make([]models.Presence, 0, len(userIds))
+	for _, id := range userIds {
+		if at, ok := lastSeen[id]; ok {
+			presences = append(presences, models.Presence{UserId: id, Online: at.After(onlineSince), LastSeenAt: at})
+			continue
+		}
+
+		presences = append(presences, models.Presence{UserId: id, LastSeenAt: stored[id]})
+	}
+
+	return presences, nil
+}
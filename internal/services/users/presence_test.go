@@ -0,0 +1,392 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestPresenceTTL       = time.Minute
+	TestPresenceBatchSize = 5
+	TestLastSeen          = time.Unix(1700000000, 0).UTC()
+)
+
+func TestUsers_Heartbeat(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		presenceTracker *mocks.PresenceTracker,
+		ctx context.Context,
+	)
+
+	// recent проверяет, что активность отмечена текущим временем.
+	recent := mock.MatchedBy(func(at time.Time) bool {
+		return time.Since(at) < time.Minute
+	})
+
+	tests := []struct {
+		name         string
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, presenceTracker *mocks.PresenceTracker, ctx context.Context) {
+				userProvider.On("GetUserById", ctx, TestUserId).Return(TestUser, nil)
+				presenceTracker.On("Touch", ctx, TestUserId, recent).Return(nil)
+			},
+		},
+		{
+			name: "UserNotFound",
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, presenceTracker *mocks.PresenceTracker, ctx context.Context) {
+				userProvider.On("GetUserById", ctx, TestUserId).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "UserInactive",
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, presenceTracker *mocks.PresenceTracker, ctx context.Context) {
+				userProvider.On("GetUserById", ctx, TestUserId).Return(TestInactiveUser, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "ErrorUser",
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, presenceTracker *mocks.PresenceTracker, ctx context.Context) {
+				userProvider.On("GetUserById", ctx, TestUserId).Return(EmptyUser, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "Error",
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, presenceTracker *mocks.PresenceTracker, ctx context.Context) {
+				userProvider.On("GetUserById", ctx, TestUserId).Return(TestUser, nil)
+				presenceTracker.On("Touch", ctx, TestUserId, recent).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			presenceTracker := mocks.NewPresenceTracker(t)
+
+			tt.mockBehavior(log, userProvider, presenceTracker, ctx)
+			u := &Users{
+				log:             log,
+				userProvider:    userProvider,
+				presenceTracker: presenceTracker,
+				presenceTTL:     TestPresenceTTL,
+			}
+			err := u.Heartbeat(ctx, TestUserId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.Heartbeat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.Heartbeat, "+tt.wantErr.Error(), fmt.Sprintf("users.Heartbeat() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_GetPresence(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		presenceTracker *mocks.PresenceTracker,
+		presenceSaver *mocks.PresenceSaver,
+		presenceProvider *mocks.PresenceProvider,
+		ctx context.Context,
+	)
+
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		userIds      []int64
+		mockBehavior mockBehavior
+		want         []models.Presence
+		wantErr      error
+	}{
+		{
+			name:    "OK",
+			userIds: []int64{1, 2, 1, 3, 4},
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("GetLastSeen", ctx, []int64{1, 2, 3, 4}).
+					Return(map[int64]time.Time{1: now, 2: now.Add(-time.Hour)}, nil)
+				presenceProvider.On("GetLastSeen", ctx, []int64{3, 4}).
+					Return(map[int64]time.Time{3: TestLastSeen}, nil)
+			},
+			want: []models.Presence{
+				{UserId: 1, Online: true, LastSeenAt: now},
+				{UserId: 2, LastSeenAt: now.Add(-time.Hour)},
+				{UserId: 3, LastSeenAt: TestLastSeen},
+				{UserId: 4},
+			},
+		},
+		{
+			name:    "AllInTracker",
+			userIds: []int64{1},
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("GetLastSeen", ctx, []int64{1}).Return(map[int64]time.Time{1: now}, nil)
+			},
+			want: []models.Presence{{UserId: 1, Online: true, LastSeenAt: now}},
+		},
+		{
+			name:    "BatchTooLarge",
+			userIds: []int64{1, 2, 3, 4, 5, 6},
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrBatchTooLarge,
+		},
+		{
+			name:    "Error",
+			userIds: []int64{1},
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("GetLastSeen", ctx, []int64{1}).Return(map[int64]time.Time{}, nil)
+				presenceProvider.On("GetLastSeen", ctx, []int64{1}).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			presenceTracker := mocks.NewPresenceTracker(t)
+			presenceSaver := mocks.NewPresenceSaver(t)
+			presenceProvider := mocks.NewPresenceProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, presenceTracker, presenceSaver, presenceProvider, ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:              log,
+				presenceTracker:  presenceTracker,
+				presenceSaver:    presenceSaver,
+				presenceProvider: presenceProvider,
+				privacyProvider:  privacyProvider,
+				presenceTTL:      TestPresenceTTL,
+				maxBatchSize:     TestPresenceBatchSize,
+			}
+			got, err := u.GetPresence(ctx, TestUserId, tt.userIds)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.GetPresence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.GetPresence, "+tt.wantErr.Error(), fmt.Sprintf("users.GetPresence() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_WatchPresence(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		presenceTracker *mocks.PresenceTracker,
+		presenceSaver *mocks.PresenceSaver,
+		presenceProvider *mocks.PresenceProvider,
+		ctx context.Context,
+	)
+
+	event := models.Presence{UserId: TestUserId, LastSeenAt: TestLastSeen}
+
+	// newEvents возвращает закрытый канал с событием, как после отмены подписки.
+	newEvents := func() <-chan models.Presence {
+		events := make(chan models.Presence, 1)
+		events <- event
+		close(events)
+
+		return events
+	}
+
+	tests := []struct {
+		name         string
+		userIds      []int64
+		sendErr      error
+		mockBehavior mockBehavior
+		want         []models.Presence
+		wantErr      error
+	}{
+		{
+			name:    "OK",
+			userIds: []int64{TestUserId},
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("Subscribe", mock.Anything, []int64{TestUserId}).Return(newEvents(), nil)
+				presenceTracker.On("GetLastSeen", mock.Anything, []int64{TestUserId}).Return(map[int64]time.Time{}, nil)
+				presenceProvider.On("GetLastSeen", mock.Anything, []int64{TestUserId}).Return(map[int64]time.Time{}, nil)
+			},
+			want: []models.Presence{{UserId: TestUserId}, event},
+		},
+		{
+			name:    "SendError",
+			userIds: []int64{TestUserId},
+			sendErr: errors.New(""),
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("Subscribe", mock.Anything, []int64{TestUserId}).Return(newEvents(), nil)
+				presenceTracker.On("GetLastSeen", mock.Anything, []int64{TestUserId}).Return(map[int64]time.Time{}, nil)
+				presenceProvider.On("GetLastSeen", mock.Anything, []int64{TestUserId}).Return(map[int64]time.Time{}, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			want:    []models.Presence{{UserId: TestUserId}},
+			wantErr: errors.New(""),
+		},
+		{
+			name:    "SubscribeError",
+			userIds: []int64{TestUserId},
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("Subscribe", mock.Anything, []int64{TestUserId}).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name:    "BatchTooLarge",
+			userIds: []int64{1, 2, 3, 4, 5, 6},
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrBatchTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			presenceTracker := mocks.NewPresenceTracker(t)
+			presenceSaver := mocks.NewPresenceSaver(t)
+			presenceProvider := mocks.NewPresenceProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, presenceTracker, presenceSaver, presenceProvider, ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:              log,
+				presenceTracker:  presenceTracker,
+				presenceSaver:    presenceSaver,
+				presenceProvider: presenceProvider,
+				privacyProvider:  privacyProvider,
+				presenceTTL:      TestPresenceTTL,
+				maxBatchSize:     TestPresenceBatchSize,
+			}
+
+			var got []models.Presence
+			err := u.WatchPresence(ctx, TestUserId, tt.userIds, func(p models.Presence) error {
+				got = append(got, p)
+				return tt.sendErr
+			})
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.WatchPresence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.WatchPresence, "+tt.wantErr.Error(), fmt.Sprintf("users.WatchPresence() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_FlushPresence(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		presenceTracker *mocks.PresenceTracker,
+		presenceSaver *mocks.PresenceSaver,
+		presenceProvider *mocks.PresenceProvider,
+		ctx context.Context,
+	)
+
+	pending := map[int64]time.Time{TestUserId: TestLastSeen}
+
+	// expireBefore проверяет, что из карты удаляются пользователи без активности дольше presenceTTL.
+	expireBefore := mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= TestPresenceTTL && time.Since(before) < TestPresenceTTL+time.Minute
+	})
+
+	tests := []struct {
+		name         string
+		mockBehavior mockBehavior
+		want         int
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("Pending", ctx).Return(pending, nil)
+				presenceSaver.On("SaveLastSeen", ctx, pending).Return(nil)
+				presenceTracker.On("MarkFlushed", ctx, pending).Return(nil)
+				presenceTracker.On("Expire", ctx, expireBefore).Return(1, nil)
+			},
+			want: 1,
+		},
+		{
+			name: "NothingPending",
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("Pending", ctx).Return(map[int64]time.Time{}, nil)
+				presenceTracker.On("Expire", ctx, expireBefore).Return(0, nil)
+			},
+		},
+		{
+			name: "SaveError",
+			mockBehavior: func(log *loggermocks.Logger, presenceTracker *mocks.PresenceTracker, presenceSaver *mocks.PresenceSaver, presenceProvider *mocks.PresenceProvider, ctx context.Context) {
+				presenceTracker.On("Pending", ctx).Return(pending, nil)
+				presenceSaver.On("SaveLastSeen", ctx, pending).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			presenceTracker := mocks.NewPresenceTracker(t)
+			presenceSaver := mocks.NewPresenceSaver(t)
+			presenceProvider := mocks.NewPresenceProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, presenceTracker, presenceSaver, presenceProvider, ctx)
+			//Настройки приватности всех пользователей - настройки по умолчанию
+			privacyProvider.On("GetPrivacySettings", ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:              log,
+				presenceTracker:  presenceTracker,
+				presenceSaver:    presenceSaver,
+				presenceProvider: presenceProvider,
+				privacyProvider:  privacyProvider,
+				presenceTTL:      TestPresenceTTL,
+				maxBatchSize:     TestPresenceBatchSize,
+			}
+			got, err := u.FlushPresence(ctx)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.FlushPresence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.FlushPresence, "+tt.wantErr.Error(), fmt.Sprintf("users.FlushPresence() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// PrivacySaver предоставляет метод изменения настроек приватности пользователя.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=PrivacySaver
+type PrivacySaver interface {
+	// UpdatePrivacySettings изменяет указанные в fields настройки приватности пользователя и возвращает настройки после изменения.
+	// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+	UpdatePrivacySettings(ctx context.Context, userId int64, settings models.PrivacySettings, fields []string) (models.PrivacySettings, error)
+}
+
+// PrivacyProvider предоставляет метод получения настроек приватности пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=PrivacyProvider
+type PrivacyProvider interface {
+	// GetPrivacySettings получает сохраненные настройки приватности пользователей.
+	// Пользователи, которые не изменяли настройки, в результат не попадают.
+	GetPrivacySettings(ctx context.Context, userIds []int64) (map[int64]models.PrivacySettings, error)
+}
+
+// GetPrivacySettings реализует логику получения настроек приватности пользователя.
+// Если пользователь не изменял настройки, возвращает настройки по умолчанию.
+func (u *Users) GetPrivacySettings(ctx context.Context, userId int64) (models.PrivacySettings, error) {
+	const op = "users.GetPrivacySettings"
+
+	settings, err := u.privacyProvider.GetPrivacySettings(ctx, []int64{userId})
+	if err != nil {
+		u.log.Errorf("error getting privacy settings. %w", err)
+		return models.PrivacySettings{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if s, ok := settings[userId]; ok {
+		return s, nil
+	}
+
+	return models.DefaultPrivacySettings(), nil
+}
+
+// UpdatePrivacySettings реализует логику изменения настроек приватности пользователя.
+// Изменяются только настройки, перечисленные в fields (models.PrivacyField*), возвращаются настройки после изменения.
+// Если поле неизвестно или значение недопустимо, возвращает *users.ValidationError.
+// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+func (u *Users) UpdatePrivacySettings(ctx context.Context, userId int64, update models.PrivacySettings, fields []string) (models.PrivacySettings, error) {
+	const op = "users.UpdatePrivacySettings"
+
+	fields, err := validatePrivacySettings(update, fields)
+	if err != nil {
+		u.log.Warnf("invalid privacy settings. %w", err)
+		return models.PrivacySettings{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	settings, err := u.privacySaver.UpdatePrivacySettings(ctx, userId, update, fields)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return models.PrivacySettings{}, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+
+		u.log.Errorf("error updating privacy settings. %w", err)
+		return models.PrivacySettings{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return settings, nil
+}
+
+// validatePrivacySettings проверяет указанные поля настроек приватности и возвращает их без повторов.
+// Если поля или их значения недопустимы, возвращает *ValidationError.
+func validatePrivacySettings(settings models.PrivacySettings, fields []string) ([]string, error) {
+	var violations []FieldViolation
+	unique := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		unique = append(unique, field)
+
+		var visibility models.Visibility
+		switch field {
+		case models.PrivacyFieldLastSeen:
+			visibility = settings.LastSeen
+		case models.PrivacyFieldAvatar:
+			visibility = settings.Avatar
+		case models.PrivacyFieldSearch:
+			visibility = settings.Search
+		default:
+			violations = append(violations, FieldViolation{
+				Field:       "update_mask",
+				Description: fmt.Sprintf("unknown field %q", field),
+			})
+			continue
+		}
+
+		switch visibility {
+		case models.VisibilityEveryone, models.VisibilityContacts, models.VisibilityNobody:
+		default:
+			violations = append(violations, FieldViolation{Field: field, Description: "must be everyone, contacts or nobody"})
+		}
+	}
+
+	if err := newValidationError(violations...); err != nil {
+		return nil, err
+	}
+
+	return unique, nil
+}
+
+// privacyView - настройки приватности владельцев данных с точки зрения пользователя viewerId.
+// viewerId, равный 0, означает анонимный запрос, которому доступны только данные, открытые всем.
+type privacyView struct {
+	viewerId int64
+	settings map[int64]models.PrivacySettings
+	contacts map[int64]bool
+}
+
+// privacyView получает настройки приватности владельцев ownerIds и,
+// если какие-то данные открыты только контактам, проверяет, кто из владельцев состоит в контактах viewerId.
+func (u *Users) privacyView(ctx context.Context, viewerId int64, ownerIds []int64) (privacyView, error) {
+	view := privacyView{viewerId: viewerId}
+
+	others := make([]int64, 0, len(ownerIds))
+	for _, id := range ownerIds {
+		if id != viewerId {
+			others = append(others, id)
+		}
+	}
+
+	if len(others) == 0 {
+		return view, nil
+	}
+
+	settings, err := u.privacyProvider.GetPrivacySettings(ctx, others)
+	if err != nil {
+		u.log.Errorf("error getting privacy settings. %w", err)
+		return privacyView{}, err
+	}
+	view.settings = settings
+
+	if viewerId == 0 {
+		return view, nil
+	}
+
+	var restricted []int64
+	for _, id := range others {
+		s, ok := settings[id]
+		if ok && (s.LastSeen == models.VisibilityContacts || s.Avatar == models.VisibilityContacts) {
+			restricted = append(restricted, id)
+		}
+	}
+
+	if len(restricted) == 0 {
+		return view, nil
+	}
+
+	contactIds, err := u.contactProvider.FilterContacts(ctx, viewerId, restricted)
+	if err != nil {
+		u.log.Errorf("error filtering contacts. %w", err)
+		return privacyView{}, err
+	}
+
+	view.contacts = make(map[int64]bool, len(contactIds))
+	for _, id := range contactIds {
+		view.contacts[id] = true
+	}
+
+	return view, nil
+}
+
+// allows сообщает, доступны ли viewerId данные владельца ownerId с видимостью visibility.
+func (v privacyView) allows(ownerId int64, visibility models.Visibility) bool {
+	if ownerId == v.viewerId {
+		return true
+	}
+
+	switch visibility {
+	case models.VisibilityEveryone:
+		return true
+	case models.VisibilityContacts:
+		return v.contacts[ownerId]
+	default:
+		return false
+	}
+}
+
+// of возвращает настройки приватности владельца ownerId.
+func (v privacyView) of(ownerId int64) models.PrivacySettings {
+	if s, ok := v.settings[ownerId]; ok {
+		return s
+	}
+
+	return models.DefaultPrivacySettings()
+}
+
+// applyProfile скрывает данные профиля, недоступные viewerId.
+func (v privacyView) applyProfile(user *models.User) {
+	if !v.allows(user.Id, v.of(user.Id).Avatar) {
+		user.AvatarURL = ""
+	}
+}
+
+// applyPresence скрывает присутствие в сети и время последней активности, если они недоступны viewerId.
+func (v privacyView) applyPresence(p *models.Presence) {
+	if !v.allows(p.UserId, v.of(p.UserId).LastSeen) {
+		p.Online = false
+		p.LastSeenAt = time.Time{}
+	}
+}
@@ -0,0 +1,353 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestAvatarURL       = "https://example.com/avatar.png"
+	TestPrivacySettings = models.PrivacySettings{
+		LastSeen: models.VisibilityContacts,
+		Avatar:   models.VisibilityEveryone,
+		Search:   models.VisibilityNobody,
+	}
+)
+
+func TestUsers_GetPrivacySettings(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		privacySaver *mocks.PrivacySaver,
+		privacyProvider *mocks.PrivacyProvider,
+		contactProvider *mocks.ContactProvider,
+		ctx context.Context,
+	)
+
+	tests := []struct {
+		name         string
+		mockBehavior mockBehavior
+		want         models.PrivacySettings
+		wantErr      error
+	}{
+		{
+			name: "Stored",
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestUserId}).
+					Return(map[int64]models.PrivacySettings{TestUserId: TestPrivacySettings}, nil)
+			},
+			want: TestPrivacySettings,
+		},
+		{
+			name: "Default",
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestUserId}).
+					Return(map[int64]models.PrivacySettings{}, nil)
+			},
+			want: models.DefaultPrivacySettings(),
+		},
+		{
+			name: "Error",
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestUserId}).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			privacySaver := mocks.NewPrivacySaver(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+			contactProvider := mocks.NewContactProvider(t)
+
+			tt.mockBehavior(log, privacySaver, privacyProvider, contactProvider, ctx)
+			u := &Users{
+				log:             log,
+				privacySaver:    privacySaver,
+				privacyProvider: privacyProvider,
+				contactProvider: contactProvider,
+			}
+			got, err := u.GetPrivacySettings(ctx, TestUserId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.GetPrivacySettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.GetPrivacySettings, "+tt.wantErr.Error(), fmt.Sprintf("users.GetPrivacySettings() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_UpdatePrivacySettings(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		privacySaver *mocks.PrivacySaver,
+		privacyProvider *mocks.PrivacyProvider,
+		contactProvider *mocks.ContactProvider,
+		ctx context.Context,
+	)
+
+	updated := TestPrivacySettings
+	updated.Avatar = models.VisibilityNobody
+
+	type args struct {
+		settings models.PrivacySettings
+		fields   []string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.PrivacySettings
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				settings: models.PrivacySettings{Avatar: models.VisibilityNobody, Search: models.VisibilityEveryone},
+				fields:   []string{models.PrivacyFieldAvatar},
+			},
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacySaver.On("UpdatePrivacySettings", ctx, TestUserId, models.PrivacySettings{Avatar: models.VisibilityNobody, Search: models.VisibilityEveryone}, []string{models.PrivacyFieldAvatar}).
+					Return(updated, nil)
+			},
+			want: updated,
+		},
+		{
+			name: "DuplicateFields",
+			args: args{
+				settings: models.PrivacySettings{Avatar: models.VisibilityNobody},
+				fields:   []string{models.PrivacyFieldAvatar, models.PrivacyFieldAvatar},
+			},
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacySaver.On("UpdatePrivacySettings", ctx, TestUserId, models.PrivacySettings{Avatar: models.VisibilityNobody}, []string{models.PrivacyFieldAvatar}).
+					Return(updated, nil)
+			},
+			want: updated,
+		},
+		{
+			name: "InvalidSettings",
+			args: args{
+				settings: models.PrivacySettings{LastSeen: "friends"},
+				fields:   []string{models.PrivacyFieldLastSeen, "phone"},
+			},
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{
+				{Field: models.PrivacyFieldLastSeen, Description: "must be everyone, contacts or nobody"},
+				{Field: "update_mask", Description: `unknown field "phone"`},
+			}},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				settings: models.PrivacySettings{Avatar: models.VisibilityNobody},
+				fields:   []string{models.PrivacyFieldAvatar},
+			},
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacySaver.On("UpdatePrivacySettings", ctx, TestUserId, models.PrivacySettings{Avatar: models.VisibilityNobody}, []string{models.PrivacyFieldAvatar}).
+					Return(models.PrivacySettings{}, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				settings: models.PrivacySettings{Avatar: models.VisibilityNobody},
+				fields:   []string{models.PrivacyFieldAvatar},
+			},
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacySaver.On("UpdatePrivacySettings", ctx, TestUserId, models.PrivacySettings{Avatar: models.VisibilityNobody}, []string{models.PrivacyFieldAvatar}).
+					Return(models.PrivacySettings{}, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			privacySaver := mocks.NewPrivacySaver(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+			contactProvider := mocks.NewContactProvider(t)
+
+			tt.mockBehavior(log, privacySaver, privacyProvider, contactProvider, ctx)
+			u := &Users{
+				log:             log,
+				privacySaver:    privacySaver,
+				privacyProvider: privacyProvider,
+				contactProvider: contactProvider,
+			}
+			got, err := u.UpdatePrivacySettings(ctx, TestUserId, tt.args.settings, tt.args.fields)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.UpdatePrivacySettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.UpdatePrivacySettings, "+tt.wantErr.Error(), fmt.Sprintf("users.UpdatePrivacySettings() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_privacyView(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		privacySaver *mocks.PrivacySaver,
+		privacyProvider *mocks.PrivacyProvider,
+		contactProvider *mocks.ContactProvider,
+		ctx context.Context,
+	)
+
+	user := models.User{Id: TestContactId, Profile: models.Profile{AvatarURL: TestAvatarURL}}
+	presence := models.Presence{UserId: TestContactId, Online: true, LastSeenAt: TestLastSeen}
+	hiddenUser := models.User{Id: TestContactId}
+	hiddenPresence := models.Presence{UserId: TestContactId}
+
+	contactsOnly := models.PrivacySettings{
+		LastSeen: models.VisibilityContacts,
+		Avatar:   models.VisibilityContacts,
+		Search:   models.VisibilityEveryone,
+	}
+	nobody := models.PrivacySettings{
+		LastSeen: models.VisibilityNobody,
+		Avatar:   models.VisibilityNobody,
+		Search:   models.VisibilityNobody,
+	}
+
+	tests := []struct {
+		name         string
+		viewerId     int64
+		mockBehavior mockBehavior
+		wantUser     models.User
+		wantPresence models.Presence
+		wantErr      bool
+	}{
+		{
+			name:     "Default",
+			viewerId: TestUserId,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestContactId}).Return(map[int64]models.PrivacySettings{}, nil)
+			},
+			wantUser:     user,
+			wantPresence: presence,
+		},
+		{
+			name:     "Contact",
+			viewerId: TestUserId,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestContactId}).
+					Return(map[int64]models.PrivacySettings{TestContactId: contactsOnly}, nil)
+				contactProvider.On("FilterContacts", ctx, TestUserId, []int64{TestContactId}).Return([]int64{TestContactId}, nil)
+			},
+			wantUser:     user,
+			wantPresence: presence,
+		},
+		{
+			name:     "NotContact",
+			viewerId: TestUserId,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestContactId}).
+					Return(map[int64]models.PrivacySettings{TestContactId: contactsOnly}, nil)
+				contactProvider.On("FilterContacts", ctx, TestUserId, []int64{TestContactId}).Return(nil, nil)
+			},
+			wantUser:     hiddenUser,
+			wantPresence: hiddenPresence,
+		},
+		{
+			name:     "Anonymous",
+			viewerId: 0,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestContactId}).
+					Return(map[int64]models.PrivacySettings{TestContactId: contactsOnly}, nil)
+			},
+			wantUser:     hiddenUser,
+			wantPresence: hiddenPresence,
+		},
+		{
+			name:     "Nobody",
+			viewerId: TestUserId,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestContactId}).
+					Return(map[int64]models.PrivacySettings{TestContactId: nobody}, nil)
+			},
+			wantUser:     hiddenUser,
+			wantPresence: hiddenPresence,
+		},
+		{
+			name:     "Self",
+			viewerId: TestContactId,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+			},
+			wantUser:     user,
+			wantPresence: presence,
+		},
+		{
+			name:     "SettingsError",
+			viewerId: TestUserId,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestContactId}).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: true,
+		},
+		{
+			name:     "ContactsError",
+			viewerId: TestUserId,
+			mockBehavior: func(log *loggermocks.Logger, privacySaver *mocks.PrivacySaver, privacyProvider *mocks.PrivacyProvider, contactProvider *mocks.ContactProvider, ctx context.Context) {
+				privacyProvider.On("GetPrivacySettings", ctx, []int64{TestContactId}).
+					Return(map[int64]models.PrivacySettings{TestContactId: contactsOnly}, nil)
+				contactProvider.On("FilterContacts", ctx, TestUserId, []int64{TestContactId}).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			log := loggermocks.NewLogger(t)
+			privacySaver := mocks.NewPrivacySaver(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+			contactProvider := mocks.NewContactProvider(t)
+
+			tt.mockBehavior(log, privacySaver, privacyProvider, contactProvider, ctx)
+			u := &Users{
+				log:             log,
+				privacySaver:    privacySaver,
+				privacyProvider: privacyProvider,
+				contactProvider: contactProvider,
+			}
+
+			view, err := u.privacyView(ctx, tt.viewerId, []int64{TestContactId})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("users.privacyView() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			gotUser, gotPresence := user, presence
+			view.applyProfile(&gotUser)
+			view.applyPresence(&gotPresence)
+			assert.Equal(t, tt.wantUser, gotUser)
+			assert.Equal(t, tt.wantPresence, gotPresence)
+		})
+	}
+}
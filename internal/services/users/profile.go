@@ -0,0 +1,248 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"golang.org/x/text/language"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxAvatarURLLength   = 2048
+)
+
+// GetUserById реализует логику получения активного пользователя по id пользователем viewerId.
+// Данные, скрытые настройками приватности от viewerId, не заполняются.
+// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+func (u *Users) GetUserById(ctx context.Context, viewerId, userId int64) (models.User, error) {
+	const op = "users.GetUserById"
+
+	user, err := u.userProvider.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return models.User{}, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if !user.IsActive {
+		u.log.Warnf("user is inactive. user_id=%d", userId)
+		return models.User{}, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+	}
+
+	view, err := u.privacyView(ctx, viewerId, []int64{user.Id})
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+	view.applyProfile(&user)
+
+	return user, nil
+}
+
+// GetUserByUsername реализует логику получения активного пользователя по username пользователем viewerId.
+// Поиск выполняется по канонической форме username. Данные, скрытые настройками приватности от viewerId, не заполняются.
+// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+func (u *Users) GetUserByUsername(ctx context.Context, viewerId int64, username string) (models.User, error) {
+	const op = "users.GetUserByUsername"
+
+	user, err := u.userProvider.GetUser(ctx, u.usernamePolicy.Canonical(username))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return models.User{}, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	view, err := u.privacyView(ctx, viewerId, []int64{user.Id})
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+	view.applyProfile(&user)
+
+	return user, nil
+}
+
+// GetUsers реализует логику получения активных пользователей по списку id одним запросом пользователем viewerId.
+// Возвращает найденных пользователей по их id и id, для которых активный пользователь не найден.
+// Повторяющиеся id учитываются один раз. Данные, скрытые настройками приватности от viewerId, не заполняются.
+// Если различных id больше, чем допускает конфигурация, возвращает users.ErrBatchTooLarge.
+func (u *Users) GetUsers(ctx context.Context, viewerId int64, userIds []int64) (map[int64]models.User, []int64, error) {
+	const op = "users.GetUsers"
+
+	unique := uniqueIds(userIds)
+	if len(unique) > u.maxBatchSize {
+		u.log.Warnf("batch too large. size=%d", len(unique))
+		return nil, nil, fmt.Errorf("%s, %w", op, ErrBatchTooLarge)
+	}
+
+	found := make(map[int64]models.User, len(unique))
+	if len(unique) == 0 {
+		return found, nil, nil
+	}
+
+	users, err := u.userProvider.GetUsersByIds(ctx, unique)
+	if err != nil {
+		u.log.Errorf("error getting users. %w", err)
+		return nil, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	ids := make([]int64, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.Id)
+	}
+
+	view, err := u.privacyView(ctx, viewerId, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	for _, user := range users {
+		view.applyProfile(&user)
+		found[user.Id] = user
+	}
+
+	var missing []int64
+	for _, id := range unique {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	return found, missing, nil
+}
+
+// UpdateProfile реализует логику изменения профиля пользователя.
+// Изменяются только поля, перечисленные в fields (models.ProfileField*), пустое значение очищает поле.
+// Если поле неизвестно или значение не соответствует правилам, возвращает *users.ValidationError.
+// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+func (u *Users) UpdateProfile(ctx context.Context, userId int64, profile models.Profile, fields []string) (models.User, error) {
+	const op = "users.UpdateProfile"
+
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	fields, err := validateProfile(profile, fields)
+	if err != nil {
+		u.log.Warnf("invalid profile. %w", err)
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	user, err := u.userSaver.UpdateProfile(ctx, userId, profile, fields)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return models.User{}, fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+
+		u.log.Errorf("error updating profile. %w", err)
+		return models.User{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return user, nil
+}
+
+// validateProfile проверяет указанные поля профиля и возвращает их без повторов.
+// Если поля или их значения недопустимы, возвращает *ValidationError.
+func validateProfile(profile models.Profile, fields []string) ([]string, error) {
+	var violations []FieldViolation
+	unique := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		unique = append(unique, field)
+
+		switch field {
+		case models.ProfileFieldDisplayName:
+			if utf8.RuneCountInString(profile.DisplayName) > maxDisplayNameLength {
+				violations = append(violations, FieldViolation{
+					Field:       field,
+					Description: fmt.Sprintf("must be at most %d characters long", maxDisplayNameLength),
+				})
+			}
+		case models.ProfileFieldBio:
+			if utf8.RuneCountInString(profile.Bio) > maxBioLength {
+				violations = append(violations, FieldViolation{
+					Field:       field,
+					Description: fmt.Sprintf("must be at most %d characters long", maxBioLength),
+				})
+			}
+		case models.ProfileFieldAvatarURL:
+			if desc := validateAvatarURL(profile.AvatarURL); desc != "" {
+				violations = append(violations, FieldViolation{Field: field, Description: desc})
+			}
+		case models.ProfileFieldLocale:
+			if profile.Locale == "" {
+				continue
+			}
+			if _, err := language.Parse(profile.Locale); err != nil {
+				violations = append(violations, FieldViolation{Field: field, Description: "must be a valid BCP 47 language tag"})
+			}
+		case models.ProfileFieldTimeZone:
+			if profile.TimeZone == "" {
+				continue
+			}
+			//Local зависит от окружения сервера и не является именем из базы часовых поясов
+			if _, err := time.LoadLocation(profile.TimeZone); err != nil || profile.TimeZone == "Local" {
+				violations = append(violations, FieldViolation{Field: field, Description: "must be a valid IANA time zone"})
+			}
+		default:
+			violations = append(violations, FieldViolation{
+				Field:       "update_mask",
+				Description: fmt.Sprintf("unknown field %q", field),
+			})
+		}
+	}
+
+	if err := newValidationError(violations...); err != nil {
+		return nil, err
+	}
+
+	return unique, nil
+}
+
+// validateAvatarURL возвращает описание нарушения для ссылки на аватар или пустую строку, если ссылка допустима.
+func validateAvatarURL(avatarURL string) string {
+	if avatarURL == "" {
+		return ""
+	}
+	if len(avatarURL) > maxAvatarURLLength {
+		return fmt.Sprintf("must be at most %d characters long", maxAvatarURLLength)
+	}
+
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "must be an absolute http or https URL"
+	}
+
+	return ""
+}
+
+// uniqueIds возвращает id без повторов в порядке первого появления.
+func uniqueIds(ids []int64) []int64 {
+	unique := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
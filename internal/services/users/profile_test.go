@@ -0,0 +1,547 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestProfile = models.Profile{
+		DisplayName: "User One",
+		Bio:         "bio",
+		AvatarURL:   "https://example.com/avatar.png",
+		Locale:      "en-US",
+		TimeZone:    "Europe/Moscow",
+	}
+	TestProfileFields = []string{models.ProfileFieldDisplayName, models.ProfileFieldBio}
+)
+
+func TestUsers_GetUserById(t *testing.T) {
+	type mockBehavior func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context, userId int64)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context, userId int64) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+			},
+			want: TestUser,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context, userId int64) {
+				userProvider.On("GetUserById", ctx, userId).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "UserInactive",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context, userId int64) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestInactiveUser, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context, userId int64) {
+				userProvider.On("GetUserById", ctx, userId).Return(EmptyUser, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+
+			tt.mockBehavior(log, userProvider, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:          log,
+				userProvider: userProvider,
+			}
+			got, err := u.GetUserById(tt.args.ctx, TestUserId, tt.args.userId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.GetUserById() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.GetUserById, "+tt.wantErr.Error(), fmt.Sprintf("users.GetUserById() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_GetUserByUsername(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		usernamePolicy *mocks.UsernamePolicy,
+		ctx context.Context,
+		username string,
+	)
+
+	type args struct {
+		ctx      context.Context
+		username string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+			},
+			want: TestUser,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(EmptyUser, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+
+			tt.mockBehavior(log, userProvider, usernamePolicy, tt.args.ctx, tt.args.username)
+			u := &Users{
+				log:            log,
+				userProvider:   userProvider,
+				usernamePolicy: usernamePolicy,
+			}
+			got, err := u.GetUserByUsername(tt.args.ctx, TestUserId, tt.args.username)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.GetUserByUsername() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.GetUserByUsername, "+tt.wantErr.Error(), fmt.Sprintf("users.GetUserByUsername() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUsers_UpdateProfile(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userSaver *mocks.UserSaver,
+		ctx context.Context,
+		userId int64,
+		profile models.Profile,
+		fields []string,
+	)
+
+	type args struct {
+		ctx     context.Context
+		userId  int64
+		profile models.Profile
+		fields  []string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.User
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestProfile,
+				fields:  TestProfileFields,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				ctx context.Context,
+				userId int64,
+				profile models.Profile,
+				fields []string,
+			) {
+				userSaver.On("UpdateProfile", ctx, userId, profile, fields).Return(TestUser, nil)
+			},
+			want: TestUser,
+		},
+		{
+			name: "DuplicateFields",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: models.Profile{DisplayName: "  User One  "},
+				fields:  []string{models.ProfileFieldDisplayName, models.ProfileFieldDisplayName},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				ctx context.Context,
+				userId int64,
+				profile models.Profile,
+				fields []string,
+			) {
+				userSaver.On(
+					"UpdateProfile",
+					ctx,
+					userId,
+					models.Profile{DisplayName: "User One"},
+					[]string{models.ProfileFieldDisplayName},
+				).Return(TestUser, nil)
+			},
+			want: TestUser,
+		},
+		{
+			name: "InvalidProfile",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: models.Profile{Locale: "not a locale"},
+				fields:  []string{models.ProfileFieldLocale, "pass_hash"},
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				ctx context.Context,
+				userId int64,
+				profile models.Profile,
+				fields []string,
+			) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{
+				{Field: models.ProfileFieldLocale, Description: "must be a valid BCP 47 language tag"},
+				{Field: "update_mask", Description: `unknown field "pass_hash"`},
+			}},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestProfile,
+				fields:  TestProfileFields,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				ctx context.Context,
+				userId int64,
+				profile models.Profile,
+				fields []string,
+			) {
+				userSaver.On("UpdateProfile", ctx, userId, profile, fields).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userId:  TestUserId,
+				profile: TestProfile,
+				fields:  TestProfileFields,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				ctx context.Context,
+				userId int64,
+				profile models.Profile,
+				fields []string,
+			) {
+				userSaver.On("UpdateProfile", ctx, userId, profile, fields).Return(EmptyUser, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userSaver := mocks.NewUserSaver(t)
+
+			tt.mockBehavior(log, userSaver, tt.args.ctx, tt.args.userId, tt.args.profile, tt.args.fields)
+			u := &Users{
+				log:       log,
+				userSaver: userSaver,
+			}
+			got, err := u.UpdateProfile(tt.args.ctx, tt.args.userId, tt.args.profile, tt.args.fields)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.UpdateProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.UpdateProfile, "+tt.wantErr.Error(), fmt.Sprintf("users.UpdateProfile() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile models.Profile
+		fields  []string
+		wantErr bool
+	}{
+		{
+			name:    "Valid",
+			profile: TestProfile,
+			fields: []string{
+				models.ProfileFieldDisplayName,
+				models.ProfileFieldBio,
+				models.ProfileFieldAvatarURL,
+				models.ProfileFieldLocale,
+				models.ProfileFieldTimeZone,
+			},
+		},
+		{
+			name: "EmptyValues",
+			fields: []string{
+				models.ProfileFieldDisplayName,
+				models.ProfileFieldBio,
+				models.ProfileFieldAvatarURL,
+				models.ProfileFieldLocale,
+				models.ProfileFieldTimeZone,
+			},
+		},
+		{
+			name:    "LongDisplayName",
+			profile: models.Profile{DisplayName: strings.Repeat("я", maxDisplayNameLength+1)},
+			fields:  []string{models.ProfileFieldDisplayName},
+			wantErr: true,
+		},
+		{
+			name:    "LongBio",
+			profile: models.Profile{Bio: strings.Repeat("a", maxBioLength+1)},
+			fields:  []string{models.ProfileFieldBio},
+			wantErr: true,
+		},
+		{
+			name:    "AvatarURLScheme",
+			profile: models.Profile{AvatarURL: "javascript:alert(1)"},
+			fields:  []string{models.ProfileFieldAvatarURL},
+			wantErr: true,
+		},
+		{
+			name:    "AvatarURLRelative",
+			profile: models.Profile{AvatarURL: "/avatar.png"},
+			fields:  []string{models.ProfileFieldAvatarURL},
+			wantErr: true,
+		},
+		{
+			name:    "LocalTimeZone",
+			profile: models.Profile{TimeZone: "Local"},
+			fields:  []string{models.ProfileFieldTimeZone},
+			wantErr: true,
+		},
+		{
+			name:    "UnknownTimeZone",
+			profile: models.Profile{TimeZone: "Mars/Olympus"},
+			fields:  []string{models.ProfileFieldTimeZone},
+			wantErr: true,
+		},
+		{
+			name:    "InvalidValueNotInFields",
+			profile: models.Profile{TimeZone: "Mars/Olympus"},
+			fields:  []string{models.ProfileFieldBio},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateProfile(tt.profile, tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUsers_GetUsers(t *testing.T) {
+	secondUser := TestUser
+	secondUser.Id = 2
+
+	type mockBehavior func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context)
+
+	type args struct {
+		ctx     context.Context
+		userIds []int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		maxBatchSize int
+		mockBehavior mockBehavior
+		want         map[int64]models.User
+		wantMissing  []int64
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId, 2, 3, TestUserId},
+			},
+			maxBatchSize: 3,
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context) {
+				userProvider.On("GetUsersByIds", ctx, []int64{TestUserId, 2, 3}).Return([]models.User{secondUser, TestUser}, nil)
+			},
+			want:        map[int64]models.User{TestUserId: TestUser, 2: secondUser},
+			wantMissing: []int64{3},
+		},
+		{
+			name: "Empty",
+			args: args{
+				ctx: context.Background(),
+			},
+			maxBatchSize: 3,
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context) {},
+			want:         map[int64]models.User{},
+		},
+		{
+			name: "BatchTooLarge",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId, 2, 3},
+			},
+			maxBatchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context) {
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrBatchTooLarge,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:     context.Background(),
+				userIds: []int64{TestUserId},
+			},
+			maxBatchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, userProvider *mocks.UserProvider, ctx context.Context) {
+				userProvider.On("GetUsersByIds", ctx, []int64{TestUserId}).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, userProvider, tt.args.ctx)
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				userProvider:    userProvider,
+				privacyProvider: privacyProvider,
+				maxBatchSize:    tt.maxBatchSize,
+			}
+			got, missing, err := u.GetUsers(tt.args.ctx, TestUserId, tt.args.userIds)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.GetUsers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.GetUsers, "+tt.wantErr.Error(), fmt.Sprintf("users.GetUsers() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantMissing, missing)
+		})
+	}
+}
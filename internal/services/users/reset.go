@@ -0,0 +1,167 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+const (
+	// resetCodeAlphabet - алфавит кодов сброса пароля без визуально похожих символов.
+	resetCodeAlphabet = "ABCDEFGHJKMNPQRSTVWXYZ23456789"
+	resetCodeLength   = 10
+)
+
+// ResetCodeSaver предоставляет методы хранения и погашения кодов сброса пароля.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=ResetCodeSaver
+type ResetCodeSaver interface {
+	// SaveResetCode сохраняет хэш одноразового кода сброса пароля пользователя.
+	// Если у пользователя уже maxActive действующих кодов, возвращает ошибку repository.ErrTooManyResetCodes.
+	SaveResetCode(ctx context.Context, userId int64, codeHash []byte, expiresAt time.Time, maxActive int) error
+
+	// ResetPassword атомарно погашает код сброса и устанавливает новый хэш пароля его владельцу, возвращает id пользователя.
+	// Если код не найден, уже использован или истек, возвращает ошибку repository.ErrResetCodeNotFound.
+	ResetPassword(ctx context.Context, codeHash []byte, passHash []byte) (int64, error)
+}
+
+// ResetCodeProvider предоставляет методы получения данных по коду сброса пароля.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=ResetCodeProvider
+type ResetCodeProvider interface {
+	// GetResetCodeOwner получает активного пользователя, которому принадлежит действующий код сброса пароля.
+	// Если код не найден, уже использован или истек, возвращает ошибку repository.ErrResetCodeNotFound.
+	GetResetCodeOwner(ctx context.Context, codeHash []byte) (models.User, error)
+}
+
+// Notifier - интерфейс доставки уведомлений пользователю.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=Notifier
+type Notifier interface {
+	// SendPasswordResetCode отправляет пользователю код сброса пароля.
+	SendPasswordResetCode(ctx context.Context, user models.User, code string) error
+}
+
+// RequestPasswordReset реализует логику выдачи одноразового кода сброса пароля.
+// Чтобы не раскрывать существование аккаунтов, для неизвестного username ошибка не возвращается.
+// Запросы учитываются по username и IP клиента, при превышении лимита возвращает *users.LockoutError.
+// Если у пользователя уже слишком много действующих кодов, новый код не выдается, ошибка не возвращается.
+// Если состояние блокировок получить не удалось, возвращает users.ErrLoginUnavailable.
+func (u *Users) RequestPasswordReset(ctx context.Context, username, ip string) error {
+	const op = "users.RequestPasswordReset"
+
+	canonical := u.usernamePolicy.Canonical(username)
+	//Запрос учитывается до поиска пользователя, чтобы лимит срабатывал одинаково для существующих и неизвестных username
+	attempts := u.resetAttempts(canonical, ip)
+	if err := u.checkLockout(ctx, attempts); err != nil {
+		u.log.Warnf("password reset rejected. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+	u.registerAttempts(ctx, u.resetThrottle, attempts)
+
+	user, err := u.userProvider.GetUser(ctx, canonical)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("password reset requested for unknown user. %w", err)
+			return nil
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	code, err := generateResetCode()
+	if err != nil {
+		u.log.Errorf("error generating reset code. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.resetCodeSaver.SaveResetCode(ctx, user.Id, hashToken(code), time.Now().Add(u.resetCodeTTL), u.maxResetCodes); err != nil {
+		if errors.Is(err, repository.ErrTooManyResetCodes) {
+			u.log.Warnf("too many active reset codes. %w", err)
+			return nil
+		}
+
+		u.log.Errorf("error saving reset code. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.notifier.SendPasswordResetCode(ctx, user, code); err != nil {
+		u.log.Errorf("error sending reset code. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ResetPassword реализует логику установки нового пароля по коду сброса.
+// После сброса все сессии пользователя завершаются.
+// Если код не найден, уже использован или истек, возвращает users.ErrInvalidResetCode.
+// Если новый пароль не соответствует политике паролей, возвращает *users.ValidationError.
+func (u *Users) ResetPassword(ctx context.Context, code, newPassword string) error {
+	const op = "users.ResetPassword"
+
+	codeHash := hashToken(code)
+
+	user, err := u.resetCodeProvider.GetResetCodeOwner(ctx, codeHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrResetCodeNotFound) {
+			u.log.Warnf("reset code not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrInvalidResetCode)
+		}
+
+		u.log.Errorf("error getting reset code owner. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.validatePassword("new_password", user.Username, newPassword); err != nil {
+		u.log.Warnf("password policy violation. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	passHash, err := u.crypter.GenerateFromPassword([]byte(newPassword))
+	if err != nil {
+		u.log.Errorf("error generating hash from password. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if _, err = u.resetCodeSaver.ResetPassword(ctx, codeHash, passHash); err != nil {
+		if errors.Is(err, repository.ErrResetCodeNotFound) {
+			u.log.Warnf("reset code not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrInvalidResetCode)
+		}
+
+		u.log.Errorf("error resetting password. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// generateResetCode возвращает случайный код сброса пароля.
+func generateResetCode() (string, error) {
+	return randomCode(resetCodeLength)
+}
+
+// randomCode возвращает случайный код указанной длины из алфавита resetCodeAlphabet.
+func randomCode(length int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(resetCodeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		code[i] = resetCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,470 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestResetCode           = "ABCDE23456"
+	TestResetCodeTTL        = 15 * time.Minute
+	TestMaxResetCodes       = 3
+	TestResetUserAttemptKey = "reset:" + TestUserAttemptKey
+	TestResetIPAttemptKey   = "reset:" + TestIPAttemptKey
+	TestResetThrottle       = ThrottleParams{
+		Window:           time.Hour,
+		UserFreeAttempts: 3,
+		IPFreeAttempts:   10,
+		BaseLockout:      time.Minute,
+		MaxLockout:       time.Hour,
+	}
+)
+
+func TestUsers_RequestPasswordReset(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		resetCodeSaver *mocks.ResetCodeSaver,
+		notifier *mocks.Notifier,
+		usernamePolicy *mocks.UsernamePolicy,
+		attemptTracker *mocks.AttemptTracker,
+		ctx context.Context,
+		username string,
+	)
+
+	type args struct {
+		ctx      context.Context
+		username string
+		ip       string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				notifier *mocks.Notifier,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestResetUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestResetIPAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetIPAttemptKey, mock.Anything).Return(1, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				resetCodeSaver.On("SaveResetCode", ctx, TestUserId, mock.Anything, mock.Anything, TestMaxResetCodes).Return(nil)
+				notifier.On("SendPasswordResetCode", ctx, TestUser, mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				notifier *mocks.Notifier,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestResetUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestResetIPAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetIPAttemptKey, mock.Anything).Return(1, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Lockout",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				notifier *mocks.Notifier,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestResetUserAttemptKey).Return(TestLockedUntil, nil)
+				attemptTracker.On("GetLockout", ctx, TestResetIPAttemptKey).Return(time.Time{}, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &LockoutError{Until: TestLockedUntil},
+		},
+		{
+			name: "LimitExceeded",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				notifier *mocks.Notifier,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestResetUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestResetIPAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetUserAttemptKey, mock.Anything).Return(TestResetThrottle.UserFreeAttempts+1, nil)
+				attemptTracker.On("SetLockout", ctx, TestResetUserAttemptKey, mock.Anything).Return(nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetIPAttemptKey, mock.Anything).Return(1, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				resetCodeSaver.On("SaveResetCode", ctx, TestUserId, mock.Anything, mock.Anything, TestMaxResetCodes).Return(nil)
+				notifier.On("SendPasswordResetCode", ctx, TestUser, mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "TooManyResetCodes",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				notifier *mocks.Notifier,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestResetUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestResetIPAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetIPAttemptKey, mock.Anything).Return(1, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				resetCodeSaver.On("SaveResetCode", ctx, TestUserId, mock.Anything, mock.Anything, TestMaxResetCodes).Return(repository.ErrTooManyResetCodes)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "ErrorSaveResetCode",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				notifier *mocks.Notifier,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestResetUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestResetIPAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetIPAttemptKey, mock.Anything).Return(1, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				resetCodeSaver.On("SaveResetCode", ctx, TestUserId, mock.Anything, mock.Anything, TestMaxResetCodes).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "ErrorNotify",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				notifier *mocks.Notifier,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestResetUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestResetIPAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestResetIPAttemptKey, mock.Anything).Return(1, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				resetCodeSaver.On("SaveResetCode", ctx, TestUserId, mock.Anything, mock.Anything, TestMaxResetCodes).Return(nil)
+				notifier.On("SendPasswordResetCode", ctx, TestUser, mock.Anything).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			resetCodeSaver := mocks.NewResetCodeSaver(t)
+			notifier := mocks.NewNotifier(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+			attemptTracker := mocks.NewAttemptTracker(t)
+
+			tt.mockBehavior(log, userProvider, resetCodeSaver, notifier, usernamePolicy, attemptTracker, tt.args.ctx, tt.args.username)
+			u := &Users{
+				log:            log,
+				userProvider:   userProvider,
+				resetCodeSaver: resetCodeSaver,
+				notifier:       notifier,
+				usernamePolicy: usernamePolicy,
+				attemptTracker: attemptTracker,
+				resetCodeTTL:   TestResetCodeTTL,
+				maxResetCodes:  TestMaxResetCodes,
+				resetThrottle:  TestResetThrottle,
+			}
+			err := u.RequestPasswordReset(tt.args.ctx, tt.args.username, tt.args.ip)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.RequestPasswordReset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.RequestPasswordReset, "+tt.wantErr.Error(), fmt.Sprintf("users.RequestPasswordReset() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_ResetPassword(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		crypter *mocks.Crypter,
+		resetCodeSaver *mocks.ResetCodeSaver,
+		resetCodeProvider *mocks.ResetCodeProvider,
+		passwordPolicy *mocks.PasswordPolicy,
+		ctx context.Context,
+		code string,
+		newPassword string,
+	)
+
+	type args struct {
+		ctx         context.Context
+		code        string
+		newPassword string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:         context.Background(),
+				code:        TestResetCode,
+				newPassword: TestNewPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				crypter *mocks.Crypter,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				resetCodeProvider *mocks.ResetCodeProvider,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				code string,
+				newPassword string,
+			) {
+				resetCodeProvider.On("GetResetCodeOwner", ctx, hashToken(code)).Return(TestUser, nil)
+				passwordPolicy.On("Validate", TestUsername, newPassword).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(newPassword)).Return(TestNewPassHash, nil)
+				resetCodeSaver.On("ResetPassword", ctx, hashToken(code), TestNewPassHash).Return(TestUserId, nil)
+			},
+		},
+		{
+			name: "InvalidResetCode",
+			args: args{
+				ctx:         context.Background(),
+				code:        TestResetCode,
+				newPassword: TestNewPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				crypter *mocks.Crypter,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				resetCodeProvider *mocks.ResetCodeProvider,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				code string,
+				newPassword string,
+			) {
+				resetCodeProvider.On("GetResetCodeOwner", ctx, hashToken(code)).Return(TestUser, nil)
+				passwordPolicy.On("Validate", TestUsername, newPassword).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(newPassword)).Return(TestNewPassHash, nil)
+				resetCodeSaver.On("ResetPassword", ctx, hashToken(code), TestNewPassHash).Return(EmptyUserId, repository.ErrResetCodeNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidResetCode,
+		},
+		{
+			name: "ResetCodeOwnerNotFound",
+			args: args{
+				ctx:         context.Background(),
+				code:        TestResetCode,
+				newPassword: TestNewPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				crypter *mocks.Crypter,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				resetCodeProvider *mocks.ResetCodeProvider,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				code string,
+				newPassword string,
+			) {
+				resetCodeProvider.On("GetResetCodeOwner", ctx, hashToken(code)).Return(EmptyUser, repository.ErrResetCodeNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidResetCode,
+		},
+		{
+			name: "PasswordPolicyViolation",
+			args: args{
+				ctx:         context.Background(),
+				code:        TestResetCode,
+				newPassword: TestWeakPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				crypter *mocks.Crypter,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				resetCodeProvider *mocks.ResetCodeProvider,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				code string,
+				newPassword string,
+			) {
+				resetCodeProvider.On("GetResetCodeOwner", ctx, hashToken(code)).Return(TestUser, nil)
+				passwordPolicy.On("Validate", TestUsername, newPassword).Return(TestPolicyViolations)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{{Field: "new_password", Description: TestPolicyViolations[0]}}},
+		},
+		{
+			name: "ErrorGenerateHash",
+			args: args{
+				ctx:         context.Background(),
+				code:        TestResetCode,
+				newPassword: TestNewPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				crypter *mocks.Crypter,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				resetCodeProvider *mocks.ResetCodeProvider,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				code string,
+				newPassword string,
+			) {
+				resetCodeProvider.On("GetResetCodeOwner", ctx, hashToken(code)).Return(TestUser, nil)
+				passwordPolicy.On("Validate", TestUsername, newPassword).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(newPassword)).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:         context.Background(),
+				code:        TestResetCode,
+				newPassword: TestNewPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				crypter *mocks.Crypter,
+				resetCodeSaver *mocks.ResetCodeSaver,
+				resetCodeProvider *mocks.ResetCodeProvider,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				code string,
+				newPassword string,
+			) {
+				resetCodeProvider.On("GetResetCodeOwner", ctx, hashToken(code)).Return(TestUser, nil)
+				passwordPolicy.On("Validate", TestUsername, newPassword).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(newPassword)).Return(TestNewPassHash, nil)
+				resetCodeSaver.On("ResetPassword", ctx, hashToken(code), TestNewPassHash).Return(EmptyUserId, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			crypter := mocks.NewCrypter(t)
+			resetCodeSaver := mocks.NewResetCodeSaver(t)
+			resetCodeProvider := mocks.NewResetCodeProvider(t)
+			passwordPolicy := mocks.NewPasswordPolicy(t)
+
+			tt.mockBehavior(log, crypter, resetCodeSaver, resetCodeProvider, passwordPolicy, tt.args.ctx, tt.args.code, tt.args.newPassword)
+			u := &Users{
+				log:               log,
+				crypter:           crypter,
+				resetCodeSaver:    resetCodeSaver,
+				resetCodeProvider: resetCodeProvider,
+				passwordPolicy:    passwordPolicy,
+			}
+			err := u.ResetPassword(tt.args.ctx, tt.args.code, tt.args.newPassword)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ResetPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ResetPassword, "+tt.wantErr.Error(), fmt.Sprintf("users.ResetPassword() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+)
+
+const maxSearchQueryLength = 64
+
+// searchPageToken - содержимое токена страницы поиска.
+// Токен привязан к запросу, чтобы его нельзя было применить к результатам другого поиска.
+type searchPageToken struct {
+	Query       string  `json:"q"`
+	PrefixMatch bool    `json:"p"`
+	Score       float32 `json:"s"`
+	UserId      int64   `json:"id"`
+}
+
+// SearchUsers реализует логику поиска активных пользователей по username пользователем viewerId.
+// Сначала возвращаются пользователи, чей username начинается с query, затем похожие на него.
+// Пользователи, скрывшие себя из поиска от viewerId, не находятся, а скрытые от него данные не заполняются.
+// pageToken - токен страницы из предыдущего ответа, пустой для первой страницы.
+// Возвращает найденных пользователей и токен следующей страницы, пустой, если результатов больше нет.
+// Если query пустой или слишком длинный, возвращает *users.ValidationError.
+// Если токен страницы недействителен, возвращает users.ErrInvalidPageToken.
+func (u *Users) SearchUsers(ctx context.Context, viewerId int64, query, pageToken string, limit int) ([]models.User, string, error) {
+	const op = "users.SearchUsers"
+
+	canonical := u.usernamePolicy.Canonical(query)
+	if err := validateSearchQuery(canonical); err != nil {
+		u.log.Warnf("invalid search query. %w", err)
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	var after *models.UserSearchCursor
+	if pageToken != "" {
+		var token searchPageToken
+		if err := decodePageToken(pageToken, &token); err != nil {
+			u.log.Warnf("invalid page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+		if token.Query != canonical {
+			u.log.Warnf("page token belongs to another query. query=%s", canonical)
+			return nil, "", fmt.Errorf("%s, %w", op, ErrInvalidPageToken)
+		}
+
+		after = &models.UserSearchCursor{PrefixMatch: token.PrefixMatch, Score: token.Score, UserId: token.UserId}
+	}
+
+	limit = u.pageSize.pageSize(limit)
+	//Лишний результат запрашивается, чтобы узнать, есть ли следующая страница
+	hits, err := u.userProvider.SearchUsers(ctx, viewerId, canonical, after, limit+1)
+	if err != nil {
+		u.log.Errorf("error searching users. %w", err)
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	var nextPageToken string
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[limit-1].Cursor
+		nextPageToken, err = encodePageToken(searchPageToken{
+			Query:       canonical,
+			PrefixMatch: last.PrefixMatch,
+			Score:       last.Score,
+			UserId:      last.UserId,
+		})
+		if err != nil {
+			u.log.Errorf("error encoding page token. %w", err)
+			return nil, "", fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	ids := make([]int64, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.User.Id)
+	}
+
+	view, err := u.privacyView(ctx, viewerId, ids)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, %w", op, err)
+	}
+
+	users := make([]models.User, 0, len(hits))
+	for _, hit := range hits {
+		view.applyProfile(&hit.User)
+		users = append(users, hit.User)
+	}
+
+	return users, nextPageToken, nil
+}
+
+// validateSearchQuery проверяет каноническую форму поискового запроса.
+// Если запрос недопустим, возвращает *ValidationError с нарушением для поля query.
+func validateSearchQuery(query string) error {
+	if query == "" {
+		return newValidationError(FieldViolation{Field: "query", Description: "must not be empty"})
+	}
+
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		return newValidationError(FieldViolation{
+			Field:       "query",
+			Description: fmt.Sprintf("must be at most %d characters long", maxSearchQueryLength),
+		})
+	}
+
+	return nil
+}
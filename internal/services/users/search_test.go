@@ -0,0 +1,236 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestSearchQuery    = "User"
+	TestSearchPageSize = PageSizeParams{Default: 2, Max: 3}
+)
+
+// newTestSearchPageToken возвращает токен страницы поиска, следующей после cursor.
+func newTestSearchPageToken(query string, cursor models.UserSearchCursor) string {
+	token, err := encodePageToken(searchPageToken{
+		Query:       query,
+		PrefixMatch: cursor.PrefixMatch,
+		Score:       cursor.Score,
+		UserId:      cursor.UserId,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return token
+}
+
+func TestUsers_SearchUsers(t *testing.T) {
+	hits := make([]models.UserSearchHit, 0, 3)
+	for i := int64(1); i <= 3; i++ {
+		user := TestUser
+		user.Id = i
+		hits = append(hits, models.UserSearchHit{
+			User:   user,
+			Cursor: models.UserSearchCursor{PrefixMatch: true, Score: 1 / float32(i), UserId: i},
+		})
+	}
+	after := hits[1].Cursor
+
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		usernamePolicy *mocks.UsernamePolicy,
+		ctx context.Context,
+		query string,
+	)
+
+	type args struct {
+		ctx       context.Context
+		query     string
+		pageToken string
+		limit     int
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.User
+		wantToken    string
+		wantErr      error
+	}{
+		{
+			name: "FirstPage",
+			args: args{
+				ctx:   context.Background(),
+				query: TestSearchQuery,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				query string,
+			) {
+				usernamePolicy.On("Canonical", query).Return(TestCanonical)
+				userProvider.On("SearchUsers", ctx, TestUserId, TestCanonical, (*models.UserSearchCursor)(nil), 3).Return(hits, nil)
+			},
+			want:      []models.User{hits[0].User, hits[1].User},
+			wantToken: newTestSearchPageToken(TestCanonical, after),
+		},
+		{
+			name: "LastPage",
+			args: args{
+				ctx:       context.Background(),
+				query:     TestSearchQuery,
+				pageToken: newTestSearchPageToken(TestCanonical, after),
+				limit:     10,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				query string,
+			) {
+				usernamePolicy.On("Canonical", query).Return(TestCanonical)
+				userProvider.On("SearchUsers", ctx, TestUserId, TestCanonical, &after, 4).Return(hits[2:], nil)
+			},
+			want: []models.User{hits[2].User},
+		},
+		{
+			name: "EmptyQuery",
+			args: args{
+				ctx:   context.Background(),
+				query: " ",
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				query string,
+			) {
+				usernamePolicy.On("Canonical", query).Return("")
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{{Field: "query", Description: "must not be empty"}}},
+		},
+		{
+			name: "LongQuery",
+			args: args{
+				ctx:   context.Background(),
+				query: strings.Repeat("a", maxSearchQueryLength+1),
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				query string,
+			) {
+				usernamePolicy.On("Canonical", query).Return(query)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{{
+				Field:       "query",
+				Description: fmt.Sprintf("must be at most %d characters long", maxSearchQueryLength),
+			}}},
+		},
+		{
+			name: "MalformedPageToken",
+			args: args{
+				ctx:       context.Background(),
+				query:     TestSearchQuery,
+				pageToken: "!",
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				query string,
+			) {
+				usernamePolicy.On("Canonical", query).Return(TestCanonical)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name: "PageTokenOfAnotherQuery",
+			args: args{
+				ctx:       context.Background(),
+				query:     TestSearchQuery,
+				pageToken: newTestSearchPageToken("other", after),
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				query string,
+			) {
+				usernamePolicy.On("Canonical", query).Return(TestCanonical)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidPageToken,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:   context.Background(),
+				query: TestSearchQuery,
+				limit: 100,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				query string,
+			) {
+				usernamePolicy.On("Canonical", query).Return(TestCanonical)
+				userProvider.On("SearchUsers", ctx, TestUserId, TestCanonical, (*models.UserSearchCursor)(nil), 4).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+			privacyProvider := mocks.NewPrivacyProvider(t)
+
+			tt.mockBehavior(log, userProvider, usernamePolicy, tt.args.ctx, tt.args.query)
+			privacyProvider.On("GetPrivacySettings", tt.args.ctx, mock.Anything).Return(map[int64]models.PrivacySettings{}, nil).Maybe()
+			u := &Users{
+				log:             log,
+				userProvider:    userProvider,
+				usernamePolicy:  usernamePolicy,
+				privacyProvider: privacyProvider,
+				pageSize:        TestSearchPageSize,
+			}
+			got, token, err := u.SearchUsers(tt.args.ctx, TestUserId, tt.args.query, tt.args.pageToken, tt.args.limit)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.SearchUsers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.SearchUsers, "+tt.wantErr.Error(), fmt.Sprintf("users.SearchUsers() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
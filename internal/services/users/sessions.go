@@ -0,0 +1,144 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+)
+
+// SessionSaver предоставляет методы создания, обновления и удаления сессий пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=SessionSaver
+type SessionSaver interface {
+	// SaveSession создает новую сессию пользователя вместе с ее refresh токеном, возвращает id сессии.
+	SaveSession(ctx context.Context, session models.Session, tokenHash []byte, expiresAt time.Time) (int64, error)
+
+	// RotateRefreshToken атомарно заменяет refresh токен новым, возвращает id владельца токена и id сессии.
+	// Если токен не найден, истек или принадлежит неактивному пользователю, возвращает ошибку repository.ErrTokenNotFound.
+	RotateRefreshToken(ctx context.Context, oldHash []byte, newHash []byte, expiresAt time.Time) (int64, int64, error)
+
+	// TouchSession обновляет время последней активности сессии, если оно раньше touchBefore.
+	// Если сессия не найдена, возвращает ошибку repository.ErrSessionNotFound.
+	TouchSession(ctx context.Context, sessionId int64, touchBefore time.Time) error
+
+	// DeleteSession удаляет сессию пользователя вместе с ее refresh токенами.
+	// Если сессия не найдена, возвращает ошибку repository.ErrSessionNotFound.
+	DeleteSession(ctx context.Context, userId int64, sessionId int64) error
+
+	// DeleteSessions удаляет все сессии пользователя вместе с их refresh токенами.
+	DeleteSessions(ctx context.Context, userId int64) error
+
+	// DeleteExpiredSessions удаляет не более limit сессий, у которых не осталось неистекших refresh токенов.
+	// Возвращает число удаленных сессий.
+	DeleteExpiredSessions(ctx context.Context, limit int) (int, error)
+}
+
+// SessionProvider предоставляет методы получения сессий пользователей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=SessionProvider
+type SessionProvider interface {
+	// ListSessions возвращает действующие сессии пользователя, начиная с последней активной.
+	ListSessions(ctx context.Context, userId int64) ([]models.Session, error)
+}
+
+// Logout реализует логику завершения одной сессии пользователя.
+// Если сессия не найдена, возвращает users.ErrSessionNotFound.
+func (u *Users) Logout(ctx context.Context, userId, sessionId int64) error {
+	const op = "users.Logout"
+
+	if err := u.sessionSaver.DeleteSession(ctx, userId, sessionId); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			u.log.Warnf("session not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrSessionNotFound)
+		}
+
+		u.log.Errorf("error deleting session. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// LogoutAll реализует логику завершения всех сессий пользователя.
+func (u *Users) LogoutAll(ctx context.Context, userId int64) error {
+	const op = "users.LogoutAll"
+
+	if err := u.sessionSaver.DeleteSessions(ctx, userId); err != nil {
+		u.log.Errorf("error deleting sessions. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ListSessions возвращает активные сессии пользователя.
+func (u *Users) ListSessions(ctx context.Context, userId int64) ([]models.Session, error) {
+	const op = "users.ListSessions"
+
+	sessions, err := u.sessionProvider.ListSessions(ctx, userId)
+	if err != nil {
+		u.log.Errorf("error listing sessions. %w", err)
+		return nil, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// PurgeExpiredSessions удаляет сессии с истекшими refresh токенами пачками по batchSize.
+// Возвращает число удаленных сессий.
+func (u *Users) PurgeExpiredSessions(ctx context.Context, batchSize int) (int, error) {
+	const op = "users.PurgeExpiredSessions"
+
+	var purged int
+	for {
+		deleted, err := u.sessionSaver.DeleteExpiredSessions(ctx, batchSize)
+		if err != nil {
+			u.log.Errorf("error deleting expired sessions. %w", err)
+			return purged, fmt.Errorf("%s, %w", op, err)
+		}
+
+		purged += deleted
+		if deleted < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// startSession создает сессию пользователя и выпускает для нее пару токенов.
+func (u *Users) startSession(ctx context.Context, userId int64, client models.ClientInfo) (models.Tokens, error) {
+	refreshToken, refreshExpiresAt, err := u.tokenManager.NewRefreshToken()
+	if err != nil {
+		u.log.Errorf("error generating refresh token. %w", err)
+		return models.Tokens{}, err
+	}
+
+	session := models.Session{
+		UserId:     userId,
+		ClientInfo: client,
+	}
+
+	sessionId, err := u.sessionSaver.SaveSession(ctx, session, hashToken(refreshToken), refreshExpiresAt)
+	if err != nil {
+		u.log.Errorf("error saving session. %w", err)
+		return models.Tokens{}, err
+	}
+
+	accessToken, accessExpiresAt, err := u.tokenManager.NewAccessToken(userId, sessionId)
+	if err != nil {
+		u.log.Errorf("error generating access token. %w", err)
+		return models.Tokens{}, err
+	}
+
+	return models.Tokens{
+		UserId:           userId,
+		SessionId:        sessionId,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
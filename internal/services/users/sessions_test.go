@@ -0,0 +1,335 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/al3ksus/messengerusers/internal/domain/models"
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUsers_Logout(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		sessionSaver *mocks.SessionSaver,
+		ctx context.Context,
+		userId int64,
+		sessionId int64,
+	)
+
+	type args struct {
+		ctx       context.Context
+		userId    int64
+		sessionId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				sessionId: TestSessionId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				userId int64,
+				sessionId int64,
+			) {
+				sessionSaver.On("DeleteSession", ctx, userId, sessionId).Return(nil)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				sessionId: TestSessionId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				userId int64,
+				sessionId int64,
+			) {
+				sessionSaver.On("DeleteSession", ctx, userId, sessionId).Return(repository.ErrSessionNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrSessionNotFound,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:       context.Background(),
+				userId:    TestUserId,
+				sessionId: TestSessionId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				userId int64,
+				sessionId int64,
+			) {
+				sessionSaver.On("DeleteSession", ctx, userId, sessionId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			sessionSaver := mocks.NewSessionSaver(t)
+
+			tt.mockBehavior(log, sessionSaver, tt.args.ctx, tt.args.userId, tt.args.sessionId)
+			u := &Users{
+				log:          log,
+				sessionSaver: sessionSaver,
+			}
+			err := u.Logout(tt.args.ctx, tt.args.userId, tt.args.sessionId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.Logout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.Logout, "+tt.wantErr.Error(), fmt.Sprintf("users.Logout() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_LogoutAll(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		sessionSaver *mocks.SessionSaver,
+		ctx context.Context,
+		userId int64,
+	)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				userId int64,
+			) {
+				sessionSaver.On("DeleteSessions", ctx, userId).Return(nil)
+			},
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				userId int64,
+			) {
+				sessionSaver.On("DeleteSessions", ctx, userId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			sessionSaver := mocks.NewSessionSaver(t)
+
+			tt.mockBehavior(log, sessionSaver, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:          log,
+				sessionSaver: sessionSaver,
+			}
+			err := u.LogoutAll(tt.args.ctx, tt.args.userId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.LogoutAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.LogoutAll, "+tt.wantErr.Error(), fmt.Sprintf("users.LogoutAll() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_ListSessions(t *testing.T) {
+	sessions := []models.Session{
+		{
+			Id:         TestSessionId,
+			UserId:     TestUserId,
+			ClientInfo: TestClient,
+			CreatedAt:  time.Unix(1700000000, 0),
+			LastSeenAt: time.Unix(1700000100, 0),
+		},
+	}
+
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		sessionProvider *mocks.SessionProvider,
+		ctx context.Context,
+		userId int64,
+	)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         []models.Session
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				sessionProvider *mocks.SessionProvider,
+				ctx context.Context,
+				userId int64,
+			) {
+				sessionProvider.On("ListSessions", ctx, userId).Return(sessions, nil)
+			},
+			want: sessions,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				sessionProvider *mocks.SessionProvider,
+				ctx context.Context,
+				userId int64,
+			) {
+				sessionProvider.On("ListSessions", ctx, userId).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			sessionProvider := mocks.NewSessionProvider(t)
+
+			tt.mockBehavior(log, sessionProvider, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:             log,
+				sessionProvider: sessionProvider,
+			}
+			got, err := u.ListSessions(tt.args.ctx, tt.args.userId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ListSessions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(
+					t,
+					err,
+					"users.ListSessions, "+tt.wantErr.Error(),
+					fmt.Sprintf("users.ListSessions() error = %v, wantErr %v", err, tt.wantErr),
+				)
+			}
+			assert.Equal(t, got, tt.want, fmt.Sprintf("users.ListSessions() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestUsers_PurgeExpiredSessions(t *testing.T) {
+	type mockBehavior func(log *loggermocks.Logger, sessionSaver *mocks.SessionSaver)
+
+	tests := []struct {
+		name         string
+		batchSize    int
+		mockBehavior mockBehavior
+		want         int
+		wantErr      error
+	}{
+		{
+			name:      "OK",
+			batchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, sessionSaver *mocks.SessionSaver) {
+				sessionSaver.On("DeleteExpiredSessions", mock.Anything, 2).Return(2, nil).Once()
+				sessionSaver.On("DeleteExpiredSessions", mock.Anything, 2).Return(1, nil).Once()
+			},
+			want: 3,
+		},
+		{
+			name:      "Error",
+			batchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, sessionSaver *mocks.SessionSaver) {
+				sessionSaver.On("DeleteExpiredSessions", mock.Anything, 2).Return(0, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			sessionSaver := mocks.NewSessionSaver(t)
+
+			tt.mockBehavior(log, sessionSaver)
+			u := &Users{
+				log:          log,
+				sessionSaver: sessionSaver,
+			}
+			got, err := u.PurgeExpiredSessions(context.Background(), tt.batchSize)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.PurgeExpiredSessions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(
+					t,
+					err,
+					"users.PurgeExpiredSessions, "+tt.wantErr.Error(),
+					fmt.Sprintf("users.PurgeExpiredSessions() error = %v, wantErr %v", err, tt.wantErr),
+				)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
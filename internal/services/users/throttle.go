@@ -0,0 +1,196 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AttemptTracker предоставляет методы учета неудачных попыток входа по ключам (username, IP).
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=AttemptTracker
+type AttemptTracker interface {
+	// GetLockout возвращает время окончания блокировки входа по ключу.
+	// Если ключ не заблокирован, возвращает нулевое время.
+	GetLockout(ctx context.Context, key string) (time.Time, error)
+
+	// RegisterFailedAttempt регистрирует неудачную попытку входа по ключу и возвращает количество неудачных попыток подряд.
+	// Если предыдущая неудачная попытка была раньше windowStart, счетчик начинается заново.
+	RegisterFailedAttempt(ctx context.Context, key string, windowStart time.Time) (int, error)
+
+	// SetLockout блокирует вход по ключу до указанного времени.
+	SetLockout(ctx context.Context, key string, until time.Time) error
+
+	// ResetFailedAttempts сбрасывает счетчик неудачных попыток и блокировку по ключу.
+	ResetFailedAttempts(ctx context.Context, key string) error
+
+	// DeleteExpiredAttempts удаляет не более limit ключей, последняя неудачная попытка по которым была раньше before
+	// и блокировка по которым истекла. Возвращает число удаленных ключей.
+	DeleteExpiredAttempts(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// ThrottleParams - параметры защиты от перебора: входа по паролю или запросов сброса пароля.
+// После FreeAttempts попыток в пределах Window вход блокируется на BaseLockout,
+// каждая следующая неудачная попытка удваивает блокировку, но не более чем до MaxLockout.
+type ThrottleParams struct {
+	Window           time.Duration
+	UserFreeAttempts int
+	IPFreeAttempts   int
+	BaseLockout      time.Duration
+	MaxLockout       time.Duration
+}
+
+// LockoutError - ошибка временной блокировки входа, содержит время окончания блокировки.
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrTooManyAttempts, e.Until.Format(time.RFC3339))
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// loginAttempt - ключ учета неудачных попыток входа и количество попыток, допустимых без блокировки.
+type loginAttempt struct {
+	key          string
+	freeAttempts int
+}
+
+// ClearLockout реализует логику снятия блокировки входа по username и (или) IP адресу.
+// Пустые значения пропускаются.
+func (u *Users) ClearLockout(ctx context.Context, username, ip string) error {
+	const op = "users.ClearLockout"
+
+	canonical := ""
+	if username != "" {
+		canonical = u.usernamePolicy.Canonical(username)
+	}
+
+	for _, attempt := range u.loginAttempts(canonical, ip) {
+		if err := u.attemptTracker.ResetFailedAttempts(ctx, attempt.key); err != nil {
+			u.log.Errorf("error resetting failed attempts. %w", err)
+			return fmt.Errorf("%s, %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// PurgeExpiredAttempts удаляет пачками по batchSize ключи учета попыток, окно и блокировка которых истекли.
+// Окно берется наибольшее из окон входа и запросов сброса пароля. Возвращает число удаленных ключей.
+func (u *Users) PurgeExpiredAttempts(ctx context.Context, batchSize int) (int, error) {
+	const op = "users.PurgeExpiredAttempts"
+
+	before := time.Now().Add(-max(u.throttle.Window, u.resetThrottle.Window))
+
+	var purged int
+	for {
+		deleted, err := u.attemptTracker.DeleteExpiredAttempts(ctx, before, batchSize)
+		if err != nil {
+			u.log.Errorf("error deleting expired attempts. %w", err)
+			return purged, fmt.Errorf("%s, %w", op, err)
+		}
+
+		purged += deleted
+		if deleted < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// loginAttempts возвращает ключи учета неудачных попыток для канонической формы username и IP адреса клиента.
+// Пустые значения пропускаются.
+func (u *Users) loginAttempts(canonical, ip string) []loginAttempt {
+	return throttleAttempts("", u.throttle, canonical, ip)
+}
+
+// resetAttempts возвращает ключи учета запросов сброса пароля для канонической формы username и IP адреса клиента.
+// Ключи отличаются от ключей входа, поэтому запросы сброса не блокируют вход и наоборот.
+func (u *Users) resetAttempts(canonical, ip string) []loginAttempt {
+	return throttleAttempts("reset:", u.resetThrottle, canonical, ip)
+}
+
+// throttleAttempts возвращает ключи учета попыток с префиксом prefix и лимитами throttle.
+// Пустые значения пропускаются.
+func throttleAttempts(prefix string, throttle ThrottleParams, canonical, ip string) []loginAttempt {
+	var attempts []loginAttempt
+	if canonical != "" {
+		attempts = append(attempts, loginAttempt{
+			key:          prefix + userAttemptKey(canonical),
+			freeAttempts: throttle.UserFreeAttempts,
+		})
+	}
+
+	if ip != "" {
+		attempts = append(attempts, loginAttempt{
+			key:          prefix + "ip:" + ip,
+			freeAttempts: throttle.IPFreeAttempts,
+		})
+	}
+
+	return attempts
+}
+
+// userAttemptKey возвращает ключ учета неудачных попыток для канонической формы username.
+func userAttemptKey(canonical string) string {
+	return "user:" + canonical
+}
+
+// checkLockout проверяет, что вход не заблокирован ни по одному из ключей.
+// Если вход заблокирован, возвращает *users.LockoutError с наиболее поздним временем окончания блокировки.
+// Если состояние блокировок получить не удалось, возвращает users.ErrLoginUnavailable.
+func (u *Users) checkLockout(ctx context.Context, attempts []loginAttempt) error {
+	var until time.Time
+	for _, attempt := range attempts {
+		lockedUntil, err := u.attemptTracker.GetLockout(ctx, attempt.key)
+		if err != nil {
+			u.log.Errorf("error getting lockout. %w", err)
+			return ErrLoginUnavailable
+		}
+
+		if lockedUntil.After(until) {
+			until = lockedUntil
+		}
+	}
+
+	if until.After(time.Now()) {
+		return &LockoutError{Until: until}
+	}
+
+	return nil
+}
+
+// registerAttempts учитывает попытку по всем ключам и блокирует ключи, превысившие лимит throttle.
+// Ошибки только логируются, так как не должны менять ответ на попытку.
+func (u *Users) registerAttempts(ctx context.Context, throttle ThrottleParams, attempts []loginAttempt) {
+	now := time.Now()
+	for _, attempt := range attempts {
+		failures, err := u.attemptTracker.RegisterFailedAttempt(ctx, attempt.key, now.Add(-throttle.Window))
+		if err != nil {
+			u.log.Errorf("error registering failed attempt. %w", err)
+			continue
+		}
+
+		if failures <= attempt.freeAttempts {
+			continue
+		}
+
+		until := now.Add(throttle.lockoutDuration(failures - attempt.freeAttempts))
+		if err = u.attemptTracker.SetLockout(ctx, attempt.key, until); err != nil {
+			u.log.Errorf("error setting lockout. %w", err)
+		}
+	}
+}
+
+// lockoutDuration возвращает длительность блокировки после excess попыток сверх допустимых.
+func (p ThrottleParams) lockoutDuration(excess int) time.Duration {
+	d := p.BaseLockout
+	for i := 1; i < excess && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+
+	return min(d, p.MaxLockout)
+}
@@ -0,0 +1,207 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUsers_ClearLockout(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		usernamePolicy *mocks.UsernamePolicy,
+		attemptTracker *mocks.AttemptTracker,
+		ctx context.Context,
+		username string,
+		ip string,
+	)
+
+	type args struct {
+		ctx      context.Context
+		username string
+		ip       string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				ip:       TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+				ip string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestIPAttemptKey).Return(nil)
+			},
+		},
+		{
+			name: "OnlyIP",
+			args: args{
+				ctx: context.Background(),
+				ip:  TestClient.IP,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+				ip string,
+			) {
+				attemptTracker.On("ResetFailedAttempts", ctx, TestIPAttemptKey).Return(nil)
+			},
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				ctx context.Context,
+				username string,
+				ip string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+			attemptTracker := mocks.NewAttemptTracker(t)
+
+			tt.mockBehavior(log, usernamePolicy, attemptTracker, tt.args.ctx, tt.args.username, tt.args.ip)
+			u := &Users{
+				log:            log,
+				usernamePolicy: usernamePolicy,
+				attemptTracker: attemptTracker,
+				throttle:       TestThrottle,
+			}
+			err := u.ClearLockout(tt.args.ctx, tt.args.username, tt.args.ip)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ClearLockout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ClearLockout, "+tt.wantErr.Error(), fmt.Sprintf("users.ClearLockout() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
+
+func TestUsers_PurgeExpiredAttempts(t *testing.T) {
+	type mockBehavior func(log *loggermocks.Logger, attemptTracker *mocks.AttemptTracker)
+
+	// before проверяет, что ключи удаляются по наибольшему из окон входа и запросов сброса пароля.
+	before := mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= TestResetThrottle.Window && time.Since(before) < TestResetThrottle.Window+time.Minute
+	})
+
+	tests := []struct {
+		name         string
+		batchSize    int
+		mockBehavior mockBehavior
+		want         int
+		wantErr      error
+	}{
+		{
+			name:      "OK",
+			batchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, attemptTracker *mocks.AttemptTracker) {
+				attemptTracker.On("DeleteExpiredAttempts", mock.Anything, before, 2).Return(2, nil).Once()
+				attemptTracker.On("DeleteExpiredAttempts", mock.Anything, before, 2).Return(0, nil).Once()
+			},
+			want: 2,
+		},
+		{
+			name:      "Error",
+			batchSize: 2,
+			mockBehavior: func(log *loggermocks.Logger, attemptTracker *mocks.AttemptTracker) {
+				attemptTracker.On("DeleteExpiredAttempts", mock.Anything, before, 2).Return(0, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			attemptTracker := mocks.NewAttemptTracker(t)
+
+			tt.mockBehavior(log, attemptTracker)
+			u := &Users{
+				log:            log,
+				attemptTracker: attemptTracker,
+				throttle:       TestThrottle,
+				resetThrottle:  TestResetThrottle,
+			}
+			got, err := u.PurgeExpiredAttempts(context.Background(), tt.batchSize)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.PurgeExpiredAttempts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.PurgeExpiredAttempts, "+tt.wantErr.Error(), fmt.Sprintf("users.PurgeExpiredAttempts() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestThrottleParams_lockoutDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		excess int
+		want   time.Duration
+	}{
+		{
+			name:   "First",
+			excess: 1,
+			want:   TestThrottle.BaseLockout,
+		},
+		{
+			name:   "Doubled",
+			excess: 3,
+			want:   4 * TestThrottle.BaseLockout,
+		},
+		{
+			name:   "Capped",
+			excess: 100,
+			want:   TestThrottle.MaxLockout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TestThrottle.lockoutDuration(tt.excess)
+			assert.Equal(t, tt.want, got, fmt.Sprintf("ThrottleParams.lockoutDuration() = %v, want %v", got, tt.want))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	loggermocks "github.com/al3ksus/messengerusers/internal/logger/mocks"
+	repository "github.com/al3ksus/messengerusers/internal/repositories"
+	"github.com/al3ksus/messengerusers/internal/services/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	TestNewUsername         = "user2"
+	TestNewCanonical        = "user2"
+	TestUsernameReservation = 30 * 24 * time.Hour
+)
+
+func TestUsers_ChangeUsername(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userSaver *mocks.UserSaver,
+		usernamePolicy *mocks.UsernamePolicy,
+		ctx context.Context,
+		userId int64,
+	)
+
+	// reservedUntil проверяет, что прежний username зарезервирован на время из конфигурации.
+	reservedUntil := mock.MatchedBy(func(t time.Time) bool {
+		return time.Until(t) > TestUsernameReservation-time.Minute && time.Until(t) <= TestUsernameReservation
+	})
+
+	type args struct {
+		ctx      context.Context
+		userId   int64
+		username string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				username: " " + TestNewUsername + " ",
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				usernamePolicy.On("Validate", TestNewUsername).Return([]string(nil))
+				usernamePolicy.On("Canonical", TestNewUsername).Return(TestNewCanonical)
+				userSaver.On("ChangeUsername", ctx, userId, TestNewUsername, TestNewCanonical, reservedUntil).Return(nil)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				username: TestNewUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				usernamePolicy.On("Validate", TestNewUsername).Return(TestUsernameViolations)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{{Field: "username", Description: TestUsernameViolations[0]}}},
+		},
+		{
+			name: "UsernameTaken",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				username: TestNewUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				usernamePolicy.On("Validate", TestNewUsername).Return([]string(nil))
+				usernamePolicy.On("Canonical", TestNewUsername).Return(TestNewCanonical)
+				userSaver.On("ChangeUsername", ctx, userId, TestNewUsername, TestNewCanonical, reservedUntil).
+					Return(repository.ErrUserAlredyExists)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserAlreadyExists,
+		},
+		{
+			name: "UsernameReserved",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				username: TestNewUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				usernamePolicy.On("Validate", TestNewUsername).Return([]string(nil))
+				usernamePolicy.On("Canonical", TestNewUsername).Return(TestNewCanonical)
+				userSaver.On("ChangeUsername", ctx, userId, TestNewUsername, TestNewCanonical, reservedUntil).
+					Return(repository.ErrUsernameReserved)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserAlreadyExists,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				username: TestNewUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				usernamePolicy.On("Validate", TestNewUsername).Return([]string(nil))
+				usernamePolicy.On("Canonical", TestNewUsername).Return(TestNewCanonical)
+				userSaver.On("ChangeUsername", ctx, userId, TestNewUsername, TestNewCanonical, reservedUntil).
+					Return(repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "Error",
+			args: args{
+				ctx:      context.Background(),
+				userId:   TestUserId,
+				username: TestNewUsername,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				usernamePolicy.On("Validate", TestNewUsername).Return([]string(nil))
+				usernamePolicy.On("Canonical", TestNewUsername).Return(TestNewCanonical)
+				userSaver.On("ChangeUsername", ctx, userId, TestNewUsername, TestNewCanonical, reservedUntil).
+					Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userSaver := mocks.NewUserSaver(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+
+			tt.mockBehavior(log, userSaver, usernamePolicy, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				log:                 log,
+				userSaver:           userSaver,
+				usernamePolicy:      usernamePolicy,
+				usernameReservation: TestUsernameReservation,
+			}
+			err := u.ChangeUsername(tt.args.ctx, tt.args.userId, tt.args.username)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ChangeUsername() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.ChangeUsername, "+tt.wantErr.Error(), fmt.Sprintf("users.ChangeUsername() error = %v, wantErr %v", err, tt.wantErr))
+			}
+		})
+	}
+}
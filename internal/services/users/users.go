@@ -2,21 +2,64 @@ package users
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/al3ksus/messengerusers/internal/domain/models"
 	"github.com/al3ksus/messengerusers/internal/logger"
 	repository "github.com/al3ksus/messengerusers/internal/repositories"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Users - объект сервиса, реализует логику работы с данными пользователя.
 type Users struct {
-	log          logger.Logger
-	userSaver    UserSaver
-	userProvider UserProvider
-	crypter      Crypter
+	log                  logger.Logger
+	userSaver            UserSaver
+	userProvider         UserProvider
+	crypter              Crypter
+	tokenManager         TokenManager
+	sessionSaver         SessionSaver
+	sessionProvider      SessionProvider
+	resetCodeSaver       ResetCodeSaver
+	resetCodeProvider    ResetCodeProvider
+	notifier             Notifier
+	resetCodeTTL         time.Duration
+	maxResetCodes        int
+	resetThrottle        ThrottleParams
+	passwordPolicy       PasswordPolicy
+	usernamePolicy       UsernamePolicy
+	attemptTracker       AttemptTracker
+	throttle             ThrottleParams
+	secondFactorSaver    SecondFactorSaver
+	secondFactorProvider SecondFactorProvider
+	totpManager          TOTPManager
+	secretEncrypter      SecretEncrypter
+	challengeTTL         time.Duration
+	maxBatchSize         int
+	pageSize             PageSizeParams
+	usernameReservation  time.Duration
+	deletionGracePeriod  time.Duration
+	contactSaver         ContactSaver
+	contactProvider      ContactProvider
+	blockSaver           BlockSaver
+	blockProvider        BlockProvider
+	presenceTracker      PresenceTracker
+	presenceSaver        PresenceSaver
+	presenceProvider     PresenceProvider
+	presenceTTL          time.Duration
+	sessionTouchInterval time.Duration
+	privacySaver         PrivacySaver
+	privacyProvider      PrivacyProvider
+	deviceSaver          DeviceSaver
+	deviceProvider       DeviceProvider
+	eventOutbox          EventOutbox
+	eventPublisher       EventPublisher
+	changeProvider       UserChangeProvider
+	changeListener       UserChangeListener
+	changePollInterval   time.Duration
+	changeRetention      time.Duration
 }
 
 // UserSaver предоставляет методы создания новых пользователей и изменения существующих.
@@ -24,88 +67,440 @@ type Users struct {
 //go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=UserSaver
 type UserSaver interface {
 	// SaveUser сохраняет нового пользователя в базу данных, возвращает id нового пользователя.
+	// canonical - каноническая форма username, уникальная среди всех пользователей.
 	// В случае нарушения constraint unique, возвращает ошибку repository.ErrUserAlredyExists.
-	SaveUser(ctx context.Context, username string, password []byte) (int64, error)
+	// Если username зарезервирован после смены другим пользователем, возвращает ошибку repository.ErrUsernameReserved.
+	SaveUser(ctx context.Context, username, canonical string, password []byte) (int64, error)
+
+	// ChangeUsername заменяет username активного пользователя и сохраняет прежний username в истории,
+	// резервируя его за пользователем до reservedUntil. Если username не изменился, ничего не делает.
+	// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+	// Если новый username зарезервирован за другим пользователем, возвращает ошибку repository.ErrUsernameReserved.
+	// В случае нарушения constraint unique, возвращает ошибку repository.ErrUserAlredyExists.
+	ChangeUsername(ctx context.Context, userId int64, username, canonical string, reservedUntil time.Time) error
 
 	// SetInactive устанавливает пользователю с указанным id значение is_active = false.
 	// Если пользователь с таким id не найден, возвращает ошибку repository.ErrUserNotFound.
 	// Если пользователь уже неактивен, возвращает ошибку repository.ErrUserAlreadyInactive.
 	SetInactive(ctx context.Context, userId int64) error
+
+	// SetActive устанавливает пользователю с указанным id значение is_active = true.
+	// Если пользователь с таким id не найден или ожидает удаления, возвращает ошибку repository.ErrUserNotFound.
+	// Если пользователь уже активен, возвращает ошибку repository.ErrUserAlreadyActive.
+	SetActive(ctx context.Context, userId int64) error
+
+	// UpdatePasswordHash заменяет хэш пароля пользователя с указанным id.
+	// Если пользователь с таким id не найден, возвращает ошибку repository.ErrUserNotFound.
+	UpdatePasswordHash(ctx context.Context, userId int64, passHash []byte) error
+
+	// UpdateProfile изменяет поля профиля активного пользователя, перечисленные в fields, возвращает пользователя после изменения.
+	// Если пользователь не найден или неактивен, возвращает ошибку repository.ErrUserNotFound.
+	UpdateProfile(ctx context.Context, userId int64, profile models.Profile, fields []string) (models.User, error)
+
+	// ScheduleUserDeletion переводит пользователя в статус 'неактивен', завершает все его сессии
+	// и назначает окончательное удаление на deleteAfter.
+	// Если пользователь с таким id не найден, возвращает ошибку repository.ErrUserNotFound.
+	// Если удаление уже назначено, возвращает ошибку repository.ErrDeletionScheduled.
+	ScheduleUserDeletion(ctx context.Context, userId int64, deleteAfter time.Time) error
+
+	// CancelUserDeletion отменяет назначенное удаление пользователя и возвращает ему статус 'активен'.
+	// Если пользователь не найден или его удаление не назначено, возвращает ошибку repository.ErrDeletionNotScheduled.
+	CancelUserDeletion(ctx context.Context, userId int64) error
+
+	// PurgeDeletedUsers окончательно удаляет не более limit пользователей, срок удаления которых наступил,
+	// вместе со всеми зависимыми данными. Возвращает удаленных пользователей.
+	PurgeDeletedUsers(ctx context.Context, limit int) ([]models.ErasedUser, error)
 }
 
 // UserProvider предоставляет методы получения пользователей.
 //
 //go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=UserProvider
 type UserProvider interface {
-	// GetUser получает пользователя по username. Если пользователь не найден, возвращает ошибку repository.ErrUserNotFound.
-	GetUser(ctx context.Context, username string) (models.User, error)
+	// GetUser получает активного пользователя по канонической форме username.
+	// Если пользователь не найден, возвращает ошибку repository.ErrUserNotFound.
+	GetUser(ctx context.Context, canonical string) (models.User, error)
+
+	// GetUserById получает пользователя по id вне зависимости от статуса активности.
+	// Если пользователь не найден, возвращает ошибку repository.ErrUserNotFound.
+	GetUserById(ctx context.Context, userId int64) (models.User, error)
+
+	// GetUsersByIds получает активных пользователей с указанными id одним запросом.
+	// Ненайденные и неактивные пользователи в результат не попадают.
+	GetUsersByIds(ctx context.Context, userIds []int64) ([]models.User, error)
+
+	// SearchUsers ищет активных пользователей по префиксу и триграммной схожести канонической формы username.
+	// Пользователи, скрывшие себя из поиска от viewerId настройками приватности, в результат не попадают.
+	// Если after не nil, возвращает результаты, следующие после указанной позиции. Возвращает не более limit результатов.
+	SearchUsers(ctx context.Context, viewerId int64, query string, after *models.UserSearchCursor, limit int) ([]models.UserSearchHit, error)
+
+	// ListUsers получает пользователей, подходящих под условия filter, упорядоченных по id, по убыванию, если desc = true.
+	// Если afterId не равен 0, возвращает пользователей, следующих после пользователя с этим id. Возвращает не более limit пользователей.
+	ListUsers(ctx context.Context, filter models.UserFilter, desc bool, afterId int64, limit int) ([]models.User, error)
+
+	// GetUsernameHistory получает историю смены username пользователя, начиная с последней смены.
+	GetUsernameHistory(ctx context.Context, userId int64) ([]models.UsernameChange, error)
+
+	// GetErasureEvents получает записи журнала удаления пользователя в порядке их появления.
+	GetErasureEvents(ctx context.Context, userId int64) ([]models.ErasureEvent, error)
 }
 
 // Crypter - интерфейс для работы с хэшами.
 //
 //go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=Crypter
 type Crypter interface {
-	// GenerateFromPassword возвращает хэш указанного пароля с заданной стоимостью.
-	GenerateFromPassword(pass []byte, cost int) ([]byte, error)
+	// GenerateFromPassword возвращает хэш указанного пароля. Алгоритм и параметры задаются конфигурацией.
+	GenerateFromPassword(pass []byte) ([]byte, error)
 
 	// CompareHashAndPassword сравнивает захэшированный пароль с исходным.
 	CompareHashAndPassword(hashedPassword []byte, password []byte) error
+
+	// NeedsRehash сообщает, что хэш получен устаревшим алгоритмом или с устаревшими параметрами.
+	NeedsRehash(hashedPassword []byte) bool
+}
+
+// TokenManager - интерфейс для выпуска и проверки токенов.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=TokenManager
+type TokenManager interface {
+	// NewAccessToken возвращает подписанный access токен сессии пользователя и время его истечения.
+	NewAccessToken(userId int64, sessionId int64) (string, time.Time, error)
+
+	// NewRefreshToken возвращает случайный refresh токен и время его истечения.
+	NewRefreshToken() (string, time.Time, error)
+
+	// ParseAccessToken проверяет подпись и срок действия access токена, возвращает его данные.
+	ParseAccessToken(token string) (models.TokenInfo, error)
 }
 
 var (
-	ErrInvalidCredentials  = errors.New("invalid credentials")
-	ErrUserAlreadyExists   = errors.New("user already exists")
-	ErrUserAlreadyInactive = errors.New("user already inactive")
+	ErrInvalidCredentials     = errors.New("invalid credentials")
+	ErrUserAlreadyExists      = errors.New("user already exists")
+	ErrUserAlreadyInactive    = errors.New("user already inactive")
+	ErrUserAlreadyActive      = errors.New("user already active")
+	ErrInvalidToken           = errors.New("invalid token")
+	ErrSessionNotFound        = errors.New("session not found")
+	ErrSamePassword           = errors.New("new password equals current password")
+	ErrInvalidResetCode       = errors.New("invalid reset code")
+	ErrTooManyAttempts        = errors.New("too many login attempts")
+	ErrLoginUnavailable       = errors.New("login temporarily unavailable")
+	ErrSecondFactorRequired   = errors.New("second factor required")
+	ErrInvalidSecondFactor    = errors.New("invalid second factor code")
+	ErrInvalidChallenge       = errors.New("invalid challenge")
+	ErrTOTPAlreadyEnabled     = errors.New("totp already enabled")
+	ErrTOTPNotEnabled         = errors.New("totp not enabled")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrBatchTooLarge          = errors.New("too many ids in batch")
+	ErrInvalidPageToken       = errors.New("invalid page token")
+	ErrDeletionScheduled      = errors.New("deletion already scheduled")
+	ErrDeletionNotScheduled   = errors.New("deletion not scheduled")
+	ErrSelfContact            = errors.New("cannot add yourself to contacts")
+	ErrAlreadyContacts        = errors.New("already contacts")
+	ErrContactRequestExists   = errors.New("contact request already exists")
+	ErrContactRequestNotFound = errors.New("contact request not found")
+	ErrContactNotFound        = errors.New("contact not found")
+	ErrContactRequestBlocked  = errors.New("contact request blocked")
+	ErrSelfBlock              = errors.New("cannot block yourself")
+	ErrAlreadyBlocked         = errors.New("user already blocked")
+	ErrBlockNotFound          = errors.New("user not blocked")
+	ErrDeviceNotFound         = errors.New("device not found")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+	ErrCursorExpired          = errors.New("cursor expired")
 )
 
+// Deps - зависимости сервиса. Каждая зависимость передается именованным полем,
+// поэтому перепутать зависимости одного типа при создании сервиса нельзя.
+type Deps struct {
+	UserSaver            UserSaver
+	UserProvider         UserProvider
+	Crypter              Crypter
+	TokenManager         TokenManager
+	SessionSaver         SessionSaver
+	SessionProvider      SessionProvider
+	ResetCodeSaver       ResetCodeSaver
+	ResetCodeProvider    ResetCodeProvider
+	Notifier             Notifier
+	PasswordPolicy       PasswordPolicy
+	UsernamePolicy       UsernamePolicy
+	AttemptTracker       AttemptTracker
+	SecondFactorSaver    SecondFactorSaver
+	SecondFactorProvider SecondFactorProvider
+	TOTPManager          TOTPManager
+	SecretEncrypter      SecretEncrypter
+	ContactSaver         ContactSaver
+	ContactProvider      ContactProvider
+	BlockSaver           BlockSaver
+	BlockProvider        BlockProvider
+	PresenceTracker      PresenceTracker
+	PresenceSaver        PresenceSaver
+	PresenceProvider     PresenceProvider
+	PrivacySaver         PrivacySaver
+	PrivacyProvider      PrivacyProvider
+	DeviceSaver          DeviceSaver
+	DeviceProvider       DeviceProvider
+	EventOutbox          EventOutbox
+	EventPublisher       EventPublisher
+	ChangeProvider       UserChangeProvider
+	ChangeListener       UserChangeListener
+}
+
+// Params - параметры сервиса, задаваемые конфигурацией.
+type Params struct {
+	// ResetCodeTTL - время жизни кода сброса пароля.
+	ResetCodeTTL time.Duration
+	// MaxResetCodes - наибольшее число действующих кодов сброса пароля у одного пользователя.
+	MaxResetCodes int
+	// ResetThrottle - параметры ограничения запросов сброса пароля.
+	ResetThrottle ThrottleParams
+	// Throttle - параметры ограничения неудачных попыток входа.
+	Throttle ThrottleParams
+	// ChallengeTTL - время жизни токена незавершенного входа с TOTP.
+	ChallengeTTL time.Duration
+	// MaxBatchSize - наибольшее число id в одном пакетном запросе.
+	MaxBatchSize int
+	// PageSize - размер страницы постраничных методов.
+	PageSize PageSizeParams
+	// UsernameReservation - срок резервирования прежнего username за пользователем.
+	UsernameReservation time.Duration
+	// DeletionGracePeriod - срок до окончательного удаления пользователя.
+	DeletionGracePeriod time.Duration
+	// PresenceTTL - время, в течение которого пользователь считается в сети после последней активности.
+	PresenceTTL time.Duration
+	// SessionTouchInterval - наименьший интервал между обновлениями времени активности сессии при проверке токена.
+	SessionTouchInterval time.Duration
+	// ChangePollInterval - интервал перечитывания журнала изменений в потоке WatchUsers без уведомлений.
+	ChangePollInterval time.Duration
+	// ChangeRetention - срок хранения изменений в журнале изменений пользователей.
+	ChangeRetention time.Duration
+}
+
 // New - конструктор для типа Users.
-func New(log logger.Logger, userSaver UserSaver, userProvider UserProvider, crypter Crypter) *Users {
+func New(log logger.Logger, deps Deps, params Params) *Users {
 	return &Users{
-		log:          log,
-		userSaver:    userSaver,
-		userProvider: userProvider,
-		crypter:      crypter,
+		log:                  log,
+		userSaver:            deps.UserSaver,
+		userProvider:         deps.UserProvider,
+		crypter:              deps.Crypter,
+		tokenManager:         deps.TokenManager,
+		sessionSaver:         deps.SessionSaver,
+		sessionProvider:      deps.SessionProvider,
+		resetCodeSaver:       deps.ResetCodeSaver,
+		resetCodeProvider:    deps.ResetCodeProvider,
+		notifier:             deps.Notifier,
+		passwordPolicy:       deps.PasswordPolicy,
+		usernamePolicy:       deps.UsernamePolicy,
+		attemptTracker:       deps.AttemptTracker,
+		secondFactorSaver:    deps.SecondFactorSaver,
+		secondFactorProvider: deps.SecondFactorProvider,
+		totpManager:          deps.TOTPManager,
+		secretEncrypter:      deps.SecretEncrypter,
+		contactSaver:         deps.ContactSaver,
+		contactProvider:      deps.ContactProvider,
+		blockSaver:           deps.BlockSaver,
+		blockProvider:        deps.BlockProvider,
+		presenceTracker:      deps.PresenceTracker,
+		presenceSaver:        deps.PresenceSaver,
+		presenceProvider:     deps.PresenceProvider,
+		privacySaver:         deps.PrivacySaver,
+		privacyProvider:      deps.PrivacyProvider,
+		deviceSaver:          deps.DeviceSaver,
+		deviceProvider:       deps.DeviceProvider,
+		eventOutbox:          deps.EventOutbox,
+		eventPublisher:       deps.EventPublisher,
+		changeProvider:       deps.ChangeProvider,
+		changeListener:       deps.ChangeListener,
+		resetCodeTTL:         params.ResetCodeTTL,
+		maxResetCodes:        params.MaxResetCodes,
+		resetThrottle:        params.ResetThrottle,
+		throttle:             params.Throttle,
+		challengeTTL:         params.ChallengeTTL,
+		maxBatchSize:         params.MaxBatchSize,
+		pageSize:             params.PageSize,
+		usernameReservation:  params.UsernameReservation,
+		deletionGracePeriod:  params.DeletionGracePeriod,
+		presenceTTL:          params.PresenceTTL,
+		sessionTouchInterval: params.SessionTouchInterval,
+		changePollInterval:   params.ChangePollInterval,
+		changeRetention:      params.ChangeRetention,
 	}
 }
 
 // Login реализует логику авторизации пользователя по логину и паролю.
+// Создает новую сессию для клиента и возвращает ее пару токенов.
+// Если хэш пароля получен устаревшим алгоритмом или с устаревшими параметрами, он пересчитывается.
+// Если у пользователя подключен TOTP, сессия не создается: возвращает *users.SecondFactorRequiredError
+// с токеном незавершенного входа, который завершается методом VerifySecondFactor.
 // Если логин или пароль неверные, возвращает users.ErrInvalidCredentials.
-func (u *Users) Login(ctx context.Context, username, password string) (int64, error) {
+// Неудачные попытки учитываются по username и IP клиента, при превышении лимита возвращает *users.LockoutError.
+// Если состояние блокировок получить не удалось, возвращает users.ErrLoginUnavailable.
+func (u *Users) Login(ctx context.Context, username, password string, client models.ClientInfo) (models.Tokens, error) {
 	const op = "users.Login"
 
-	user, err := u.userProvider.GetUser(ctx, username)
+	canonical := u.usernamePolicy.Canonical(username)
+	attempts := u.loginAttempts(canonical, client.IP)
+	if err := u.checkLockout(ctx, attempts); err != nil {
+		u.log.Warnf("login rejected. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	user, err := u.userProvider.GetUser(ctx, canonical)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			u.log.Warnf("user not found. %w", err)
-			return 0, fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+			u.registerAttempts(ctx, u.throttle, attempts)
+			return models.Tokens{}, fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
 		}
 
 		u.log.Errorf("error getting user. %w", err)
-		return 0, fmt.Errorf("%s, %w", op, err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
 	}
 
 	if err = u.crypter.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
 		u.log.Warnf("invalid credentials. %w", err)
-		return 0, fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+		u.registerAttempts(ctx, u.throttle, attempts)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+	}
+
+	if u.crypter.NeedsRehash(user.PasswordHash) {
+		u.rehashPassword(ctx, user.Id, password)
+	}
+
+	totpEnabled, err := u.secondFactorProvider.TOTPEnabled(ctx, user.Id)
+	if err != nil {
+		u.log.Errorf("error checking totp. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	//Счетчик по username не сбрасывается до ввода второго фактора, иначе знание пароля позволит перебирать коды
+	if totpEnabled {
+		challengeErr, err := u.newSecondFactorChallenge(ctx, user.Id)
+		if err != nil {
+			return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+		}
+
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, challengeErr)
+	}
+
+	//Успешный вход сбрасывает счетчик по username, счетчик по IP продолжает учитывать перебор по разным аккаунтам
+	if err = u.attemptTracker.ResetFailedAttempts(ctx, userAttemptKey(canonical)); err != nil {
+		u.log.Errorf("error resetting failed attempts. %w", err)
+	}
+
+	tokens, err := u.startSession(ctx, user.Id, client)
+	if err != nil {
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// RefreshTokens реализует логику ротации токенов.
+// Старый refresh токен становится недействительным, взамен выдается новая пара токенов той же сессии.
+// Если refresh токен не найден или истек, возвращает users.ErrInvalidToken.
+func (u *Users) RefreshTokens(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	const op = "users.RefreshTokens"
+
+	newRefreshToken, refreshExpiresAt, err := u.tokenManager.NewRefreshToken()
+	if err != nil {
+		u.log.Errorf("error generating refresh token. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
 	}
 
-	return user.Id, nil
+	userId, sessionId, err := u.sessionSaver.RotateRefreshToken(
+		ctx,
+		hashToken(refreshToken),
+		hashToken(newRefreshToken),
+		refreshExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			u.log.Warnf("refresh token not found. %w", err)
+			return models.Tokens{}, fmt.Errorf("%s, %w", op, ErrInvalidToken)
+		}
+
+		u.log.Errorf("error rotating refresh token. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	accessToken, accessExpiresAt, err := u.tokenManager.NewAccessToken(userId, sessionId)
+	if err != nil {
+		u.log.Errorf("error generating access token. %w", err)
+		return models.Tokens{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return models.Tokens{
+		UserId:           userId,
+		SessionId:        sessionId,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     newRefreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// ValidateToken реализует логику проверки access токена.
+// Если токен поддельный, истек, его сессия завершена или он принадлежит неактивному пользователю,
+// возвращает users.ErrInvalidToken.
+func (u *Users) ValidateToken(ctx context.Context, accessToken string) (models.TokenInfo, error) {
+	const op = "users.ValidateToken"
+
+	info, err := u.tokenManager.ParseAccessToken(accessToken)
+	if err != nil {
+		u.log.Warnf("error parsing access token. %w", err)
+		return models.TokenInfo{}, fmt.Errorf("%s, %w", op, ErrInvalidToken)
+	}
+
+	user, err := u.userProvider.GetUserById(ctx, info.UserId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return models.TokenInfo{}, fmt.Errorf("%s, %w", op, ErrInvalidToken)
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return models.TokenInfo{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	if !user.IsActive {
+		u.log.Warnf("token belongs to inactive user. user_id=%d", info.UserId)
+		return models.TokenInfo{}, fmt.Errorf("%s, %w", op, ErrInvalidToken)
+	}
+
+	//Время активности обновляется не чаще sessionTouchInterval, чтобы проверка токена не писала в базу на каждый вызов
+	if err = u.sessionSaver.TouchSession(ctx, info.SessionId, time.Now().Add(-u.sessionTouchInterval)); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			u.log.Warnf("session not found. %w", err)
+			return models.TokenInfo{}, fmt.Errorf("%s, %w", op, ErrInvalidToken)
+		}
+
+		u.log.Errorf("error touching session. %w", err)
+		return models.TokenInfo{}, fmt.Errorf("%s, %w", op, err)
+	}
+
+	return info, nil
 }
 
 // RegisterNewUser реализует логику регистрации нового пользователя.
-// Если заданный username уже занят, возвращает users.ErrUserAlreadyExists.
+// Если заданный username уже занят или зарезервирован после смены другим пользователем, возвращает users.ErrUserAlreadyExists.
+// Username сохраняется без пробелов по краям, уникальность проверяется по его канонической форме.
+// Если username или пароль не соответствуют правилам, возвращает *users.ValidationError.
 func (u *Users) RegisterNewUser(ctx context.Context, username, password string) (int64, error) {
 	const op = "users.RegisterNewUser"
 
-	passHash, err := u.crypter.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	username = strings.TrimSpace(username)
+	if err := u.validateCredentials(username, password); err != nil {
+		u.log.Warnf("invalid credentials format. %w", err)
+		return 0, fmt.Errorf("%s, %w", op, err)
+	}
+
+	passHash, err := u.crypter.GenerateFromPassword([]byte(password))
 	if err != nil {
 		u.log.Errorf("error generating hash from password. %w", err)
 		return 0, fmt.Errorf("%s, %w", op, err)
 	}
 
-	id, err := u.userSaver.SaveUser(ctx, username, passHash)
+	id, err := u.userSaver.SaveUser(ctx, username, u.usernamePolicy.Canonical(username), passHash)
 	if err != nil {
-		if errors.Is(err, repository.ErrUserAlredyExists) {
+		if errors.Is(err, repository.ErrUserAlredyExists) || errors.Is(err, repository.ErrUsernameReserved) {
 			u.log.Warnf("user already exists. %w", err)
 			return 0, fmt.Errorf("%s, %w", op, ErrUserAlreadyExists)
 		}
@@ -117,6 +512,96 @@ func (u *Users) RegisterNewUser(ctx context.Context, username, password string)
 	return id, nil
 }
 
+// ChangePassword реализует логику смены пароля пользователя.
+// Если пользователь не найден, неактивен или старый пароль неверный, возвращает users.ErrInvalidCredentials.
+// Если новый пароль совпадает с текущим, возвращает users.ErrSamePassword.
+// Если новый пароль не соответствует политике паролей, возвращает *users.ValidationError.
+func (u *Users) ChangePassword(ctx context.Context, userId int64, oldPassword, newPassword string) error {
+	const op = "users.ChangePassword"
+
+	user, err := u.userProvider.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+		}
+
+		u.log.Errorf("error getting user. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if !user.IsActive {
+		u.log.Warnf("user is inactive. user_id=%d", userId)
+		return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+	}
+
+	if err = u.crypter.CompareHashAndPassword(user.PasswordHash, []byte(oldPassword)); err != nil {
+		u.log.Warnf("invalid credentials. %w", err)
+		return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+	}
+
+	if err = u.crypter.CompareHashAndPassword(user.PasswordHash, []byte(newPassword)); err == nil {
+		u.log.Warnf("new password equals current password. user_id=%d", userId)
+		return fmt.Errorf("%s, %w", op, ErrSamePassword)
+	}
+
+	if err = u.validatePassword("new_password", user.Username, newPassword); err != nil {
+		u.log.Warnf("password policy violation. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	passHash, err := u.crypter.GenerateFromPassword([]byte(newPassword))
+	if err != nil {
+		u.log.Errorf("error generating hash from password. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	if err = u.userSaver.UpdatePasswordHash(ctx, userId, passHash); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+		}
+
+		u.log.Errorf("error updating password hash. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// ChangeUsername реализует логику смены username пользователя.
+// Прежний username сохраняется в истории и резервируется за пользователем на время, заданное конфигурацией,
+// чтобы его не занял другой пользователь. Сам пользователь может вернуть его себе в любой момент.
+// Если новый username не соответствует правилам, возвращает *users.ValidationError.
+// Если новый username занят или зарезервирован за другим пользователем, возвращает users.ErrUserAlreadyExists.
+// Если пользователь не найден или неактивен, возвращает users.ErrUserNotFound.
+func (u *Users) ChangeUsername(ctx context.Context, userId int64, username string) error {
+	const op = "users.ChangeUsername"
+
+	username = strings.TrimSpace(username)
+	if err := newValidationError(fieldViolations("username", u.usernamePolicy.Validate(username))...); err != nil {
+		u.log.Warnf("invalid username format. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	reservedUntil := time.Now().Add(u.usernameReservation)
+	if err := u.userSaver.ChangeUsername(ctx, userId, username, u.usernamePolicy.Canonical(username), reservedUntil); err != nil {
+		if errors.Is(err, repository.ErrUserAlredyExists) || errors.Is(err, repository.ErrUsernameReserved) {
+			u.log.Warnf("username unavailable. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrUserAlreadyExists)
+		}
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrUserNotFound)
+		}
+
+		u.log.Errorf("error changing username. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
 // MakeUserInactive реализует логику переведения пользователя в статус 'неактивен'.
 // Если пользователь с заданным userId не найден, возвращает users.ErrInvalidCredentials.
 // Если найденный пользователь уже имеет статус 'неактивен', возвращает ошибку repository.ErrUserAlreadyInactive.
@@ -139,3 +624,46 @@ func (u *Users) MakeUserInactive(ctx context.Context, userId int64) error {
 
 	return nil
 }
+
+// MakeUserActive реализует логику возвращения неактивного пользователя в статус 'активен'.
+// Если пользователь с заданным userId не найден, возвращает users.ErrInvalidCredentials.
+// Если найденный пользователь уже активен, возвращает ошибку users.ErrUserAlreadyActive.
+func (u *Users) MakeUserActive(ctx context.Context, userId int64) error {
+	const op = "users.MakeUserActive"
+
+	if err := u.userSaver.SetActive(ctx, userId); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			u.log.Warnf("user not found. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrInvalidCredentials)
+		}
+		if errors.Is(err, repository.ErrUserAlreadyActive) {
+			u.log.Warnf("user already active. %w", err)
+			return fmt.Errorf("%s, %w", op, ErrUserAlreadyActive)
+		}
+
+		u.log.Errorf("error making user active. %w", err)
+		return fmt.Errorf("%s, %w", op, err)
+	}
+
+	return nil
+}
+
+// rehashPassword пересчитывает хэш пароля пользователя с текущими параметрами хэширования.
+// Ошибки только логируются, так как не должны мешать авторизации.
+func (u *Users) rehashPassword(ctx context.Context, userId int64, password string) {
+	passHash, err := u.crypter.GenerateFromPassword([]byte(password))
+	if err != nil {
+		u.log.Errorf("error generating hash from password. %w", err)
+		return
+	}
+
+	if err = u.userSaver.UpdatePasswordHash(ctx, userId, passHash); err != nil {
+		u.log.Errorf("error updating password hash. %w", err)
+	}
+}
+
+// hashToken возвращает sha256 хэш токена. В базе данных хранятся только хэши refresh токенов.
+func hashToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
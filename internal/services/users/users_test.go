@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/al3ksus/messengerusers/internal/domain/models"
 	"github.com/al3ksus/messengerusers/internal/lib/crypt"
@@ -21,10 +22,68 @@ var (
 	TestPass                = "qwerty"
 	TestWrongPassword       = "wrongpass"
 	TestPassHash            = generateTestPassHash(TestPass)
+	TestNewPass             = "newqwerty"
+	TestNewPassHash         = []byte("new_hash")
 	TestUserId        int64 = 1
 	EmptyUserId       int64 = 0
 )
 
+var (
+	TestWeakPass           = "a"
+	TestPolicyViolations   = []string{"must be at least 8 characters long"}
+	TestCanonical          = "user1"
+	TestUsernameViolations = []string{"is reserved"}
+)
+
+var (
+	TestUserAttemptKey = "user:" + TestCanonical
+	TestIPAttemptKey   = "ip:" + TestClient.IP
+	TestLockedUntil    = time.Now().Add(time.Hour)
+	TestThrottle       = ThrottleParams{
+		Window:           15 * time.Minute,
+		UserFreeAttempts: 5,
+		IPFreeAttempts:   20,
+		BaseLockout:      30 * time.Second,
+		MaxLockout:       time.Hour,
+	}
+)
+
+var (
+	TestSessionId            int64 = 2
+	EmptySessionId           int64 = 0
+	TestSessionTouchInterval       = 5 * time.Minute
+	TestClient                     = models.ClientInfo{
+		DeviceName: "phone",
+		IP:         "127.0.0.1",
+		UserAgent:  "grpc-go",
+	}
+	TestSession = models.Session{
+		UserId:     TestUserId,
+		ClientInfo: TestClient,
+	}
+	TestTokenInfo = models.TokenInfo{
+		UserId:    TestUserId,
+		SessionId: TestSessionId,
+		ExpiresAt: TestAccessExpiresAt,
+	}
+)
+
+var (
+	TestAccessToken      = "access"
+	TestRefreshToken     = "refresh"
+	TestOldRefreshToken  = "old_refresh"
+	TestAccessExpiresAt  = time.Unix(1700000000, 0)
+	TestRefreshExpiresAt = time.Unix(1800000000, 0)
+	TestTokens           = models.Tokens{
+		UserId:           TestUserId,
+		SessionId:        TestSessionId,
+		AccessToken:      TestAccessToken,
+		AccessExpiresAt:  TestAccessExpiresAt,
+		RefreshToken:     TestRefreshToken,
+		RefreshExpiresAt: TestRefreshExpiresAt,
+	}
+)
+
 var (
 	TestUser = models.User{
 		Id:           TestUserId,
@@ -32,6 +91,12 @@ var (
 		PasswordHash: TestPassHash,
 		IsActive:     true,
 	}
+	TestInactiveUser = models.User{
+		Id:           TestUserId,
+		Username:     TestUsername,
+		PasswordHash: TestPassHash,
+		IsActive:     false,
+	}
 	EmptyUser = models.User{}
 )
 
@@ -45,6 +110,12 @@ func TestUsers_Login(t *testing.T) {
 		userSaver *mocks.UserSaver,
 		userProvider *mocks.UserProvider,
 		crypter *mocks.Crypter,
+		tokenManager *mocks.TokenManager,
+		sessionSaver *mocks.SessionSaver,
+		usernamePolicy *mocks.UsernamePolicy,
+		attemptTracker *mocks.AttemptTracker,
+		secondFactorSaver *mocks.SecondFactorSaver,
+		secondFactorProvider *mocks.SecondFactorProvider,
 		ctx context.Context,
 		username string,
 		password string,
@@ -59,11 +130,913 @@ func TestUsers_Login(t *testing.T) {
 		name         string
 		args         args
 		mockBehavior mockBehavior
-		want         int64
+		want         models.Tokens
 		wantErr      error
 	}{
 		{
-			name: "OK",
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(nil)
+				crypter.On("NeedsRehash", TestUser.PasswordHash).Return(false)
+				secondFactorProvider.On("TOTPEnabled", ctx, TestUserId).Return(false, nil)
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.On("SaveSession", ctx, TestSession, hashToken(TestRefreshToken), TestRefreshExpiresAt).Return(TestSessionId, nil)
+				tokenManager.On("NewAccessToken", TestUserId, TestSessionId).Return(TestAccessToken, TestAccessExpiresAt, nil)
+			},
+			want: TestTokens,
+		},
+		{
+			name: "Rehash",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(nil)
+				crypter.On("NeedsRehash", TestUser.PasswordHash).Return(true)
+				secondFactorProvider.On("TOTPEnabled", ctx, TestUserId).Return(false, nil)
+				crypter.On("GenerateFromPassword", []byte(TestPass)).Return(TestNewPassHash, nil)
+				userSaver.On("UpdatePasswordHash", ctx, TestUserId, TestNewPassHash).Return(nil)
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.On("SaveSession", ctx, TestSession, hashToken(TestRefreshToken), TestRefreshExpiresAt).Return(TestSessionId, nil)
+				tokenManager.On("NewAccessToken", TestUserId, TestSessionId).Return(TestAccessToken, TestAccessExpiresAt, nil)
+			},
+			want: TestTokens,
+		},
+		{
+			name: "RehashError",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(nil)
+				crypter.On("NeedsRehash", TestUser.PasswordHash).Return(true)
+				secondFactorProvider.On("TOTPEnabled", ctx, TestUserId).Return(false, nil)
+				crypter.On("GenerateFromPassword", []byte(TestPass)).Return(TestNewPassHash, nil)
+				userSaver.On("UpdatePasswordHash", ctx, TestUserId, TestNewPassHash).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.On("SaveSession", ctx, TestSession, hashToken(TestRefreshToken), TestRefreshExpiresAt).Return(TestSessionId, nil)
+				tokenManager.On("NewAccessToken", TestUserId, TestSessionId).Return(TestAccessToken, TestAccessExpiresAt, nil)
+			},
+			want: TestTokens,
+		},
+		{
+			name: "SecondFactorRequired",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				crypter.On("NeedsRehash", TestUser.PasswordHash).Return(false)
+				secondFactorProvider.On("TOTPEnabled", ctx, TestUserId).Return(true, nil)
+				secondFactorSaver.On("SaveChallenge", ctx, TestUserId, mock.Anything, mock.Anything).Return(nil)
+			},
+			wantErr: ErrSecondFactorRequired,
+		},
+		{
+			name: "TOTPCheckError",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				crypter.On("NeedsRehash", TestUser.PasswordHash).Return(false)
+				secondFactorProvider.On("TOTPEnabled", ctx, TestUserId).Return(false, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "SaveChallengeError",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				crypter.On("NeedsRehash", TestUser.PasswordHash).Return(false)
+				secondFactorProvider.On("TOTPEnabled", ctx, TestUserId).Return(true, nil)
+				secondFactorSaver.On("SaveChallenge", ctx, TestUserId, mock.Anything, mock.Anything).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "WrongUsername",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestIPAttemptKey, mock.Anything).Return(1, nil)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "WrongPassword",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestWrongPassword,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestWrongPassword)).Return(errors.New(""))
+				log.On("Warnf", mock.Anything, mock.Anything)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestUserAttemptKey, mock.Anything).Return(1, nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestIPAttemptKey, mock.Anything).Return(1, nil)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "WrongPasswordLockout",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestWrongPassword,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestWrongPassword)).Return(errors.New(""))
+				log.On("Warnf", mock.Anything, mock.Anything)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestUserAttemptKey, mock.Anything).Return(TestThrottle.UserFreeAttempts+1, nil)
+				attemptTracker.On("SetLockout", ctx, TestUserAttemptKey, mock.Anything).Return(nil)
+				attemptTracker.On("RegisterFailedAttempt", ctx, TestIPAttemptKey, mock.Anything).Return(1, nil)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "LockedOut",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(TestLockedUntil, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &LockoutError{Until: TestLockedUntil},
+		},
+		{
+			name: "AttemptTrackerError",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrLoginUnavailable,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(EmptyUser, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+		{
+			name: "SaveTokenError",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				usernamePolicy *mocks.UsernamePolicy,
+				attemptTracker *mocks.AttemptTracker,
+				secondFactorSaver *mocks.SecondFactorSaver,
+				secondFactorProvider *mocks.SecondFactorProvider,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				attemptTracker.On("GetLockout", ctx, TestUserAttemptKey).Return(time.Time{}, nil)
+				attemptTracker.On("GetLockout", ctx, TestIPAttemptKey).Return(time.Time{}, nil)
+				userProvider.On("GetUser", ctx, TestCanonical).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				attemptTracker.On("ResetFailedAttempts", ctx, TestUserAttemptKey).Return(nil)
+				crypter.On("NeedsRehash", TestUser.PasswordHash).Return(false)
+				secondFactorProvider.On("TOTPEnabled", ctx, TestUserId).Return(false, nil)
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.On("SaveSession", ctx, TestSession, hashToken(TestRefreshToken), TestRefreshExpiresAt).Return(EmptySessionId, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userSaver := mocks.NewUserSaver(t)
+			userProvider := mocks.NewUserProvider(t)
+			log := loggermocks.NewLogger(t)
+			crypter := mocks.NewCrypter(t)
+			tokenManager := mocks.NewTokenManager(t)
+			sessionSaver := mocks.NewSessionSaver(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
+			attemptTracker := mocks.NewAttemptTracker(t)
+			secondFactorSaver := mocks.NewSecondFactorSaver(t)
+			secondFactorProvider := mocks.NewSecondFactorProvider(t)
+
+			tt.mockBehavior(log, userSaver, userProvider, crypter, tokenManager, sessionSaver, usernamePolicy, attemptTracker, secondFactorSaver, secondFactorProvider, tt.args.ctx, tt.args.username, tt.args.password)
+			u := &Users{
+				log:                  log,
+				userSaver:            userSaver,
+				userProvider:         userProvider,
+				crypter:              crypter,
+				tokenManager:         tokenManager,
+				sessionSaver:         sessionSaver,
+				usernamePolicy:       usernamePolicy,
+				attemptTracker:       attemptTracker,
+				throttle:             TestThrottle,
+				secondFactorSaver:    secondFactorSaver,
+				secondFactorProvider: secondFactorProvider,
+			}
+			got, err := u.Login(tt.args.ctx, tt.args.username, tt.args.password, TestClient)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.Login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, "users.Login, "+tt.wantErr.Error(), fmt.Sprintf("users.Login() error = %v, wantErr %v", err, tt.wantErr))
+			}
+			assert.Equal(t, got, tt.want, fmt.Sprintf("users.Login() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestUsers_RefreshTokens(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		tokenManager *mocks.TokenManager,
+		sessionSaver *mocks.SessionSaver,
+		ctx context.Context,
+		refreshToken string,
+	)
+
+	type args struct {
+		ctx          context.Context
+		refreshToken string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.Tokens
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:          context.Background(),
+				refreshToken: TestOldRefreshToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				refreshToken string,
+			) {
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.
+					On("RotateRefreshToken", ctx, hashToken(refreshToken), hashToken(TestRefreshToken), TestRefreshExpiresAt).
+					Return(TestUserId, TestSessionId, nil)
+				tokenManager.On("NewAccessToken", TestUserId, TestSessionId).Return(TestAccessToken, TestAccessExpiresAt, nil)
+			},
+			want: TestTokens,
+		},
+		{
+			name: "InvalidToken",
+			args: args{
+				ctx:          context.Background(),
+				refreshToken: TestOldRefreshToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				refreshToken string,
+			) {
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.
+					On("RotateRefreshToken", ctx, hashToken(refreshToken), hashToken(TestRefreshToken), TestRefreshExpiresAt).
+					Return(EmptyUserId, EmptySessionId, repository.ErrTokenNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:          context.Background(),
+				refreshToken: TestOldRefreshToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				refreshToken string,
+			) {
+				tokenManager.On("NewRefreshToken").Return(TestRefreshToken, TestRefreshExpiresAt, nil)
+				sessionSaver.
+					On("RotateRefreshToken", ctx, hashToken(refreshToken), hashToken(TestRefreshToken), TestRefreshExpiresAt).
+					Return(EmptyUserId, EmptySessionId, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			tokenManager := mocks.NewTokenManager(t)
+			sessionSaver := mocks.NewSessionSaver(t)
+
+			tt.mockBehavior(log, tokenManager, sessionSaver, tt.args.ctx, tt.args.refreshToken)
+			u := &Users{
+				log:          log,
+				tokenManager: tokenManager,
+				sessionSaver: sessionSaver,
+			}
+			got, err := u.RefreshTokens(tt.args.ctx, tt.args.refreshToken)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.RefreshTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(
+					t,
+					err,
+					"users.RefreshTokens, "+tt.wantErr.Error(),
+					fmt.Sprintf("users.RefreshTokens() error = %v, wantErr %v", err, tt.wantErr),
+				)
+			}
+			assert.Equal(t, got, tt.want, fmt.Sprintf("users.RefreshTokens() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestUsers_ValidateToken(t *testing.T) {
+	// touchBefore проверяет, что время активности обновляется не чаще TestSessionTouchInterval.
+	touchBefore := mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= TestSessionTouchInterval && time.Since(before) < TestSessionTouchInterval+time.Minute
+	})
+
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userProvider *mocks.UserProvider,
+		tokenManager *mocks.TokenManager,
+		sessionSaver *mocks.SessionSaver,
+		ctx context.Context,
+		accessToken string,
+	)
+
+	type args struct {
+		ctx         context.Context
+		accessToken string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         models.TokenInfo
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:         context.Background(),
+				accessToken: TestAccessToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				accessToken string,
+			) {
+				tokenManager.On("ParseAccessToken", accessToken).Return(TestTokenInfo, nil)
+				userProvider.On("GetUserById", ctx, TestUserId).Return(TestUser, nil)
+				sessionSaver.On("TouchSession", ctx, TestSessionId, touchBefore).Return(nil)
+			},
+			want: TestTokenInfo,
+		},
+		{
+			name: "SessionNotFound",
+			args: args{
+				ctx:         context.Background(),
+				accessToken: TestAccessToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				accessToken string,
+			) {
+				tokenManager.On("ParseAccessToken", accessToken).Return(TestTokenInfo, nil)
+				userProvider.On("GetUserById", ctx, TestUserId).Return(TestUser, nil)
+				sessionSaver.On("TouchSession", ctx, TestSessionId, touchBefore).Return(repository.ErrSessionNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "BadToken",
+			args: args{
+				ctx:         context.Background(),
+				accessToken: TestAccessToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				accessToken string,
+			) {
+				tokenManager.On("ParseAccessToken", accessToken).Return(models.TokenInfo{}, errors.New(""))
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "UserNotFound",
+			args: args{
+				ctx:         context.Background(),
+				accessToken: TestAccessToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				accessToken string,
+			) {
+				tokenManager.On("ParseAccessToken", accessToken).Return(TestTokenInfo, nil)
+				userProvider.On("GetUserById", ctx, TestUserId).Return(EmptyUser, repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "UserInactive",
+			args: args{
+				ctx:         context.Background(),
+				accessToken: TestAccessToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				accessToken string,
+			) {
+				tokenManager.On("ParseAccessToken", accessToken).Return(TestTokenInfo, nil)
+				userProvider.On("GetUserById", ctx, TestUserId).Return(TestInactiveUser, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:         context.Background(),
+				accessToken: TestAccessToken,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userProvider *mocks.UserProvider,
+				tokenManager *mocks.TokenManager,
+				sessionSaver *mocks.SessionSaver,
+				ctx context.Context,
+				accessToken string,
+			) {
+				tokenManager.On("ParseAccessToken", accessToken).Return(TestTokenInfo, nil)
+				userProvider.On("GetUserById", ctx, TestUserId).Return(EmptyUser, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := loggermocks.NewLogger(t)
+			userProvider := mocks.NewUserProvider(t)
+			tokenManager := mocks.NewTokenManager(t)
+			sessionSaver := mocks.NewSessionSaver(t)
+
+			tt.mockBehavior(log, userProvider, tokenManager, sessionSaver, tt.args.ctx, tt.args.accessToken)
+			u := &Users{
+				log:                  log,
+				userProvider:         userProvider,
+				tokenManager:         tokenManager,
+				sessionSaver:         sessionSaver,
+				sessionTouchInterval: TestSessionTouchInterval,
+			}
+			got, err := u.ValidateToken(tt.args.ctx, tt.args.accessToken)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.ValidateToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(
+					t,
+					err,
+					"users.ValidateToken, "+tt.wantErr.Error(),
+					fmt.Sprintf("users.ValidateToken() error = %v, wantErr %v", err, tt.wantErr),
+				)
+			}
+			assert.Equal(t, got, tt.want, fmt.Sprintf("users.ValidateToken() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestUsers_RegisterNewUser(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userSaver *mocks.UserSaver,
+		userProvider *mocks.UserProvider,
+		crypter *mocks.Crypter,
+		passwordPolicy *mocks.PasswordPolicy,
+		usernamePolicy *mocks.UsernamePolicy,
+		ctx context.Context,
+		username string,
+		password string,
+	)
+
+	type args struct {
+		ctx      context.Context
+		username string
+		password string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		want         int64
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Validate", username).Return([]string(nil))
+				passwordPolicy.On("Validate", username, password).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestPass)).Return(TestPassHash, nil)
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				userSaver.On("SaveUser", ctx, username, TestCanonical, TestPassHash).Return(TestUserId, nil)
+			},
+			want: TestUserId,
+		},
+		{
+			name: "UsernameTaken",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Validate", username).Return([]string(nil))
+				passwordPolicy.On("Validate", username, password).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestPass)).Return(TestPassHash, nil)
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				userSaver.On("SaveUser", ctx, username, TestCanonical, TestPassHash).Return(EmptyUserId, repository.ErrUserAlredyExists)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserAlreadyExists,
+		},
+		{
+			name: "UsernameReserved",
+			args: args{
+				ctx:      context.Background(),
+				username: TestUsername,
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Validate", username).Return([]string(nil))
+				passwordPolicy.On("Validate", username, password).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestPass)).Return(TestPassHash, nil)
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				userSaver.On("SaveUser", ctx, username, TestCanonical, TestPassHash).Return(EmptyUserId, repository.ErrUsernameReserved)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserAlreadyExists,
+		},
+		{
+			name: "TrimmedUsername",
+			args: args{
+				ctx:      context.Background(),
+				username: " " + TestUsername + " ",
+				password: TestPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
+				ctx context.Context,
+				username string,
+				password string,
+			) {
+				usernamePolicy.On("Validate", TestUsername).Return([]string(nil))
+				passwordPolicy.On("Validate", TestUsername, password).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestPass)).Return(TestPassHash, nil)
+				usernamePolicy.On("Canonical", TestUsername).Return(TestCanonical)
+				userSaver.On("SaveUser", ctx, TestUsername, TestCanonical, TestPassHash).Return(TestUserId, nil)
+			},
+			want: TestUserId,
+		},
+		{
+			name: "UsernamePolicyViolation",
 			args: args{
 				ctx:      context.Background(),
 				username: TestUsername,
@@ -74,38 +1047,44 @@ func TestUsers_Login(t *testing.T) {
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
 				ctx context.Context,
 				username string,
 				password string,
 			) {
-				userProvider.On("GetUser", ctx, username).Return(TestUser, nil)
-				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				usernamePolicy.On("Validate", username).Return(TestUsernameViolations)
+				passwordPolicy.On("Validate", username, password).Return([]string(nil))
+				log.On("Warnf", mock.Anything, mock.Anything)
 			},
-			want: TestUserId,
+			wantErr: &ValidationError{Violations: []FieldViolation{{Field: "username", Description: TestUsernameViolations[0]}}},
 		},
 		{
-			name: "WrongUsername",
+			name: "PasswordPolicyViolation",
 			args: args{
 				ctx:      context.Background(),
 				username: TestUsername,
-				password: TestPass,
+				password: TestWeakPass,
 			},
 			mockBehavior: func(
 				log *loggermocks.Logger,
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
 				ctx context.Context,
 				username string,
 				password string,
 			) {
-				userProvider.On("GetUser", ctx, username).Return(EmptyUser, repository.ErrUserNotFound)
+				usernamePolicy.On("Validate", username).Return([]string(nil))
+				passwordPolicy.On("Validate", username, password).Return(TestPolicyViolations)
 				log.On("Warnf", mock.Anything, mock.Anything)
 			},
-			wantErr: ErrInvalidCredentials,
+			wantErr: &ValidationError{Violations: []FieldViolation{{Field: "password", Description: TestPolicyViolations[0]}}},
 		},
 		{
-			name: "WrongPassword",
+			name: "GenPassError",
 			args: args{
 				ctx:      context.Background(),
 				username: TestUsername,
@@ -116,15 +1095,18 @@ func TestUsers_Login(t *testing.T) {
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
 				ctx context.Context,
 				username string,
 				password string,
 			) {
-				userProvider.On("GetUser", ctx, username).Return(TestUser, nil)
-				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestWrongPassword)).Return(errors.New(""))
-				log.On("Warnf", mock.Anything, mock.Anything)
+				usernamePolicy.On("Validate", username).Return([]string(nil))
+				passwordPolicy.On("Validate", username, password).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestWrongPassword)).Return(nil, errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
 			},
-			wantErr: ErrInvalidCredentials,
+			wantErr: errors.New(""),
 		},
 		{
 			name: "InternalError",
@@ -138,11 +1120,17 @@ func TestUsers_Login(t *testing.T) {
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				usernamePolicy *mocks.UsernamePolicy,
 				ctx context.Context,
 				username string,
 				password string,
 			) {
-				userProvider.On("GetUser", ctx, username).Return(EmptyUser, errors.New(""))
+				usernamePolicy.On("Validate", username).Return([]string(nil))
+				passwordPolicy.On("Validate", username, password).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestPass)).Return(TestPassHash, nil)
+				usernamePolicy.On("Canonical", username).Return(TestCanonical)
+				userSaver.On("SaveUser", ctx, username, TestCanonical, TestPassHash).Return(EmptyUserId, errors.New(""))
 				log.On("Errorf", mock.Anything, mock.Anything)
 			},
 			wantErr: errors.New(""),
@@ -154,132 +1142,223 @@ func TestUsers_Login(t *testing.T) {
 			userProvider := mocks.NewUserProvider(t)
 			log := loggermocks.NewLogger(t)
 			crypter := mocks.NewCrypter(t)
+			passwordPolicy := mocks.NewPasswordPolicy(t)
+			usernamePolicy := mocks.NewUsernamePolicy(t)
 
-			tt.mockBehavior(log, userSaver, userProvider, crypter, tt.args.ctx, tt.args.username, tt.args.password)
+			tt.mockBehavior(log, userSaver, userProvider, crypter, passwordPolicy, usernamePolicy, tt.args.ctx, tt.args.username, tt.args.password)
 			u := &Users{
-				userSaver:    userSaver,
-				log:          log,
-				userProvider: userProvider,
-				crypter:      crypter,
+				userSaver:      userSaver,
+				log:            log,
+				userProvider:   userProvider,
+				crypter:        crypter,
+				passwordPolicy: passwordPolicy,
+				usernamePolicy: usernamePolicy,
 			}
-			got, err := u.Login(tt.args.ctx, tt.args.username, tt.args.password)
+			got, err := u.RegisterNewUser(tt.args.ctx, tt.args.username, tt.args.password)
 			if (err != nil) != (tt.wantErr != nil) {
-				t.Errorf("users.Login() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("users.RegisterNewUser() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
 			if tt.wantErr != nil {
-				assert.EqualError(t, err, "users.Login, "+tt.wantErr.Error(), fmt.Sprintf("users.Login() error = %v, wantErr %v", err, tt.wantErr))
+				assert.EqualError(
+					t,
+					err,
+					"users.RegisterNewUser, "+tt.wantErr.Error(),
+					fmt.Sprintf("users.RegisterNewUser() error = %v, wantErr %v", err, tt.wantErr),
+				)
 			}
-			assert.Equal(t, got, tt.want, fmt.Sprintf("users.Login() = %v, want %v", got, tt.want))
+			assert.Equal(t, got, tt.want, fmt.Sprintf("users.RegisterNewUser() = %v, want %v", got, tt.want))
 		})
 	}
 }
 
-func TestUsers_RegisterNewUser(t *testing.T) {
+func TestUsers_ChangePassword(t *testing.T) {
 	type mockBehavior func(
 		log *loggermocks.Logger,
 		userSaver *mocks.UserSaver,
 		userProvider *mocks.UserProvider,
 		crypter *mocks.Crypter,
+		passwordPolicy *mocks.PasswordPolicy,
 		ctx context.Context,
-		username string,
-		password string,
+		userId int64,
 	)
 
 	type args struct {
-		ctx      context.Context
-		username string
-		password string
+		ctx         context.Context
+		userId      int64
+		oldPassword string
+		newPassword string
 	}
 	tests := []struct {
 		name         string
 		args         args
 		mockBehavior mockBehavior
-		want         int64
 		wantErr      error
 	}{
 		{
 			name: "OK",
 			args: args{
-				ctx:      context.Background(),
-				username: TestUsername,
-				password: TestPass,
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				oldPassword: TestPass,
+				newPassword: TestNewPass,
 			},
 			mockBehavior: func(
 				log *loggermocks.Logger,
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
 				ctx context.Context,
-				username string,
-				password string,
+				userId int64,
 			) {
-				crypter.On("GenerateFromPassword", []byte(TestPass), bcrypt.DefaultCost).Return(TestPassHash, nil)
-				userSaver.On("SaveUser", ctx, username, TestPassHash).Return(TestUserId, nil)
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestNewPass)).Return(errors.New(""))
+				passwordPolicy.On("Validate", TestUsername, TestNewPass).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestNewPass)).Return(TestNewPassHash, nil)
+				userSaver.On("UpdatePasswordHash", ctx, userId, TestNewPassHash).Return(nil)
 			},
-			want: TestUserId,
 		},
 		{
-			name: "UsernameTaken",
+			name: "UserNotFound",
 			args: args{
-				ctx:      context.Background(),
-				username: TestUsername,
-				password: TestPass,
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				oldPassword: TestPass,
+				newPassword: TestNewPass,
 			},
 			mockBehavior: func(
 				log *loggermocks.Logger,
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
 				ctx context.Context,
-				username string,
-				password string,
+				userId int64,
 			) {
-				crypter.On("GenerateFromPassword", []byte(TestPass), bcrypt.DefaultCost).Return(TestPassHash, nil)
-				userSaver.On("SaveUser", ctx, username, TestPassHash).Return(EmptyUserId, repository.ErrUserAlredyExists)
+				userProvider.On("GetUserById", ctx, userId).Return(EmptyUser, repository.ErrUserNotFound)
 				log.On("Warnf", mock.Anything, mock.Anything)
 			},
-			wantErr: ErrUserAlreadyExists,
+			wantErr: ErrInvalidCredentials,
 		},
 		{
-			name: "GenPassError",
+			name: "UserInactive",
 			args: args{
-				ctx:      context.Background(),
-				username: TestUsername,
-				password: TestWrongPassword,
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				oldPassword: TestPass,
+				newPassword: TestNewPass,
 			},
 			mockBehavior: func(
 				log *loggermocks.Logger,
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
 				ctx context.Context,
-				username string,
-				password string,
+				userId int64,
 			) {
-				crypter.On("GenerateFromPassword", []byte(TestWrongPassword), bcrypt.DefaultCost).Return(nil, errors.New(""))
-				log.On("Errorf", mock.Anything, mock.Anything)
+				userProvider.On("GetUserById", ctx, userId).Return(TestInactiveUser, nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
 			},
-			wantErr: errors.New(""),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "WrongOldPassword",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				oldPassword: TestPass,
+				newPassword: TestNewPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(errors.New(""))
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "SamePassword",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				oldPassword: TestPass,
+				newPassword: TestNewPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestNewPass)).Return(nil)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrSamePassword,
+		},
+		{
+			name: "PasswordPolicyViolation",
+			args: args{
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				oldPassword: TestPass,
+				newPassword: TestWeakPass,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
+				ctx context.Context,
+				userId int64,
+			) {
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestWeakPass)).Return(errors.New(""))
+				passwordPolicy.On("Validate", TestUsername, TestWeakPass).Return(TestPolicyViolations)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: &ValidationError{Violations: []FieldViolation{{Field: "new_password", Description: TestPolicyViolations[0]}}},
 		},
 		{
 			name: "InternalError",
 			args: args{
-				ctx:      context.Background(),
-				username: TestUsername,
-				password: TestPass,
+				ctx:         context.Background(),
+				userId:      TestUserId,
+				oldPassword: TestPass,
+				newPassword: TestNewPass,
 			},
 			mockBehavior: func(
 				log *loggermocks.Logger,
 				userSaver *mocks.UserSaver,
 				userProvider *mocks.UserProvider,
 				crypter *mocks.Crypter,
+				passwordPolicy *mocks.PasswordPolicy,
 				ctx context.Context,
-				username string,
-				password string,
+				userId int64,
 			) {
-				crypter.On("GenerateFromPassword", []byte(TestPass), bcrypt.DefaultCost).Return(TestPassHash, nil)
-				userSaver.On("SaveUser", ctx, username, TestPassHash).Return(EmptyUserId, errors.New(""))
+				userProvider.On("GetUserById", ctx, userId).Return(TestUser, nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestPass)).Return(nil)
+				crypter.On("CompareHashAndPassword", TestUser.PasswordHash, []byte(TestNewPass)).Return(errors.New(""))
+				passwordPolicy.On("Validate", TestUsername, TestNewPass).Return([]string(nil))
+				crypter.On("GenerateFromPassword", []byte(TestNewPass)).Return(TestNewPassHash, nil)
+				userSaver.On("UpdatePasswordHash", ctx, userId, TestNewPassHash).Return(errors.New(""))
 				log.On("Errorf", mock.Anything, mock.Anything)
 			},
 			wantErr: errors.New(""),
@@ -291,28 +1370,29 @@ func TestUsers_RegisterNewUser(t *testing.T) {
 			userProvider := mocks.NewUserProvider(t)
 			log := loggermocks.NewLogger(t)
 			crypter := mocks.NewCrypter(t)
+			passwordPolicy := mocks.NewPasswordPolicy(t)
 
-			tt.mockBehavior(log, userSaver, userProvider, crypter, tt.args.ctx, tt.args.username, tt.args.password)
+			tt.mockBehavior(log, userSaver, userProvider, crypter, passwordPolicy, tt.args.ctx, tt.args.userId)
 			u := &Users{
-				userSaver:    userSaver,
-				log:          log,
-				userProvider: userProvider,
-				crypter:      crypter,
+				userSaver:      userSaver,
+				log:            log,
+				userProvider:   userProvider,
+				crypter:        crypter,
+				passwordPolicy: passwordPolicy,
 			}
-			got, err := u.RegisterNewUser(tt.args.ctx, tt.args.username, tt.args.password)
+			err := u.ChangePassword(tt.args.ctx, tt.args.userId, tt.args.oldPassword, tt.args.newPassword)
 			if (err != nil) != (tt.wantErr != nil) {
-				t.Errorf("users.RegisterNewUser() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("users.ChangePassword() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
 			if tt.wantErr != nil {
 				assert.EqualError(
 					t,
 					err,
-					"users.RegisterNewUser, "+tt.wantErr.Error(),
-					fmt.Sprintf("users.RegisterNewUser() error = %v, wantErr %v", err, tt.wantErr),
+					"users.ChangePassword, "+tt.wantErr.Error(),
+					fmt.Sprintf("users.ChangePassword() error = %v, wantErr %v", err, tt.wantErr),
 				)
 			}
-			assert.Equal(t, got, tt.want, fmt.Sprintf("users.RegisterNewUser() = %v, want %v", got, tt.want))
 		})
 	}
 }
@@ -443,9 +1523,139 @@ func TestUsers_MakeUserInactive(t *testing.T) {
 	}
 }
 
+func TestUsers_MakeUserActive(t *testing.T) {
+	type mockBehavior func(
+		log *loggermocks.Logger,
+		userSaver *mocks.UserSaver,
+		userProvider *mocks.UserProvider,
+		crypter *mocks.Crypter,
+		ctx context.Context,
+		userId int64,
+	)
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+	tests := []struct {
+		name         string
+		args         args
+		mockBehavior mockBehavior
+		wantErr      error
+	}{
+		{
+			name: "OK",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userSaver.On("SetActive", ctx, userId).Return(nil)
+			},
+		},
+		{
+			name: "WrongUserId",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userSaver.On("SetActive", ctx, userId).Return(repository.ErrUserNotFound)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name: "AlreadyActive",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userSaver.On("SetActive", ctx, userId).Return(repository.ErrUserAlreadyActive)
+				log.On("Warnf", mock.Anything, mock.Anything)
+			},
+			wantErr: ErrUserAlreadyActive,
+		},
+		{
+			name: "InternalError",
+			args: args{
+				ctx:    context.Background(),
+				userId: TestUserId,
+			},
+			mockBehavior: func(
+				log *loggermocks.Logger,
+				userSaver *mocks.UserSaver,
+				userProvider *mocks.UserProvider,
+				crypter *mocks.Crypter,
+				ctx context.Context,
+				userId int64,
+			) {
+				userSaver.On("SetActive", ctx, userId).Return(errors.New(""))
+				log.On("Errorf", mock.Anything, mock.Anything)
+			},
+			wantErr: errors.New(""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userSaver := mocks.NewUserSaver(t)
+			userProvider := mocks.NewUserProvider(t)
+			log := loggermocks.NewLogger(t)
+			crypter := mocks.NewCrypter(t)
+
+			tt.mockBehavior(log, userSaver, userProvider, crypter, tt.args.ctx, tt.args.userId)
+			u := &Users{
+				userSaver:    userSaver,
+				log:          log,
+				userProvider: userProvider,
+				crypter:      crypter,
+			}
+			err := u.MakeUserActive(tt.args.ctx, tt.args.userId)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("users.MakeUserActive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.EqualError(
+					t,
+					err,
+					"users.MakeUserActive, "+tt.wantErr.Error(),
+					fmt.Sprintf("users.MakeUserActive() error = %v, wantErr %v", err, tt.wantErr),
+				)
+			}
+		})
+	}
+}
+
 func generateTestPassHash(pass string) []byte {
-	crypter := crypt.Crypter{}
-	passHash, err := crypter.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	crypter, err := crypt.New(crypt.Params{Algorithm: crypt.AlgorithmBcrypt, BcryptCost: bcrypt.DefaultCost})
+	if err != nil {
+		panic(err)
+	}
+
+	passHash, err := crypter.GenerateFromPassword([]byte(pass))
 	if err != nil {
 		panic(err)
 	}
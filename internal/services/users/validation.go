@@ -0,0 +1,79 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PasswordPolicy - интерфейс проверки пароля на соответствие политике паролей.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=PasswordPolicy
+type PasswordPolicy interface {
+	// Validate возвращает список нарушений политики. Пустой список означает, что пароль допустим.
+	Validate(username, password string) []string
+}
+
+// UsernamePolicy - интерфейс нормализации username и проверки его на соответствие правилам.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.52.2 --name=UsernamePolicy
+type UsernamePolicy interface {
+	// Canonical возвращает каноническую форму username, по которой проверяется уникальность и выполняется поиск.
+	Canonical(username string) string
+
+	// Validate возвращает список нарушений правил. Пустой список означает, что username допустим.
+	Validate(username string) []string
+}
+
+// FieldViolation - нарушение правил валидации для одного поля запроса.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError - ошибка валидации входных данных, содержит список нарушений по полям.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Field+" "+v.Description)
+	}
+
+	return fmt.Sprintf("validation failed: %s", strings.Join(descriptions, "; "))
+}
+
+// validatePassword проверяет пароль на соответствие политике паролей.
+// Если пароль недопустим, возвращает *ValidationError с нарушениями для поля field.
+func (u *Users) validatePassword(field, username, password string) error {
+	return newValidationError(fieldViolations(field, u.passwordPolicy.Validate(username, password))...)
+}
+
+// validateCredentials проверяет username и пароль нового пользователя.
+// Если они недопустимы, возвращает *ValidationError с нарушениями для полей username и password.
+func (u *Users) validateCredentials(username, password string) error {
+	violations := fieldViolations("username", u.usernamePolicy.Validate(username))
+	violations = append(violations, fieldViolations("password", u.passwordPolicy.Validate(username, password))...)
+
+	return newValidationError(violations...)
+}
+
+// fieldViolations возвращает нарушения для поля field по их описаниям.
+func fieldViolations(field string, descriptions []string) []FieldViolation {
+	violations := make([]FieldViolation, 0, len(descriptions))
+	for _, d := range descriptions {
+		violations = append(violations, FieldViolation{Field: field, Description: d})
+	}
+
+	return violations
+}
+
+// newValidationError возвращает *ValidationError с указанными нарушениями или nil, если нарушений нет.
+func newValidationError(violations ...FieldViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Violations: violations}
+}
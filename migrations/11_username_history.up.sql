@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS username_history
+(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    username_canonical TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- До этого момента прежний username может занять только его бывший владелец.
+    reserved_until TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_user_id ON username_history (user_id);
+CREATE INDEX IF NOT EXISTS idx_username_history_canonical ON username_history (username_canonical, reserved_until);
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS delete_after TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_delete_after ON users (delete_after) WHERE delete_after IS NOT NULL;
+
+-- Журнал удаления пользователей. Не ссылается на users, чтобы пережить удаление строки пользователя,
+-- и не содержит персональных данных.
+CREATE TABLE IF NOT EXISTS erasure_audit
+(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    delete_after TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_erasure_audit_user_id ON erasure_audit (user_id);
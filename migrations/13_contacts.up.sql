@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS contact_requests
+(
+    from_user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    to_user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (from_user_id, to_user_id),
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_requests_to_user_id ON contact_requests (to_user_id);
+
+-- Каждая пара контактов хранится двумя строками, по одной для каждого пользователя,
+-- чтобы список контактов выбирался по первичному ключу.
+CREATE TABLE IF NOT EXISTS contacts
+(
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    contact_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, contact_id),
+    CHECK (user_id <> contact_id)
+);
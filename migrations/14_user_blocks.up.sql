@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_blocks
+(
+    blocker_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+-- Обратный составной индекс нужен для поиска блокировок пользователя со стороны заблокированного
+-- и для каскадного удаления.
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked_id_blocker_id ON user_blocks (blocked_id, blocker_id);
@@ -0,0 +1,9 @@
+-- Строка появляется при первом изменении настроек, до этого действуют настройки по умолчанию.
+CREATE TABLE IF NOT EXISTS user_privacy_settings
+(
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    last_seen TEXT NOT NULL DEFAULT 'everyone' CHECK (last_seen IN ('everyone', 'contacts', 'nobody')),
+    avatar TEXT NOT NULL DEFAULT 'everyone' CHECK (avatar IN ('everyone', 'contacts', 'nobody')),
+    search TEXT NOT NULL DEFAULT 'everyone' CHECK (search IN ('everyone', 'contacts', 'nobody')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
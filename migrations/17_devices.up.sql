@@ -0,0 +1,13 @@
+-- Push токен принадлежит одному устройству, поэтому уникален среди всех пользователей.
+CREATE TABLE IF NOT EXISTS devices
+(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    platform TEXT NOT NULL CHECK (platform IN ('ios', 'android', 'web')),
+    push_token TEXT NOT NULL UNIQUE,
+    app_version TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_active_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_devices_user_id ON devices (user_id);
@@ -0,0 +1,16 @@
+-- gen_random_uuid() встроена в PostgreSQL начиная с 13, на более ранних версиях ее дает pgcrypto.
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- События жизненного цикла пользователей, записанные в одной транзакции с изменением.
+-- Строка удаляется после публикации. Событие может быть опубликовано повторно,
+-- поэтому потребители отбрасывают повторы по idempotency_key.
+-- Внешний ключ на users не задан, чтобы события пережили окончательное удаление пользователя.
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id BIGSERIAL PRIMARY KEY,
+    idempotency_key UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    event_type TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
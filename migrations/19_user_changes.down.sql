@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS user_changes_notify ON user_changes;
+DROP FUNCTION IF EXISTS notify_user_changes();
+DROP TABLE IF EXISTS user_changes;
@@ -0,0 +1,31 @@
+-- Журнал изменений пользователей для потока WatchUsers. Позиция изменения - пара (tx_id, id).
+-- tx_id - транзакция, добавившая изменение. Изменения читаются только из транзакций с tx_id меньше
+-- pg_snapshot_xmin(pg_current_snapshot()): все такие транзакции завершены, поэтому новые изменения
+-- всегда получают позицию после уже прочитанных, и чтение после курсора ничего не пропускает.
+-- xid8 и pg_current_xact_id() требуют PostgreSQL 13 и новее.
+-- Журнал хранит только id пользователя и изменения профиля, записи старше срока хранения удаляются,
+-- а записи окончательно удаленного пользователя - вместе с ним.
+CREATE TABLE IF NOT EXISTS user_changes
+(
+    id BIGSERIAL PRIMARY KEY,
+    tx_id xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    event_type TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_changes_position ON user_changes (tx_id, id);
+CREATE INDEX IF NOT EXISTS idx_user_changes_user_id ON user_changes (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_changes_created_at ON user_changes (created_at);
+
+-- Слушатели канала user_changes получают уведомление при фиксации транзакции, добавившей изменения.
+CREATE OR REPLACE FUNCTION notify_user_changes() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('user_changes', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_changes_notify AFTER INSERT ON user_changes
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_user_changes();
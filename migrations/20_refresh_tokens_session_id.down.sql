@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
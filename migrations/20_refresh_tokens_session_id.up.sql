@@ -0,0 +1,2 @@
+-- Действующие сессии и сессии для очистки выбираются по наличию неистекшего refresh токена.
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id, expires_at);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_login_attempts_last_failure_at;
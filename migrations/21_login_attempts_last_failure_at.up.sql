@@ -0,0 +1,2 @@
+-- Ключи с истекшими окном и блокировкой удаляются фоновой очисткой.
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts (last_failure_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_username_canonical;
+DROP TABLE IF EXISTS username_conflicts;
+ALTER TABLE users DROP COLUMN IF EXISTS username_canonical;
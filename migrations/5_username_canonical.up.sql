@@ -0,0 +1,33 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_canonical TEXT;
+
+-- Предварительная каноническая форма - username без пробелов по краям в нижнем регистре.
+-- Полная форма (NFKC и замена похожих символов) вычисляется политикой username,
+-- migrator пересчитывает ее для всех пользователей после применения миграций.
+UPDATE users SET username_canonical = lower(btrim(username));
+
+-- Пользователи, каноническая форма которых совпала с формой более раннего пользователя.
+-- Такому пользователю назначается служебная форма <каноническая форма>#<id>, недопустимая для username,
+-- поэтому уникальный индекс создается без ошибок, а конфликт остается для ручного разбора.
+CREATE TABLE IF NOT EXISTS username_conflicts
+(
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    username_canonical TEXT NOT NULL,
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+WITH ranked AS (
+    SELECT id, username, username_canonical,
+        row_number() OVER (PARTITION BY username_canonical ORDER BY id) AS n
+    FROM users
+), conflicts AS (
+    INSERT INTO username_conflicts (user_id, username, username_canonical)
+    SELECT id, username, username_canonical FROM ranked WHERE n > 1
+    RETURNING user_id
+)
+UPDATE users SET username_canonical = username_canonical || '#' || id
+WHERE id IN (SELECT user_id FROM conflicts);
+
+ALTER TABLE users ALTER COLUMN username_canonical SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_canonical ON users (username_canonical);
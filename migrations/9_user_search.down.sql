@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_username_canonical_prefix;
+DROP INDEX IF EXISTS idx_users_username_canonical_trgm;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Триграммный индекс для нечеткого поиска по username.
+CREATE INDEX IF NOT EXISTS idx_users_username_canonical_trgm ON users USING gin (username_canonical gin_trgm_ops);
+
+-- Индекс для поиска по префиксу username.
+CREATE INDEX IF NOT EXISTS idx_users_username_canonical_prefix ON users (username_canonical text_pattern_ops);
@@ -0,0 +1,8 @@
+module github.com/al3ksus/messengerprotos
+
+go 1.23
+
+require (
+	google.golang.org/grpc v1.70.0
+	google.golang.org/protobuf v1.36.5
+)